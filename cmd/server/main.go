@@ -9,6 +9,8 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	// Embed the time zone database so user time zones resolve on hosts without zoneinfo
+	_ "time/tzdata"
 
 	"github.com/jayk0001/my-go-next-todo/internal/config"
 	"github.com/jayk0001/my-go-next-todo/internal/database"
@@ -38,7 +40,11 @@ func main() {
 	defer db.Close()
 
 	// Initialize server
-	srv := server.New(cfg, db)
+	srv, err := server.New(cfg, db)
+	if err != nil {
+		log.Fatalf("Failed to initialize server: %v", err)
+	}
+	defer srv.Close()
 
 	// Create HTTP server with timeouts
 	httpServer := &http.Server{
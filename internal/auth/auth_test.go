@@ -69,20 +69,6 @@ func TestJWTService(t *testing.T) {
 		t.Errorf("Expected Email %s, got %s", email, claims.Email)
 	}
 
-	// Test refresh token
-	refreshToken, err := jwtService.GenerateRefreshToken(userID)
-	if err != nil {
-		t.Fatalf("Failed to generate refresh token: %v", err)
-	}
-
-	extractedUserID, err := jwtService.ValidateRefreshToken(refreshToken)
-	if err != nil {
-		t.Fatalf("Failed to validate refresh token: %v", err)
-	}
-
-	if extractedUserID != userID {
-		t.Errorf("Expected Subject: %d, got %s", TestData.userID, claims.Subject)
-	}
 }
 
 // TestValidatorService tests input validation
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DataExportTokenExpiry is how long a data export download link stays valid
+const DataExportTokenExpiry = 15 * time.Minute
+
+var ErrDataExportTokenInvalid = errors.New("invalid or expired data export token")
+
+// DataExportToken is the stored record of a data export download token
+type DataExportToken struct {
+	ID        int        `db:"id" json:"id"`
+	UserID    int        `db:"user_id" json:"user_id"`
+	TokenHash string     `db:"token_hash" json:"-"`
+	ExpiresAt time.Time  `db:"expires_at" json:"expires_at"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	UsedAt    *time.Time `db:"used_at" json:"used_at,omitempty"`
+}
+
+// DataExportGrant lets the holder download the user's data export once, without other credentials
+type DataExportGrant struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// DataExportTokenRepositoryInterface defines data export token persistence used by AuthService
+type DataExportTokenRepositoryInterface interface {
+	Create(ctx context.Context, token *DataExportToken) error
+	Consume(ctx context.Context, tokenHash string) (*DataExportToken, error)
+}
+
+// DataExportTokenRepository handles data export token database operations
+type DataExportTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewDataExportTokenRepository creates a new data export token repository
+func NewDataExportTokenRepository(db *pgxpool.Pool) *DataExportTokenRepository {
+	return &DataExportTokenRepository{
+		db: db,
+	}
+}
+
+// Create stores a newly issued export token, filling in its ID and creation time
+func (r *DataExportTokenRepository) Create(ctx context.Context, token *DataExportToken) error {
+	query := `
+		INSERT INTO data_export_tokens (user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, NOW())
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRow(ctx, query, token.UserID, token.TokenHash, token.ExpiresAt).Scan(&token.ID, &token.CreatedAt)
+}
+
+// Consume marks an unused, unexpired token as used and returns it
+func (r *DataExportTokenRepository) Consume(ctx context.Context, tokenHash string) (*DataExportToken, error) {
+	query := `
+		UPDATE data_export_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, user_id, token_hash, expires_at, created_at, used_at
+	`
+
+	var token DataExportToken
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+		&token.UsedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDataExportTokenInvalid
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// RequestDataExport issues a short-lived, single-use token for downloading the user's data export.
+// Browsers can follow a link carrying it without sending the access token.
+func (s *AuthService) RequestDataExport(ctx context.Context, userID int) (*DataExportGrant, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request data export: %w", err)
+	}
+
+	token, hash, err := generateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate data export token: %w", err)
+	}
+
+	record := &DataExportToken{
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(DataExportTokenExpiry),
+	}
+	if err := s.dataExportRepo.Create(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to store data export token: %w", err)
+	}
+
+	return &DataExportGrant{
+		Token:     token,
+		ExpiresAt: record.ExpiresAt,
+	}, nil
+}
+
+// RedeemDataExportToken uses up a token from RequestDataExport and returns the user whose data it exports
+func (s *AuthService) RedeemDataExportToken(ctx context.Context, token string) (*User, error) {
+	record, err := s.dataExportRepo.Consume(ctx, hashToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("data export failed: %w", err)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, record.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("data export failed: %w", err)
+	}
+
+	if user.IsDisabled() {
+		return nil, fmt.Errorf("data export failed: %w", ErrAccountDisabled)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// MockDataExportTokenRepository implements DataExportTokenRepositoryInterface in memory for testing
+type MockDataExportTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[int]*DataExportToken
+	nextID int
+}
+
+// NewMockDataExportTokenRepository creates a new mock data export token repository
+func NewMockDataExportTokenRepository() *MockDataExportTokenRepository {
+	return &MockDataExportTokenRepository{
+		tokens: make(map[int]*DataExportToken),
+		nextID: 1,
+	}
+}
+
+func (m *MockDataExportTokenRepository) Create(ctx context.Context, token *DataExportToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token.ID = m.nextID
+	token.CreatedAt = time.Now()
+	m.nextID++
+
+	copied := *token
+	m.tokens[token.ID] = &copied
+	return nil
+}
+
+func (m *MockDataExportTokenRepository) Consume(ctx context.Context, tokenHash string) (*DataExportToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, token := range m.tokens {
+		if token.TokenHash == tokenHash && token.UsedAt == nil && time.Now().Before(token.ExpiresAt) {
+			now := time.Now()
+			token.UsedAt = &now
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, ErrDataExportTokenInvalid
+}
+
+// expireAll moves the expiry of every stored token into the past
+func (m *MockDataExportTokenRepository) expireAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, token := range m.tokens {
+		token.ExpiresAt = time.Now().Add(-time.Minute)
+	}
+}
+
+// TestRequestDataExport tests issuing and redeeming data export tokens
+func TestRequestDataExport(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("token is stored hashed and works once", func(t *testing.T) {
+		authService, _ := createTestAuthServiceWithMock()
+		result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+
+		grant, err := authService.RequestDataExport(ctx, result.User.ID)
+		if err != nil {
+			t.Fatalf("RequestDataExport failed: %v", err)
+		}
+
+		if grant.Token == "" {
+			t.Fatal("Expected a token")
+		}
+		if until := time.Until(grant.ExpiresAt); until <= 0 || until > DataExportTokenExpiry {
+			t.Errorf("Expected expiry within %v, got %v", DataExportTokenExpiry, until)
+		}
+
+		repo := authService.dataExportRepo.(*MockDataExportTokenRepository)
+		for _, stored := range repo.tokens {
+			if stored.TokenHash == grant.Token {
+				t.Error("Expected the token to be stored hashed")
+			}
+		}
+
+		user, err := authService.RedeemDataExportToken(ctx, grant.Token)
+		if err != nil {
+			t.Fatalf("RedeemDataExportToken failed: %v", err)
+		}
+		if user.ID != result.User.ID {
+			t.Errorf("Expected user %d, got %d", result.User.ID, user.ID)
+		}
+
+		if _, err := authService.RedeemDataExportToken(ctx, grant.Token); !errors.Is(err, ErrDataExportTokenInvalid) {
+			t.Errorf("Expected ErrDataExportTokenInvalid on reuse, got %v", err)
+		}
+	})
+
+	t.Run("expired token is rejected", func(t *testing.T) {
+		authService, _ := createTestAuthServiceWithMock()
+		result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+
+		grant, err := authService.RequestDataExport(ctx, result.User.ID)
+		if err != nil {
+			t.Fatalf("RequestDataExport failed: %v", err)
+		}
+		authService.dataExportRepo.(*MockDataExportTokenRepository).expireAll()
+
+		if _, err := authService.RedeemDataExportToken(ctx, grant.Token); !errors.Is(err, ErrDataExportTokenInvalid) {
+			t.Errorf("Expected ErrDataExportTokenInvalid, got %v", err)
+		}
+	})
+
+	t.Run("disabled account cannot download", func(t *testing.T) {
+		authService, mockRepo := createTestAuthServiceWithMock()
+		result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+
+		grant, err := authService.RequestDataExport(ctx, result.User.ID)
+		if err != nil {
+			t.Fatalf("RequestDataExport failed: %v", err)
+		}
+
+		if err := mockRepo.SetDisabled(ctx, result.User.ID, true); err != nil {
+			t.Fatalf("SetDisabled failed: %v", err)
+		}
+
+		if _, err := authService.RedeemDataExportToken(ctx, grant.Token); !errors.Is(err, ErrAccountDisabled) {
+			t.Errorf("Expected ErrAccountDisabled, got %v", err)
+		}
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		authService, _ := createTestAuthServiceWithMock()
+
+		if _, err := authService.RequestDataExport(ctx, 999); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("Expected ErrUserNotFound, got %v", err)
+		}
+	})
+}
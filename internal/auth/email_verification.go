@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// EmailVerificationTokenExpiry is how long an email verification link stays valid
+const EmailVerificationTokenExpiry = 24 * time.Hour
+
+var (
+	ErrEmailNotVerified         = errors.New("email address has not been verified")
+	ErrVerificationTokenInvalid = errors.New("invalid or expired email verification token")
+)
+
+// UnverifiedPolicy decides what users who have not verified their email address may do
+type UnverifiedPolicy string
+
+const (
+	// UnverifiedAllow treats unverified users like verified ones
+	UnverifiedAllow UnverifiedPolicy = "allow"
+	// UnverifiedReadOnly lets unverified users sign in but not change data
+	UnverifiedReadOnly UnverifiedPolicy = "read_only"
+	// UnverifiedBlock keeps unverified users from signing in
+	UnverifiedBlock UnverifiedPolicy = "block"
+)
+
+// EmailVerificationToken is the stored record of an email verification token.
+// It verifies one specific address, so a token stops working if the user's email changes.
+type EmailVerificationToken struct {
+	ID        int        `db:"id" json:"id"`
+	UserID    int        `db:"user_id" json:"user_id"`
+	Email     string     `db:"email" json:"email"`
+	TokenHash string     `db:"token_hash" json:"-"`
+	ExpiresAt time.Time  `db:"expires_at" json:"expires_at"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	UsedAt    *time.Time `db:"used_at" json:"used_at,omitempty"`
+}
+
+// EmailVerificationRepositoryInterface defines email verification token persistence used by AuthService
+type EmailVerificationRepositoryInterface interface {
+	Create(ctx context.Context, token *EmailVerificationToken) error
+	Consume(ctx context.Context, tokenHash string) (*EmailVerificationToken, error)
+	InvalidateForUser(ctx context.Context, userID int) error
+}
+
+// EmailVerificationRepository handles email verification token database operations
+type EmailVerificationRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewEmailVerificationRepository creates a new email verification token repository
+func NewEmailVerificationRepository(db *pgxpool.Pool) *EmailVerificationRepository {
+	return &EmailVerificationRepository{
+		db: db,
+	}
+}
+
+// Create stores a newly issued verification token, filling in its ID and creation time
+func (r *EmailVerificationRepository) Create(ctx context.Context, token *EmailVerificationToken) error {
+	query := `
+		INSERT INTO email_verification_tokens (user_id, email, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRow(ctx, query, token.UserID, token.Email, token.TokenHash, token.ExpiresAt).Scan(&token.ID, &token.CreatedAt)
+}
+
+// Consume marks an unused, unexpired token as used and returns it
+func (r *EmailVerificationRepository) Consume(ctx context.Context, tokenHash string) (*EmailVerificationToken, error) {
+	query := `
+		UPDATE email_verification_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, user_id, email, token_hash, expires_at, created_at, used_at
+	`
+
+	var token EmailVerificationToken
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Email,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+		&token.UsedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVerificationTokenInvalid
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// InvalidateForUser marks every outstanding verification token of the user as used
+func (r *EmailVerificationRepository) InvalidateForUser(ctx context.Context, userID int) error {
+	query := `UPDATE email_verification_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`
+
+	_, err := r.db.Exec(ctx, query, userID)
+	return err
+}
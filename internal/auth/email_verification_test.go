@@ -0,0 +1,251 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+const verifySubject = "Verify your email address"
+
+// MockEmailVerificationRepository implements EmailVerificationRepositoryInterface in memory for testing
+type MockEmailVerificationRepository struct {
+	mu     sync.Mutex
+	tokens map[int]*EmailVerificationToken
+	nextID int
+}
+
+// NewMockEmailVerificationRepository creates a new mock email verification repository
+func NewMockEmailVerificationRepository() *MockEmailVerificationRepository {
+	return &MockEmailVerificationRepository{
+		tokens: make(map[int]*EmailVerificationToken),
+		nextID: 1,
+	}
+}
+
+func (m *MockEmailVerificationRepository) Create(ctx context.Context, token *EmailVerificationToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token.ID = m.nextID
+	token.CreatedAt = time.Now()
+	m.nextID++
+
+	copied := *token
+	m.tokens[token.ID] = &copied
+	return nil
+}
+
+func (m *MockEmailVerificationRepository) Consume(ctx context.Context, tokenHash string) (*EmailVerificationToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, token := range m.tokens {
+		if token.TokenHash == tokenHash && token.UsedAt == nil && time.Now().Before(token.ExpiresAt) {
+			now := time.Now()
+			token.UsedAt = &now
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, ErrVerificationTokenInvalid
+}
+
+func (m *MockEmailVerificationRepository) InvalidateForUser(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.UsedAt == nil {
+			token.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+// TestRegisterSendsVerificationEmail tests that new accounts start unverified and get a verification link
+func TestRegisterSendsVerificationEmail(t *testing.T) {
+	authService, _, mailer := createTestAuthServiceWithMailer()
+	ctx := context.Background()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	if result.User.IsEmailVerified() {
+		t.Error("New users should not be verified")
+	}
+	if result.User.ToGraphQLUser().EmailVerified {
+		t.Error("GraphQL user should report emailVerified false")
+	}
+
+	msg := waitForMail(t, mailer, verifySubject, 1)
+	if msg.To != serviceTestData.testEmail {
+		t.Errorf("Expected mail to %s, got %s", serviceTestData.testEmail, msg.To)
+	}
+
+	token := tokenFromLink(t, msg.Body, "https://app.example.com/verify-email")
+	stored := authService.verificationRepo.(*MockEmailVerificationRepository).tokens[1]
+	if stored.TokenHash != hashToken(token) || stored.Email != serviceTestData.testEmail {
+		t.Errorf("Unexpected stored token: %+v", stored)
+	}
+}
+
+// TestVerifyEmail tests that the emailed token verifies the address exactly once
+func TestVerifyEmail(t *testing.T) {
+	authService, _, mailer := createTestAuthServiceWithMailer()
+	ctx := context.Background()
+
+	if _, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword); err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+	token := tokenFromLink(t, waitForMail(t, mailer, verifySubject, 1).Body, "/verify-email")
+
+	user, err := authService.VerifyEmail(ctx, token)
+	if err != nil {
+		t.Fatalf("VerifyEmail should succeed: %v", err)
+	}
+	if !user.IsEmailVerified() || !user.ToGraphQLUser().EmailVerified {
+		t.Error("User should be verified")
+	}
+
+	if _, err := authService.VerifyEmail(ctx, token); !errors.Is(err, ErrVerificationTokenInvalid) {
+		t.Errorf("Expected ErrVerificationTokenInvalid on reuse, got: %v", err)
+	}
+
+	// Verified users don't get more links
+	if err := authService.ResendVerification(ctx, user.ID); err != nil {
+		t.Fatalf("ResendVerification should succeed: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if count := countMail(mailer, verifySubject); count != 1 {
+		t.Errorf("Expected no new verification mail, got %d in total", count)
+	}
+}
+
+// TestVerifyEmailRejectsChangedAddress tests that a token only verifies the address it was sent to
+func TestVerifyEmailRejectsChangedAddress(t *testing.T) {
+	authService, mockRepo, mailer := createTestAuthServiceWithMailer()
+	ctx := context.Background()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+	token := tokenFromLink(t, waitForMail(t, mailer, verifySubject, 1).Body, "/verify-email")
+
+	mockRepo.users[result.User.ID].Email = "changed@example.com"
+
+	if _, err := authService.VerifyEmail(ctx, token); !errors.Is(err, ErrVerificationTokenInvalid) {
+		t.Errorf("Expected ErrVerificationTokenInvalid for a changed address, got: %v", err)
+	}
+	if mockRepo.users[result.User.ID].IsEmailVerified() {
+		t.Error("Changed address should not be verified")
+	}
+}
+
+// TestResendVerification tests that resending replaces the previous link
+func TestResendVerification(t *testing.T) {
+	authService, _, mailer := createTestAuthServiceWithMailer()
+	ctx := context.Background()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+	first := tokenFromLink(t, waitForMail(t, mailer, verifySubject, 1).Body, "/verify-email")
+
+	if err := authService.ResendVerification(ctx, result.User.ID); err != nil {
+		t.Fatalf("ResendVerification should succeed: %v", err)
+	}
+	second := tokenFromLink(t, waitForMail(t, mailer, verifySubject, 2).Body, "/verify-email")
+
+	if _, err := authService.VerifyEmail(ctx, first); !errors.Is(err, ErrVerificationTokenInvalid) {
+		t.Errorf("Expected ErrVerificationTokenInvalid for a superseded token, got: %v", err)
+	}
+	if _, err := authService.VerifyEmail(ctx, second); err != nil {
+		t.Errorf("Latest token should verify: %v", err)
+	}
+}
+
+// TestResendVerificationByEmail tests resending without signing in
+func TestResendVerificationByEmail(t *testing.T) {
+	authService, _, mailer := createTestAuthServiceWithMailer()
+	ctx := context.Background()
+
+	if err := authService.ResendVerificationByEmail(ctx, "nobody@example.com"); err != nil {
+		t.Errorf("ResendVerificationByEmail should not reveal unknown emails, got: %v", err)
+	}
+
+	if _, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword); err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	if err := authService.ResendVerificationByEmail(ctx, serviceTestData.testEmail); err != nil {
+		t.Fatalf("ResendVerificationByEmail should succeed: %v", err)
+	}
+	if msg := waitForMail(t, mailer, verifySubject, 2); msg.To != serviceTestData.testEmail {
+		t.Errorf("Expected mail to %s, got %s", serviceTestData.testEmail, msg.To)
+	}
+}
+
+// TestUnverifiedPolicyBlock tests that unverified users cannot sign in when blocked
+func TestUnverifiedPolicyBlock(t *testing.T) {
+	authService, _, mailer := createTestAuthServiceWithMailer()
+	authService.SetUnverifiedPolicy(UnverifiedBlock)
+	ctx := context.Background()
+
+	if _, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword); !errors.Is(err, ErrEmailNotVerified) {
+		t.Fatalf("Expected ErrEmailNotVerified from Register, got: %v", err)
+	}
+
+	if _, err := authService.Login(ctx, serviceTestData.testEmail, serviceTestData.testPassword); !errors.Is(err, ErrEmailNotVerified) {
+		t.Errorf("Expected ErrEmailNotVerified from Login, got: %v", err)
+	}
+
+	token := tokenFromLink(t, waitForMail(t, mailer, verifySubject, 1).Body, "/verify-email")
+	if _, err := authService.VerifyEmail(ctx, token); err != nil {
+		t.Fatalf("VerifyEmail should succeed: %v", err)
+	}
+
+	if _, err := authService.Login(ctx, serviceTestData.testEmail, serviceTestData.testPassword); err != nil {
+		t.Errorf("Login should succeed once verified: %v", err)
+	}
+}
+
+// TestUnverifiedPolicyReadOnly tests that unverified users sign in as read-only principals
+func TestUnverifiedPolicyReadOnly(t *testing.T) {
+	authService, _, mailer := createTestAuthServiceWithMailer()
+	authService.SetUnverifiedPolicy(UnverifiedReadOnly)
+	ctx := context.Background()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	principal, err := authService.AuthenticateToken(ctx, result.Token)
+	if err != nil {
+		t.Fatalf("AuthenticateToken should succeed: %v", err)
+	}
+	if !principal.ReadOnly {
+		t.Error("Unverified principal should be read-only")
+	}
+
+	token := tokenFromLink(t, waitForMail(t, mailer, verifySubject, 1).Body, "/verify-email")
+	if _, err := authService.VerifyEmail(ctx, token); err != nil {
+		t.Fatalf("VerifyEmail should succeed: %v", err)
+	}
+
+	principal, err = authService.AuthenticateToken(ctx, result.Token)
+	if err != nil {
+		t.Fatalf("AuthenticateToken should succeed: %v", err)
+	}
+	if principal.ReadOnly {
+		t.Error("Verified principal should not be read-only")
+	}
+}
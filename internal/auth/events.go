@@ -0,0 +1,24 @@
+package auth
+
+import "time"
+
+// StatusChannel is the Postgres NOTIFY channel used to fan auth status events out across instances
+const StatusChannel = "auth_status"
+
+// StatusReason identifies why a user's credentials stopped being valid
+type StatusReason string
+
+const (
+	StatusSessionRevoked  StatusReason = "SESSION_REVOKED"
+	StatusPasswordChanged StatusReason = "PASSWORD_CHANGED"
+	StatusAccountDisabled StatusReason = "ACCOUNT_DISABLED"
+	StatusAccountDeleted  StatusReason = "ACCOUNT_DELETED"
+)
+
+// StatusEvent tells a user's open clients to drop their credentials
+type StatusEvent struct {
+	Reason     StatusReason `json:"reason"`
+	UserID     int          `json:"user_id"`
+	SessionID  string       `json:"session_id,omitempty"` // empty when every session is affected
+	OccurredAt time.Time    `json:"occurred_at"`
+}
@@ -0,0 +1,336 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// OIDCLoginStateExpiry is how long a user has to finish signing in at the provider
+const OIDCLoginStateExpiry = 10 * time.Minute
+
+var (
+	ErrIdentityNotFound       = errors.New("identity not found")
+	ErrIdentityAlreadyLinked  = errors.New("identity is already linked to an account")
+	ErrIdentityLinkUnverified = errors.New("an account with this email address exists but is not verified, sign in with its password first")
+)
+
+// UserIdentity links a user to their account at an identity provider
+type UserIdentity struct {
+	ID       int    `db:"id" json:"id"`
+	UserID   int    `db:"user_id" json:"user_id"`
+	Provider string `db:"provider" json:"provider"`
+	// Subject is the provider's stable ID for the account (the ID token's sub claim)
+	Subject string `db:"subject" json:"subject"`
+	// Email is the address the provider reported when the identity was linked
+	Email       string     `db:"email" json:"email"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	LastLoginAt *time.Time `db:"last_login_at" json:"last_login_at,omitempty"`
+}
+
+// OIDCLoginState ties a provider callback to the sign-in that started it
+type OIDCLoginState struct {
+	ID        int    `db:"id" json:"id"`
+	Provider  string `db:"provider" json:"provider"`
+	StateHash string `db:"state_hash" json:"-"`
+	// CodeVerifier is the PKCE secret; it is useless without the code the provider returns
+	CodeVerifier string    `db:"code_verifier" json:"-"`
+	Nonce        string    `db:"nonce" json:"-"`
+	ExpiresAt    time.Time `db:"expires_at" json:"expires_at"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// IdentityRepositoryInterface defines linked identity persistence used by AuthService
+type IdentityRepositoryInterface interface {
+	Create(ctx context.Context, identity *UserIdentity) error
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*UserIdentity, error)
+	Touch(ctx context.Context, id int) error
+}
+
+// OIDCLoginStateRepositoryInterface defines sign-in state persistence used by AuthService
+type OIDCLoginStateRepositoryInterface interface {
+	Create(ctx context.Context, state *OIDCLoginState) error
+	Consume(ctx context.Context, provider, stateHash string) (*OIDCLoginState, error)
+}
+
+// IdentityRepository handles linked identity database operations
+type IdentityRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewIdentityRepository creates a new identity repository
+func NewIdentityRepository(db *pgxpool.Pool) *IdentityRepository {
+	return &IdentityRepository{
+		db: db,
+	}
+}
+
+// Create links an identity to its user, filling in its ID and creation time
+func (r *IdentityRepository) Create(ctx context.Context, identity *UserIdentity) error {
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email, created_at, last_login_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		RETURNING id, created_at, last_login_at
+	`
+
+	err := r.db.QueryRow(ctx, query, identity.UserID, identity.Provider, identity.Subject, identity.Email).Scan(
+		&identity.ID,
+		&identity.CreatedAt,
+		&identity.LastLoginAt,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return ErrIdentityAlreadyLinked
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetByProviderSubject retrieves the identity for a provider account
+func (r *IdentityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*UserIdentity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at, last_login_at
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2
+	`
+
+	var identity UserIdentity
+	err := r.db.QueryRow(ctx, query, provider, subject).Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Provider,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+		&identity.LastLoginAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrIdentityNotFound
+		}
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+// Touch records a sign-in through the identity
+func (r *IdentityRepository) Touch(ctx context.Context, id int) error {
+	query := `UPDATE user_identities SET last_login_at = NOW() WHERE id = $1`
+
+	_, err := r.db.Exec(ctx, query, id)
+
+	return err
+}
+
+// OIDCLoginStateRepository handles sign-in state database operations
+type OIDCLoginStateRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewOIDCLoginStateRepository creates a new sign-in state repository
+func NewOIDCLoginStateRepository(db *pgxpool.Pool) *OIDCLoginStateRepository {
+	return &OIDCLoginStateRepository{
+		db: db,
+	}
+}
+
+// Create stores the state of a sign-in that was just started, filling in its ID and creation time
+func (r *OIDCLoginStateRepository) Create(ctx context.Context, state *OIDCLoginState) error {
+	query := `
+		INSERT INTO oidc_login_states (provider, state_hash, code_verifier, nonce, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRow(ctx, query, state.Provider, state.StateHash, state.CodeVerifier, state.Nonce, state.ExpiresAt).Scan(&state.ID, &state.CreatedAt)
+}
+
+// Consume deletes an unexpired state of the provider and returns it, so each callback is handled once.
+// Expired states of any provider are cleaned up along the way.
+func (r *OIDCLoginStateRepository) Consume(ctx context.Context, provider, stateHash string) (*OIDCLoginState, error) {
+	if _, err := r.db.Exec(ctx, `DELETE FROM oidc_login_states WHERE expires_at <= NOW()`); err != nil {
+		return nil, err
+	}
+
+	query := `
+		DELETE FROM oidc_login_states
+		WHERE provider = $1 AND state_hash = $2 AND expires_at > NOW()
+		RETURNING id, provider, state_hash, code_verifier, nonce, expires_at, created_at
+	`
+
+	var state OIDCLoginState
+	err := r.db.QueryRow(ctx, query, provider, stateHash).Scan(
+		&state.ID,
+		&state.Provider,
+		&state.StateHash,
+		&state.CodeVerifier,
+		&state.Nonce,
+		&state.ExpiresAt,
+		&state.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOIDCStateInvalid
+		}
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// SetOIDCProviders replaces the identity providers users can sign in with
+func (s *AuthService) SetOIDCProviders(providers ...*OIDCProvider) {
+	s.oidcProviders = make(map[string]*OIDCProvider, len(providers))
+	for _, provider := range providers {
+		s.oidcProviders[provider.Name()] = provider
+	}
+}
+
+// StartOIDCLogin begins signing in through a provider. It returns the provider URL to send the
+// user to and the state the callback will carry, which the caller should bind to the browser
+// (e.g. in a cookie) so a callback started by someone else is rejected.
+func (s *AuthService) StartOIDCLogin(ctx context.Context, providerName string) (string, string, error) {
+	provider, ok := s.oidcProviders[providerName]
+	if !ok {
+		return "", "", ErrOIDCProviderUnknown
+	}
+
+	state, stateHash, err := generateOpaqueToken()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate sign-in state: %w", err)
+	}
+	nonce, _, err := generateOpaqueToken()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate sign-in nonce: %w", err)
+	}
+	codeVerifier, _, err := generateOpaqueToken()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		return "", "", err
+	}
+
+	record := &OIDCLoginState{
+		Provider:     provider.Name(),
+		StateHash:    stateHash,
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(OIDCLoginStateExpiry),
+	}
+	if err := s.oidcStateRepo.Create(ctx, record); err != nil {
+		return "", "", fmt.Errorf("failed to store sign-in state: %w", err)
+	}
+
+	return authURL, state, nil
+}
+
+// CompleteOIDCLogin handles the provider's callback, signing in the user linked to the provider
+// account. An unknown provider account is linked to the user with the same email address, or a
+// new user without a password is created; both need an email address the provider has verified.
+// Like Login, it returns a *TwoFactorRequiredError when the user has two-factor enabled.
+func (s *AuthService) CompleteOIDCLogin(ctx context.Context, providerName, state, code string) (*AuthResult, error) {
+	provider, ok := s.oidcProviders[providerName]
+	if !ok {
+		return nil, ErrOIDCProviderUnknown
+	}
+
+	record, err := s.oidcStateRepo.Consume(ctx, provider.Name(), hashToken(state))
+	if err != nil {
+		return nil, fmt.Errorf("sign-in failed: %w", err)
+	}
+
+	claims, err := provider.Exchange(ctx, code, record.CodeVerifier, record.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("sign-in failed: %w", err)
+	}
+
+	user, err := s.userForIdentity(ctx, provider.Name(), claims)
+	if err != nil {
+		return nil, fmt.Errorf("sign-in failed: %w", err)
+	}
+
+	if user.IsDisabled() {
+		return nil, fmt.Errorf("sign-in failed: %w", ErrAccountDisabled)
+	}
+
+	if err := s.checkEmailVerified(user); err != nil {
+		return nil, fmt.Errorf("sign-in failed: %w", err)
+	}
+
+	// The provider vouches for the user, but not for our second factor
+	enabled, err := s.twoFactorEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, s.startTwoFactorChallenge(ctx, user.ID)
+	}
+
+	return s.startSession(ctx, user)
+}
+
+// userForIdentity returns the user linked to the provider account in claims, linking or creating one if needed
+func (s *AuthService) userForIdentity(ctx context.Context, providerName string, claims *OIDCClaims) (*User, error) {
+	identity, err := s.identityRepo.GetByProviderSubject(ctx, providerName, claims.Subject)
+	if err == nil {
+		if err := s.identityRepo.Touch(ctx, identity.ID); err != nil {
+			fmt.Printf("AuthService: failed to record use of identity %d: %v\n", identity.ID, err)
+		}
+		return s.userRepo.GetByID(ctx, identity.UserID)
+	}
+	if !errors.Is(err, ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	// Linking by an unverified address would hand the account to whoever typed it in at the provider
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+	if err := s.validatorService.ValidateEmail(claims.Email); err != nil {
+		return nil, fmt.Errorf("invalid email from provider: %w", err)
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, claims.Email)
+	if errors.Is(err, ErrUserNotFound) {
+		user, err = s.userRepo.Create(ctx, CreateUserInput{
+			Email:         claims.Email,
+			EmailVerified: true,
+		})
+		if err == nil {
+			s.userCreated(ctx, user)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Whoever registered an unverified account may not own the address, and linking would
+	// leave their password working on the account of the user signing in now
+	if !user.IsEmailVerified() {
+		return nil, ErrIdentityLinkUnverified
+	}
+
+	if err := s.identityRepo.Create(ctx, &UserIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	return user, nil
+}
@@ -0,0 +1,412 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// MockIdentityRepository implements IdentityRepositoryInterface in memory for testing
+type MockIdentityRepository struct {
+	mu         sync.Mutex
+	identities map[int]*UserIdentity
+	nextID     int
+}
+
+// NewMockIdentityRepository creates a new mock identity repository
+func NewMockIdentityRepository() *MockIdentityRepository {
+	return &MockIdentityRepository{
+		identities: make(map[int]*UserIdentity),
+		nextID:     1,
+	}
+}
+
+func (m *MockIdentityRepository) Create(ctx context.Context, identity *UserIdentity) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.identities {
+		if existing.Provider == identity.Provider && existing.Subject == identity.Subject {
+			return ErrIdentityAlreadyLinked
+		}
+	}
+
+	now := time.Now()
+	identity.ID = m.nextID
+	identity.CreatedAt = now
+	identity.LastLoginAt = &now
+	m.nextID++
+
+	copied := *identity
+	m.identities[identity.ID] = &copied
+	return nil
+}
+
+func (m *MockIdentityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*UserIdentity, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, identity := range m.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			copied := *identity
+			return &copied, nil
+		}
+	}
+	return nil, ErrIdentityNotFound
+}
+
+func (m *MockIdentityRepository) Touch(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if identity, exists := m.identities[id]; exists {
+		now := time.Now()
+		identity.LastLoginAt = &now
+	}
+	return nil
+}
+
+// MockOIDCLoginStateRepository implements OIDCLoginStateRepositoryInterface in memory for testing
+type MockOIDCLoginStateRepository struct {
+	mu     sync.Mutex
+	states map[int]*OIDCLoginState
+	nextID int
+}
+
+// NewMockOIDCLoginStateRepository creates a new mock sign-in state repository
+func NewMockOIDCLoginStateRepository() *MockOIDCLoginStateRepository {
+	return &MockOIDCLoginStateRepository{
+		states: make(map[int]*OIDCLoginState),
+		nextID: 1,
+	}
+}
+
+func (m *MockOIDCLoginStateRepository) Create(ctx context.Context, state *OIDCLoginState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state.ID = m.nextID
+	state.CreatedAt = time.Now()
+	m.nextID++
+
+	copied := *state
+	m.states[state.ID] = &copied
+	return nil
+}
+
+func (m *MockOIDCLoginStateRepository) Consume(ctx context.Context, provider, stateHash string) (*OIDCLoginState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, state := range m.states {
+		if state.Provider == provider && state.StateHash == stateHash && time.Now().Before(state.ExpiresAt) {
+			delete(m.states, id)
+			return state, nil
+		}
+	}
+	return nil, ErrOIDCStateInvalid
+}
+
+// expireAll moves the expiry of every stored state into the past
+func (m *MockOIDCLoginStateRepository) expireAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, state := range m.states {
+		state.ExpiresAt = time.Now().Add(-time.Minute)
+	}
+}
+
+// createTestAuthServiceWithOIDC creates a test auth service that signs in through a stub provider
+func createTestAuthServiceWithOIDC(t *testing.T) (*AuthService, *MockUserRepository, *stubOIDCProvider) {
+	t.Helper()
+
+	stub := newStubOIDCProvider(t)
+	authService, mockRepo := createTestAuthServiceWithMock()
+	authService.SetOIDCProviders(NewOIDCProvider(stub.config(), nil))
+
+	return authService, mockRepo, stub
+}
+
+// oidcLogin signs in through the stub provider like a browser would
+func oidcLogin(t *testing.T, authService *AuthService) (*AuthResult, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	authURL, state, err := authService.StartOIDCLogin(ctx, "stub")
+	if err != nil {
+		t.Fatalf("StartOIDCLogin failed: %v", err)
+	}
+
+	code, returnedState := approve(t, authURL)
+	if returnedState != state {
+		t.Fatalf("Expected the provider to return state %q, got %q", state, returnedState)
+	}
+
+	return authService.CompleteOIDCLogin(ctx, "stub", state, code)
+}
+
+// TestCompleteOIDCLogin tests signing in through an identity provider
+func TestCompleteOIDCLogin(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("new user is created without a password", func(t *testing.T) {
+		authService, mockRepo, _ := createTestAuthServiceWithOIDC(t)
+
+		result, err := oidcLogin(t, authService)
+		if err != nil {
+			t.Fatalf("CompleteOIDCLogin failed: %v", err)
+		}
+
+		if result.Token == "" || result.RefreshToken == "" || result.Session == nil {
+			t.Error("Expected tokens and a session")
+		}
+		if result.User.Email != "oidc@example.com" {
+			t.Errorf("Expected oidc@example.com, got %s", result.User.Email)
+		}
+		if !result.User.IsEmailVerified() {
+			t.Error("Expected the provider-verified email to be marked verified")
+		}
+		if result.User.HasPassword() {
+			t.Error("Expected no password")
+		}
+
+		if _, err := authService.Login(ctx, "oidc@example.com", serviceTestData.testPassword); err == nil {
+			t.Error("Expected password login to fail without a password")
+		}
+		if err := authService.ChangePassword(ctx, result.User.ID, "", "anything", "new-password-456"); !errors.Is(err, ErrPasswordNotSet) {
+			t.Errorf("Expected ErrPasswordNotSet, got %v", err)
+		}
+
+		// Signing in again uses the linked identity
+		again, err := oidcLogin(t, authService)
+		if err != nil {
+			t.Fatalf("Second CompleteOIDCLogin failed: %v", err)
+		}
+		if again.User.ID != result.User.ID {
+			t.Errorf("Expected user %d, got %d", result.User.ID, again.User.ID)
+		}
+		if len(mockRepo.users) != 1 {
+			t.Errorf("Expected one user, got %d", len(mockRepo.users))
+		}
+		if mockRepo.users[result.User.ID].LoginCount != 2 {
+			t.Errorf("Expected 2 logins, got %d", mockRepo.users[result.User.ID].LoginCount)
+		}
+	})
+
+	t.Run("identity is linked by subject, not email", func(t *testing.T) {
+		authService, _, stub := createTestAuthServiceWithOIDC(t)
+
+		first, err := oidcLogin(t, authService)
+		if err != nil {
+			t.Fatalf("CompleteOIDCLogin failed: %v", err)
+		}
+
+		// The provider account changed its address; it still signs in as the same user
+		stub.email = "renamed@example.com"
+		second, err := oidcLogin(t, authService)
+		if err != nil {
+			t.Fatalf("CompleteOIDCLogin failed: %v", err)
+		}
+		if second.User.ID != first.User.ID {
+			t.Errorf("Expected user %d, got %d", first.User.ID, second.User.ID)
+		}
+	})
+
+	t.Run("verified existing account is linked by email", func(t *testing.T) {
+		authService, mockRepo, stub := createTestAuthServiceWithOIDC(t)
+		registered, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+		if err := mockRepo.MarkEmailVerified(ctx, registered.User.ID, serviceTestData.testEmail); err != nil {
+			t.Fatalf("MarkEmailVerified failed: %v", err)
+		}
+
+		stub.email = serviceTestData.testEmail
+		result, err := oidcLogin(t, authService)
+		if err != nil {
+			t.Fatalf("CompleteOIDCLogin failed: %v", err)
+		}
+		if result.User.ID != registered.User.ID {
+			t.Errorf("Expected user %d, got %d", registered.User.ID, result.User.ID)
+		}
+
+		// The password keeps working alongside the provider
+		if _, err := authService.Login(ctx, serviceTestData.testEmail, serviceTestData.testPassword); err != nil {
+			t.Errorf("Expected password login to keep working, got %v", err)
+		}
+	})
+
+	t.Run("unverified existing account is not linked", func(t *testing.T) {
+		authService, _, stub := createTestAuthServiceWithOIDC(t)
+		if _, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword); err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+
+		stub.email = serviceTestData.testEmail
+		if _, err := oidcLogin(t, authService); !errors.Is(err, ErrIdentityLinkUnverified) {
+			t.Errorf("Expected ErrIdentityLinkUnverified, got %v", err)
+		}
+		if len(authService.identityRepo.(*MockIdentityRepository).identities) != 0 {
+			t.Error("Expected no identity to be linked")
+		}
+	})
+
+	t.Run("unverified provider email", func(t *testing.T) {
+		authService, mockRepo, stub := createTestAuthServiceWithOIDC(t)
+		stub.emailVerified = false
+
+		if _, err := oidcLogin(t, authService); !errors.Is(err, ErrOIDCEmailNotVerified) {
+			t.Errorf("Expected ErrOIDCEmailNotVerified, got %v", err)
+		}
+		if len(mockRepo.users) != 0 {
+			t.Error("Expected no user to be created")
+		}
+	})
+
+	t.Run("disabled account", func(t *testing.T) {
+		authService, mockRepo, _ := createTestAuthServiceWithOIDC(t)
+		result, err := oidcLogin(t, authService)
+		if err != nil {
+			t.Fatalf("CompleteOIDCLogin failed: %v", err)
+		}
+		if err := mockRepo.SetDisabled(ctx, result.User.ID, true); err != nil {
+			t.Fatalf("SetDisabled failed: %v", err)
+		}
+
+		if _, err := oidcLogin(t, authService); !errors.Is(err, ErrAccountDisabled) {
+			t.Errorf("Expected ErrAccountDisabled, got %v", err)
+		}
+	})
+
+	t.Run("two-factor is still required", func(t *testing.T) {
+		authService, mockRepo, stub := createTestAuthServiceWithOIDC(t)
+		registered, _, _ := enableTwoFactor(t, authService)
+		if err := mockRepo.MarkEmailVerified(ctx, registered.User.ID, serviceTestData.testEmail); err != nil {
+			t.Fatalf("MarkEmailVerified failed: %v", err)
+		}
+
+		stub.email = serviceTestData.testEmail
+		_, err := oidcLogin(t, authService)
+
+		var required *TwoFactorRequiredError
+		if !errors.As(err, &required) {
+			t.Fatalf("Expected TwoFactorRequiredError, got %v", err)
+		}
+	})
+
+	t.Run("state works once", func(t *testing.T) {
+		authService, _, _ := createTestAuthServiceWithOIDC(t)
+
+		authURL, state, err := authService.StartOIDCLogin(ctx, "stub")
+		if err != nil {
+			t.Fatalf("StartOIDCLogin failed: %v", err)
+		}
+		code, _ := approve(t, authURL)
+
+		if _, err := authService.CompleteOIDCLogin(ctx, "stub", state, code); err != nil {
+			t.Fatalf("CompleteOIDCLogin failed: %v", err)
+		}
+		if _, err := authService.CompleteOIDCLogin(ctx, "stub", state, code); !errors.Is(err, ErrOIDCStateInvalid) {
+			t.Errorf("Expected ErrOIDCStateInvalid on reuse, got %v", err)
+		}
+	})
+
+	t.Run("state is stored hashed and expires", func(t *testing.T) {
+		authService, _, _ := createTestAuthServiceWithOIDC(t)
+
+		authURL, state, err := authService.StartOIDCLogin(ctx, "stub")
+		if err != nil {
+			t.Fatalf("StartOIDCLogin failed: %v", err)
+		}
+		if !strings.Contains(authURL, "state="+state) {
+			t.Error("Expected the state in the provider URL")
+		}
+
+		repo := authService.oidcStateRepo.(*MockOIDCLoginStateRepository)
+		for _, stored := range repo.states {
+			if stored.StateHash != hashToken(state) {
+				t.Error("Expected the state to be stored as its hash")
+			}
+		}
+		repo.expireAll()
+
+		code, _ := approve(t, authURL)
+		if _, err := authService.CompleteOIDCLogin(ctx, "stub", state, code); !errors.Is(err, ErrOIDCStateInvalid) {
+			t.Errorf("Expected ErrOIDCStateInvalid, got %v", err)
+		}
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		authService, _, _ := createTestAuthServiceWithOIDC(t)
+
+		if _, _, err := authService.StartOIDCLogin(ctx, "unknown"); !errors.Is(err, ErrOIDCProviderUnknown) {
+			t.Errorf("Expected ErrOIDCProviderUnknown, got %v", err)
+		}
+		if _, err := authService.CompleteOIDCLogin(ctx, "unknown", "state", "code"); !errors.Is(err, ErrOIDCProviderUnknown) {
+			t.Errorf("Expected ErrOIDCProviderUnknown, got %v", err)
+		}
+	})
+}
+
+// TestPasswordlessReauthentication tests that accounts without a password confirm sensitive
+// changes with a recent sign-in
+func TestPasswordlessReauthentication(t *testing.T) {
+	ctx := context.Background()
+	authService, mockRepo, _ := createTestAuthServiceWithOIDC(t)
+
+	result, err := oidcLogin(t, authService)
+	if err != nil {
+		t.Fatalf("CompleteOIDCLogin failed: %v", err)
+	}
+	userID, sessionID := result.User.ID, result.Session.ID
+
+	// A session that just signed in may make changes without a password
+	if _, err := authService.ChangeEmail(ctx, userID, sessionID, "renamed@example.com", ""); err != nil {
+		t.Errorf("ChangeEmail should succeed after a recent sign-in: %v", err)
+	}
+	if err := authService.DisableTwoFactor(ctx, userID, sessionID, ""); !errors.Is(err, ErrTwoFactorNotEnrolled) {
+		t.Errorf("Expected the sign-in to be accepted and ErrTwoFactorNotEnrolled, got %v", err)
+	}
+
+	// Tokens without a session never count as a recent sign-in
+	if _, err := authService.ChangeEmail(ctx, userID, "", "other@example.com", ""); !errors.Is(err, ErrReauthRequired) {
+		t.Errorf("Expected ErrReauthRequired without a session, got %v", err)
+	}
+
+	// An older session has to sign in again
+	sessionMock(authService).sessions[sessionID].CreatedAt = time.Now().Add(-RecentSignInWindow - time.Minute)
+
+	principal, err := authService.AuthenticateToken(ctx, result.Token)
+	if err != nil {
+		t.Fatalf("AuthenticateToken should succeed: %v", err)
+	}
+	if err := authService.DeleteAccount(ctx, principal, ""); !errors.Is(err, ErrReauthRequired) {
+		t.Errorf("Expected ErrReauthRequired for an old session, got %v", err)
+	}
+	if err := authService.DisableTwoFactor(ctx, userID, sessionID, "anything"); !errors.Is(err, ErrReauthRequired) {
+		t.Errorf("Expected ErrReauthRequired for an old session, got %v", err)
+	}
+	if _, exists := mockRepo.users[userID]; !exists {
+		t.Fatal("User should not be deleted without a recent sign-in")
+	}
+
+	again, err := oidcLogin(t, authService)
+	if err != nil {
+		t.Fatalf("Second CompleteOIDCLogin failed: %v", err)
+	}
+	principal, err = authService.AuthenticateToken(ctx, again.Token)
+	if err != nil {
+		t.Fatalf("AuthenticateToken should succeed: %v", err)
+	}
+	if err := authService.DeleteAccount(ctx, principal, ""); err != nil {
+		t.Fatalf("DeleteAccount should succeed after signing in again: %v", err)
+	}
+	if _, exists := mockRepo.users[userID]; exists {
+		t.Error("User should be deleted")
+	}
+}
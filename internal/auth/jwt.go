@@ -3,7 +3,6 @@ package auth
 import (
 	"errors"
 	"fmt"
-	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -11,8 +10,9 @@ import (
 
 // JWTClaims defines the structure of JWT claims
 type JWTClaims struct {
-	UserID int    `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    int    `json:"user_id"`
+	Email     string `json:"email"`
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -20,6 +20,10 @@ type JWTClaims struct {
 type JWTService struct {
 	secretKey   string
 	expiryHours time.Duration
+	// keys switches access tokens to asymmetric signing when set
+	keys *KeySet
+	// rejectHMAC stops accepting HS256 tokens once keys are set
+	rejectHMAC bool
 }
 
 // NewJWTService creates a new JWT service
@@ -30,30 +34,53 @@ func NewJWTService(secretKey string, expiryHours time.Duration) *JWTService {
 	}
 }
 
+// NewJWTServiceWithKeys creates a JWT service that signs access tokens with an asymmetric key.
+// HS256 tokens signed with secretKey are still accepted so sessions survive the switch.
+func NewJWTServiceWithKeys(secretKey string, expiryHours time.Duration, keys *KeySet) *JWTService {
+	service := NewJWTService(secretKey, expiryHours)
+	service.keys = keys
+
+	return service
+}
+
 // GenerateToken creates a new JWT token for the user
 func (j *JWTService) GenerateToken(userID int, email string) (string, error) {
+	return j.GenerateSessionToken(userID, email, "")
+}
+
+// GenerateSessionToken creates a new JWT token bound to a session
+func (j *JWTService) GenerateSessionToken(userID int, email, sessionID string) (string, error) {
 	now := time.Now()
 	expiresAt := now.Add(j.expiryHours)
 
+	// A unique token ID lets a single token be revoked before it expires
+	tokenID, err := newRandomID()
+	if err != nil {
+		return "", err
+	}
+
 	claims := JWTClaims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		Email:     email,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "todo-app",
 			Subject:   fmt.Sprintf("%d", userID),
+			ID:        tokenID,
 		},
 	}
 
+	if j.keys != nil {
+		return j.keys.sign(claims)
+	}
+
 	// Create token with explicit HMAC signing method
 	token := jwt.New(jwt.SigningMethodHS256)
 	token.Claims = claims
 
-	// Debug: Print signing method
-	fmt.Printf("DEBUG: Using signing method: %v\n", token.Method.Alg())
-
 	// Sign token with byte slice key
 	tokenString, err := token.SignedString([]byte(j.secretKey))
 	if err != nil {
@@ -63,32 +90,19 @@ func (j *JWTService) GenerateToken(userID int, email string) (string, error) {
 	return tokenString, nil
 }
 
-// GenerateRefreshToken creates a refresh token with longer expiry
-func (j *JWTService) GenerateRefreshToken(userID int) (string, error) {
-	now := time.Now()
-	expiresAt := now.Add(24 * 7 * time.Hour) // 7 days
-
-	claims := jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(expiresAt),
-		IssuedAt:  jwt.NewNumericDate(now),
-		NotBefore: jwt.NewNumericDate(now),
-		Issuer:    "todo-app-refresh",
-		Subject:   fmt.Sprintf("%d", userID),
-	}
-
-	// Create token with explicit HMAC signing method
-	token := jwt.New(jwt.SigningMethodHS256)
-	token.Claims = claims
-
-	return token.SignedString([]byte(j.secretKey))
-}
-
 // ValidateToken validates and parses a JWT token
 func (j *JWTService) ValidateToken(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
+		// Asymmetric tokens name their key in the kid header
+		if _, hasKeyID := token.Header["kid"]; hasKeyID && j.keys != nil {
+			return j.keys.verificationKey(token)
+		}
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("invalid signing method")
 		}
+		if j.keys != nil && j.rejectHMAC {
+			return nil, errors.New("HS256 tokens are no longer accepted")
+		}
 		return []byte(j.secretKey), nil
 	})
 
@@ -103,26 +117,10 @@ func (j *JWTService) ValidateToken(tokenString string) (*JWTClaims, error) {
 	return nil, errors.New("invalid token")
 }
 
-// ValidateRefreshToken validates a refresh token
-func (j *JWTService) ValidateRefreshToken(tokenString string) (int, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid signing method")
-		}
-		return []byte(j.secretKey), nil
-	})
-
-	if err != nil {
-		return 0, err
+// JWKS returns the public keys that verify access tokens, empty when tokens are signed with HS256
+func (j *JWTService) JWKS() JWKS {
+	if j.keys == nil {
+		return JWKS{Keys: []JWK{}}
 	}
-
-	if claims, ok := token.Claims.(*jwt.RegisteredClaims); ok && token.Valid {
-		userID, err := strconv.Atoi(claims.Subject)
-		if err != nil {
-			return 0, err
-		}
-		return userID, nil
-	}
-
-	return 0, errors.New("invalid refresh token")
+	return j.keys.JWKS()
 }
@@ -4,8 +4,6 @@ import (
 	"strings"
 	"testing"
 	"time"
-
-	"github.com/golang-jwt/jwt/v5"
 )
 
 type JTWTestData struct {
@@ -63,25 +61,6 @@ func TestGenerateToken(t *testing.T) {
 	}
 }
 
-// TestGenerateRefreshToken tests refresh token generation
-func TestGenerateRefreshToken(t *testing.T) {
-
-	token, err := service.GenerateRefreshToken(TestData.userID)
-	if err != nil {
-		t.Fatalf("Failed to refresh token: %v", err)
-	}
-
-	if token == "" {
-		t.Error("Token should not be empty")
-	}
-
-	// Check if refresh token has proper JWT structure
-	parts := strings.Split(token, ".")
-	if len(parts) != 3 {
-		t.Errorf("Refresh token should have 3 aprts, got %d", len(parts))
-	}
-}
-
 // TestValidationToken tests JWT token validation
 func TestValidationToken(t *testing.T) {
 
@@ -217,33 +196,3 @@ func TestTokenClaims(t *testing.T) {
 		t.Error("NotBefore should be in the future")
 	}
 }
-
-// TestRefreshTokenExpiry tests refresh token expiry (7 days)
-func TestRefreshTokenExpiry(t *testing.T) {
-	refreshToken, err := service.GenerateRefreshToken(TestData.userID)
-	if err != nil {
-		t.Fatalf("Failed to refresh token: %v", err)
-	}
-
-	// Parse the refresh token to check expiry
-	token, err := jwt.ParseWithClaims(refreshToken, &jwt.RegisteredClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return []byte(service.secretKey), nil
-	})
-
-	if err != nil {
-		t.Fatalf("Failed to parse refresh token: %v", err)
-	}
-
-	if claims, ok := token.Claims.(*jwt.RegisteredClaims); ok {
-		expectedExpirty := time.Now().Add(7 * 24 * time.Hour)
-		timeDiff := claims.ExpiresAt.Time.Sub(expectedExpirty)
-
-		// Allow 1 minute tolerance for test execution time
-		if timeDiff > time.Minute || timeDiff < -time.Minute {
-			t.Errorf("Refresh token expiry time is incorrect. Expected around %v, got %v",
-				expectedExpirty, claims.ExpiresAt.Time)
-		}
-	} else {
-		t.Error("Failed to extract climas from refresh token")
-	}
-}
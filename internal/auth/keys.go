@@ -0,0 +1,318 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSAKeyBits is the smallest RSA modulus accepted for signing or verification
+const minRSAKeyBits = 2048
+
+var ErrUnknownKeyID = errors.New("unknown signing key ID")
+
+// KeySet holds the asymmetric key used to sign access tokens and every public key
+// accepted when verifying them. Keeping retired public keys in the set lets tokens
+// signed before a rotation stay valid until they expire.
+type KeySet struct {
+	signingKeyID  string
+	signingMethod jwt.SigningMethod
+	signingKey    crypto.Signer
+	publicKeys    map[string]crypto.PublicKey
+	keyIDs        []string // verification key IDs in load order, signing key first
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set as served from /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadKeySet reads a PEM private signing key (RSA or Ed25519) and optional PEM public keys
+// or certificates of retired keys that should still be accepted for verification
+func LoadKeySet(signingKeyFile string, verificationKeyFiles []string) (*KeySet, error) {
+	data, err := os.ReadFile(signingKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	signer, err := parsePrivateKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key %s: %w", signingKeyFile, err)
+	}
+
+	var publicKeys []crypto.PublicKey
+	for _, file := range verificationKeyFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read verification key: %w", err)
+		}
+
+		publicKey, err := parsePublicKeyPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse verification key %s: %w", file, err)
+		}
+		publicKeys = append(publicKeys, publicKey)
+	}
+
+	return NewKeySet(signer, publicKeys...)
+}
+
+// NewKeySet creates a key set that signs with signer and also accepts the given public keys
+func NewKeySet(signer crypto.Signer, verificationKeys ...crypto.PublicKey) (*KeySet, error) {
+	method, err := signingMethodFor(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	ks := &KeySet{
+		signingMethod: method,
+		signingKey:    signer,
+		publicKeys:    make(map[string]crypto.PublicKey),
+	}
+
+	for _, publicKey := range append([]crypto.PublicKey{signer.Public()}, verificationKeys...) {
+		if _, err := signingMethodFor(publicKey); err != nil {
+			return nil, err
+		}
+
+		kid, err := keyThumbprint(publicKey)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, exists := ks.publicKeys[kid]; exists {
+			continue
+		}
+		ks.publicKeys[kid] = publicKey
+		ks.keyIDs = append(ks.keyIDs, kid)
+	}
+
+	ks.signingKeyID = ks.keyIDs[0]
+
+	return ks, nil
+}
+
+// SigningKeyID returns the kid placed in the header of newly signed tokens
+func (ks *KeySet) SigningKeyID() string {
+	return ks.signingKeyID
+}
+
+// sign signs claims with the current signing key and tags the token with its kid
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signingMethod, claims)
+	token.Header["kid"] = ks.signingKeyID
+
+	return token.SignedString(ks.signingKey)
+}
+
+// verificationKey returns the public key for a token's kid, checking that the token's
+// algorithm matches the key type so an RSA key can never be used to check an EdDSA token
+func (ks *KeySet) verificationKey(token *jwt.Token) (crypto.PublicKey, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	publicKey, ok := ks.publicKeys[kid]
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+
+	method, err := signingMethodFor(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.Method.Alg() != method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %s", token.Method.Alg(), kid)
+	}
+
+	return publicKey, nil
+}
+
+// JWKS returns every verification key in JSON Web Key format
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: make([]JWK, 0, len(ks.keyIDs))}
+
+	for _, kid := range ks.keyIDs {
+		jwk, err := publicJWK(ks.publicKeys[kid])
+		if err != nil {
+			continue
+		}
+		jwk.Kid = kid
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
+
+// signingMethodFor maps a supported public key type to its JWT algorithm
+func signingMethodFor(publicKey crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA key must be at least %d bits", minRSAKeyBits)
+		}
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T (expected RSA or Ed25519)", publicKey)
+	}
+}
+
+// publicJWK encodes a public key as a JWK without its kid
+func publicJWK(publicKey crypto.PublicKey) (JWK, error) {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: jwt.SigningMethodRS256.Alg(),
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Use: "sig",
+			Alg: jwt.SigningMethodEdDSA.Alg(),
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key),
+		}, nil
+	default:
+		return JWK{}, fmt.Errorf("unsupported key type %T", publicKey)
+	}
+}
+
+// PublicKey decodes the RSA or Ed25519 public key held by a JWK
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA exponent: %w", err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// keyThumbprint derives a stable kid from the key itself (RFC 7638 JWK thumbprint)
+func keyThumbprint(publicKey crypto.PublicKey) (string, error) {
+	jwk, err := publicJWK(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	// Only the required members, in lexicographic order
+	var members any
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// parsePrivateKeyPEM parses a PKCS#8 or PKCS#1 private key
+func parsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+}
+
+// parsePublicKeyPEM parses a PKIX or PKCS#1 public key, or the key of a certificate
+func parsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+}
@@ -0,0 +1,304 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writePEM writes a PEM block to a file in dir and returns its path
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+// writePrivateKey writes key as a PKCS#8 PEM file
+func writePrivateKey(t *testing.T, dir, name string, key crypto.Signer) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal private key: %v", err)
+	}
+	return writePEM(t, dir, name, "PRIVATE KEY", der)
+}
+
+// writePublicKey writes key as a PKIX PEM file
+func writePublicKey(t *testing.T, dir, name string, key crypto.PublicKey) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal public key: %v", err)
+	}
+	return writePEM(t, dir, name, "PUBLIC KEY", der)
+}
+
+func generateRSAKey(t *testing.T, bits int) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	return key
+}
+
+func generateEd25519Key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+	return key
+}
+
+// TestAsymmetricSigning tests signing and verifying with RSA and Ed25519 keys loaded from PEM files
+func TestAsymmetricSigning(t *testing.T) {
+	tests := []struct {
+		name string
+		key  crypto.Signer
+		alg  string
+		kty  string
+	}{
+		{"RS256", generateRSAKey(t, 2048), "RS256", "RSA"},
+		{"EdDSA", generateEd25519Key(t), "EdDSA", "OKP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyFile := writePrivateKey(t, t.TempDir(), "signing.pem", tt.key)
+
+			keys, err := LoadKeySet(keyFile, nil)
+			if err != nil {
+				t.Fatalf("LoadKeySet should succeed: %v", err)
+			}
+
+			jwtService := NewJWTServiceWithKeys(TestData.secretKey, TestData.expiryHours, keys)
+			tokenString, err := jwtService.GenerateToken(TestData.userID, TestData.email)
+			if err != nil {
+				t.Fatalf("GenerateToken should succeed: %v", err)
+			}
+
+			// The header names the algorithm and key
+			parsed, _, err := jwt.NewParser().ParseUnverified(tokenString, &JWTClaims{})
+			if err != nil {
+				t.Fatalf("Failed to parse token: %v", err)
+			}
+			if parsed.Method.Alg() != tt.alg {
+				t.Errorf("Expected alg %s, got %s", tt.alg, parsed.Method.Alg())
+			}
+			if parsed.Header["kid"] != keys.SigningKeyID() {
+				t.Errorf("Expected kid %s, got %v", keys.SigningKeyID(), parsed.Header["kid"])
+			}
+
+			claims, err := jwtService.ValidateToken(tokenString)
+			if err != nil {
+				t.Fatalf("ValidateToken should succeed: %v", err)
+			}
+			if claims.UserID != TestData.userID {
+				t.Errorf("Expected user ID %d, got %d", TestData.userID, claims.UserID)
+			}
+
+			jwks := jwtService.JWKS()
+			if len(jwks.Keys) != 1 {
+				t.Fatalf("Expected 1 JWK, got %d", len(jwks.Keys))
+			}
+			if jwks.Keys[0].Kid != keys.SigningKeyID() || jwks.Keys[0].Alg != tt.alg || jwks.Keys[0].Kty != tt.kty {
+				t.Errorf("Unexpected JWK: %+v", jwks.Keys[0])
+			}
+		})
+	}
+}
+
+// TestKeyRotation tests that tokens signed by a retired key stay valid while its public key is listed
+func TestKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldKey := generateEd25519Key(t)
+	newKey := generateRSAKey(t, 2048)
+
+	oldKeys, err := LoadKeySet(writePrivateKey(t, dir, "old.pem", oldKey), nil)
+	if err != nil {
+		t.Fatalf("LoadKeySet should succeed: %v", err)
+	}
+	oldToken, err := NewJWTServiceWithKeys(TestData.secretKey, TestData.expiryHours, oldKeys).GenerateToken(TestData.userID, TestData.email)
+	if err != nil {
+		t.Fatalf("GenerateToken should succeed: %v", err)
+	}
+
+	// Rotate: sign with the new key, keep verifying the old one
+	rotatedKeys, err := LoadKeySet(
+		writePrivateKey(t, dir, "new.pem", newKey),
+		[]string{writePublicKey(t, dir, "old.pub.pem", oldKey.Public())},
+	)
+	if err != nil {
+		t.Fatalf("LoadKeySet should succeed: %v", err)
+	}
+	rotated := NewJWTServiceWithKeys(TestData.secretKey, TestData.expiryHours, rotatedKeys)
+
+	if _, err := rotated.ValidateToken(oldToken); err != nil {
+		t.Errorf("Token signed by the retired key should still validate: %v", err)
+	}
+
+	newToken, _ := rotated.GenerateToken(TestData.userID, TestData.email)
+	if _, err := rotated.ValidateToken(newToken); err != nil {
+		t.Errorf("Token signed by the new key should validate: %v", err)
+	}
+
+	if len(rotated.JWKS().Keys) != 2 {
+		t.Errorf("Expected both keys in the JWKS, got %d", len(rotated.JWKS().Keys))
+	}
+
+	// Once the old key is dropped its tokens are rejected
+	newOnly, _ := NewKeySet(newKey)
+	if _, err := NewJWTServiceWithKeys(TestData.secretKey, TestData.expiryHours, newOnly).ValidateToken(oldToken); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("Expected ErrUnknownKeyID for a dropped key, got: %v", err)
+	}
+}
+
+// TestAsymmetricRejectsForgedTokens tests tokens that try to confuse algorithms or keys
+func TestAsymmetricRejectsForgedTokens(t *testing.T) {
+	rsaKey := generateRSAKey(t, 2048)
+	keys, err := NewKeySet(rsaKey)
+	if err != nil {
+		t.Fatalf("NewKeySet should succeed: %v", err)
+	}
+	jwtService := NewJWTServiceWithKeys(TestData.secretKey, TestData.expiryHours, keys)
+
+	claims := JWTClaims{UserID: TestData.userID, Email: TestData.email}
+
+	// HS256 "signed" with the public key, claiming the RSA kid
+	publicDER, _ := x509.MarshalPKIXPublicKey(rsaKey.Public())
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	forged.Header["kid"] = keys.SigningKeyID()
+	forgedString, _ := forged.SignedString(publicDER)
+	if _, err := jwtService.ValidateToken(forgedString); err == nil {
+		t.Error("HS256 token using the RSA kid should be rejected")
+	}
+
+	// Signed by a key we never trusted
+	otherKeys, _ := NewKeySet(generateEd25519Key(t))
+	otherToken, _ := NewJWTServiceWithKeys(TestData.secretKey, TestData.expiryHours, otherKeys).GenerateToken(TestData.userID, TestData.email)
+	if _, err := jwtService.ValidateToken(otherToken); err == nil {
+		t.Error("Token signed by an untrusted key should be rejected")
+	}
+}
+
+// TestAsymmetricAcceptsLegacyHS256 tests that tokens issued before switching to asymmetric keys keep working
+func TestAsymmetricAcceptsLegacyHS256(t *testing.T) {
+	legacyToken, err := NewJWTService(TestData.secretKey, TestData.expiryHours).GenerateToken(TestData.userID, TestData.email)
+	if err != nil {
+		t.Fatalf("GenerateToken should succeed: %v", err)
+	}
+
+	keys, _ := NewKeySet(generateEd25519Key(t))
+	if _, err := NewJWTServiceWithKeys(TestData.secretKey, TestData.expiryHours, keys).ValidateToken(legacyToken); err != nil {
+		t.Errorf("Legacy HS256 token should validate: %v", err)
+	}
+}
+
+// TestAsymmetricRejectsHS256WhenRetired tests that HS256 verification can be turned off to finish the rollover
+func TestAsymmetricRejectsHS256WhenRetired(t *testing.T) {
+	legacyToken, err := NewJWTService(TestData.secretKey, TestData.expiryHours).GenerateToken(TestData.userID, TestData.email)
+	if err != nil {
+		t.Fatalf("GenerateToken should succeed: %v", err)
+	}
+
+	keys, _ := NewKeySet(generateEd25519Key(t))
+	jwtService := NewJWTServiceWithKeys(TestData.secretKey, TestData.expiryHours, keys)
+	jwtService.rejectHMAC = true
+
+	if _, err := jwtService.ValidateToken(legacyToken); err == nil {
+		t.Error("Legacy HS256 token should be rejected once HS256 is retired")
+	}
+
+	token, err := jwtService.GenerateToken(TestData.userID, TestData.email)
+	if err != nil {
+		t.Fatalf("GenerateToken should succeed: %v", err)
+	}
+	if _, err := jwtService.ValidateToken(token); err != nil {
+		t.Errorf("Asymmetric token should still validate: %v", err)
+	}
+}
+
+// TestLoadKeySetErrors tests rejected key files
+func TestLoadKeySetErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := LoadKeySet(filepath.Join(dir, "missing.pem"), nil); err == nil {
+		t.Error("Missing key file should fail")
+	}
+
+	notPEM := filepath.Join(dir, "garbage.pem")
+	_ = os.WriteFile(notPEM, []byte("not a key"), 0o600)
+	if _, err := LoadKeySet(notPEM, nil); err == nil {
+		t.Error("Non-PEM key file should fail")
+	}
+
+	weak := writePrivateKey(t, dir, "weak.pem", generateRSAKey(t, 1024))
+	if _, err := LoadKeySet(weak, nil); err == nil {
+		t.Error("RSA keys under 2048 bits should be rejected")
+	}
+
+	signing := writePrivateKey(t, dir, "signing.pem", generateEd25519Key(t))
+	if _, err := LoadKeySet(signing, []string{notPEM}); err == nil {
+		t.Error("Invalid verification key should fail")
+	}
+}
+
+// TestHS256JWKSEmpty tests that no keys are published when tokens use the shared secret
+func TestHS256JWKSEmpty(t *testing.T) {
+	if keys := NewJWTService(TestData.secretKey, TestData.expiryHours).JWKS().Keys; len(keys) != 0 {
+		t.Errorf("Expected no JWKs for HS256, got %d", len(keys))
+	}
+}
+
+// TestJWKPublicKey tests that published JWKs decode back to the same keys
+func TestJWKPublicKey(t *testing.T) {
+	keys := []crypto.PublicKey{generateRSAKey(t, 2048).Public(), generateEd25519Key(t).Public()}
+
+	for _, publicKey := range keys {
+		jwk, err := publicJWK(publicKey)
+		if err != nil {
+			t.Fatalf("publicJWK failed: %v", err)
+		}
+
+		decoded, err := jwk.PublicKey()
+		if err != nil {
+			t.Fatalf("PublicKey failed for %s: %v", jwk.Kty, err)
+		}
+
+		equal, ok := decoded.(interface{ Equal(crypto.PublicKey) bool })
+		if !ok || !equal.Equal(publicKey) {
+			t.Errorf("Expected the decoded %s key to match the original", jwk.Kty)
+		}
+	}
+
+	invalid := []JWK{
+		{Kty: "EC", Crv: "P-256"},
+		{Kty: "OKP", Crv: "X25519", X: "AAAA"},
+		{Kty: "OKP", Crv: "Ed25519", X: "AAAA"},
+		{Kty: "RSA", N: "!!", E: "AQAB"},
+		{Kty: "RSA", N: "AQAB", E: ""},
+	}
+	for _, jwk := range invalid {
+		if _, err := jwk.PublicKey(); err == nil {
+			t.Errorf("Expected %+v to be rejected", jwk)
+		}
+	}
+}
@@ -0,0 +1,449 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrAccountLocked = errors.New("account is temporarily locked")
+
+// AccountLockedError is returned by Login while an account or IP address is locked out or backing off
+// after failed attempts. It matches ErrAccountLocked with errors.Is.
+type AccountLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *AccountLockedError) Error() string {
+	return fmt.Sprintf("%v, try again in %v", ErrAccountLocked, e.RetryAfter)
+}
+
+func (e *AccountLockedError) Is(target error) bool {
+	return target == ErrAccountLocked
+}
+
+// LockoutThreshold sets when failed logins start to be throttled. Zero disables a stage.
+type LockoutThreshold struct {
+	// BackoffAfter is how many failures are allowed before each further attempt has to wait,
+	// twice as long after every failure
+	BackoffAfter int
+	// LockAfter is how many failures lock logins out for LockoutPolicy.LockoutDuration
+	LockAfter int
+}
+
+// LockoutPolicy configures brute-force protection for logins
+type LockoutPolicy struct {
+	Account LockoutThreshold
+	IP      LockoutThreshold
+	// BackoffBase is the wait after the first throttled failure, capped at BackoffMax
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// LockoutDuration is how long a lockout lasts. Failures older than this are forgotten.
+	LockoutDuration time.Duration
+}
+
+// DefaultLockoutPolicy returns the policy used unless the server configures another one
+func DefaultLockoutPolicy() LockoutPolicy {
+	return LockoutPolicy{
+		Account:         LockoutThreshold{BackoffAfter: 3, LockAfter: 10},
+		IP:              LockoutThreshold{BackoffAfter: 20, LockAfter: 100},
+		BackoffBase:     time.Second,
+		BackoffMax:      5 * time.Minute,
+		LockoutDuration: 15 * time.Minute,
+	}
+}
+
+// wait returns how long the next attempt has to wait after attempts, or 0 if it may go ahead
+func (p LockoutPolicy) wait(threshold LockoutThreshold, attempts LoginAttempts, now time.Time) time.Duration {
+	if attempts.Failures == 0 || now.Sub(attempts.LastFailureAt) >= p.LockoutDuration {
+		return 0
+	}
+
+	var delay time.Duration
+	switch {
+	case threshold.LockAfter > 0 && attempts.Failures >= threshold.LockAfter:
+		delay = p.LockoutDuration
+	case threshold.BackoffAfter > 0 && attempts.Failures >= threshold.BackoffAfter:
+		delay = p.BackoffMax
+		// Stop doubling well before the duration would overflow
+		if shift := attempts.Failures - threshold.BackoffAfter; shift < 32 {
+			delay = min(p.BackoffBase<<shift, p.BackoffMax)
+		}
+	default:
+		return 0
+	}
+
+	return max(attempts.LastFailureAt.Add(delay).Sub(now), 0)
+}
+
+// LoginAttempts counts recent failed logins for one account or IP address
+type LoginAttempts struct {
+	Failures      int
+	LastFailureAt time.Time
+	// Pending counts logins that have been reserved but not found to fail or succeed yet
+	Pending    int
+	ReservedAt time.Time
+}
+
+// current drops failures and reservations older than window
+func (a LoginAttempts) current(now time.Time, window time.Duration) LoginAttempts {
+	if now.Sub(a.LastFailureAt) >= window {
+		a.Failures = 0
+	}
+	if now.Sub(a.ReservedAt) >= window {
+		a.Pending = 0
+	}
+	return a
+}
+
+// LoginAttemptStore keeps failed login counts. Every timestamp is taken from the now passed
+// in by the caller, and failures and reservations older than window are ignored.
+type LoginAttemptStore interface {
+	Get(ctx context.Context, key string) (LoginAttempts, error)
+	// Reserve atomically counts a login in progress for key, unless wait returns a delay
+	// for the current attempts. The delay is returned and nothing is counted then.
+	Reserve(ctx context.Context, key string, now time.Time, window time.Duration, wait func(LoginAttempts) time.Duration) (time.Duration, error)
+	// RecordFailure turns a login reserved for key into a failure at now
+	RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) error
+	// Release takes back a login reserved for key that didn't fail, leaving the failures alone
+	Release(ctx context.Context, key string) error
+	// Reset forgets the failures of key
+	Reset(ctx context.Context, key string) error
+}
+
+// LoginLimiter throttles logins per account and per IP address after failed attempts
+type LoginLimiter struct {
+	store  LoginAttemptStore
+	policy LockoutPolicy
+	now    func() time.Time
+}
+
+// NewLoginLimiter creates a login limiter enforcing policy with counts kept in store
+func NewLoginLimiter(store LoginAttemptStore, policy LockoutPolicy) *LoginLimiter {
+	return &LoginLimiter{
+		store:  store,
+		policy: policy,
+		now:    time.Now,
+	}
+}
+
+// Reserve counts a login for email from ipAddress as in progress before the password is checked.
+// Logins in progress are throttled as if they had just failed, so concurrent guesses can't all
+// get past the limit. It returns an *AccountLockedError, counting nothing, if the login has to wait.
+// Every reserved login ends with RecordFailure, Release or RecordSuccess.
+func (l *LoginLimiter) Reserve(ctx context.Context, email, ipAddress string) error {
+	now := l.now()
+
+	retryAfter, err := l.store.Reserve(ctx, accountAttemptKey(email), now, l.policy.LockoutDuration, l.waitFor(l.policy.Account, now))
+	if err != nil {
+		return fmt.Errorf("failed to reserve login attempt: %w", err)
+	}
+
+	if retryAfter == 0 && ipAddress != "" {
+		retryAfter, err = l.store.Reserve(ctx, ipAttemptKey(ipAddress), now, l.policy.LockoutDuration, l.waitFor(l.policy.IP, now))
+		if err != nil || retryAfter > 0 {
+			if err := l.store.Release(ctx, accountAttemptKey(email)); err != nil {
+				fmt.Printf("LoginLimiter: failed to release login attempt: %v\n", err)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to reserve login attempt: %w", err)
+		}
+	}
+
+	if retryAfter > 0 {
+		// Round up so clients retrying after the hint aren't a moment too early
+		return &AccountLockedError{RetryAfter: (retryAfter + time.Second - 1).Truncate(time.Second)}
+	}
+
+	return nil
+}
+
+// waitFor returns how long a login has to wait under threshold, counting logins in progress
+// as failures that just happened
+func (l *LoginLimiter) waitFor(threshold LockoutThreshold, now time.Time) func(LoginAttempts) time.Duration {
+	return func(attempts LoginAttempts) time.Duration {
+		if attempts.Pending > 0 {
+			attempts.Failures += attempts.Pending
+			attempts.LastFailureAt = now
+		}
+		return l.policy.wait(threshold, attempts, now)
+	}
+}
+
+// RecordFailure counts a login reserved for email and ipAddress as failed
+func (l *LoginLimiter) RecordFailure(ctx context.Context, email, ipAddress string) error {
+	now := l.now()
+
+	if err := l.store.RecordFailure(ctx, accountAttemptKey(email), now, l.policy.LockoutDuration); err != nil {
+		return err
+	}
+
+	if ipAddress != "" {
+		return l.store.RecordFailure(ctx, ipAttemptKey(ipAddress), now, l.policy.LockoutDuration)
+	}
+
+	return nil
+}
+
+// Release takes back a login reserved for email and ipAddress that didn't fail,
+// without clearing earlier failures
+func (l *LoginLimiter) Release(ctx context.Context, email, ipAddress string) error {
+	if err := l.store.Release(ctx, accountAttemptKey(email)); err != nil {
+		return err
+	}
+
+	if ipAddress != "" {
+		return l.store.Release(ctx, ipAttemptKey(ipAddress))
+	}
+
+	return nil
+}
+
+// RecordSuccess takes back a login reserved for email and ipAddress and clears the failed
+// logins of email. The IP address keeps its earlier failures, so signing in to one account
+// doesn't buy more guesses at others.
+func (l *LoginLimiter) RecordSuccess(ctx context.Context, email, ipAddress string) error {
+	if err := l.Release(ctx, email, ipAddress); err != nil {
+		return err
+	}
+
+	return l.store.Reset(ctx, accountAttemptKey(email))
+}
+
+// Reset forgets the failed logins of email
+func (l *LoginLimiter) Reset(ctx context.Context, email string) error {
+	return l.store.Reset(ctx, accountAttemptKey(email))
+}
+
+func accountAttemptKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipAttemptKey(ipAddress string) string {
+	return "ip:" + ipAddress
+}
+
+// MemoryLoginAttemptStore keeps failed login counts in process memory.
+// Counts are not shared between instances; use PostgresLoginAttemptStore when running several replicas.
+type MemoryLoginAttemptStore struct {
+	mu        sync.Mutex
+	attempts  map[string]LoginAttempts
+	lastSweep time.Time
+}
+
+// NewMemoryLoginAttemptStore creates an empty in-memory login attempt store
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{
+		attempts:  make(map[string]LoginAttempts),
+		lastSweep: time.Now(),
+	}
+}
+
+// Get returns the failed logins recorded for key
+func (m *MemoryLoginAttemptStore) Get(ctx context.Context, key string) (LoginAttempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.attempts[key], nil
+}
+
+// Reserve counts a login in progress for key unless wait returns a delay
+func (m *MemoryLoginAttemptStore) Reserve(ctx context.Context, key string, now time.Time, window time.Duration, wait func(LoginAttempts) time.Duration) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now, window)
+
+	attempts := m.attempts[key].current(now, window)
+	if delay := wait(attempts); delay > 0 {
+		return delay, nil
+	}
+
+	attempts.Pending++
+	attempts.ReservedAt = now
+	m.attempts[key] = attempts
+
+	return 0, nil
+}
+
+// RecordFailure turns a login reserved for key into a failure, starting over if the last
+// failure is older than window
+func (m *MemoryLoginAttemptStore) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempts := m.attempts[key]
+	if now.Sub(attempts.LastFailureAt) >= window {
+		attempts.Failures = 0
+	}
+	attempts.Failures++
+	attempts.LastFailureAt = now
+	attempts.Pending = max(attempts.Pending-1, 0)
+	m.attempts[key] = attempts
+
+	return nil
+}
+
+// Release takes back a login reserved for key
+func (m *MemoryLoginAttemptStore) Release(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if attempts, ok := m.attempts[key]; ok && attempts.Pending > 0 {
+		attempts.Pending--
+		m.attempts[key] = attempts
+	}
+
+	return nil
+}
+
+// Reset forgets the failed logins of key
+func (m *MemoryLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if attempts, ok := m.attempts[key]; ok {
+		attempts.Failures = 0
+		m.attempts[key] = attempts
+	}
+
+	return nil
+}
+
+// sweep drops keys without current failures or reservations at most once per window.
+// The caller holds m.mu.
+func (m *MemoryLoginAttemptStore) sweep(now time.Time, window time.Duration) {
+	if now.Sub(m.lastSweep) < window {
+		return
+	}
+
+	for key, attempts := range m.attempts {
+		if current := attempts.current(now, window); current.Failures == 0 && current.Pending == 0 {
+			delete(m.attempts, key)
+		}
+	}
+	m.lastSweep = now
+}
+
+// PostgresLoginAttemptStore keeps failed login counts in the login_attempts table, shared by every instance.
+// Timestamps are written from the caller's clock rather than NOW(), so windows are measured with one clock.
+type PostgresLoginAttemptStore struct {
+	db *pgxpool.Pool
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewPostgresLoginAttemptStore creates a login attempt store backed by Postgres
+func NewPostgresLoginAttemptStore(db *pgxpool.Pool) *PostgresLoginAttemptStore {
+	return &PostgresLoginAttemptStore{
+		db: db,
+	}
+}
+
+// loginAttemptColumns is the column list scanned into LoginAttempts
+const loginAttemptColumns = `failures, last_failure_at, pending, COALESCE(reserved_at, to_timestamp(0))`
+
+// Get returns the failed logins recorded for key
+func (p *PostgresLoginAttemptStore) Get(ctx context.Context, key string) (LoginAttempts, error) {
+	query := `SELECT ` + loginAttemptColumns + ` FROM login_attempts WHERE key = $1`
+
+	var attempts LoginAttempts
+	err := p.db.QueryRow(ctx, query, key).Scan(&attempts.Failures, &attempts.LastFailureAt, &attempts.Pending, &attempts.ReservedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return LoginAttempts{}, err
+	}
+
+	return attempts, nil
+}
+
+// Reserve counts a login in progress for key unless wait returns a delay. The row is locked
+// while wait runs, so concurrent reservations for the same key take turns.
+func (p *PostgresLoginAttemptStore) Reserve(ctx context.Context, key string, now time.Time, window time.Duration, wait func(LoginAttempts) time.Duration) (time.Duration, error) {
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Make sure there is a row to lock; it has no failures yet
+	if _, err := tx.Exec(ctx, `INSERT INTO login_attempts (key, failures, last_failure_at) VALUES ($1, 0, to_timestamp(0)) ON CONFLICT (key) DO NOTHING`, key); err != nil {
+		return 0, err
+	}
+
+	query := `SELECT ` + loginAttemptColumns + ` FROM login_attempts WHERE key = $1 FOR UPDATE`
+
+	var attempts LoginAttempts
+	if err := tx.QueryRow(ctx, query, key).Scan(&attempts.Failures, &attempts.LastFailureAt, &attempts.Pending, &attempts.ReservedAt); err != nil {
+		return 0, err
+	}
+
+	attempts = attempts.current(now, window)
+	if delay := wait(attempts); delay > 0 {
+		return delay, nil
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE login_attempts SET pending = $2, reserved_at = $3 WHERE key = $1`, key, attempts.Pending+1, now); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	p.sweep(ctx, now, window)
+
+	return 0, nil
+}
+
+// RecordFailure turns a login reserved for key into a failure, starting over if the last
+// failure is older than window
+func (p *PostgresLoginAttemptStore) RecordFailure(ctx context.Context, key string, now time.Time, window time.Duration) error {
+	query := `
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE
+		SET failures = CASE
+				WHEN login_attempts.last_failure_at > $3 THEN login_attempts.failures + 1
+				ELSE 1
+			END,
+			last_failure_at = $2,
+			pending = GREATEST(login_attempts.pending - 1, 0)
+	`
+
+	_, err := p.db.Exec(ctx, query, key, now, now.Add(-window))
+	return err
+}
+
+// Release takes back a login reserved for key
+func (p *PostgresLoginAttemptStore) Release(ctx context.Context, key string) error {
+	_, err := p.db.Exec(ctx, `UPDATE login_attempts SET pending = GREATEST(pending - 1, 0) WHERE key = $1`, key)
+	return err
+}
+
+// Reset forgets the failed logins of key
+func (p *PostgresLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	_, err := p.db.Exec(ctx, `UPDATE login_attempts SET failures = 0 WHERE key = $1`, key)
+	return err
+}
+
+// sweep deletes rows without current failures or reservations at most once per window
+func (p *PostgresLoginAttemptStore) sweep(ctx context.Context, now time.Time, window time.Duration) {
+	p.mu.Lock()
+	if now.Sub(p.lastSweep) < window {
+		p.mu.Unlock()
+		return
+	}
+	p.lastSweep = now
+	p.mu.Unlock()
+
+	// Stale counts are ignored by LoginLimiter, so a failed sweep is harmless
+	_, _ = p.db.Exec(ctx, `DELETE FROM login_attempts WHERE last_failure_at <= $1 AND COALESCE(reserved_at, last_failure_at) <= $1`, now.Add(-window))
+}
@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testLockoutPolicy backs off after 2 failures and locks out after 5
+var testLockoutPolicy = LockoutPolicy{
+	Account:         LockoutThreshold{BackoffAfter: 2, LockAfter: 5},
+	IP:              LockoutThreshold{BackoffAfter: 0, LockAfter: 8},
+	BackoffBase:     time.Second,
+	BackoffMax:      10 * time.Second,
+	LockoutDuration: time.Minute,
+}
+
+// TestLockoutPolicyWait tests the backoff schedule and lockout
+func TestLockoutPolicyWait(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		failures int
+		since    time.Duration
+		want     time.Duration
+	}{
+		{"no failures", 0, 0, 0},
+		{"below backoff", 1, 0, 0},
+		{"first backoff", 2, 0, time.Second},
+		{"doubles", 4, 0, 4 * time.Second},
+		{"partly waited", 4, 3 * time.Second, time.Second},
+		{"locked out", 5, 0, time.Minute},
+		{"lockout over", 5, time.Minute, 0},
+		{"far past lockout", 50, 30 * time.Second, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := LoginAttempts{Failures: tt.failures, LastFailureAt: now.Add(-tt.since)}
+			if got := testLockoutPolicy.wait(testLockoutPolicy.Account, attempts, now); got != tt.want {
+				t.Errorf("Expected wait %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// Backoff is capped
+	capped := testLockoutPolicy
+	capped.Account.LockAfter = 0
+	if got := capped.wait(capped.Account, LoginAttempts{Failures: 40, LastFailureAt: now}, now); got != capped.BackoffMax {
+		t.Errorf("Expected wait capped at %v, got %v", capped.BackoffMax, got)
+	}
+}
+
+// TestLoginLimiter tests throttling per account and per IP address
+func TestLoginLimiter(t *testing.T) {
+	store := NewMemoryLoginAttemptStore()
+	limiter := NewLoginLimiter(store, testLockoutPolicy)
+	ctx := context.Background()
+
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	if err := limiter.Reserve(ctx, "user@example.com", "10.0.0.1"); err != nil {
+		t.Errorf("First attempt should go ahead, got: %v", err)
+	}
+	if err := limiter.RecordFailure(ctx, "user@example.com", "10.0.0.1"); err != nil {
+		t.Fatalf("RecordFailure should succeed: %v", err)
+	}
+
+	// Emails are matched case-insensitively
+	if err := limiter.Reserve(ctx, "USER@example.com", "10.0.0.1"); err != nil {
+		t.Errorf("One failure should not throttle, got: %v", err)
+	}
+	if err := limiter.RecordFailure(ctx, "USER@example.com", "10.0.0.1"); err != nil {
+		t.Fatalf("RecordFailure should succeed: %v", err)
+	}
+	err := limiter.Reserve(ctx, "user@example.com", "10.0.0.2")
+
+	var locked *AccountLockedError
+	if !errors.As(err, &locked) || !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("Expected AccountLockedError, got: %v", err)
+	}
+	if locked.RetryAfter != time.Second {
+		t.Errorf("Expected retry after 1s, got %v", locked.RetryAfter)
+	}
+
+	// Turned away attempts aren't counted
+	if attempts, _ := store.Get(ctx, accountAttemptKey("user@example.com")); attempts.Failures != 2 || attempts.Pending != 0 {
+		t.Errorf("Expected 2 failures and nothing pending for the account, got %+v", attempts)
+	}
+	if attempts, _ := store.Get(ctx, ipAttemptKey("10.0.0.2")); attempts.Failures != 0 || attempts.Pending != 0 {
+		t.Errorf("Expected nothing counted for the IP address, got %+v", attempts)
+	}
+
+	now = now.Add(time.Second)
+	if err := limiter.Reserve(ctx, "user@example.com", "10.0.0.1"); err != nil {
+		t.Errorf("Attempt after the backoff should go ahead, got: %v", err)
+	}
+
+	// Signing in clears the account, but the IP address keeps its earlier failures
+	if err := limiter.RecordSuccess(ctx, "user@example.com", "10.0.0.1"); err != nil {
+		t.Fatalf("RecordSuccess should succeed: %v", err)
+	}
+	if attempts, _ := store.Get(ctx, accountAttemptKey("user@example.com")); attempts.Failures != 0 || attempts.Pending != 0 {
+		t.Errorf("Expected the account to be cleared, got %+v", attempts)
+	}
+	if attempts, _ := store.Get(ctx, ipAttemptKey("10.0.0.1")); attempts.Failures != 2 || attempts.Pending != 0 {
+		t.Errorf("Expected 2 failures kept for the IP address, got %+v", attempts)
+	}
+
+	// Guessing across accounts from one IP address locks the address out
+	for i := 0; i < 6; i++ {
+		email := fmt.Sprintf("other%d@example.com", i)
+		if err := limiter.Reserve(ctx, email, "10.0.0.1"); err != nil {
+			t.Fatalf("Attempt %d should go ahead, got: %v", i+1, err)
+		}
+		_ = limiter.RecordFailure(ctx, email, "10.0.0.1")
+	}
+	if err := limiter.Reserve(ctx, "fresh@example.com", "10.0.0.1"); !errors.Is(err, ErrAccountLocked) {
+		t.Errorf("Expected IP address to be locked out, got: %v", err)
+	}
+	if attempts, _ := store.Get(ctx, accountAttemptKey("fresh@example.com")); attempts.Pending != 0 {
+		t.Errorf("Expected the account attempt to be released, got %d pending", attempts.Pending)
+	}
+	if err := limiter.Reserve(ctx, "fresh@example.com", "10.0.0.9"); err != nil {
+		t.Errorf("Other IP addresses should not be affected, got: %v", err)
+	}
+	_ = limiter.Release(ctx, "fresh@example.com", "10.0.0.9")
+
+	// Failures are forgotten after the lockout duration
+	now = now.Add(testLockoutPolicy.LockoutDuration)
+	if err := limiter.Reserve(ctx, "fresh@example.com", "10.0.0.1"); err != nil {
+		t.Errorf("Lockout should have ended, got: %v", err)
+	}
+	_ = limiter.RecordFailure(ctx, "fresh@example.com", "10.0.0.1")
+	if attempts, _ := store.Get(ctx, ipAttemptKey("10.0.0.1")); attempts.Failures != 1 {
+		t.Errorf("Expected the count to start over, got %d", attempts.Failures)
+	}
+}
+
+// TestLoginLimiterReleasedAttempts tests that logins which don't fail leave the failures alone
+func TestLoginLimiterReleasedAttempts(t *testing.T) {
+	store := NewMemoryLoginAttemptStore()
+	limiter := NewLoginLimiter(store, testLockoutPolicy)
+	ctx := context.Background()
+
+	start := time.Now()
+	now := start
+	limiter.now = func() time.Time { return now }
+
+	_ = limiter.Reserve(ctx, "user@example.com", "10.0.0.1")
+	_ = limiter.RecordFailure(ctx, "user@example.com", "10.0.0.1")
+
+	// A released reservation doesn't push the failure forward, so the failure still expires on time
+	now = start.Add(testLockoutPolicy.LockoutDuration / 2)
+	if err := limiter.Reserve(ctx, "user@example.com", "10.0.0.1"); err != nil {
+		t.Fatalf("Attempt should go ahead, got: %v", err)
+	}
+	if err := limiter.Release(ctx, "user@example.com", "10.0.0.1"); err != nil {
+		t.Fatalf("Release should succeed: %v", err)
+	}
+
+	attempts, _ := store.Get(ctx, accountAttemptKey("user@example.com"))
+	if attempts.Failures != 1 || attempts.Pending != 0 || !attempts.LastFailureAt.Equal(start) {
+		t.Errorf("Expected 1 failure at the start and nothing pending, got %+v", attempts)
+	}
+
+	now = start.Add(testLockoutPolicy.LockoutDuration)
+	_ = limiter.Reserve(ctx, "user@example.com", "10.0.0.1")
+	_ = limiter.RecordFailure(ctx, "user@example.com", "10.0.0.1")
+	if attempts, _ := store.Get(ctx, accountAttemptKey("user@example.com")); attempts.Failures != 1 {
+		t.Errorf("Expected the expired failure to be forgotten, got %d failures", attempts.Failures)
+	}
+
+	// Reservations that never end, e.g. from a crashed request, stop counting after the lockout duration
+	_ = limiter.Reserve(ctx, "stuck@example.com", "")
+	_ = limiter.Reserve(ctx, "stuck@example.com", "")
+	if err := limiter.Reserve(ctx, "stuck@example.com", ""); !errors.Is(err, ErrAccountLocked) {
+		t.Errorf("Expected logins in progress to throttle, got: %v", err)
+	}
+	now = now.Add(testLockoutPolicy.LockoutDuration)
+	if err := limiter.Reserve(ctx, "stuck@example.com", ""); err != nil {
+		t.Errorf("Expected stale reservations to be ignored, got: %v", err)
+	}
+}
+
+// TestLoginLimiterConcurrentAttempts tests that simultaneous guesses can't all get past the limit
+func TestLoginLimiterConcurrentAttempts(t *testing.T) {
+	limiter := NewLoginLimiter(NewMemoryLoginAttemptStore(), testLockoutPolicy)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var allowed atomic.Int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if limiter.Reserve(ctx, "user@example.com", "") == nil {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := int(allowed.Load()); got != testLockoutPolicy.Account.BackoffAfter {
+		t.Errorf("Expected %d attempts to go ahead, got %d", testLockoutPolicy.Account.BackoffAfter, got)
+	}
+}
+
+// TestLoginLockout tests that Login refuses locked accounts even with the right password
+func TestLoginLockout(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	authService.SetLoginLimiter(NewLoginLimiter(NewMemoryLoginAttemptStore(), LockoutPolicy{
+		Account:         LockoutThreshold{LockAfter: 3},
+		BackoffBase:     time.Second,
+		BackoffMax:      time.Second,
+		LockoutDuration: time.Minute,
+	}))
+	ctx := WithClientInfo(context.Background(), ClientInfo{IPAddress: "10.0.0.1"})
+
+	if _, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword); err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := authService.Login(ctx, serviceTestData.testEmail, "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("Attempt %d: expected ErrInvalidCredentials, got: %v", i+1, err)
+		}
+	}
+
+	_, err := authService.Login(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+
+	var locked *AccountLockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("Expected AccountLockedError, got: %v", err)
+	}
+	if locked.RetryAfter <= 0 || locked.RetryAfter > time.Minute {
+		t.Errorf("Unexpected retry-after hint %v", locked.RetryAfter)
+	}
+
+	// Unknown emails are throttled the same way, so lockouts don't reveal which accounts exist
+	for i := 0; i < 3; i++ {
+		_, _ = authService.Login(ctx, "nobody@example.com", "wrong-password")
+	}
+	if _, err := authService.Login(ctx, "nobody@example.com", "wrong-password"); !errors.Is(err, ErrAccountLocked) {
+		t.Errorf("Expected ErrAccountLocked for an unknown email, got: %v", err)
+	}
+}
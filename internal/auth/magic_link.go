@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jayk0001/my-go-next-todo/internal/mail"
+)
+
+// MagicLinkTokenExpiry is how long a sign-in link stays valid
+const MagicLinkTokenExpiry = 15 * time.Minute
+
+var ErrMagicLinkInvalid = errors.New("invalid or expired sign-in link")
+
+// MagicLinkToken is the stored record of a sign-in link. It is tied to an email address
+// rather than a user, so a link can also create the account when auto-registration is on.
+type MagicLinkToken struct {
+	ID        int        `db:"id" json:"id"`
+	Email     string     `db:"email" json:"email"`
+	TokenHash string     `db:"token_hash" json:"-"`
+	ExpiresAt time.Time  `db:"expires_at" json:"expires_at"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	UsedAt    *time.Time `db:"used_at" json:"used_at,omitempty"`
+}
+
+// MagicLinkRepositoryInterface defines sign-in link persistence used by AuthService
+type MagicLinkRepositoryInterface interface {
+	Create(ctx context.Context, token *MagicLinkToken) error
+	Consume(ctx context.Context, tokenHash string) (*MagicLinkToken, error)
+	InvalidateForEmail(ctx context.Context, email string) error
+}
+
+// MagicLinkRepository handles sign-in link database operations
+type MagicLinkRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewMagicLinkRepository creates a new sign-in link repository
+func NewMagicLinkRepository(db *pgxpool.Pool) *MagicLinkRepository {
+	return &MagicLinkRepository{
+		db: db,
+	}
+}
+
+// Create stores a newly issued sign-in link token, filling in its ID and creation time
+func (r *MagicLinkRepository) Create(ctx context.Context, token *MagicLinkToken) error {
+	query := `
+		INSERT INTO magic_link_tokens (email, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, NOW())
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRow(ctx, query, token.Email, token.TokenHash, token.ExpiresAt).Scan(&token.ID, &token.CreatedAt)
+}
+
+// Consume marks an unused, unexpired token as used and returns it.
+// The update is a single statement, so a link signs in at most once.
+func (r *MagicLinkRepository) Consume(ctx context.Context, tokenHash string) (*MagicLinkToken, error) {
+	query := `
+		UPDATE magic_link_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, email, token_hash, expires_at, created_at, used_at
+	`
+
+	var token MagicLinkToken
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.Email,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+		&token.UsedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMagicLinkInvalid
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// InvalidateForEmail marks every outstanding sign-in link for the address as used
+func (r *MagicLinkRepository) InvalidateForEmail(ctx context.Context, email string) error {
+	query := `UPDATE magic_link_tokens SET used_at = NOW() WHERE email = $1 AND used_at IS NULL`
+
+	_, err := r.db.Exec(ctx, query, email)
+	return err
+}
+
+// SetMagicLinkAutoRegister decides whether a sign-in link may be sent to an unregistered
+// address, creating an account without a password when it is opened
+func (s *AuthService) SetMagicLinkAutoRegister(enabled bool) {
+	s.magicLinkAutoRegister = enabled
+}
+
+// RequestMagicLink emails a single-use sign-in link if email belongs to an active account,
+// or to any address when auto-registration is on. Like RequestPasswordReset, it returns nil
+// whether or not the account exists.
+func (s *AuthService) RequestMagicLink(ctx context.Context, email string) error {
+	if err := s.validatorService.ValidateEmail(email); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	registered := true
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, ErrUserNotFound) {
+			return fmt.Errorf("failed to request sign-in link: %w", err)
+		}
+		if !s.magicLinkAutoRegister {
+			return nil
+		}
+		registered = false
+	} else if user.IsDisabled() {
+		return nil
+	}
+
+	// Only the newest link works
+	if err := s.magicLinkRepo.InvalidateForEmail(ctx, email); err != nil {
+		return fmt.Errorf("failed to request sign-in link: %w", err)
+	}
+
+	token, hash, err := generateOpaqueToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate sign-in link: %w", err)
+	}
+
+	if err := s.magicLinkRepo.Create(ctx, &MagicLinkToken{
+		Email:     email,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(MagicLinkTokenExpiry),
+	}); err != nil {
+		return fmt.Errorf("failed to store sign-in link: %w", err)
+	}
+
+	action := "sign in"
+	if !registered {
+		action = "create your account and sign in"
+	}
+
+	s.sendMail(ctx, mail.Message{
+		To:      email,
+		Subject: "Your sign-in link",
+		Body: fmt.Sprintf(
+			"Open this link within %d minutes to %s:\n%s/magic-link?token=%s\n\n"+
+				"The link works once. If you didn't ask for it, you can ignore this email.\n",
+			int(MagicLinkTokenExpiry.Minutes()), action, s.appURL, token,
+		),
+	})
+
+	return nil
+}
+
+// ConsumeMagicLink signs in with a token from a sign-in link email. Opening the link proves the
+// user owns the address, so it is marked verified. Like Login, it returns a *TwoFactorRequiredError
+// when the user has two-factor enabled.
+func (s *AuthService) ConsumeMagicLink(ctx context.Context, token string) (*AuthResult, error) {
+	record, err := s.magicLinkRepo.Consume(ctx, hashToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("sign-in failed: %w", err)
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, record.Email)
+	if errors.Is(err, ErrUserNotFound) {
+		// The account was deleted since the link was sent, or never existed
+		if !s.magicLinkAutoRegister {
+			return nil, fmt.Errorf("sign-in failed: %w", ErrMagicLinkInvalid)
+		}
+		user, err = s.userRepo.Create(ctx, CreateUserInput{
+			Email:         record.Email,
+			EmailVerified: true,
+		})
+		if err == nil {
+			s.userCreated(ctx, user)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sign-in failed: %w", err)
+	}
+
+	if user.IsDisabled() {
+		return nil, fmt.Errorf("sign-in failed: %w", ErrAccountDisabled)
+	}
+
+	if !user.IsEmailVerified() {
+		if err := s.userRepo.MarkEmailVerified(ctx, user.ID, user.Email); err != nil {
+			return nil, fmt.Errorf("sign-in failed: %w", err)
+		}
+		if user, err = s.userRepo.GetByID(ctx, user.ID); err != nil {
+			return nil, fmt.Errorf("sign-in failed: %w", err)
+		}
+	}
+
+	enabled, err := s.twoFactorEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, s.startTwoFactorChallenge(ctx, user.ID)
+	}
+
+	return s.startSession(ctx, user)
+}
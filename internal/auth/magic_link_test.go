@@ -0,0 +1,338 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jayk0001/my-go-next-todo/internal/mail"
+)
+
+const magicLinkSubject = "Your sign-in link"
+
+// MockMagicLinkRepository implements MagicLinkRepositoryInterface in memory for testing
+type MockMagicLinkRepository struct {
+	mu     sync.Mutex
+	tokens map[int]*MagicLinkToken
+	nextID int
+}
+
+// NewMockMagicLinkRepository creates a new mock sign-in link repository
+func NewMockMagicLinkRepository() *MockMagicLinkRepository {
+	return &MockMagicLinkRepository{
+		tokens: make(map[int]*MagicLinkToken),
+		nextID: 1,
+	}
+}
+
+func (m *MockMagicLinkRepository) Create(ctx context.Context, token *MagicLinkToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token.ID = m.nextID
+	token.CreatedAt = time.Now()
+	m.nextID++
+
+	copied := *token
+	m.tokens[token.ID] = &copied
+	return nil
+}
+
+func (m *MockMagicLinkRepository) Consume(ctx context.Context, tokenHash string) (*MagicLinkToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, token := range m.tokens {
+		if token.TokenHash == tokenHash && token.UsedAt == nil && time.Now().Before(token.ExpiresAt) {
+			now := time.Now()
+			token.UsedAt = &now
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, ErrMagicLinkInvalid
+}
+
+func (m *MockMagicLinkRepository) InvalidateForEmail(ctx context.Context, email string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, token := range m.tokens {
+		if token.Email == email && token.UsedAt == nil {
+			token.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+// expireAll moves the expiry of every stored token into the past
+func (m *MockMagicLinkRepository) expireAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, token := range m.tokens {
+		token.ExpiresAt = time.Now().Add(-time.Minute)
+	}
+}
+
+// requestMagicLink requests a sign-in link for email and returns the nth emailed token
+func requestMagicLink(t *testing.T, authService *AuthService, mailer *mail.MemoryMailer, email string, n int) string {
+	t.Helper()
+
+	if err := authService.RequestMagicLink(context.Background(), email); err != nil {
+		t.Fatalf("RequestMagicLink should succeed: %v", err)
+	}
+
+	msg := waitForMail(t, mailer, magicLinkSubject, n)
+	if msg.To != email {
+		t.Errorf("Expected mail to %s, got %s", email, msg.To)
+	}
+	return tokenFromLink(t, msg.Body, "/magic-link")
+}
+
+// TestRequestMagicLinkSendsEmail tests that a sign-in link is emailed and only its hash is stored
+func TestRequestMagicLinkSendsEmail(t *testing.T) {
+	authService, _, mailer := createTestAuthServiceWithMailer()
+	ctx := context.Background()
+
+	if _, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword); err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	token := requestMagicLink(t, authService, mailer, serviceTestData.testEmail, 1)
+
+	msg := waitForMail(t, mailer, magicLinkSubject, 1)
+	if !strings.Contains(msg.Body, "https://app.example.com/magic-link?token=") {
+		t.Errorf("Expected sign-in link on the app URL, got: %q", msg.Body)
+	}
+
+	repo := authService.magicLinkRepo.(*MockMagicLinkRepository)
+	stored := repo.tokens[1]
+	if stored.TokenHash == token || stored.TokenHash != hashToken(token) {
+		t.Error("Only the hash of the sign-in token should be stored")
+	}
+	if until := time.Until(stored.ExpiresAt); until <= 0 || until > MagicLinkTokenExpiry {
+		t.Errorf("Unexpected token expiry in %v", until)
+	}
+}
+
+// TestRequestMagicLinkUnknownEmail tests that unknown and disabled accounts look the same as real ones
+func TestRequestMagicLinkUnknownEmail(t *testing.T) {
+	authService, mockRepo, mailer := createTestAuthServiceWithMailer()
+	ctx := context.Background()
+
+	if err := authService.RequestMagicLink(ctx, "nobody@example.com"); err != nil {
+		t.Errorf("Unknown email should not be reported, got: %v", err)
+	}
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+	if err := mockRepo.SetDisabled(ctx, result.User.ID, true); err != nil {
+		t.Fatalf("SetDisabled should succeed: %v", err)
+	}
+	if err := authService.RequestMagicLink(ctx, serviceTestData.testEmail); err != nil {
+		t.Errorf("Disabled account should not be reported, got: %v", err)
+	}
+
+	if err := authService.RequestMagicLink(ctx, "not-an-email"); err == nil {
+		t.Error("Invalid email should fail validation")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if count := countMail(mailer, magicLinkSubject); count != 0 {
+		t.Errorf("Expected no sign-in mail, got %d", count)
+	}
+}
+
+// TestConsumeMagicLink tests signing in with an emailed link
+func TestConsumeMagicLink(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Signs in once", func(t *testing.T) {
+		authService, _, mailer := createTestAuthServiceWithMailer()
+
+		registered, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+		if err != nil {
+			t.Fatalf("Registration should succeed: %v", err)
+		}
+
+		token := requestMagicLink(t, authService, mailer, serviceTestData.testEmail, 1)
+
+		result, err := authService.ConsumeMagicLink(ctx, token)
+		if err != nil {
+			t.Fatalf("ConsumeMagicLink should succeed: %v", err)
+		}
+		if result.User.ID != registered.User.ID {
+			t.Errorf("Expected user %d, got %d", registered.User.ID, result.User.ID)
+		}
+		if result.Token == "" || result.RefreshToken == "" || result.Session == nil {
+			t.Error("Expected a full session like login returns")
+		}
+		if !result.User.IsEmailVerified() {
+			t.Error("Opening the link should verify the email address")
+		}
+
+		if _, err := authService.ConsumeMagicLink(ctx, token); !errors.Is(err, ErrMagicLinkInvalid) {
+			t.Errorf("Expected ErrMagicLinkInvalid on reuse, got %v", err)
+		}
+	})
+
+	t.Run("Expired link", func(t *testing.T) {
+		authService, _, mailer := createTestAuthServiceWithMailer()
+
+		if _, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword); err != nil {
+			t.Fatalf("Registration should succeed: %v", err)
+		}
+
+		token := requestMagicLink(t, authService, mailer, serviceTestData.testEmail, 1)
+		authService.magicLinkRepo.(*MockMagicLinkRepository).expireAll()
+
+		if _, err := authService.ConsumeMagicLink(ctx, token); !errors.Is(err, ErrMagicLinkInvalid) {
+			t.Errorf("Expected ErrMagicLinkInvalid, got %v", err)
+		}
+	})
+
+	t.Run("Only the newest link works", func(t *testing.T) {
+		authService, _, mailer := createTestAuthServiceWithMailer()
+
+		if _, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword); err != nil {
+			t.Fatalf("Registration should succeed: %v", err)
+		}
+
+		first := requestMagicLink(t, authService, mailer, serviceTestData.testEmail, 1)
+		second := requestMagicLink(t, authService, mailer, serviceTestData.testEmail, 2)
+
+		if _, err := authService.ConsumeMagicLink(ctx, first); !errors.Is(err, ErrMagicLinkInvalid) {
+			t.Errorf("Expected the older link to be invalid, got %v", err)
+		}
+		if _, err := authService.ConsumeMagicLink(ctx, second); err != nil {
+			t.Errorf("Newest link should work: %v", err)
+		}
+	})
+
+	t.Run("Unknown token", func(t *testing.T) {
+		authService, _, _ := createTestAuthServiceWithMailer()
+
+		if _, err := authService.ConsumeMagicLink(ctx, "not-a-token"); !errors.Is(err, ErrMagicLinkInvalid) {
+			t.Errorf("Expected ErrMagicLinkInvalid, got %v", err)
+		}
+	})
+
+	t.Run("Disabled account", func(t *testing.T) {
+		authService, mockRepo, mailer := createTestAuthServiceWithMailer()
+
+		registered, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+		if err != nil {
+			t.Fatalf("Registration should succeed: %v", err)
+		}
+
+		token := requestMagicLink(t, authService, mailer, serviceTestData.testEmail, 1)
+		if err := mockRepo.SetDisabled(ctx, registered.User.ID, true); err != nil {
+			t.Fatalf("SetDisabled should succeed: %v", err)
+		}
+
+		if _, err := authService.ConsumeMagicLink(ctx, token); !errors.Is(err, ErrAccountDisabled) {
+			t.Errorf("Expected ErrAccountDisabled, got %v", err)
+		}
+	})
+
+	t.Run("Two-factor challenge", func(t *testing.T) {
+		authService, _, mailer := createTestAuthServiceWithMailer()
+		enableTwoFactor(t, authService)
+
+		token := requestMagicLink(t, authService, mailer, serviceTestData.testEmail, 1)
+
+		_, err := authService.ConsumeMagicLink(ctx, token)
+		var required *TwoFactorRequiredError
+		if !errors.As(err, &required) {
+			t.Fatalf("Expected TwoFactorRequiredError, got: %v", err)
+		}
+	})
+}
+
+// TestMagicLinkAutoRegister tests that a first-time address gets an account only when auto-registration is on
+func TestMagicLinkAutoRegister(t *testing.T) {
+	ctx := context.Background()
+	email := "new-user@example.com"
+
+	authService, mockRepo, mailer := createTestAuthServiceWithMailer()
+	authService.SetMagicLinkAutoRegister(true)
+
+	hookCalls := 0
+	authService.SetUserCreatedHook(func(ctx context.Context, user *User) error {
+		hookCalls++
+		return nil
+	})
+
+	token := requestMagicLink(t, authService, mailer, email, 1)
+
+	if exists, _ := mockRepo.EmailExists(ctx, email); exists {
+		t.Error("Requesting a link should not create the account yet")
+	}
+
+	result, err := authService.ConsumeMagicLink(ctx, token)
+	if err != nil {
+		t.Fatalf("ConsumeMagicLink should succeed: %v", err)
+	}
+	if result.User.Email != email {
+		t.Errorf("Expected user %s, got %s", email, result.User.Email)
+	}
+	if !result.User.IsEmailVerified() {
+		t.Error("Auto-registered user should have a verified email")
+	}
+	if result.User.HasPassword() {
+		t.Error("Auto-registered user should not have a password")
+	}
+	if hookCalls != 1 {
+		t.Errorf("Expected the user created hook to run once, got %d", hookCalls)
+	}
+
+	t.Run("Switched off after the link was sent", func(t *testing.T) {
+		token := requestMagicLink(t, authService, mailer, "later@example.com", 2)
+		authService.SetMagicLinkAutoRegister(false)
+
+		if _, err := authService.ConsumeMagicLink(ctx, token); !errors.Is(err, ErrMagicLinkInvalid) {
+			t.Errorf("Expected ErrMagicLinkInvalid, got %v", err)
+		}
+	})
+}
+
+// TestMagicLinkUserDeletesAccount tests that an auto-registered account, which has no password,
+// can be deleted from a session that just signed in through a link
+func TestMagicLinkUserDeletesAccount(t *testing.T) {
+	ctx := context.Background()
+	email := "new-user@example.com"
+
+	authService, mockRepo, mailer := createTestAuthServiceWithMailer()
+	authService.SetMagicLinkAutoRegister(true)
+
+	result, err := authService.ConsumeMagicLink(ctx, requestMagicLink(t, authService, mailer, email, 1))
+	if err != nil {
+		t.Fatalf("ConsumeMagicLink should succeed: %v", err)
+	}
+
+	principal, err := authService.AuthenticateToken(ctx, result.Token)
+	if err != nil {
+		t.Fatalf("AuthenticateToken should succeed: %v", err)
+	}
+
+	// Once the sign-in is no longer recent, a fresh link has to be requested first
+	sessionMock(authService).sessions[result.Session.ID].CreatedAt = time.Now().Add(-RecentSignInWindow)
+	if err := authService.DeleteAccount(ctx, principal, ""); !errors.Is(err, ErrReauthRequired) {
+		t.Errorf("Expected ErrReauthRequired, got %v", err)
+	}
+
+	sessionMock(authService).sessions[result.Session.ID].CreatedAt = time.Now()
+	if err := authService.DeleteAccount(ctx, principal, ""); err != nil {
+		t.Fatalf("DeleteAccount should succeed: %v", err)
+	}
+	if exists, _ := mockRepo.EmailExists(ctx, email); exists {
+		t.Error("User should be deleted")
+	}
+}
@@ -0,0 +1,367 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// oidcHTTPTimeout bounds each request made to an identity provider
+const oidcHTTPTimeout = 10 * time.Second
+
+// oidcKeyRefreshInterval limits how often a provider's keys are refetched for an unknown kid
+const oidcKeyRefreshInterval = time.Minute
+
+// oidcMaxResponseBytes limits how much of a provider response is read
+const oidcMaxResponseBytes = 1 << 20
+
+// DefaultOIDCScopes are requested when a provider has no scopes configured
+var DefaultOIDCScopes = []string{"openid", "email", "profile"}
+
+var (
+	ErrOIDCProviderUnknown  = errors.New("unknown sign-in provider")
+	ErrOIDCDiscoveryFailed  = errors.New("failed to discover sign-in provider")
+	ErrOIDCExchangeFailed   = errors.New("failed to exchange authorization code")
+	ErrIDTokenInvalid       = errors.New("invalid ID token")
+	ErrOIDCEmailNotVerified = errors.New("sign-in provider did not return a verified email address")
+	ErrOIDCStateInvalid     = errors.New("invalid or expired sign-in state")
+)
+
+// OIDCProviderConfig describes an OpenID Connect identity provider users can sign in with
+type OIDCProviderConfig struct {
+	// Name identifies the provider in URLs and linked identities, e.g. "google"
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is our callback URL registered with the provider
+	RedirectURL string
+	// Scopes defaults to DefaultOIDCScopes when empty
+	Scopes []string
+}
+
+// OIDCClaims are the ID token claims used to find or create the user signing in
+type OIDCClaims struct {
+	Email           string   `json:"email"`
+	EmailVerified   oidcBool `json:"email_verified"`
+	Name            string   `json:"name"`
+	Nonce           string   `json:"nonce"`
+	AuthorizedParty string   `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// oidcBool accepts booleans sent as JSON strings, which some providers do for email_verified
+type oidcBool bool
+
+func (b *oidcBool) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case bool:
+		*b = oidcBool(v)
+	case string:
+		*b = oidcBool(v == "true")
+	default:
+		*b = false
+	}
+	return nil
+}
+
+// oidcMetadata is the part of a provider's discovery document the client uses
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCProvider signs users in through an OpenID Connect provider with the authorization code flow and PKCE.
+// The discovery document and signing keys are fetched on first use and cached.
+type OIDCProvider struct {
+	config OIDCProviderConfig
+	client *http.Client
+
+	mu            sync.Mutex
+	metadata      *oidcMetadata
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+// NewOIDCProvider creates a client for the provider described by cfg.
+// A nil httpClient uses a client with a short timeout.
+func NewOIDCProvider(cfg OIDCProviderConfig, httpClient *http.Client) *OIDCProvider {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: oidcHTTPTimeout}
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = DefaultOIDCScopes
+	}
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+
+	return &OIDCProvider{
+		config: cfg,
+		client: httpClient,
+	}
+}
+
+// Name returns the name the provider is configured under
+func (p *OIDCProvider) Name() string {
+	return p.config.Name
+}
+
+// AuthCodeURL returns the provider URL that starts a sign-in. The provider redirects back
+// to the configured RedirectURL with state and a code for Exchange.
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(p.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return metadata.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange trades an authorization code for tokens and returns the claims of the verified ID token.
+// nonce must be the value passed to AuthCodeURL for this sign-in.
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*OIDCClaims, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCExchangeFailed, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// client_secret_basic, with both parts form-encoded as RFC 6749 section 2.3.1 requires
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	var response struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.doJSON(req, &response)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCExchangeFailed, err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d: %s %s", ErrOIDCExchangeFailed, status, response.Error, response.ErrorDescription)
+	}
+	if response.IDToken == "" {
+		return nil, fmt.Errorf("%w: no ID token in response", ErrOIDCExchangeFailed)
+	}
+
+	return p.verifyIDToken(ctx, response.IDToken, nonce)
+}
+
+// verifyIDToken checks the ID token's signature, issuer, audience, expiry and nonce
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, rawToken, nonce string) (*OIDCClaims, error) {
+	var claims OIDCClaims
+	_, err := jwt.ParseWithClaims(rawToken, &claims,
+		func(token *jwt.Token) (any, error) {
+			return p.verificationKey(ctx, token)
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(p.config.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIDTokenInvalid, err)
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrIDTokenInvalid)
+	}
+
+	if nonce == "" || claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrIDTokenInvalid)
+	}
+
+	// A token issued to several clients names the one it was meant for
+	if claims.AuthorizedParty != "" && claims.AuthorizedParty != p.config.ClientID {
+		return nil, fmt.Errorf("%w: issued to another client", ErrIDTokenInvalid)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty == "" {
+		return nil, fmt.Errorf("%w: missing authorized party", ErrIDTokenInvalid)
+	}
+
+	return &claims, nil
+}
+
+// verificationKey returns the provider key for a token's kid, refetching the provider's keys
+// once if the kid is unknown, since providers rotate keys without notice
+func (p *OIDCProvider) verificationKey(ctx context.Context, token *jwt.Token) (crypto.PublicKey, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	p.mu.Lock()
+	publicKey, ok := p.keys[kid]
+	stale := time.Since(p.keysFetchedAt) >= oidcKeyRefreshInterval
+	p.mu.Unlock()
+
+	if !ok && stale {
+		if err := p.fetchKeys(ctx); err != nil {
+			return nil, err
+		}
+
+		p.mu.Lock()
+		publicKey, ok = p.keys[kid]
+		p.mu.Unlock()
+	}
+
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+
+	method, err := signingMethodFor(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %s", token.Method.Alg(), kid)
+	}
+
+	return publicKey, nil
+}
+
+// fetchKeys replaces the cached keys with the provider's current JWKS, skipping keys
+// that are not for signatures or of an unsupported type
+func (p *OIDCProvider) fetchKeys(ctx context.Context) error {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadata.JWKSURI, nil)
+	if err != nil {
+		return err
+	}
+
+	var jwks JWKS
+	status, err := p.doJSON(req, &jwks)
+	if err != nil {
+		return fmt.Errorf("failed to fetch provider keys: %w", err)
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("failed to fetch provider keys: status %d", status)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		publicKey, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		if _, err := signingMethodFor(publicKey); err != nil {
+			continue
+		}
+		keys[jwk.Kid] = publicKey
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+	p.mu.Unlock()
+
+	return nil
+}
+
+// discover fetches and caches the provider's discovery document
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcMetadata, error) {
+	p.mu.Lock()
+	metadata := p.metadata
+	p.mu.Unlock()
+	if metadata != nil {
+		return metadata, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCDiscoveryFailed, err)
+	}
+
+	metadata = &oidcMetadata{}
+	status, err := p.doJSON(req, metadata)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCDiscoveryFailed, err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d", ErrOIDCDiscoveryFailed, status)
+	}
+
+	// The issuer must be exactly the one configured, or its ID tokens would not verify
+	if strings.TrimSuffix(metadata.Issuer, "/") != p.config.Issuer {
+		return nil, fmt.Errorf("%w: issuer %q does not match %q", ErrOIDCDiscoveryFailed, metadata.Issuer, p.config.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: missing endpoints", ErrOIDCDiscoveryFailed)
+	}
+
+	p.mu.Lock()
+	p.metadata = metadata
+	p.mu.Unlock()
+
+	return metadata, nil
+}
+
+// doJSON sends req and decodes a JSON response body into v, returning the status code
+func (p *OIDCProvider) doJSON(req *http.Request, v any) (int, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseBytes)).Decode(v); err != nil && resp.StatusCode == http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("invalid response: %w", err)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,402 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	stubClientID     = "todo-app"
+	stubClientSecret = "client-secret"
+	stubRedirectURL  = "http://localhost:8080/auth/oidc/stub/callback"
+)
+
+// stubAuthorization is what the stub provider remembers about an issued authorization code
+type stubAuthorization struct {
+	challenge string
+	nonce     string
+}
+
+// stubOIDCProvider is a minimal OpenID Connect provider serving discovery, authorization,
+// token and JWKS endpoints. It signs users in as subject/email without asking anything.
+type stubOIDCProvider struct {
+	server *httptest.Server
+
+	mu             sync.Mutex
+	key            *rsa.PrivateKey
+	kid            string
+	codes          map[string]stubAuthorization
+	subject        string
+	email          string
+	emailVerified  any
+	discoveryIssue string
+	// tamper changes the ID token claims before they are signed
+	tamper func(claims jwt.MapClaims)
+	// signWith signs ID tokens with a key that is not published, when set
+	signWith *rsa.PrivateKey
+}
+
+// newStubOIDCProvider starts a stub provider that signs users in as sub-123 / oidc@example.com
+func newStubOIDCProvider(t *testing.T) *stubOIDCProvider {
+	t.Helper()
+
+	stub := &stubOIDCProvider{
+		key:           generateRSAKey(t, 2048),
+		kid:           "key-1",
+		codes:         make(map[string]stubAuthorization),
+		subject:       "sub-123",
+		email:         "oidc@example.com",
+		emailVerified: true,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", stub.discovery)
+	mux.HandleFunc("/authorize", stub.authorize)
+	mux.HandleFunc("/token", stub.token)
+	mux.HandleFunc("/jwks", stub.jwks)
+
+	stub.server = httptest.NewServer(mux)
+	t.Cleanup(stub.server.Close)
+
+	return stub
+}
+
+// config returns the provider settings for signing in through the stub
+func (s *stubOIDCProvider) config() OIDCProviderConfig {
+	return OIDCProviderConfig{
+		Name:         "stub",
+		Issuer:       s.server.URL,
+		ClientID:     stubClientID,
+		ClientSecret: stubClientSecret,
+		RedirectURL:  stubRedirectURL,
+	}
+}
+
+// rotateKey replaces the signing key with a new one under a new kid
+func (s *stubOIDCProvider) rotateKey(t *testing.T, kid string) {
+	key := generateRSAKey(t, 2048)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.key = key
+	s.kid = kid
+}
+
+func (s *stubOIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	issuer := s.server.URL
+	if s.discoveryIssue != "" {
+		issuer = s.discoveryIssue
+	}
+	s.mu.Unlock()
+
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 issuer,
+		"authorization_endpoint": s.server.URL + "/authorize",
+		"token_endpoint":         s.server.URL + "/token",
+		"jwks_uri":               s.server.URL + "/jwks",
+	})
+}
+
+// authorize approves every request straight away, redirecting back with a code
+func (s *stubOIDCProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("response_type") != "code" || query.Get("client_id") != stubClientID ||
+		query.Get("redirect_uri") != stubRedirectURL || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	code, _, _ := generateOpaqueToken()
+
+	s.mu.Lock()
+	s.codes[code] = stubAuthorization{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	s.mu.Unlock()
+
+	callback := query.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+	http.Redirect(w, r, callback, http.StatusFound)
+}
+
+// token redeems a code once, checking client credentials and the PKCE verifier
+func (s *stubOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	writeError := func(code string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": code})
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != stubClientID || clientSecret != stubClientSecret {
+		writeError("invalid_client")
+		return
+	}
+
+	if r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("redirect_uri") != stubRedirectURL {
+		writeError("invalid_request")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	authorization, ok := s.codes[r.PostFormValue("code")]
+	delete(s.codes, r.PostFormValue("code"))
+	if !ok {
+		writeError("invalid_grant")
+		return
+	}
+
+	verifier := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(verifier[:]) != authorization.challenge {
+		writeError("invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            s.server.URL,
+		"sub":            s.subject,
+		"aud":            stubClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          authorization.nonce,
+		"email":          s.email,
+		"email_verified": s.emailVerified,
+	}
+	if s.tamper != nil {
+		s.tamper(claims)
+	}
+
+	key := s.key
+	if s.signWith != nil {
+		key = s.signWith
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = s.kid
+	idToken, err := token.SignedString(key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "provider-access-token",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func (s *stubOIDCProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jwk, _ := publicJWK(s.key.Public())
+	jwk.Kid = s.kid
+	_ = json.NewEncoder(w).Encode(JWKS{Keys: []JWK{jwk}})
+}
+
+// approve follows a sign-in URL to the stub provider and returns the code and state of its callback
+func approve(t *testing.T, authURL string) (string, string) {
+	t.Helper()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("Authorization request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("Expected a redirect back to the app, got status %d", resp.StatusCode)
+	}
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Invalid callback URL: %v", err)
+	}
+	return callback.Query().Get("code"), callback.Query().Get("state")
+}
+
+// TestOIDCProviderExchange tests the authorization code flow against the stub provider
+func TestOIDCProviderExchange(t *testing.T) {
+	ctx := context.Background()
+
+	// signIn runs a full sign-in, exchanging with verifier and expecting nonce in the ID token
+	signIn := func(t *testing.T, provider *OIDCProvider, verifier, nonce string) (*OIDCClaims, error) {
+		t.Helper()
+
+		authURL, err := provider.AuthCodeURL(ctx, "state-1", "nonce-1", "verifier-1")
+		if err != nil {
+			t.Fatalf("AuthCodeURL failed: %v", err)
+		}
+
+		code, state := approve(t, authURL)
+		if state != "state-1" {
+			t.Errorf("Expected state to round-trip, got %q", state)
+		}
+
+		return provider.Exchange(ctx, code, verifier, nonce)
+	}
+
+	t.Run("valid sign-in", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		provider := NewOIDCProvider(stub.config(), nil)
+
+		claims, err := signIn(t, provider, "verifier-1", "nonce-1")
+		if err != nil {
+			t.Fatalf("Exchange failed: %v", err)
+		}
+
+		if claims.Subject != "sub-123" || claims.Email != "oidc@example.com" || !claims.EmailVerified {
+			t.Errorf("Unexpected claims: %+v", claims)
+		}
+	})
+
+	t.Run("email_verified sent as a string", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		stub.emailVerified = "true"
+		provider := NewOIDCProvider(stub.config(), nil)
+
+		claims, err := signIn(t, provider, "verifier-1", "nonce-1")
+		if err != nil {
+			t.Fatalf("Exchange failed: %v", err)
+		}
+		if !claims.EmailVerified {
+			t.Error("Expected \"true\" to count as verified")
+		}
+	})
+
+	t.Run("wrong PKCE verifier", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		provider := NewOIDCProvider(stub.config(), nil)
+
+		if _, err := signIn(t, provider, "another-verifier", "nonce-1"); !errors.Is(err, ErrOIDCExchangeFailed) {
+			t.Errorf("Expected ErrOIDCExchangeFailed, got %v", err)
+		}
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		provider := NewOIDCProvider(stub.config(), nil)
+
+		if _, err := signIn(t, provider, "verifier-1", "another-nonce"); !errors.Is(err, ErrIDTokenInvalid) {
+			t.Errorf("Expected ErrIDTokenInvalid, got %v", err)
+		}
+	})
+
+	tampered := []struct {
+		name   string
+		tamper func(claims jwt.MapClaims)
+	}{
+		{"wrong issuer", func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example.com" }},
+		{"wrong audience", func(claims jwt.MapClaims) { claims["aud"] = "another-client" }},
+		{"expired", func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{"no expiry", func(claims jwt.MapClaims) { delete(claims, "exp") }},
+		{"no subject", func(claims jwt.MapClaims) { delete(claims, "sub") }},
+		{"issued to another client", func(claims jwt.MapClaims) {
+			claims["aud"] = []string{stubClientID, "another-client"}
+			claims["azp"] = "another-client"
+		}},
+		{"several audiences without azp", func(claims jwt.MapClaims) {
+			claims["aud"] = []string{stubClientID, "another-client"}
+		}},
+	}
+
+	for _, tt := range tampered {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newStubOIDCProvider(t)
+			stub.tamper = tt.tamper
+			provider := NewOIDCProvider(stub.config(), nil)
+
+			if _, err := signIn(t, provider, "verifier-1", "nonce-1"); !errors.Is(err, ErrIDTokenInvalid) {
+				t.Errorf("Expected ErrIDTokenInvalid, got %v", err)
+			}
+		})
+	}
+
+	t.Run("signed with an unpublished key", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		stub.signWith = generateRSAKey(t, 2048)
+		provider := NewOIDCProvider(stub.config(), nil)
+
+		if _, err := signIn(t, provider, "verifier-1", "nonce-1"); !errors.Is(err, ErrIDTokenInvalid) {
+			t.Errorf("Expected ErrIDTokenInvalid, got %v", err)
+		}
+	})
+
+	t.Run("rotated provider keys are refetched", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		provider := NewOIDCProvider(stub.config(), nil)
+
+		if _, err := signIn(t, provider, "verifier-1", "nonce-1"); err != nil {
+			t.Fatalf("Exchange failed: %v", err)
+		}
+
+		stub.rotateKey(t, "key-2")
+		// Pretend the cached keys are old enough to be refreshed
+		provider.keysFetchedAt = time.Time{}
+
+		if _, err := signIn(t, provider, "verifier-1", "nonce-1"); err != nil {
+			t.Fatalf("Exchange after rotation failed: %v", err)
+		}
+	})
+
+	t.Run("issuer mismatch in discovery", func(t *testing.T) {
+		stub := newStubOIDCProvider(t)
+		stub.discoveryIssue = "https://evil.example.com"
+		provider := NewOIDCProvider(stub.config(), nil)
+
+		if _, err := provider.AuthCodeURL(ctx, "state", "nonce", "verifier"); !errors.Is(err, ErrOIDCDiscoveryFailed) {
+			t.Errorf("Expected ErrOIDCDiscoveryFailed, got %v", err)
+		}
+	})
+}
+
+// TestOIDCProviderAuthCodeURL tests the parameters sent to the provider
+func TestOIDCProviderAuthCodeURL(t *testing.T) {
+	stub := newStubOIDCProvider(t)
+	provider := NewOIDCProvider(stub.config(), nil)
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+	if err != nil {
+		t.Fatalf("AuthCodeURL failed: %v", err)
+	}
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("Invalid URL: %v", err)
+	}
+	query := parsed.Query()
+
+	challenge := sha256.Sum256([]byte("verifier"))
+	expected := map[string]string{
+		"response_type":         "code",
+		"client_id":             stubClientID,
+		"redirect_uri":          stubRedirectURL,
+		"scope":                 "openid email profile",
+		"state":                 "state",
+		"nonce":                 "nonce",
+		"code_challenge":        base64.RawURLEncoding.EncodeToString(challenge[:]),
+		"code_challenge_method": "S256",
+	}
+	for key, value := range expected {
+		if query.Get(key) != value {
+			t.Errorf("Expected %s=%q, got %q", key, value, query.Get(key))
+		}
+	}
+}
@@ -1,27 +1,105 @@
 package auth
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
 
 const (
 	// DefaultCost is the default bcrypt cost for password hashing
 	DefaultCost = 12
 )
 
-// PasswordService handles password hashing and verification
+var (
+	ErrPasswordMismatch     = errors.New("password does not match")
+	ErrUnknownHashAlgorithm = errors.New("unknown password hash algorithm")
+)
+
+// PasswordHasher hashes and verifies passwords with one algorithm.
+// Hashes are self-describing, so the algorithm that made one can be told from its prefix.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Verify returns ErrPasswordMismatch if password doesn't match encodedHash
+	Verify(encodedHash, password string) error
+	// Recognizes reports whether encodedHash was made by this algorithm
+	Recognizes(encodedHash string) bool
+	// NeedsRehash reports whether encodedHash, made by this algorithm, uses other parameters than the hasher
+	NeedsRehash(encodedHash string) bool
+}
+
+// PasswordService handles password hashing and verification.
+// New hashes use the configured hasher; existing bcrypt and argon2id hashes keep verifying.
 type PasswordService struct {
-	cost int
+	hasher PasswordHasher
 }
 
-// NewPasswordService created a new password service
+// NewPasswordService created a new password service hashing with argon2id
 func NewPasswordService() *PasswordService {
 	return &PasswordService{
-		cost: DefaultCost,
+		hasher: NewArgon2idHasher(DefaultArgon2Params()),
 	}
 }
 
-// HashPassword hashes a plain text password using bcrypt
+// SetHasher changes the algorithm used for new hashes
+func (p *PasswordService) SetHasher(hasher PasswordHasher) {
+	p.hasher = hasher
+}
+
+// HashPassword hashes a plain text password with the configured hasher
 func (p *PasswordService) HashPassword(password string) (string, error) {
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), p.cost)
+	return p.hasher.Hash(password)
+}
+
+// VerifyPassword verifies a plain text password against a hashed password of any supported algorithm
+func (p *PasswordService) VerifyPassword(hashedPassword, password string) error {
+	hasher, err := p.hasherFor(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	return hasher.Verify(hashedPassword, password)
+}
+
+// NeedsRehash reports whether hashedPassword should be replaced by a hash from the configured hasher,
+// because it uses another algorithm or other parameters
+func (p *PasswordService) NeedsRehash(hashedPassword string) bool {
+	return !p.hasher.Recognizes(hashedPassword) || p.hasher.NeedsRehash(hashedPassword)
+}
+
+// hasherFor detects the algorithm of hashedPassword from its prefix
+func (p *PasswordService) hasherFor(hashedPassword string) (PasswordHasher, error) {
+	// Parameters are read from the hash itself, so any instance verifies any hash of its algorithm
+	for _, hasher := range []PasswordHasher{p.hasher, NewArgon2idHasher(DefaultArgon2Params()), NewBcryptHasher(DefaultCost)} {
+		if hasher.Recognizes(hashedPassword) {
+			return hasher, nil
+		}
+	}
+
+	return nil, ErrUnknownHashAlgorithm
+}
+
+// BcryptHasher hashes passwords with bcrypt. Passwords longer than 72 bytes are rejected.
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher creates a bcrypt hasher with cost
+func NewBcryptHasher(cost int) *BcryptHasher {
+	return &BcryptHasher{
+		cost: cost,
+	}
+}
+
+// Hash hashes password with bcrypt
+func (b *BcryptHasher) Hash(password string) (string, error) {
+	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), b.cost)
 	if err != nil {
 		return "", err
 	}
@@ -29,14 +107,145 @@ func (p *PasswordService) HashPassword(password string) (string, error) {
 	return string(hashedBytes), nil
 }
 
-// VerifyPassword verifies a plain text password against a hashed password
-func (p *PasswordService) VerifyPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+// Verify checks password against a bcrypt hash
+func (b *BcryptHasher) Verify(encodedHash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrPasswordMismatch
+	}
+	return err
+}
+
+// Recognizes reports whether encodedHash is a bcrypt hash
+func (b *BcryptHasher) Recognizes(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$2a$") || strings.HasPrefix(encodedHash, "$2b$") || strings.HasPrefix(encodedHash, "$2y$")
+}
+
+// NeedsRehash reports whether encodedHash has a lower cost than the hasher
+func (b *BcryptHasher) NeedsRehash(encodedHash string) bool {
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	return err != nil || cost < b.cost
+}
+
+// Argon2Params are the argon2id cost parameters
+type Argon2Params struct {
+	// Memory is in KiB
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params returns the OWASP recommended minimum: 19 MiB of memory, 2 iterations, 1 thread
+func DefaultArgon2Params() Argon2Params {
+	return Argon2Params{
+		Memory:      19 * 1024,
+		Iterations:  2,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
 }
 
-// IsValidPassword checks if password meets minimum requirements
-func (p *PasswordService) IsValidPassword(password string) bool {
-	// Basic password validation
-	// In production, might want more sophisticated rules
-	return len(password) >= 8
+// argon2idPrefix starts every hash in the PHC string format used by the reference implementation
+const argon2idPrefix = "$argon2id$"
+
+// Argon2idHasher hashes passwords with argon2id
+type Argon2idHasher struct {
+	params Argon2Params
+}
+
+// NewArgon2idHasher creates an argon2id hasher with params
+func NewArgon2idHasher(params Argon2Params) *Argon2idHasher {
+	return &Argon2idHasher{
+		params: params,
+	}
+}
+
+// Hash hashes password with argon2id and a random salt,
+// encoded as $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>
+func (a *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, a.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.params.Iterations, a.params.Memory, a.params.Parallelism, a.params.KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, a.params.Memory, a.params.Iterations, a.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify checks password against an argon2id hash using the parameters stored in the hash
+func (a *Argon2idHasher) Verify(encodedHash, password string) error {
+	params, salt, key, err := decodeArgon2idHash(encodedHash)
+	if err != nil {
+		return err
+	}
+
+	computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	if subtle.ConstantTimeCompare(key, computed) != 1 {
+		return ErrPasswordMismatch
+	}
+
+	return nil
+}
+
+// Recognizes reports whether encodedHash is an argon2id hash
+func (a *Argon2idHasher) Recognizes(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, argon2idPrefix)
+}
+
+// NeedsRehash reports whether encodedHash was made with other parameters than the hasher's
+func (a *Argon2idHasher) NeedsRehash(encodedHash string) bool {
+	params, _, _, err := decodeArgon2idHash(encodedHash)
+	if err != nil {
+		return true
+	}
+
+	return params.Memory != a.params.Memory ||
+		params.Iterations != a.params.Iterations ||
+		params.Parallelism != a.params.Parallelism ||
+		params.SaltLength < a.params.SaltLength ||
+		params.KeyLength < a.params.KeyLength
+}
+
+// decodeArgon2idHash parses a hash made by Argon2idHasher.Hash
+func decodeArgon2idHash(encodedHash string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version")
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+	if params.Iterations < 1 || params.Parallelism < 1 {
+		return params, nil, nil, fmt.Errorf("invalid argon2id parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, fmt.Errorf("invalid argon2id key")
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
 }
@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordPolicy is the set of rules new passwords have to meet
+type PasswordPolicy struct {
+	// MinLength and MaxLength count characters, not bytes. MaxLength 0 means no limit.
+	MinLength int
+	MaxLength int
+	// MinCharacterClasses is how many of lowercase letters, uppercase letters, digits and
+	// other characters a password needs to mix
+	MinCharacterClasses int
+	// RejectEmailLocalPart rejects passwords containing the part of the email before the @
+	RejectEmailLocalPart bool
+	// Breached rejects common or breached passwords. Nil skips the check.
+	Breached BreachedPasswordList
+}
+
+// DefaultPasswordPolicy returns the policy used unless the server configures another one
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:            8,
+		MaxLength:            128,
+		RejectEmailLocalPart: true,
+	}
+}
+
+// minEmailLocalPartLength is the shortest local part worth rejecting; shorter ones match too many passwords by chance
+const minEmailLocalPartLength = 3
+
+// Check returns the rules password breaks for the account with email, or nil if it meets the policy.
+// The error is only set if the breached password list couldn't be read.
+func (p PasswordPolicy) Check(password, email string) (ValidationErrors, error) {
+	ve := ValidationErrors{}
+	if err := p.check(&ve, password, email); err != nil {
+		return nil, err
+	}
+
+	if ve.HasErrors() {
+		return ve, nil
+	}
+
+	return nil, nil
+}
+
+// check adds the rules password breaks to ve
+func (p PasswordPolicy) check(ve *ValidationErrors, password, email string) error {
+	if password == "" {
+		ve.add("password", CodeRequired, "password is required")
+		return nil
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		ve.add("password", CodeTooShort, fmt.Sprintf("password must be at least %d character long", p.MinLength))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		ve.add("password", CodeTooLong, fmt.Sprintf("password must be at most %d characters long", p.MaxLength))
+	}
+
+	if p.MinCharacterClasses > 0 && characterClasses(password) < p.MinCharacterClasses {
+		ve.add("password", CodeCharacterClasses, fmt.Sprintf(
+			"password must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinCharacterClasses,
+		))
+	}
+
+	if p.RejectEmailLocalPart {
+		localPart, _, found := strings.Cut(email, "@")
+		if found && utf8.RuneCountInString(localPart) >= minEmailLocalPartLength &&
+			strings.Contains(strings.ToLower(password), strings.ToLower(localPart)) {
+			ve.add("password", CodeContainsEmail, "password must not contain your email address")
+		}
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.IsBreached(password)
+		if err != nil {
+			return fmt.Errorf("failed to check breached passwords: %w", err)
+		}
+		if breached {
+			ve.add("password", CodeBreached, "password is too common, it appears in a list of breached passwords")
+		}
+	}
+
+	return nil
+}
+
+// characterClasses counts how many of lowercase, uppercase, digits and other characters password uses
+func characterClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	count := 0
+	for _, present := range []bool{lower, upper, digit, other} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
+
+// BreachedPasswordList tells whether a password is known to attackers
+type BreachedPasswordList interface {
+	IsBreached(password string) (bool, error)
+}
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+var (
+	commonPasswordsOnce sync.Once
+	commonPasswords     map[string]struct{}
+)
+
+// CommonPasswordList is the bundled list of the most common passwords, compared case-insensitively
+type CommonPasswordList struct{}
+
+// NewCommonPasswordList returns the bundled common password list
+func NewCommonPasswordList() CommonPasswordList {
+	return CommonPasswordList{}
+}
+
+// IsBreached reports whether password is on the bundled list
+func (CommonPasswordList) IsBreached(password string) (bool, error) {
+	commonPasswordsOnce.Do(func() {
+		commonPasswords = make(map[string]struct{})
+		for _, line := range strings.Split(commonPasswordsFile, "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				commonPasswords[strings.ToLower(line)] = struct{}{}
+			}
+		}
+	})
+
+	_, found := commonPasswords[strings.ToLower(password)]
+	return found, nil
+}
+
+// sha1HexLength is the length of a hex encoded SHA-1 hash
+const sha1HexLength = 40
+
+// SHA1PasswordFile checks passwords against a file of SHA-1 hashes, one uppercase hex hash per line,
+// optionally followed by ":<count>", sorted by hash. This is the format of the Pwned Passwords
+// downloads ordered by hash. The file is searched on disk, so it can be many gigabytes.
+type SHA1PasswordFile struct {
+	file *os.File
+	size int64
+}
+
+// OpenSHA1PasswordFile opens a sorted SHA-1 password file at path
+func OpenSHA1PasswordFile(path string) (*SHA1PasswordFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat breached password file: %w", err)
+	}
+
+	list := &SHA1PasswordFile{file: file, size: info.Size()}
+
+	// Catch a wrong file at startup rather than letting every lookup quietly miss
+	if list.size > 0 {
+		hash, _, err := list.lineAt(0)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if !isSHA1Hex(hash) {
+			file.Close()
+			return nil, fmt.Errorf("breached password file %s doesn't start with a SHA-1 hash", path)
+		}
+	}
+
+	return list, nil
+}
+
+// Close closes the underlying file
+func (s *SHA1PasswordFile) Close() error {
+	return s.file.Close()
+}
+
+// IsBreached reports whether the SHA-1 hash of password is in the file
+func (s *SHA1PasswordFile) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	target := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	// Binary search over byte offsets, looking at the first full line at or after each one
+	low, high := int64(0), s.size
+	for low < high {
+		mid := low + (high-low)/2
+		hash, next, err := s.lineAt(mid)
+		if err != nil {
+			return false, err
+		}
+		if hash == "" {
+			// No line starts at or after mid
+			high = mid
+			continue
+		}
+
+		switch cmp := strings.Compare(hash, target); {
+		case cmp == 0:
+			return true, nil
+		case cmp < 0:
+			low = next
+		default:
+			high = mid
+		}
+	}
+
+	return false, nil
+}
+
+// lineAt returns the hash on the first line starting at or after offset and the offset of the line after it.
+// The hash is empty if there is no such line.
+func (s *SHA1PasswordFile) lineAt(offset int64) (string, int64, error) {
+	reader := bufio.NewReader(io.NewSectionReader(s.file, offset, s.size-offset))
+	start := offset
+
+	// Skip the rest of the line offset lands in, unless it is the start of one
+	if offset > 0 {
+		var previous [1]byte
+		if _, err := s.file.ReadAt(previous[:], offset-1); err != nil {
+			return "", 0, fmt.Errorf("failed to read breached password file: %w", err)
+		}
+		if previous[0] != '\n' {
+			skipped, err := reader.ReadString('\n')
+			if errors.Is(err, io.EOF) {
+				return "", s.size, nil
+			}
+			if err != nil {
+				return "", 0, fmt.Errorf("failed to read breached password file: %w", err)
+			}
+			start += int64(len(skipped))
+		}
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", 0, fmt.Errorf("failed to read breached password file: %w", err)
+	}
+	next := start + int64(len(line))
+
+	hash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+	return strings.ToUpper(hash), next, nil
+}
+
+// isSHA1Hex reports whether s is a hex encoded SHA-1 hash
+func isSHA1Hex(s string) bool {
+	if len(s) != sha1HexLength {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
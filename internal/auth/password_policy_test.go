@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestPasswordPolicyCheck tests each rule of the password policy
+func TestPasswordPolicyCheck(t *testing.T) {
+	policy := PasswordPolicy{
+		MinLength:            8,
+		MaxLength:            16,
+		MinCharacterClasses:  3,
+		RejectEmailLocalPart: true,
+		Breached:             NewCommonPasswordList(),
+	}
+
+	testCases := []struct {
+		name     string
+		password string
+		email    string
+		codes    []string
+	}{
+		{"valid", "Correct-Horse9", "jane@example.com", nil},
+		{"empty", "", "jane@example.com", []string{CodeRequired}},
+		{"too short", "Ab1!", "jane@example.com", []string{CodeTooShort}},
+		{"too long", "Abcdefgh1!Abcdefgh1!", "jane@example.com", []string{CodeTooLong}},
+		{"one class", "horsebatterystp", "jane@example.com", []string{CodeCharacterClasses}},
+		{"contains email", "JANE-horse-99", "jane@example.com", []string{CodeContainsEmail}},
+		{"short local part ignored", "Jo-horse-99", "jo@example.com", nil},
+		{"breached", "Password123", "jane@example.com", []string{CodeBreached}},
+		{"several rules", "jane", "jane@example.com", []string{CodeTooShort, CodeCharacterClasses, CodeContainsEmail}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ve, err := policy.Check(tc.password, tc.email)
+			if err != nil {
+				t.Fatalf("Check failed: %v", err)
+			}
+
+			if len(ve) != len(tc.codes) {
+				t.Fatalf("Expected codes %v, got %v", tc.codes, ve)
+			}
+			for _, code := range tc.codes {
+				if !ve.Has("password", code) {
+					t.Errorf("Expected %s error, got %v", code, ve)
+				}
+			}
+		})
+	}
+}
+
+// TestPasswordPolicyCountsCharacters tests that length limits count characters rather than bytes
+func TestPasswordPolicyCountsCharacters(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 8, MaxLength: 8}
+
+	if ve, _ := policy.Check("пароль12", ""); ve.HasErrors() {
+		t.Errorf("8 Cyrillic and digit characters should be valid, got %v", ve)
+	}
+}
+
+// TestValidatorUsesPasswordPolicy tests that ValidatorService enforces a configured policy
+func TestValidatorUsesPasswordPolicy(t *testing.T) {
+	validator := NewValidatorService()
+
+	if err := validator.ValidateRegisterInput("jane@example.com", "password"); err != nil {
+		t.Errorf("Default policy should not check breached passwords, got %v", err)
+	}
+
+	validator.SetPasswordPolicy(PasswordPolicy{MinLength: 12, Breached: NewCommonPasswordList()})
+
+	err := validator.ValidateRegisterInput("invalid", "password")
+	ve, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("Expected ValidationErrors, got %T: %v", err, err)
+	}
+	if !ve.Has("email", CodeInvalidFormat) || !ve.Has("password", CodeTooShort) || !ve.Has("password", CodeBreached) {
+		t.Errorf("Expected email and password errors, got %v", ve)
+	}
+	if !strings.Contains(ve.Error(), "password must be at least 12 character long") {
+		t.Errorf("Expected min length in message, got %q", ve.Error())
+	}
+}
+
+// writeSHA1PasswordFile writes passwords as a sorted SHA-1 hash file and returns its path
+func writeSHA1PasswordFile(t *testing.T, passwords []string) string {
+	t.Helper()
+
+	var lines []string
+	for i, password := range passwords {
+		sum := sha1.Sum([]byte(password))
+		lines = append(lines, strings.ToUpper(hex.EncodeToString(sum[:]))+":"+strings.Repeat("7", i+1))
+	}
+	sort.Strings(lines)
+
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write password file: %v", err)
+	}
+	return path
+}
+
+// TestSHA1PasswordFile tests lookups in a sorted SHA-1 hash file
+func TestSHA1PasswordFile(t *testing.T) {
+	var breached []string
+	for i := 0; i < 200; i++ {
+		breached = append(breached, "breached-"+strings.Repeat("x", i%7)+string(rune('a'+i%26))+strings.Repeat("y", i/26))
+	}
+
+	list, err := OpenSHA1PasswordFile(writeSHA1PasswordFile(t, breached))
+	if err != nil {
+		t.Fatalf("Failed to open password file: %v", err)
+	}
+	defer list.Close()
+
+	for _, password := range breached {
+		found, err := list.IsBreached(password)
+		if err != nil {
+			t.Fatalf("IsBreached failed: %v", err)
+		}
+		if !found {
+			t.Errorf("Expected %q to be found", password)
+		}
+	}
+
+	for _, password := range []string{"", "not-breached", "breached-", "Correct-Horse9"} {
+		found, err := list.IsBreached(password)
+		if err != nil {
+			t.Fatalf("IsBreached failed: %v", err)
+		}
+		if found {
+			t.Errorf("Expected %q not to be found", password)
+		}
+	}
+}
+
+// TestOpenSHA1PasswordFileRejectsOtherFiles tests that a file in the wrong format fails at startup
+func TestOpenSHA1PasswordFileRejectsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "passwords.txt")
+	if err := os.WriteFile(path, []byte("password\n123456\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if _, err := OpenSHA1PasswordFile(path); err == nil {
+		t.Error("Expected plain text password list to be rejected")
+	}
+
+	if _, err := OpenSHA1PasswordFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("Expected missing file to be rejected")
+	}
+}
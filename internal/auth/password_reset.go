@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PasswordResetTokenExpiry is how long a password reset link stays valid
+const PasswordResetTokenExpiry = 1 * time.Hour
+
+var ErrResetTokenInvalid = errors.New("invalid or expired password reset token")
+
+// PasswordResetToken is the stored record of a password reset token
+type PasswordResetToken struct {
+	ID        int        `db:"id" json:"id"`
+	UserID    int        `db:"user_id" json:"user_id"`
+	TokenHash string     `db:"token_hash" json:"-"`
+	ExpiresAt time.Time  `db:"expires_at" json:"expires_at"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	UsedAt    *time.Time `db:"used_at" json:"used_at,omitempty"`
+}
+
+// PasswordResetRepositoryInterface defines password reset token persistence used by AuthService
+type PasswordResetRepositoryInterface interface {
+	Create(ctx context.Context, token *PasswordResetToken) error
+	GetValid(ctx context.Context, tokenHash string) (*PasswordResetToken, error)
+	Consume(ctx context.Context, tokenHash string) (*PasswordResetToken, error)
+	InvalidateForUser(ctx context.Context, userID int) error
+}
+
+// PasswordResetRepository handles password reset token database operations
+type PasswordResetRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewPasswordResetRepository creates a new password reset token repository
+func NewPasswordResetRepository(db *pgxpool.Pool) *PasswordResetRepository {
+	return &PasswordResetRepository{
+		db: db,
+	}
+}
+
+// Create stores a newly issued reset token, filling in its ID and creation time
+func (r *PasswordResetRepository) Create(ctx context.Context, token *PasswordResetToken) error {
+	query := `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, NOW())
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRow(ctx, query, token.UserID, token.TokenHash, token.ExpiresAt).Scan(&token.ID, &token.CreatedAt)
+}
+
+// GetValid returns an unused, unexpired token without using it up
+func (r *PasswordResetRepository) GetValid(ctx context.Context, tokenHash string) (*PasswordResetToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, expires_at, created_at, used_at
+		FROM password_reset_tokens
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+	`
+
+	var token PasswordResetToken
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+		&token.UsedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrResetTokenInvalid
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// Consume marks an unused, unexpired token as used and returns it.
+// The update is a single statement, so two concurrent resets cannot both succeed.
+func (r *PasswordResetRepository) Consume(ctx context.Context, tokenHash string) (*PasswordResetToken, error) {
+	query := `
+		UPDATE password_reset_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, user_id, token_hash, expires_at, created_at, used_at
+	`
+
+	var token PasswordResetToken
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+		&token.UsedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrResetTokenInvalid
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// InvalidateForUser marks every outstanding reset token of the user as used
+func (r *PasswordResetRepository) InvalidateForUser(ctx context.Context, userID int) error {
+	query := `UPDATE password_reset_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`
+
+	_, err := r.db.Exec(ctx, query, userID)
+	return err
+}
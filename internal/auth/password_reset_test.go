@@ -0,0 +1,333 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jayk0001/my-go-next-todo/internal/mail"
+)
+
+const resetSubject = "Reset your password"
+
+// MockPasswordResetRepository implements PasswordResetRepositoryInterface in memory for testing
+type MockPasswordResetRepository struct {
+	mu     sync.Mutex
+	tokens map[int]*PasswordResetToken
+	nextID int
+}
+
+// NewMockPasswordResetRepository creates a new mock password reset repository
+func NewMockPasswordResetRepository() *MockPasswordResetRepository {
+	return &MockPasswordResetRepository{
+		tokens: make(map[int]*PasswordResetToken),
+		nextID: 1,
+	}
+}
+
+func (m *MockPasswordResetRepository) Create(ctx context.Context, token *PasswordResetToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token.ID = m.nextID
+	token.CreatedAt = time.Now()
+	m.nextID++
+
+	copied := *token
+	m.tokens[token.ID] = &copied
+	return nil
+}
+
+func (m *MockPasswordResetRepository) GetValid(ctx context.Context, tokenHash string) (*PasswordResetToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, token := range m.tokens {
+		if token.TokenHash == tokenHash && token.UsedAt == nil && time.Now().Before(token.ExpiresAt) {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, ErrResetTokenInvalid
+}
+
+func (m *MockPasswordResetRepository) Consume(ctx context.Context, tokenHash string) (*PasswordResetToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, token := range m.tokens {
+		if token.TokenHash == tokenHash && token.UsedAt == nil && time.Now().Before(token.ExpiresAt) {
+			now := time.Now()
+			token.UsedAt = &now
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, ErrResetTokenInvalid
+}
+
+func (m *MockPasswordResetRepository) InvalidateForUser(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.UsedAt == nil {
+			token.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+// expireAll moves the expiry of every stored token into the past
+func (m *MockPasswordResetRepository) expireAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, token := range m.tokens {
+		token.ExpiresAt = time.Now().Add(-time.Minute)
+	}
+}
+
+// createTestAuthServiceWithMailer creates a mock-backed auth service that records sent email
+func createTestAuthServiceWithMailer() (*AuthService, *MockUserRepository, *mail.MemoryMailer) {
+	authService, mockRepo := createTestAuthServiceWithMock()
+	mailer := mail.NewMemoryMailer()
+	authService.SetMailer(mailer, "https://app.example.com/")
+
+	return authService, mockRepo, mailer
+}
+
+// waitForMail waits until the nth (1-based) message with subject has been sent and returns it
+func waitForMail(t *testing.T, mailer *mail.MemoryMailer, subject string, n int) mail.Message {
+	t.Helper()
+
+	deadline := time.After(time.Second)
+	for {
+		var matching []mail.Message
+		for _, msg := range mailer.Messages() {
+			if msg.Subject == subject {
+				matching = append(matching, msg)
+			}
+		}
+		if len(matching) >= n {
+			return matching[n-1]
+		}
+
+		select {
+		case <-mailer.Sent():
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatalf("timed out waiting for %q mail #%d", subject, n)
+		}
+	}
+}
+
+// countMail returns how many messages with subject have been sent
+func countMail(mailer *mail.MemoryMailer, subject string) int {
+	count := 0
+	for _, msg := range mailer.Messages() {
+		if msg.Subject == subject {
+			count++
+		}
+	}
+	return count
+}
+
+// tokenFromLink extracts the token query parameter of the link containing path in an email body
+func tokenFromLink(t *testing.T, body, path string) string {
+	t.Helper()
+
+	prefix := path + "?token="
+	start := strings.Index(body, prefix)
+	if start < 0 {
+		t.Fatalf("Email body has no %s link: %q", path, body)
+	}
+
+	token := body[start+len(prefix):]
+	if end := strings.IndexAny(token, " \n"); end >= 0 {
+		token = token[:end]
+	}
+	return token
+}
+
+// requestResetToken registers a user, requests a reset and returns the registration and the emailed token
+func requestResetToken(t *testing.T, authService *AuthService, mailer *mail.MemoryMailer) (*AuthResult, string) {
+	t.Helper()
+	ctx := context.Background()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	if err := authService.RequestPasswordReset(ctx, serviceTestData.testEmail); err != nil {
+		t.Fatalf("RequestPasswordReset should succeed: %v", err)
+	}
+
+	msg := waitForMail(t, mailer, resetSubject, 1)
+	return result, tokenFromLink(t, msg.Body, "/reset-password")
+}
+
+// TestRequestPasswordResetSendsEmail tests that a reset link is emailed and only its hash is stored
+func TestRequestPasswordResetSendsEmail(t *testing.T) {
+	authService, _, mailer := createTestAuthServiceWithMailer()
+
+	_, token := requestResetToken(t, authService, mailer)
+
+	msg := waitForMail(t, mailer, resetSubject, 1)
+	if msg.To != serviceTestData.testEmail {
+		t.Errorf("Expected mail to %s, got %s", serviceTestData.testEmail, msg.To)
+	}
+	if !strings.Contains(msg.Body, "https://app.example.com/reset-password?token=") {
+		t.Errorf("Expected reset link on the app URL, got: %q", msg.Body)
+	}
+
+	repo := authService.resetRepo.(*MockPasswordResetRepository)
+	stored := repo.tokens[1]
+	if stored.TokenHash == token || stored.TokenHash != hashToken(token) {
+		t.Error("Only the hash of the reset token should be stored")
+	}
+	if until := time.Until(stored.ExpiresAt); until <= 0 || until > PasswordResetTokenExpiry {
+		t.Errorf("Unexpected token expiry in %v", until)
+	}
+}
+
+// TestRequestPasswordResetUnknownEmail tests that unknown and disabled accounts look the same as real ones
+func TestRequestPasswordResetUnknownEmail(t *testing.T) {
+	authService, _, mailer := createTestAuthServiceWithMailer()
+	ctx := context.Background()
+
+	if err := authService.RequestPasswordReset(ctx, "nobody@example.com"); err != nil {
+		t.Errorf("RequestPasswordReset should not reveal unknown emails, got: %v", err)
+	}
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+	if err := authService.DisableUser(ctx, result.User.ID); err != nil {
+		t.Fatalf("DisableUser should succeed: %v", err)
+	}
+
+	if err := authService.RequestPasswordReset(ctx, serviceTestData.testEmail); err != nil {
+		t.Errorf("RequestPasswordReset should not reveal disabled accounts, got: %v", err)
+	}
+
+	// Give a wrongly sent email time to arrive
+	time.Sleep(50 * time.Millisecond)
+	if count := countMail(mailer, resetSubject); count != 0 {
+		t.Errorf("Expected no reset mail, got %d messages", count)
+	}
+}
+
+// TestResetPassword tests that a reset changes the password, signs out every session and revokes personal access tokens
+func TestResetPassword(t *testing.T) {
+	authService, _, mailer := createTestAuthServiceWithMailer()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	result, token := requestResetToken(t, authService, mailer)
+
+	_, accessToken, err := authService.CreatePersonalAccessToken(ctx, result.User.ID, CreatePersonalAccessTokenInput{Name: "script"})
+	if err != nil {
+		t.Fatalf("CreatePersonalAccessToken should succeed: %v", err)
+	}
+
+	events, err := authService.SubscribeAuthStatus(ctx, result.User.ID, result.Session.ID)
+	if err != nil {
+		t.Fatalf("SubscribeAuthStatus should succeed: %v", err)
+	}
+
+	newPassword := "new-password-456"
+	if err := authService.ResetPassword(ctx, token, newPassword); err != nil {
+		t.Fatalf("ResetPassword should succeed: %v", err)
+	}
+
+	if event := nextStatusEvent(t, events); event.Reason != StatusPasswordChanged {
+		t.Errorf("Expected reason %s, got %s", StatusPasswordChanged, event.Reason)
+	}
+
+	if _, err := authService.Login(ctx, serviceTestData.testEmail, serviceTestData.testPassword); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Old password should be rejected, got: %v", err)
+	}
+	if _, err := authService.Login(ctx, serviceTestData.testEmail, newPassword); err != nil {
+		t.Errorf("New password should be accepted: %v", err)
+	}
+
+	// Existing credentials are revoked
+	if _, err := authService.AuthenticateToken(ctx, result.Token); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("Expected ErrSessionRevoked for the old access token, got: %v", err)
+	}
+	if _, err := authService.RefreshToken(ctx, result.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Expected ErrRefreshTokenInvalid for the old refresh token, got: %v", err)
+	}
+	if _, err := authService.AuthenticateToken(ctx, accessToken); !errors.Is(err, ErrPersonalAccessTokenInvalid) {
+		t.Errorf("Expected ErrPersonalAccessTokenInvalid for the personal access token, got: %v", err)
+	}
+
+	// The token is single-use
+	if err := authService.ResetPassword(ctx, token, "another-password"); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Errorf("Expected ErrResetTokenInvalid on reuse, got: %v", err)
+	}
+}
+
+// TestResetPasswordRejectsBadTokens tests unknown, expired and superseded tokens
+func TestResetPasswordRejectsBadTokens(t *testing.T) {
+	authService, _, mailer := createTestAuthServiceWithMailer()
+	ctx := context.Background()
+
+	if err := authService.ResetPassword(ctx, "not-a-token", "new-password-456"); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Errorf("Expected ErrResetTokenInvalid for an unknown token, got: %v", err)
+	}
+
+	_, first := requestResetToken(t, authService, mailer)
+
+	// Requesting again supersedes the first link
+	if err := authService.RequestPasswordReset(ctx, serviceTestData.testEmail); err != nil {
+		t.Fatalf("RequestPasswordReset should succeed: %v", err)
+	}
+	second := tokenFromLink(t, waitForMail(t, mailer, resetSubject, 2).Body, "/reset-password")
+
+	if err := authService.ResetPassword(ctx, first, "new-password-456"); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Errorf("Expected ErrResetTokenInvalid for a superseded token, got: %v", err)
+	}
+
+	authService.resetRepo.(*MockPasswordResetRepository).expireAll()
+	if err := authService.ResetPassword(ctx, second, "new-password-456"); !errors.Is(err, ErrResetTokenInvalid) {
+		t.Errorf("Expected ErrResetTokenInvalid for an expired token, got: %v", err)
+	}
+}
+
+// TestResetPasswordValidationKeepsToken tests that a rejected password doesn't use up the token
+func TestResetPasswordValidationKeepsToken(t *testing.T) {
+	authService, _, mailer := createTestAuthServiceWithMailer()
+	ctx := context.Background()
+
+	_, token := requestResetToken(t, authService, mailer)
+
+	var ve ValidationErrors
+	if err := authService.ResetPassword(ctx, token, "short"); !errors.As(err, &ve) {
+		t.Errorf("Expected ValidationErrors for a short password, got: %v", err)
+	}
+
+	if err := authService.ResetPassword(ctx, token, "new-password-456"); err != nil {
+		t.Errorf("Token should still be usable after a validation error: %v", err)
+	}
+}
+
+// TestResetPasswordChecksEmail tests that the new password is checked against the account's email
+func TestResetPasswordChecksEmail(t *testing.T) {
+	authService, _, mailer := createTestAuthServiceWithMailer()
+	ctx := context.Background()
+
+	_, token := requestResetToken(t, authService, mailer)
+
+	var ve ValidationErrors
+	if err := authService.ResetPassword(ctx, token, "Test-1234-pass"); !errors.As(err, &ve) || !ve.Has("password", CodeContainsEmail) {
+		t.Errorf("Expected a contains_email validation error, got: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
@@ -30,8 +31,8 @@ func TestNewPasswordService(t *testing.T) {
 		t.Fatal("Password service should not be nil")
 	}
 
-	if passwordTestData.service.cost != DefaultCost {
-		t.Errorf("Expected cost: %d, got %d", DefaultCost, passwordTestData.service.cost)
+	if _, ok := passwordTestData.service.hasher.(*Argon2idHasher); !ok {
+		t.Errorf("Expected argon2id by default, got %T", passwordTestData.service.hasher)
 	}
 }
 
@@ -51,8 +52,8 @@ func TestHashPassword(t *testing.T) {
 		t.Errorf("hashed password shold not equal original password")
 	}
 
-	if !strings.HasPrefix(hashedPassword, "$2a$") && !strings.HasPrefix(hashedPassword, "$2b$") {
-		t.Error("Hashed password should have bcrypt format")
+	if !strings.HasPrefix(hashedPassword, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Errorf("Hashed password should have argon2id format, got %s", hashedPassword)
 	}
 }
 
@@ -69,7 +70,7 @@ func TestHashPasswordConsistenty(t *testing.T) {
 		t.Fatalf("Failed to hash password: %d", err)
 	}
 
-	// Bcrypt should produce different hashes for same password (due to salt)
+	// Hashes should differ for the same password (due to salt)
 	if hash1 == hash2 {
 		t.Error("Same password should produce different hash due to salt")
 	}
@@ -105,8 +106,8 @@ func TestVerifyPasswordWithWrongPassword(t *testing.T) {
 	}
 
 	// Check if error is specifically about mismatch
-	if err != bcrypt.ErrMismatchedHashAndPassword {
-		t.Errorf("Expected bcrypt.ErrMismatchedHashAndPassword, got %v", err)
+	if !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("Expected ErrPasswordMismatch, got %v", err)
 	}
 }
 
@@ -122,6 +123,9 @@ func TestVerifyPasswordEdgecases(t *testing.T) {
 		{"empty password", "$2a$12$valid.hash.here", "", true},
 		{"invalid hash format", "not-a-bcrypt-hash", "password", true},
 		{"malformed hash", "$2a$12$invalidhash", "password", true},
+		{"malformed argon2id hash", "$argon2id$v=19$m=19456,t=2,p=1$c2FsdA", "password", true},
+		{"zero argon2id parallelism", "$argon2id$v=19$m=19456,t=2,p=0$c2FsdA$a2V5", "password", true},
+		{"unknown algorithm", "$scrypt$ln=15,r=8,p=1$c2FsdA$a2V5", "password", true},
 	}
 
 	for _, tc := range testCases {
@@ -164,9 +168,9 @@ func TestHashPasswordWithSpecialCharacters(t *testing.T) {
 	}
 }
 
-// TestPasswordServiceCost tests that the service uses correct bcrypt cost
+// TestPasswordServiceCost tests that the bcrypt hasher uses correct bcrypt cost
 func TestPasswordServiceCost(t *testing.T) {
-	hashedPassword, err := passwordTestData.service.HashPassword(passwordTestData.validPassword)
+	hashedPassword, err := NewBcryptHasher(DefaultCost).Hash(passwordTestData.validPassword)
 	if err != nil {
 		t.Fatalf("Failed to hash password :%v", err)
 	}
@@ -184,7 +188,7 @@ func TestPasswordServiceCost(t *testing.T) {
 // TestHashPasswordPerformance tests that hashing doesn't take too long
 func TestHashPasswordPerformance(t *testing.T) {
 
-	// Hashing should complete within reasonable time (bcrypt cost 12 is slow but not too slow)
+	// Hashing should complete within reasonable time (argon2id with 19 MiB is slow but not too slow)
 	start := testing.Short()
 	if start {
 		t.Skip("Skipping performance test in short mode")
@@ -195,31 +199,38 @@ func TestHashPasswordPerformance(t *testing.T) {
 		t.Fatalf("Failed to hash password: %v", err)
 	}
 
-	// Note: With cost 12, hashing can take 100-500ms, which is expected
+	// Note: Hashing can take tens of milliseconds, which is expected
 	// This test mainly ensures it doesn't hang indefinitely
 }
 
 // TestPasswordLengthLimits tests password length edge cases
 func TestPasswordLengthLimits(t *testing.T) {
 
-	// Test very long password (bcrypt has 72 byte limit)
+	// Test long password
 	longPassword := strings.Repeat("a", 50)
 	hashedPassword, err := passwordTestData.service.HashPassword(longPassword)
 	if err != nil {
 		t.Errorf("Failed to hash long password: %v", err)
 	}
 
-	// Should still verify correctly (bcrypt truncates at 72 bytes)
+	// Should still verify correctly
 	err = passwordTestData.service.VerifyPassword(hashedPassword, longPassword)
 	if err != nil {
 		t.Errorf("Failed to verify long password: %v", err)
 	}
 
-	// Test extremely long password
+	// Test extremely long password: argon2id has no length limit, unlike bcrypt's 72 bytes
 	veryLongPassword := strings.Repeat("x", 1000)
-	_, err = passwordTestData.service.HashPassword(veryLongPassword)
-	if err == nil {
-		t.Errorf("Expect to fail to hash very long password: %v", err)
+	hashedPassword, err = passwordTestData.service.HashPassword(veryLongPassword)
+	if err != nil {
+		t.Errorf("Failed to hash very long password: %v", err)
+	}
+	if err := passwordTestData.service.VerifyPassword(hashedPassword, strings.Repeat("x", 999)); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("Very long passwords should not be truncated, got: %v", err)
+	}
+
+	if _, err := NewBcryptHasher(DefaultCost).Hash(veryLongPassword); err == nil {
+		t.Error("Expect bcrypt to fail to hash very long password")
 	}
 }
 
@@ -227,10 +238,6 @@ func TestPasswordLengthLimits(t *testing.T) {
 func TestPasswordServiceMethods(t *testing.T) {
 
 	// Test the complete flow
-	if !passwordTestData.service.IsValidPassword(passwordTestData.validPassword) {
-		t.Error("Password should be valid")
-	}
-
 	hashedPassword, err := passwordTestData.service.HashPassword(passwordTestData.validPassword)
 	if err != nil {
 		t.Fatalf("Failed to hash valid password: %v", err)
@@ -241,8 +248,69 @@ func TestPasswordServiceMethods(t *testing.T) {
 		t.Errorf("Failed to verify hashed password: %v", err)
 	}
 
-	// Test with invalid password
-	if passwordTestData.service.IsValidPassword(passwordTestData.invalidPassword) {
-		t.Error("Short password should be invalid")
+	// Test with wrong password
+	if err := passwordTestData.service.VerifyPassword(hashedPassword, passwordTestData.invalidPassword); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("Expected ErrPasswordMismatch, got %v", err)
+	}
+}
+
+// TestVerifyLegacyBcryptHash tests that bcrypt hashes made before argon2id keep verifying and get flagged for rehashing
+func TestVerifyLegacyBcryptHash(t *testing.T) {
+	legacy, err := NewBcryptHasher(bcrypt.MinCost).Hash(passwordTestData.validPassword)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+
+	if err := passwordTestData.service.VerifyPassword(legacy, passwordTestData.validPassword); err != nil {
+		t.Errorf("Should verify bcrypt hash, but got %v", err)
+	}
+	if err := passwordTestData.service.VerifyPassword(legacy, passwordTestData.invalidPassword); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("Expected ErrPasswordMismatch, got %v", err)
+	}
+
+	if !passwordTestData.service.NeedsRehash(legacy) {
+		t.Error("bcrypt hash should need rehashing to argon2id")
+	}
+}
+
+// TestNeedsRehash tests when stored hashes are upgraded to the configured hasher
+func TestNeedsRehash(t *testing.T) {
+	weak := Argon2Params{Memory: 8 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	weakHash, err := NewArgon2idHasher(weak).Hash(passwordTestData.validPassword)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+
+	current, err := passwordTestData.service.HashPassword(passwordTestData.validPassword)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+
+	if passwordTestData.service.NeedsRehash(current) {
+		t.Error("Hash with current parameters should not need rehashing")
+	}
+	if !passwordTestData.service.NeedsRehash(weakHash) {
+		t.Error("Hash with weaker parameters should need rehashing")
+	}
+
+	// Hashes keep verifying with the parameters they were made with
+	if err := passwordTestData.service.VerifyPassword(weakHash, passwordTestData.validPassword); err != nil {
+		t.Errorf("Should verify hash with other parameters, but got %v", err)
+	}
+
+	// Switching back to bcrypt flags argon2id hashes and bcrypt hashes below the cost
+	service := NewPasswordService()
+	service.SetHasher(NewBcryptHasher(bcrypt.MinCost + 1))
+
+	lowCost, err := NewBcryptHasher(bcrypt.MinCost).Hash(passwordTestData.validPassword)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+
+	if !service.NeedsRehash(current) || !service.NeedsRehash(lowCost) {
+		t.Error("argon2id and low cost bcrypt hashes should need rehashing")
+	}
+	if err := service.VerifyPassword(current, passwordTestData.validPassword); err != nil {
+		t.Errorf("bcrypt service should still verify argon2id hashes, but got %v", err)
 	}
 }
@@ -0,0 +1,314 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// MockPersonalAccessTokenRepository implements PersonalAccessTokenRepositoryInterface in memory for testing
+type MockPersonalAccessTokenRepository struct {
+	tokens map[int]*PersonalAccessToken
+	nextID int
+}
+
+// NewMockPersonalAccessTokenRepository creates a new mock personal access token repository
+func NewMockPersonalAccessTokenRepository() *MockPersonalAccessTokenRepository {
+	return &MockPersonalAccessTokenRepository{
+		tokens: make(map[int]*PersonalAccessToken),
+		nextID: 1,
+	}
+}
+
+func (m *MockPersonalAccessTokenRepository) Create(ctx context.Context, token *PersonalAccessToken) error {
+	token.ID = m.nextID
+	token.CreatedAt = time.Now()
+	m.nextID++
+
+	copied := *token
+	m.tokens[token.ID] = &copied
+	return nil
+}
+
+func (m *MockPersonalAccessTokenRepository) GetActiveByHash(ctx context.Context, tokenHash string) (*PersonalAccessToken, error) {
+	for _, token := range m.tokens {
+		if token.TokenHash == tokenHash && token.IsActive() {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, ErrPersonalAccessTokenInvalid
+}
+
+func (m *MockPersonalAccessTokenRepository) ListByUser(ctx context.Context, userID int) ([]*PersonalAccessToken, error) {
+	var tokens []*PersonalAccessToken
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			copied := *token
+			tokens = append(tokens, &copied)
+		}
+	}
+
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].ID > tokens[j].ID })
+	return tokens, nil
+}
+
+func (m *MockPersonalAccessTokenRepository) Touch(ctx context.Context, id int) error {
+	if token, exists := m.tokens[id]; exists {
+		now := time.Now()
+		token.LastUsedAt = &now
+	}
+	return nil
+}
+
+func (m *MockPersonalAccessTokenRepository) Revoke(ctx context.Context, id, userID int) error {
+	token, exists := m.tokens[id]
+	if !exists || token.UserID != userID || token.RevokedAt != nil {
+		return ErrPersonalAccessTokenNotFound
+	}
+
+	now := time.Now()
+	token.RevokedAt = &now
+	return nil
+}
+
+func (m *MockPersonalAccessTokenRepository) RevokeAllForUser(ctx context.Context, userID int) error {
+	now := time.Now()
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+// accessTokenMock returns the mock personal access token repository behind a test auth service
+func accessTokenMock(authService *AuthService) *MockPersonalAccessTokenRepository {
+	return authService.accessTokenRepo.(*MockPersonalAccessTokenRepository)
+}
+
+// TestCreatePersonalAccessToken tests issuing personal access tokens
+func TestCreatePersonalAccessToken(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("token is shown once and stored hashed", func(t *testing.T) {
+		authService, _ := createTestAuthServiceWithMock()
+
+		record, token, err := authService.CreatePersonalAccessToken(ctx, 1, CreatePersonalAccessTokenInput{Name: "  backup script  "})
+		if err != nil {
+			t.Fatalf("CreatePersonalAccessToken failed: %v", err)
+		}
+
+		if !strings.HasPrefix(token, PersonalAccessTokenPrefix) {
+			t.Errorf("Expected token to start with %q, got %q", PersonalAccessTokenPrefix, token)
+		}
+		if record.Name != "backup script" {
+			t.Errorf("Expected trimmed name, got %q", record.Name)
+		}
+		if !slices.Equal(record.Scopes, Scopes) {
+			t.Errorf("Expected every scope by default, got %v", record.Scopes)
+		}
+		if record.ExpiresAt != nil {
+			t.Errorf("Expected no expiry, got %v", record.ExpiresAt)
+		}
+
+		stored := accessTokenMock(authService).tokens[record.ID]
+		if stored.TokenHash == token || stored.TokenHash != hashToken(token) {
+			t.Error("Expected the token to be stored as its hash")
+		}
+	})
+
+	t.Run("scopes are deduplicated", func(t *testing.T) {
+		authService, _ := createTestAuthServiceWithMock()
+
+		record, _, err := authService.CreatePersonalAccessToken(ctx, 1, CreatePersonalAccessTokenInput{
+			Name:   "reader",
+			Scopes: []string{ScopeTodosRead, ScopeTodosRead},
+		})
+		if err != nil {
+			t.Fatalf("CreatePersonalAccessToken failed: %v", err)
+		}
+		if !slices.Equal(record.Scopes, []string{ScopeTodosRead}) {
+			t.Errorf("Expected [%s], got %v", ScopeTodosRead, record.Scopes)
+		}
+	})
+
+	past := time.Now().Add(-time.Hour)
+	invalid := []struct {
+		name  string
+		input CreatePersonalAccessTokenInput
+	}{
+		{"missing name", CreatePersonalAccessTokenInput{Name: "  "}},
+		{"long name", CreatePersonalAccessTokenInput{Name: strings.Repeat("a", personalAccessTokenNameMaxLength+1)}},
+		{"unknown scope", CreatePersonalAccessTokenInput{Name: "admin", Scopes: []string{"users:write"}}},
+		{"expiry in the past", CreatePersonalAccessTokenInput{Name: "old", ExpiresAt: &past}},
+	}
+
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			authService, _ := createTestAuthServiceWithMock()
+
+			if _, _, err := authService.CreatePersonalAccessToken(ctx, 1, tt.input); err == nil {
+				t.Error("Expected an error")
+			}
+			if len(accessTokenMock(authService).tokens) != 0 {
+				t.Error("Expected no token to be stored")
+			}
+		})
+	}
+}
+
+// TestAuthenticatePersonalAccessToken tests signing in with personal access tokens
+func TestAuthenticatePersonalAccessToken(t *testing.T) {
+	ctx := context.Background()
+
+	register := func(t *testing.T) (*AuthService, *MockUserRepository, *User) {
+		t.Helper()
+		authService, mockRepo := createTestAuthServiceWithMock()
+		result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+		if err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+		return authService, mockRepo, result.User
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		authService, _, user := register(t)
+		record, token, err := authService.CreatePersonalAccessToken(ctx, user.ID, CreatePersonalAccessTokenInput{
+			Name:   "reader",
+			Scopes: []string{ScopeTodosRead},
+		})
+		if err != nil {
+			t.Fatalf("CreatePersonalAccessToken failed: %v", err)
+		}
+
+		principal, err := authService.AuthenticateToken(ctx, token)
+		if err != nil {
+			t.Fatalf("AuthenticateToken failed: %v", err)
+		}
+
+		if principal.User.ID != user.ID {
+			t.Errorf("Expected user %d, got %d", user.ID, principal.User.ID)
+		}
+		if principal.PersonalAccessTokenID != record.ID {
+			t.Errorf("Expected token %d, got %d", record.ID, principal.PersonalAccessTokenID)
+		}
+		if principal.SessionID != "" || principal.TokenID != "" {
+			t.Error("Expected no session or jti for a personal access token")
+		}
+		if !principal.HasScope(ScopeTodosRead) || principal.HasScope(ScopeTodosWrite) {
+			t.Errorf("Expected only %s, got %v", ScopeTodosRead, principal.Scopes)
+		}
+
+		if accessTokenMock(authService).tokens[record.ID].LastUsedAt == nil {
+			t.Error("Expected lastUsedAt to be recorded")
+		}
+	})
+
+	t.Run("revoked token", func(t *testing.T) {
+		authService, _, user := register(t)
+		record, token, err := authService.CreatePersonalAccessToken(ctx, user.ID, CreatePersonalAccessTokenInput{Name: "ci"})
+		if err != nil {
+			t.Fatalf("CreatePersonalAccessToken failed: %v", err)
+		}
+
+		if err := authService.RevokePersonalAccessToken(ctx, user.ID+1, record.ID); !errors.Is(err, ErrPersonalAccessTokenNotFound) {
+			t.Errorf("Expected another user's revoke to fail with ErrPersonalAccessTokenNotFound, got %v", err)
+		}
+
+		if err := authService.RevokePersonalAccessToken(ctx, user.ID, record.ID); err != nil {
+			t.Fatalf("RevokePersonalAccessToken failed: %v", err)
+		}
+
+		if _, err := authService.AuthenticateToken(ctx, token); !errors.Is(err, ErrPersonalAccessTokenInvalid) {
+			t.Errorf("Expected ErrPersonalAccessTokenInvalid, got %v", err)
+		}
+
+		tokens, err := authService.ListPersonalAccessTokens(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("ListPersonalAccessTokens failed: %v", err)
+		}
+		if len(tokens) != 0 {
+			t.Errorf("Expected revoked tokens to be left out of the list, got %d", len(tokens))
+		}
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		authService, _, user := register(t)
+		expiresAt := time.Now().Add(time.Hour)
+		record, token, err := authService.CreatePersonalAccessToken(ctx, user.ID, CreatePersonalAccessTokenInput{Name: "ci", ExpiresAt: &expiresAt})
+		if err != nil {
+			t.Fatalf("CreatePersonalAccessToken failed: %v", err)
+		}
+
+		expired := time.Now().Add(-time.Minute)
+		accessTokenMock(authService).tokens[record.ID].ExpiresAt = &expired
+
+		if _, err := authService.AuthenticateToken(ctx, token); !errors.Is(err, ErrPersonalAccessTokenInvalid) {
+			t.Errorf("Expected ErrPersonalAccessTokenInvalid, got %v", err)
+		}
+	})
+
+	t.Run("disabled account", func(t *testing.T) {
+		authService, mockRepo, user := register(t)
+		_, token, err := authService.CreatePersonalAccessToken(ctx, user.ID, CreatePersonalAccessTokenInput{Name: "ci"})
+		if err != nil {
+			t.Fatalf("CreatePersonalAccessToken failed: %v", err)
+		}
+
+		if err := mockRepo.SetDisabled(ctx, user.ID, true); err != nil {
+			t.Fatalf("SetDisabled failed: %v", err)
+		}
+
+		if _, err := authService.AuthenticateToken(ctx, token); !errors.Is(err, ErrAccountDisabled) {
+			t.Errorf("Expected ErrAccountDisabled, got %v", err)
+		}
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		authService, _, _ := register(t)
+
+		if _, err := authService.AuthenticateToken(ctx, PersonalAccessTokenPrefix+"unknown"); !errors.Is(err, ErrPersonalAccessTokenInvalid) {
+			t.Errorf("Expected ErrPersonalAccessTokenInvalid, got %v", err)
+		}
+	})
+
+	t.Run("list is newest first", func(t *testing.T) {
+		authService, _, user := register(t)
+		for _, name := range []string{"first", "second"} {
+			if _, _, err := authService.CreatePersonalAccessToken(ctx, user.ID, CreatePersonalAccessTokenInput{Name: name}); err != nil {
+				t.Fatalf("CreatePersonalAccessToken failed: %v", err)
+			}
+		}
+
+		tokens, err := authService.ListPersonalAccessTokens(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("ListPersonalAccessTokens failed: %v", err)
+		}
+		if len(tokens) != 2 || tokens[0].Name != "second" || tokens[1].Name != "first" {
+			t.Errorf("Expected [second first], got %+v", tokens)
+		}
+	})
+}
+
+// TestPrincipalHasScope tests scope checks for interactive and token principals
+func TestPrincipalHasScope(t *testing.T) {
+	interactive := &Principal{}
+	if !interactive.HasScope(ScopeTodosWrite) {
+		t.Error("Expected an interactive sign-in to have every scope")
+	}
+
+	unscoped := &Principal{Scopes: []string{}}
+	if unscoped.HasScope(ScopeTodosRead) {
+		t.Error("Expected a token without scopes to have none")
+	}
+
+	writer := &Principal{Scopes: []string{ScopeTodosWrite}}
+	if !writer.HasScope(ScopeTodosWrite) || writer.HasScope(ScopeTodosRead) {
+		t.Error("Expected a token to have exactly its scopes")
+	}
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RefreshTokenExpiry is how long a refresh token can be exchanged for new tokens
+const RefreshTokenExpiry = 24 * 7 * time.Hour
+
+var (
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+)
+
+// refreshTokenColumns is the column list scanned by scanRefreshToken
+const refreshTokenColumns = `id, token_hash, family_id, user_id, session_id, expires_at, created_at, rotated_at, revoked_at`
+
+// RefreshToken is the stored record of an issued refresh token.
+// Every token obtained by rotating another one shares its family ID.
+type RefreshToken struct {
+	ID        int        `db:"id" json:"id"`
+	TokenHash string     `db:"token_hash" json:"-"`
+	FamilyID  string     `db:"family_id" json:"family_id"`
+	UserID    int        `db:"user_id" json:"user_id"`
+	SessionID string     `db:"session_id" json:"session_id"`
+	ExpiresAt time.Time  `db:"expires_at" json:"expires_at"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	RotatedAt *time.Time `db:"rotated_at" json:"rotated_at,omitempty"`
+	RevokedAt *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+}
+
+// RefreshTokenRepositoryInterface defines refresh token persistence used by AuthService
+type RefreshTokenRepositoryInterface interface {
+	Create(ctx context.Context, token *RefreshToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*RefreshToken, error)
+	Rotate(ctx context.Context, currentID int, next *RefreshToken) error
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeBySession(ctx context.Context, sessionID string) error
+}
+
+// RefreshTokenRepository handles refresh token database operations
+type RefreshTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewRefreshTokenRepository creates a new refresh token repository
+func NewRefreshTokenRepository(db *pgxpool.Pool) *RefreshTokenRepository {
+	return &RefreshTokenRepository{
+		db: db,
+	}
+}
+
+// Create stores a newly issued refresh token, filling in its ID and creation time
+func (r *RefreshTokenRepository) Create(ctx context.Context, token *RefreshToken) error {
+	return insertRefreshToken(ctx, r.db, token)
+}
+
+// GetByHash retrieves a refresh token by the hash of its value
+func (r *RefreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	query := `SELECT ` + refreshTokenColumns + ` FROM refresh_tokens WHERE token_hash = $1`
+
+	return scanRefreshToken(r.db.QueryRow(ctx, query, tokenHash))
+}
+
+// Rotate marks the current token as used and stores its replacement in one transaction.
+// It returns ErrRefreshTokenReused if the current token was already rotated or revoked,
+// which also guards against two concurrent refreshes with the same token.
+func (r *RefreshTokenRepository) Rotate(ctx context.Context, currentID int, next *RefreshToken) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE refresh_tokens
+		SET rotated_at = NOW()
+		WHERE id = $1 AND rotated_at IS NULL AND revoked_at IS NULL
+	`
+
+	result, err := tx.Exec(ctx, query, currentID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrRefreshTokenReused
+	}
+
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RevokeFamily revokes every token descended from the same login
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`
+
+	_, err := r.db.Exec(ctx, query, familyID)
+
+	return err
+}
+
+// RevokeBySession revokes every token issued to a session
+func (r *RefreshTokenRepository) RevokeBySession(ctx context.Context, sessionID string) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE session_id = $1 AND revoked_at IS NULL
+	`
+
+	_, err := r.db.Exec(ctx, query, sessionID)
+
+	return err
+}
+
+// refreshTokenQuerier is satisfied by both the pool and a transaction
+type refreshTokenQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// insertRefreshToken stores token, filling in its ID and creation time
+func insertRefreshToken(ctx context.Context, db refreshTokenQuerier, token *RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (token_hash, family_id, user_id, session_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING id, created_at
+	`
+
+	return db.QueryRow(ctx, query, token.TokenHash, token.FamilyID, token.UserID, token.SessionID, token.ExpiresAt).
+		Scan(&token.ID, &token.CreatedAt)
+}
+
+// scanRefreshToken scans a row selected with refreshTokenColumns
+func scanRefreshToken(row pgx.Row) (*RefreshToken, error) {
+	var token RefreshToken
+	err := row.Scan(
+		&token.ID,
+		&token.TokenHash,
+		&token.FamilyID,
+		&token.UserID,
+		&token.SessionID,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+		&token.RotatedAt,
+		&token.RevokedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
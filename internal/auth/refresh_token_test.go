@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// MockRefreshTokenRepository implements RefreshTokenRepositoryInterface in memory for testing
+type MockRefreshTokenRepository struct {
+	tokens map[int]*RefreshToken
+	nextID int
+}
+
+// NewMockRefreshTokenRepository creates a new mock refresh token repository
+func NewMockRefreshTokenRepository() *MockRefreshTokenRepository {
+	return &MockRefreshTokenRepository{
+		tokens: make(map[int]*RefreshToken),
+		nextID: 1,
+	}
+}
+
+func (m *MockRefreshTokenRepository) Create(ctx context.Context, token *RefreshToken) error {
+	token.ID = m.nextID
+	token.CreatedAt = time.Now()
+	m.nextID++
+
+	copied := *token
+	m.tokens[token.ID] = &copied
+	return nil
+}
+
+func (m *MockRefreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	for _, token := range m.tokens {
+		if token.TokenHash == tokenHash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, ErrRefreshTokenInvalid
+}
+
+func (m *MockRefreshTokenRepository) Rotate(ctx context.Context, currentID int, next *RefreshToken) error {
+	current, exists := m.tokens[currentID]
+	if !exists || current.RotatedAt != nil || current.RevokedAt != nil {
+		return ErrRefreshTokenReused
+	}
+
+	now := time.Now()
+	current.RotatedAt = &now
+	return m.Create(ctx, next)
+}
+
+func (m *MockRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	now := time.Now()
+	for _, token := range m.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *MockRefreshTokenRepository) RevokeBySession(ctx context.Context, sessionID string) error {
+	now := time.Now()
+	for _, token := range m.tokens {
+		if token.SessionID == sessionID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+// TestRefreshTokenRotation tests that every refresh returns a new token in the same family
+func TestRefreshTokenRotation(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx := context.Background()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	refreshed, err := authService.RefreshToken(ctx, result.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshToken should succeed: %v", err)
+	}
+
+	if refreshed.RefreshToken == result.RefreshToken {
+		t.Error("Refresh should rotate the refresh token")
+	}
+
+	repo := authService.refreshRepo.(*MockRefreshTokenRepository)
+	original, _ := repo.GetByHash(ctx, hashToken(result.RefreshToken))
+	rotated, _ := repo.GetByHash(ctx, hashToken(refreshed.RefreshToken))
+	if original.FamilyID != rotated.FamilyID {
+		t.Errorf("Rotated token should stay in family %s, got %s", original.FamilyID, rotated.FamilyID)
+	}
+	if original.RotatedAt == nil {
+		t.Error("Original token should be marked as rotated")
+	}
+
+	// The rotated token keeps working
+	if _, err := authService.RefreshToken(ctx, refreshed.RefreshToken); err != nil {
+		t.Errorf("Rotated token should be accepted: %v", err)
+	}
+}
+
+// TestRefreshTokenStoredHashed tests that the plaintext refresh token is never stored
+func TestRefreshTokenStoredHashed(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx := context.Background()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	for _, token := range authService.refreshRepo.(*MockRefreshTokenRepository).tokens {
+		if token.TokenHash == result.RefreshToken {
+			t.Error("Refresh token should be stored hashed")
+		}
+	}
+}
+
+// TestRefreshTokenReplayRevokesFamily tests that replaying a rotated token revokes the whole family
+func TestRefreshTokenReplayRevokesFamily(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	events, _ := authService.SubscribeAuthStatus(ctx, result.User.ID, result.Session.ID)
+
+	// The legitimate client rotates the token
+	refreshed, err := authService.RefreshToken(ctx, result.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshToken should succeed: %v", err)
+	}
+
+	// An attacker replays the stolen original
+	if _, err := authService.RefreshToken(ctx, result.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Expected ErrRefreshTokenReused, got: %v", err)
+	}
+
+	// The whole family is revoked, including the token the legitimate client holds
+	if _, err := authService.RefreshToken(ctx, refreshed.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Expected ErrRefreshTokenInvalid for the latest token, got: %v", err)
+	}
+
+	// The session is revoked and its clients are told to drop their credentials
+	if _, err := authService.GetUserFromToken(ctx, refreshed.Token); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("Expected ErrSessionRevoked for access token, got: %v", err)
+	}
+
+	event := nextStatusEvent(t, events)
+	if event.Reason != StatusSessionRevoked || event.SessionID != result.Session.ID {
+		t.Errorf("Unexpected status event: %+v", event)
+	}
+}
+
+// TestRefreshTokenReplayLeavesOtherFamilies tests that reuse only affects the compromised family
+func TestRefreshTokenReplayLeavesOtherFamilies(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx := context.Background()
+
+	first, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+	second, err := authService.Login(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Login should succeed: %v", err)
+	}
+
+	if _, err := authService.RefreshToken(ctx, first.RefreshToken); err != nil {
+		t.Fatalf("RefreshToken should succeed: %v", err)
+	}
+	if _, err := authService.RefreshToken(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Expected ErrRefreshTokenReused, got: %v", err)
+	}
+
+	if _, err := authService.RefreshToken(ctx, second.RefreshToken); err != nil {
+		t.Errorf("Other session's refresh token should still work: %v", err)
+	}
+}
+
+// TestRefreshTokenInvalid tests unknown and expired refresh tokens
+func TestRefreshTokenInvalid(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx := context.Background()
+
+	if _, err := authService.RefreshToken(ctx, "not-a-real-token"); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Expected ErrRefreshTokenInvalid for unknown token, got: %v", err)
+	}
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	for _, token := range authService.refreshRepo.(*MockRefreshTokenRepository).tokens {
+		token.ExpiresAt = time.Now().Add(-time.Minute)
+	}
+
+	if _, err := authService.RefreshToken(ctx, result.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Expected ErrRefreshTokenInvalid for expired token, got: %v", err)
+	}
+}
+
+// TestLogoutRevokesFamily tests that logout revokes the session's refresh tokens
+func TestLogoutRevokesFamily(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx := context.Background()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+	other, err := authService.Login(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Login should succeed: %v", err)
+	}
+
+	refreshed, err := authService.RefreshToken(ctx, result.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshToken should succeed: %v", err)
+	}
+
+	principal, err := authService.AuthenticateToken(ctx, refreshed.Token)
+	if err != nil {
+		t.Fatalf("AuthenticateToken should succeed: %v", err)
+	}
+
+	if err := authService.Logout(ctx, principal); err != nil {
+		t.Fatalf("Logout should succeed: %v", err)
+	}
+
+	if _, err := authService.RefreshToken(ctx, refreshed.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Expected ErrRefreshTokenInvalid after logout, got: %v", err)
+	}
+	if _, err := authService.GetUserFromToken(ctx, result.Token); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("Expected ErrSessionRevoked for the session's older access token, got: %v", err)
+	}
+
+	// Other devices stay signed in
+	if _, err := authService.RefreshToken(ctx, other.RefreshToken); err != nil {
+		t.Errorf("Other session should still refresh: %v", err)
+	}
+}
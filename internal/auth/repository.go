@@ -4,8 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -13,8 +16,29 @@ var (
 	ErrUserNotFound       = errors.New("user not found")
 	ErrEmailExits         = errors.New("email already exists")
 	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrAccountDisabled    = errors.New("account is disabled")
+	ErrTokenRevoked       = errors.New("token has been revoked")
+	ErrForbidden          = errors.New("forbidden")
+	// ErrPasswordNotSet is returned when a password is needed from a user who only signs in through a provider
+	ErrPasswordNotSet = errors.New("account has no password, set one with a password reset")
+	// ErrReauthRequired is returned when a user without a password makes a sensitive change
+	// from a session that didn't sign in recently
+	ErrReauthRequired = errors.New("sign in again to confirm this change")
 )
 
+// Roles a user can have
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// uniqueViolation is the Postgres error code for a violated unique constraint
+const uniqueViolation = "23505"
+
+// userColumns is the column list scanned by scanUser. Users who sign in through
+// an identity provider have no password hash, which is scanned as an empty string.
+const userColumns = `id, email, COALESCE(password_hash, ''), created_at, updated_at, last_login_at, login_count, disabled_at, email_verified_at, role, time_zone`
+
 // User represents a user in the database
 type User struct {
 	ID           int        `db:"id" json:"id"`
@@ -23,23 +47,56 @@ type User struct {
 	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
 	UpdatedAt    time.Time  `db:"updated_at" json:"updated_at"`
 	LastLoginAt  *time.Time `db:"last_login_at" json:"last_login_at,omitempty"`
+	LoginCount   int        `db:"login_count" json:"login_count"`
+	DisabledAt   *time.Time `db:"disabled_at" json:"disabled_at,omitempty"`
+	// EmailVerifiedAt is set once the user proves they own Email
+	EmailVerifiedAt *time.Time `db:"email_verified_at" json:"email_verified_at,omitempty"`
+	// Role is RoleUser or RoleAdmin
+	Role string `db:"role" json:"role"`
+	// TimeZone is the IANA name of the user's time zone, e.g. for deciding what is due today
+	TimeZone string `db:"time_zone" json:"time_zone"`
+}
+
+// IsDisabled returns true if the account has been disabled
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
+
+// HasPassword returns true if the user can sign in with a password
+func (u *User) HasPassword() bool {
+	return u.PasswordHash != ""
+}
+
+// IsAdmin returns true if the user has the admin role
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// IsEmailVerified returns true if the user has verified their email address
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
 
 // CreateUserInput represents input for creating a user
 type CreateUserInput struct {
-	Email    string
+	Email string
+	// Password is empty for users who sign in through an identity provider
 	Password string
+	// EmailVerified is set when the email address is already known to belong to the user
+	EmailVerified bool
 }
 
 // UserRepository handles user database operations
 type UserRepository struct {
-	db *pgxpool.Pool
+	db        *pgxpool.Pool
+	passwords *PasswordService
 }
 
 // NewUserRepository created a new user repository
 func NewUserRepository(db *pgxpool.Pool) *UserRepository {
 	return &UserRepository{
-		db: db,
+		db:        db,
+		passwords: NewPasswordService(),
 	}
 }
 
@@ -55,111 +112,176 @@ func (r *UserRepository) Create(ctx context.Context, input CreateUserInput) (*Us
 		return nil, ErrEmailExits
 	}
 
-	// Hash password
-	PasswordService := NewPasswordService()
-	hashedPassword, err := PasswordService.HashPassword(input.Password)
-	if err != nil {
-		return nil, err
+	// Hash password, leaving it NULL for users without one
+	var hashedPassword *string
+	if input.Password != "" {
+		hash, err := r.passwords.HashPassword(input.Password)
+		if err != nil {
+			return nil, err
+		}
+		hashedPassword = &hash
 	}
 
 	// Insert User
 	query := `
-		INSERT INTO users (email, password_hash, created_at, updated_at, last_login_at)
-		VALUES($1, $2, NOW(), NOW(), NULL)
-		RETURNING id, email, password_hash, created_at, updated_at, last_login_at
-	`
+		INSERT INTO users (email, password_hash, email_verified_at, created_at, updated_at, last_login_at)
+		VALUES($1, $2, CASE WHEN $3::boolean THEN NOW() END, NOW(), NOW(), NULL)
+		RETURNING ` + userColumns
 
-	var user User
-	err = r.db.QueryRow(ctx, query, input.Email, hashedPassword).Scan(
-		&user.ID,
-		&user.Email,
-		&user.PasswordHash,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-		&user.LastLoginAt,
-	)
+	return scanUser(r.db.QueryRow(ctx, query, input.Email, hashedPassword, input.EmailVerified))
+}
+
+// GetByID retrieves a user by ID
+func (r *UserRepository) GetByID(ctx context.Context, id int) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+
+	return scanUser(r.db.QueryRow(ctx, query, id))
+}
+
+// GetByEmail retrieves a user by email
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
+
+	return scanUser(r.db.QueryRow(ctx, query, email))
+}
+
+// EmailExists checks if an email already exists
+func (r *UserRepository) EmailExists(ctx context.Context, email string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`
 
+	var exists bool
+
+	err := r.db.QueryRow(ctx, query, email).Scan(&exists)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
 
-	return &user, nil
+	return exists, nil
 }
 
-// GetByID retrieves a user by ID
-func (r *UserRepository) GetByID(ctx context.Context, id int) (*User, error) {
+// SetDisabled disables or re-enables a user account
+func (r *UserRepository) SetDisabled(ctx context.Context, userID int, disabled bool) error {
 	query := `
-		SELECT id, email, password_hash, created_at, updated_at, last_login_at
-		FROM users
+		UPDATE users
+		SET disabled_at = CASE WHEN $2 THEN COALESCE(disabled_at, NOW()) ELSE NULL END, updated_at = NOW()
 		WHERE id = $1
 	`
 
-	var user User
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&user.ID,
-		&user.Email,
-		&user.PasswordHash,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-		&user.LastLoginAt,
-	)
+	result, err := r.db.Exec(ctx, query, userID, disabled)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
 
+	return nil
+}
+
+// UpdatePassword replaces a user's password hash
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID int, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $2, updated_at = NOW() WHERE id = $1`
+
+	result, err := r.db.Exec(ctx, query, userID, passwordHash)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrUserNotFound
-		}
-		return nil, err
+		return err
 	}
 
-	return &user, nil
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
 }
 
-// GetByEmail retrieves a user by email
-func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
-	query := `
-		SELECT id, email, password_hash, created_at, updated_at, last_login_at
-		FROM users
-		WHERE email = $1
-	`
+// UpdateEmail changes a user's email address. The new address starts out unverified.
+// It returns ErrEmailExits if another account uses email.
+func (r *UserRepository) UpdateEmail(ctx context.Context, userID int, email string) error {
+	exists, err := r.EmailExists(ctx, email)
+	if err != nil {
+		return err
+	}
 
-	var user User
-	err := r.db.QueryRow(ctx, query, email).Scan(
-		&user.ID,
-		&user.Email,
-		&user.PasswordHash,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-		&user.LastLoginAt,
-	)
+	if exists {
+		return ErrEmailExits
+	}
+
+	query := `UPDATE users SET email = $2, email_verified_at = NULL, updated_at = NOW() WHERE id = $1`
 
+	result, err := r.db.Exec(ctx, query, userID, email)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrUserNotFound
+		// Another account took the address since the check above
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return ErrEmailExits
 		}
-		return nil, err
+		return err
 	}
 
-	return &user, nil
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
 }
 
-// EmailExists checks if an email already exists
-func (r *UserRepository) EmailExists(ctx context.Context, email string) (bool, error) {
-	query := `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`
+// UpdateTimeZone sets the user's time zone
+func (r *UserRepository) UpdateTimeZone(ctx context.Context, userID int, timeZone string) error {
+	query := `UPDATE users SET time_zone = $2, updated_at = NOW() WHERE id = $1`
 
-	var exists bool
+	result, err := r.db.Exec(ctx, query, userID, timeZone)
+	if err != nil {
+		return err
+	}
 
-	err := r.db.QueryRow(ctx, query, email).Scan(&exists)
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// Delete removes a user. Their todos, sessions, refresh tokens and other credentials
+// are removed with them by the ON DELETE CASCADE foreign keys.
+func (r *UserRepository) Delete(ctx context.Context, userID int) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID)
 	if err != nil {
-		return false, err
+		return err
 	}
 
-	return exists, nil
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// MarkEmailVerified records that the user verified email, unless their address has since changed.
+// It returns ErrVerificationTokenInvalid if email is no longer the user's address.
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, userID int, email string) error {
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+		WHERE id = $1 AND email = $2
+	`
+
+	result, err := r.db.Exec(ctx, query, userID, email)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrVerificationTokenInvalid
+	}
+
+	return nil
 }
 
 func (r *UserRepository) UpdateLastLogin(ctx context.Context, userID int) error {
 	query := `
 		UPDATE users
-		SET last_login_at = NOW(), updated_at = NOW()
+		SET last_login_at = NOW(), login_count = login_count + 1, updated_at = NOW()
 		WHERE id = $1
 	`
 
@@ -178,16 +300,67 @@ func (r *UserRepository) Authenticate(ctx context.Context, email, password strin
 	}
 
 	// Verify password
-	passwordService := NewPasswordService()
-	if err := passwordService.VerifyPassword(user.PasswordHash, password); err != nil {
+	if err := r.passwords.VerifyPassword(user.PasswordHash, password); err != nil {
 		return nil, ErrInvalidCredentials
 	}
 
-	// Update last login
-	if err := r.UpdateLastLogin(ctx, user.ID); err != nil {
-		// Log error but don't fail authentication
-		// In production, might want to log this
+	// The plain text password is only available now, so this is when old hashes get upgraded
+	if r.passwords.NeedsRehash(user.PasswordHash) {
+		if err := r.upgradePasswordHash(ctx, user, password); err != nil {
+			// The old hash still works, so failing to replace it isn't fatal
+			fmt.Printf("UserRepository: failed to upgrade password hash for user %d: %v\n", user.ID, err)
+		}
 	}
 
 	return user, nil
 }
+
+// upgradePasswordHash rehashes the user's verified password with the current hasher.
+// The hash is only replaced if it hasn't changed since it was verified, so a concurrent
+// password change always wins.
+func (r *UserRepository) upgradePasswordHash(ctx context.Context, user *User, password string) error {
+	passwordHash, err := r.passwords.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE users SET password_hash = $3, updated_at = NOW() WHERE id = $1 AND password_hash = $2`
+
+	result, err := r.db.Exec(ctx, query, user.ID, user.PasswordHash, passwordHash)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() > 0 {
+		user.PasswordHash = passwordHash
+	}
+
+	return nil
+}
+
+// scanUser scans a row selected with userColumns
+func scanUser(row pgx.Row) (*User, error) {
+	var user User
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.PasswordHash,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.LastLoginAt,
+		&user.LoginCount,
+		&user.DisabledAt,
+		&user.EmailVerifiedAt,
+		&user.Role,
+		&user.TimeZone,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
@@ -166,12 +166,48 @@ func (r *MockUserRepositoryDB) Authenticate(ctx context.Context, email, password
 		return nil, ErrInvalidCredentials
 	}
 
-	// Update last login
-	_ = r.UpdateLastLogin(ctx, user.ID)
-
 	return user, nil
 }
 
+func (r *MockUserRepositoryDB) UpdateEmail(ctx context.Context, userID int, email string) error {
+	if r.mockDB.shouldFail {
+		return r.mockDB.queryError
+	}
+
+	user, exists := r.mockDB.users[userID]
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	if r.mockDB.usersByEmail[email] != nil {
+		return ErrEmailExits
+	}
+
+	delete(r.mockDB.usersByEmail, user.Email)
+	user.Email = email
+	user.EmailVerifiedAt = nil
+	user.UpdatedAt = time.Now()
+	r.mockDB.usersByEmail[email] = user
+
+	return nil
+}
+
+func (r *MockUserRepositoryDB) Delete(ctx context.Context, userID int) error {
+	if r.mockDB.shouldFail {
+		return r.mockDB.queryError
+	}
+
+	user, exists := r.mockDB.users[userID]
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	delete(r.mockDB.users, userID)
+	delete(r.mockDB.usersByEmail, user.Email)
+
+	return nil
+}
+
 // Repository test data
 type RepositoryTestData struct {
 	testEmail    string
@@ -464,9 +500,9 @@ func TestAuthenticate(t *testing.T) {
 		t.Errorf("Expected email %s, got %s", repoTestData.testEmail, user.Email)
 	}
 
-	// LastLoginAt should be updated
-	if user.LastLoginAt == nil {
-		t.Error("LastLoginAt should be set after authentication")
+	// Logins are only recorded once the session starts, after every other check
+	if user.LastLoginAt != nil {
+		t.Error("LastLoginAt should not be set by authentication alone")
 	}
 }
 
@@ -510,6 +546,92 @@ func TestAuthenticateInvalidCredentials(t *testing.T) {
 	}
 }
 
+// TestUpdateEmail tests changing a user's email address
+func TestUpdateEmail(t *testing.T) {
+	repo := NewMockUserRepositoryDB()
+	ctx := context.Background()
+
+	user, err := repo.Create(ctx, CreateUserInput{
+		Email:    repoTestData.testEmail,
+		Password: repoTestData.testPassword,
+	})
+	if err != nil {
+		t.Fatalf("Create should succeed: %v", err)
+	}
+	verifiedAt := time.Now()
+	user.EmailVerifiedAt = &verifiedAt
+
+	other, err := repo.Create(ctx, CreateUserInput{
+		Email:    "other@example.com",
+		Password: repoTestData.testPassword,
+	})
+	if err != nil {
+		t.Fatalf("Create should succeed: %v", err)
+	}
+
+	// Addresses of other accounts are taken
+	if err := repo.UpdateEmail(ctx, user.ID, other.Email); !errors.Is(err, ErrEmailExits) {
+		t.Errorf("Expected ErrEmailExits, got: %v", err)
+	}
+
+	if err := repo.UpdateEmail(ctx, user.ID, "new@example.com"); err != nil {
+		t.Fatalf("UpdateEmail should succeed: %v", err)
+	}
+
+	updated, err := repo.GetByEmail(ctx, "new@example.com")
+	if err != nil {
+		t.Fatalf("GetByEmail should find new address: %v", err)
+	}
+	if updated.ID != user.ID {
+		t.Errorf("Expected user %d, got %d", user.ID, updated.ID)
+	}
+	if updated.IsEmailVerified() {
+		t.Error("New address should not be verified")
+	}
+
+	if _, err := repo.GetByEmail(ctx, repoTestData.testEmail); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Old address should be free, got: %v", err)
+	}
+
+	if err := repo.UpdateEmail(ctx, 999, "nobody@example.com"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got: %v", err)
+	}
+}
+
+// TestDeleteUser tests deleting a user
+func TestDeleteUser(t *testing.T) {
+	repo := NewMockUserRepositoryDB()
+	ctx := context.Background()
+
+	user, err := repo.Create(ctx, CreateUserInput{
+		Email:    repoTestData.testEmail,
+		Password: repoTestData.testPassword,
+	})
+	if err != nil {
+		t.Fatalf("Create should succeed: %v", err)
+	}
+
+	if err := repo.Delete(ctx, user.ID); err != nil {
+		t.Fatalf("Delete should succeed: %v", err)
+	}
+
+	if _, err := repo.GetByID(ctx, user.ID); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound after delete, got: %v", err)
+	}
+
+	exists, err := repo.EmailExists(ctx, repoTestData.testEmail)
+	if err != nil {
+		t.Fatalf("EmailExists should succeed: %v", err)
+	}
+	if exists {
+		t.Error("Email should be free after delete")
+	}
+
+	if err := repo.Delete(ctx, user.ID); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound deleting twice, got: %v", err)
+	}
+}
+
 // TestRepositoryErrorHandling tests database error scenarios
 func TestRepositoryErrorHandling(t *testing.T) {
 	repo := NewMockUserRepositoryDB()
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// revocationSweepInterval is how often expired entries are purged from a revocation store
+const revocationSweepInterval = 10 * time.Minute
+
+// RevocationStore tracks access tokens (by jti) that were revoked before they expire.
+// Entries only need to be kept until the token's own expiry.
+type RevocationStore interface {
+	Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// MemoryRevocationStore keeps revoked token IDs in process memory.
+// Revocations are not shared between instances; use PostgresRevocationStore when running several replicas.
+type MemoryRevocationStore struct {
+	mu        sync.RWMutex
+	revoked   map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryRevocationStore creates an empty in-memory revocation store
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		revoked:   make(map[string]time.Time),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Revoke marks tokenID as revoked until expiresAt
+func (m *MemoryRevocationStore) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if now.Sub(m.lastSweep) >= revocationSweepInterval {
+		for id, expiry := range m.revoked {
+			if !expiry.After(now) {
+				delete(m.revoked, id)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	if expiresAt.After(now) {
+		m.revoked[tokenID] = expiresAt
+	}
+
+	return nil
+}
+
+// IsRevoked returns true if tokenID was revoked and has not yet expired
+func (m *MemoryRevocationStore) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	expiresAt, ok := m.revoked[tokenID]
+	return ok && expiresAt.After(m.now()), nil
+}
+
+// size returns the number of tracked revocations, including expired ones not yet swept
+func (m *MemoryRevocationStore) size() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.revoked)
+}
+
+// PostgresRevocationStore keeps revoked token IDs in the revoked_tokens table, shared by every instance
+type PostgresRevocationStore struct {
+	db *pgxpool.Pool
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewPostgresRevocationStore creates a revocation store backed by Postgres
+func NewPostgresRevocationStore(db *pgxpool.Pool) *PostgresRevocationStore {
+	return &PostgresRevocationStore{
+		db: db,
+	}
+}
+
+// Revoke marks tokenID as revoked until expiresAt
+func (p *PostgresRevocationStore) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO revoked_tokens (jti, expires_at, revoked_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	if _, err := p.db.Exec(ctx, query, tokenID, expiresAt); err != nil {
+		return err
+	}
+
+	p.sweep(ctx)
+
+	return nil
+}
+
+// IsRevoked returns true if tokenID was revoked and has not yet expired
+func (p *PostgresRevocationStore) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1 AND expires_at > NOW())`
+
+	var revoked bool
+	if err := p.db.QueryRow(ctx, query, tokenID).Scan(&revoked); err != nil {
+		return false, err
+	}
+
+	return revoked, nil
+}
+
+// sweep deletes expired revocations at most once per revocationSweepInterval
+func (p *PostgresRevocationStore) sweep(ctx context.Context) {
+	p.mu.Lock()
+	if time.Since(p.lastSweep) < revocationSweepInterval {
+		p.mu.Unlock()
+		return
+	}
+	p.lastSweep = time.Now()
+	p.mu.Unlock()
+
+	// Expired entries are ignored by IsRevoked, so a failed sweep is harmless
+	_, _ = p.db.Exec(ctx, `DELETE FROM revoked_tokens WHERE expires_at <= NOW()`)
+}
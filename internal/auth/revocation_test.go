@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestMemoryRevocationStore tests revoking and expiring token IDs
+func TestMemoryRevocationStore(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	ctx := context.Background()
+
+	if err := store.Revoke(ctx, "token-1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Revoke should succeed: %v", err)
+	}
+
+	revoked, err := store.IsRevoked(ctx, "token-1")
+	if err != nil || !revoked {
+		t.Errorf("token-1 should be revoked, got %v (err %v)", revoked, err)
+	}
+
+	revoked, _ = store.IsRevoked(ctx, "token-2")
+	if revoked {
+		t.Error("token-2 should not be revoked")
+	}
+
+	// Tokens that already expired need no entry
+	_ = store.Revoke(ctx, "expired", time.Now().Add(-time.Minute))
+	if revoked, _ := store.IsRevoked(ctx, "expired"); revoked {
+		t.Error("Expired token should not be tracked")
+	}
+}
+
+// TestMemoryRevocationStoreSweep tests that expired entries are purged
+func TestMemoryRevocationStoreSweep(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	ctx := context.Background()
+
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	_ = store.Revoke(ctx, "short", now.Add(time.Minute))
+	_ = store.Revoke(ctx, "long", now.Add(time.Hour))
+
+	// Once the short-lived token expires it stops counting as revoked
+	now = now.Add(2 * time.Minute)
+	if revoked, _ := store.IsRevoked(ctx, "short"); revoked {
+		t.Error("short should no longer be revoked after expiry")
+	}
+
+	// The next revocation after the sweep interval drops it from memory
+	now = now.Add(revocationSweepInterval)
+	_ = store.Revoke(ctx, "another", now.Add(time.Hour))
+
+	if store.size() != 2 {
+		t.Errorf("Expected 2 tracked revocations after sweep, got %d", store.size())
+	}
+	if revoked, _ := store.IsRevoked(ctx, "long"); !revoked {
+		t.Error("long should still be revoked")
+	}
+}
+
+// TestRevokeAccessToken tests that a revoked access token is rejected while others keep working
+func TestRevokeAccessToken(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx := context.Background()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	refreshed, err := authService.RefreshToken(ctx, result.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshToken should succeed: %v", err)
+	}
+
+	if err := authService.RevokeAccessToken(ctx, result.Token); err != nil {
+		t.Fatalf("RevokeAccessToken should succeed: %v", err)
+	}
+
+	if _, err := authService.GetUserFromToken(ctx, result.Token); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Expected ErrTokenRevoked, got: %v", err)
+	}
+
+	// Other tokens of the same session are unaffected
+	if _, err := authService.GetUserFromToken(ctx, refreshed.Token); err != nil {
+		t.Errorf("Refreshed token should still be valid: %v", err)
+	}
+}
+
+// TestLogoutRevokesAccessToken tests that logout kills the access token immediately
+func TestLogoutRevokesAccessToken(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx := context.Background()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	principal, err := authService.AuthenticateToken(ctx, result.Token)
+	if err != nil {
+		t.Fatalf("AuthenticateToken should succeed: %v", err)
+	}
+	if principal.TokenID == "" {
+		t.Fatal("Access token should carry a jti")
+	}
+
+	if err := authService.Logout(ctx, principal); err != nil {
+		t.Fatalf("Logout should succeed: %v", err)
+	}
+
+	if _, err := authService.GetUserFromToken(ctx, result.Token); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("Expected ErrTokenRevoked after logout, got: %v", err)
+	}
+}
+
+// TestGeneratedTokensHaveUniqueIDs tests that every access token gets its own jti
+func TestGeneratedTokensHaveUniqueIDs(t *testing.T) {
+	jwtService := NewJWTService(serviceTestData.jwtSecret, serviceTestData.tokenExpiry)
+
+	first, _ := jwtService.GenerateToken(1, "a@example.com")
+	second, _ := jwtService.GenerateToken(1, "a@example.com")
+
+	firstClaims, err := jwtService.ValidateToken(first)
+	if err != nil {
+		t.Fatalf("ValidateToken should succeed: %v", err)
+	}
+	secondClaims, err := jwtService.ValidateToken(second)
+	if err != nil {
+		t.Fatalf("ValidateToken should succeed: %v", err)
+	}
+
+	if firstClaims.ID == "" || firstClaims.ID == secondClaims.ID {
+		t.Errorf("Expected unique non-empty jti values, got %q and %q", firstClaims.ID, secondClaims.ID)
+	}
+}
@@ -2,12 +2,22 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jayk0001/my-go-next-todo/internal/database"
+	"github.com/jayk0001/my-go-next-todo/internal/mail"
+	"github.com/jayk0001/my-go-next-todo/internal/pubsub"
 )
 
+// mailSendTimeout bounds how long a background email delivery may take
+const mailSendTimeout = 30 * time.Second
+
 type UserRepositoryInterface interface {
 	Create(ctx context.Context, input CreateUserInput) (*User, error)
 	GetByID(ctx context.Context, id int) (*User, error)
@@ -15,6 +25,17 @@ type UserRepositoryInterface interface {
 	EmailExists(ctx context.Context, email string) (bool, error)
 	UpdateLastLogin(ctx context.Context, userID int) error
 	Authenticate(ctx context.Context, email, password string) (*User, error)
+	SetDisabled(ctx context.Context, userID int, disabled bool) error
+	UpdatePassword(ctx context.Context, userID int, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, userID int, email string) error
+	UpdateEmail(ctx context.Context, userID int, email string) error
+	UpdateTimeZone(ctx context.Context, userID int, timeZone string) error
+	Delete(ctx context.Context, userID int) error
+}
+
+// statusNotifier broadcasts auth status events to every API instance
+type statusNotifier interface {
+	Notify(ctx context.Context, channel, payload string) error
 }
 
 // AuthService handles authentication business logic
@@ -23,25 +44,191 @@ type AuthService struct {
 	jwtService       *JWTService
 	passwordService  *PasswordService
 	validatorService *ValidatorService
+	sessionRepo      SessionRepositoryInterface
+	refreshRepo      RefreshTokenRepositoryInterface
+	revocations      RevocationStore
+	statusEvents     *pubsub.Hub[StatusEvent]
+	resetRepo        PasswordResetRepositoryInterface
+	verificationRepo EmailVerificationRepositoryInterface
+	unverifiedPolicy UnverifiedPolicy
+	twoFactorRepo    TwoFactorRepositoryInterface
+	loginLimiter     *LoginLimiter
+	dataExportRepo   DataExportTokenRepositoryInterface
+	accessTokenRepo  PersonalAccessTokenRepositoryInterface
+	identityRepo     IdentityRepositoryInterface
+	oidcStateRepo    OIDCLoginStateRepositoryInterface
+	magicLinkRepo    MagicLinkRepositoryInterface
+
+	// magicLinkAutoRegister lets sign-in links create accounts for unknown addresses
+	magicLinkAutoRegister bool
+
+	// oidcProviders are the identity providers users can sign in with, by name
+	oidcProviders map[string]*OIDCProvider
+
+	// totpIssuer names the app in authenticator apps
+	totpIssuer string
+
+	// mailer delivers account emails; links in them point at appURL
+	mailer mail.Mailer
+	appURL string
+
+	// notifier is set when status events are relayed through Postgres NOTIFY.
+	// Local subscribers then receive events through RelayStatusEvent.
+	notifier statusNotifier
+
+	// userCreatedHook runs after every new account is created, however it signed up
+	userCreatedHook func(ctx context.Context, user *User) error
 }
 
 // AuthResult represents the result of authentication operations
 type AuthResult struct {
 	User         *User
+	Session      *Session
 	Token        string
 	RefreshToken string
 	ExpiresAt    time.Time
 }
 
+// Principal is the identity behind an authenticated request
+type Principal struct {
+	User           *User
+	SessionID      string // empty for tokens not bound to a session
+	TokenID        string // jti of the access token, empty for tokens issued before jti existed
+	TokenExpiresAt time.Time
+	// ReadOnly is set when the user may read but not change data, e.g. before verifying their email
+	ReadOnly bool
+	// PersonalAccessTokenID is set when the request used a personal access token instead of a JWT
+	PersonalAccessTokenID int
+	// Scopes limits what a personal access token can do; nil for interactive sign-ins, which can do anything
+	Scopes []string
+}
+
+// HasScope returns true if the principal was granted scope
+func (p *Principal) HasScope(scope string) bool {
+	return p.Scopes == nil || slices.Contains(p.Scopes, scope)
+}
+
+// AuthInfo summarizes a user's sign-in activity
+type AuthInfo struct {
+	LoginCount             int
+	LastLoginAt            *time.Time
+	ActiveSessions         []*Session
+	TwoFactorEnabled       bool
+	RecoveryCodesRemaining int
+}
+
 func NewAuthService(db *pgxpool.Pool, jwtSecret string, tokenExpiry time.Duration) *AuthService {
+	// Registration, logins and password changes share one hashing configuration
+	passwordService := NewPasswordService()
+	userRepo := NewUserRepository(db)
+	userRepo.passwords = passwordService
+
 	return &AuthService{
-		userRepo:         NewUserRepository(db),
+		userRepo:         userRepo,
 		jwtService:       NewJWTService(jwtSecret, tokenExpiry),
-		passwordService:  NewPasswordService(),
+		passwordService:  passwordService,
 		validatorService: NewValidatorService(),
+		sessionRepo:      NewSessionRepository(db),
+		refreshRepo:      NewRefreshTokenRepository(db),
+		revocations:      NewMemoryRevocationStore(),
+		statusEvents:     pubsub.NewHub[StatusEvent](),
+		resetRepo:        NewPasswordResetRepository(db),
+		verificationRepo: NewEmailVerificationRepository(db),
+		unverifiedPolicy: UnverifiedAllow,
+		twoFactorRepo:    NewTwoFactorRepository(db),
+		loginLimiter:     NewLoginLimiter(NewMemoryLoginAttemptStore(), DefaultLockoutPolicy()),
+		dataExportRepo:   NewDataExportTokenRepository(db),
+		accessTokenRepo:  NewPersonalAccessTokenRepository(db),
+		identityRepo:     NewIdentityRepository(db),
+		oidcStateRepo:    NewOIDCLoginStateRepository(db),
+		magicLinkRepo:    NewMagicLinkRepository(db),
+		totpIssuer:       DefaultTOTPIssuer,
+	}
+}
+
+// NewAuthServiceWithNotifier creates an auth service whose status events are fanned out
+// to every API instance through Postgres LISTEN/NOTIFY
+func NewAuthServiceWithNotifier(db *pgxpool.Pool, jwtSecret string, tokenExpiry time.Duration, notifier *database.Notifier) *AuthService {
+	service := NewAuthService(db, jwtSecret, tokenExpiry)
+	service.notifier = notifier
+	notifier.Subscribe(StatusChannel, service.RelayStatusEvent)
+
+	return service
+}
+
+// SetRevocationStore replaces the store used to revoke individual access tokens.
+// The default in-memory store only covers the current instance.
+func (s *AuthService) SetRevocationStore(store RevocationStore) {
+	s.revocations = store
+}
+
+// SetSigningKeys switches access tokens to asymmetric signing with keys
+func (s *AuthService) SetSigningKeys(keys *KeySet) {
+	s.jwtService.keys = keys
+}
+
+// SetRejectHS256 stops accepting HS256 access tokens signed with the shared secret.
+// It only takes effect once signing keys are set.
+func (s *AuthService) SetRejectHS256(reject bool) {
+	s.jwtService.rejectHMAC = reject
+}
+
+// SetMailer configures how account emails are sent and the frontend URL their links point at.
+// Without a mailer, emails are dropped and only logged.
+func (s *AuthService) SetMailer(mailer mail.Mailer, appURL string) {
+	s.mailer = mailer
+	s.appURL = strings.TrimSuffix(appURL, "/")
+}
+
+// SetUnverifiedPolicy decides whether users who have not verified their email can sign in or change data
+func (s *AuthService) SetUnverifiedPolicy(policy UnverifiedPolicy) {
+	s.unverifiedPolicy = policy
+}
+
+// SetLoginLimiter replaces the brute-force protection applied to logins.
+// The default limiter keeps counts in memory, so they only cover the current instance.
+func (s *AuthService) SetLoginLimiter(limiter *LoginLimiter) {
+	s.loginLimiter = limiter
+}
+
+// SetPasswordHasher changes the algorithm used to hash new passwords. Stored hashes made
+// by another algorithm or with other parameters are replaced the next time their user logs in.
+func (s *AuthService) SetPasswordHasher(hasher PasswordHasher) {
+	s.passwordService.SetHasher(hasher)
+}
+
+// SetPasswordPolicy replaces the rules new passwords have to meet
+func (s *AuthService) SetPasswordPolicy(policy PasswordPolicy) {
+	s.validatorService.SetPasswordPolicy(policy)
+}
+
+// SetTOTPIssuer sets the name authenticator apps show next to the user's account
+func (s *AuthService) SetTOTPIssuer(issuer string) {
+	s.totpIssuer = issuer
+}
+
+// SetUserCreatedHook sets a function that runs after every new account is created, e.g. to set up
+// the user's data in other packages. A failing hook is logged and does not undo the registration.
+func (s *AuthService) SetUserCreatedHook(hook func(ctx context.Context, user *User) error) {
+	s.userCreatedHook = hook
+}
+
+// userCreated runs the user-created hook for a new account
+func (s *AuthService) userCreated(ctx context.Context, user *User) {
+	if s.userCreatedHook == nil {
+		return
+	}
+
+	if err := s.userCreatedHook(ctx, user); err != nil {
+		fmt.Printf("AuthService: user created hook failed for user %d: %v\n", user.ID, err)
 	}
 }
 
+// JWKS returns the public keys other services can use to verify access tokens
+func (s *AuthService) JWKS() JWKS {
+	return s.jwtService.JWKS()
+}
+
 // Register created a new user account
 func (s *AuthService) Register(ctx context.Context, email, password string) (*AuthResult, error) {
 	// Validate input
@@ -62,22 +249,19 @@ func (s *AuthService) Register(ctx context.Context, email, password string) (*Au
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	token, err := s.jwtService.GenerateToken(user.ID, user.Email)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+	s.userCreated(ctx, user)
+
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		return nil, err
 	}
 
-	refreshToken, err := s.jwtService.GenerateRefreshToken(user.ID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	// The account exists, but can't be used until the emailed link is opened
+	if s.unverifiedPolicy == UnverifiedBlock {
+		return nil, fmt.Errorf("account created, check your inbox to verify it: %w", ErrEmailNotVerified)
 	}
 
-	return &AuthResult{
-		User:         user,
-		Token:        token,
-		RefreshToken: refreshToken,
-		ExpiresAt:    time.Now().Add(24 * 7 * time.Hour), // Same as JWT Expiry
-	}, nil
+	// Signing up isn't counted as a login
+	return s.newSession(ctx, user)
 }
 
 // Login authenticates a user and returns tokens
@@ -87,71 +271,229 @@ func (s *AuthService) Login(ctx context.Context, email, password string) (*AuthR
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
 
+	// The attempt is throttled as if it had failed until the password checks out.
+	// Throttled attempts are turned away before any password hashing.
+	ipAddress := ClientInfoFromContext(ctx).IPAddress
+	if err := s.loginLimiter.Reserve(ctx, email, ipAddress); err != nil {
+		return nil, err
+	}
+
 	// Authenticate user
 	user, err := s.userRepo.Authenticate(ctx, email, password)
 	if err != nil {
+		// Only wrong passwords count towards the lockout
+		if errors.Is(err, ErrInvalidCredentials) {
+			if err := s.loginLimiter.RecordFailure(ctx, email, ipAddress); err != nil {
+				fmt.Printf("AuthService: failed to record login failure: %v\n", err)
+			}
+		} else if err := s.loginLimiter.Release(ctx, email, ipAddress); err != nil {
+			fmt.Printf("AuthService: failed to release login attempt: %v\n", err)
+		}
 		return nil, fmt.Errorf("authentication failed: %w", err)
 	}
 
-	// Generate tokens
-	token, err := s.jwtService.GenerateToken(user.ID, user.Email)
+	// The password is right, but tokens are only issued once the second factor is checked.
+	// Until then failed logins are kept, so knowing the password doesn't buy unlimited
+	// guesses at the code through fresh challenges.
+	enabled, err := s.twoFactorEnabled(ctx, user.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		if err := s.loginLimiter.Release(ctx, email, ipAddress); err != nil {
+			fmt.Printf("AuthService: failed to release login attempt: %v\n", err)
+		}
+		return nil, err
 	}
 
-	refreshToken, err := s.jwtService.GenerateRefreshToken(user.ID)
+	if enabled {
+		err = s.loginLimiter.Release(ctx, email, ipAddress)
+	} else {
+		err = s.loginLimiter.RecordSuccess(ctx, email, ipAddress)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to refresh token: %w", err)
+		fmt.Printf("AuthService: failed to update login failures: %v\n", err)
 	}
 
-	return &AuthResult{
-		User:         user,
-		Token:        token,
-		RefreshToken: refreshToken,
-		ExpiresAt:    time.Now().Add(24 * 7 * time.Hour),
-	}, nil
+	if user.IsDisabled() {
+		return nil, fmt.Errorf("authentication failed: %w", ErrAccountDisabled)
+	}
+
+	if err := s.checkEmailVerified(user); err != nil {
+		return nil, fmt.Errorf("authentication failed: %w", err)
+	}
+
+	if enabled {
+		return nil, s.startTwoFactorChallenge(ctx, user.ID)
+	}
+
+	return s.startSession(ctx, user)
 }
 
-// RefreshToken generates new tokens from refresh token
+// RefreshToken exchanges a refresh token for new tokens. Each refresh token can be used once:
+// it is rotated for a new one in the same family, and presenting a rotated token again
+// is treated as theft, revoking the whole family and its session.
 func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*AuthResult, error) {
-	// Validate refresh token
-	userID, err := s.jwtService.ValidateRefreshToken(refreshToken)
+	record, err := s.refreshRepo.GetByHash(ctx, hashToken(refreshToken))
 	if err != nil {
 		return nil, fmt.Errorf("invalid refresh token: %w", err)
 	}
 
+	if record.RevokedAt != nil || time.Now().After(record.ExpiresAt) {
+		return nil, fmt.Errorf("invalid refresh token: %w", ErrRefreshTokenInvalid)
+	}
+
+	if record.RotatedAt != nil {
+		s.revokeReusedFamily(ctx, record)
+		return nil, ErrRefreshTokenReused
+	}
+
 	// Get user
-	user, err := s.userRepo.GetByID(ctx, userID)
+	user, err := s.userRepo.GetByID(ctx, record.UserID)
 	if err != nil {
 		return nil, fmt.Errorf("invalid user ID in token: %w", err)
 	}
 
-	// Generate new tokens
-	newToken, err := s.jwtService.GenerateToken(user.ID, user.Email)
+	if user.IsDisabled() {
+		return nil, ErrAccountDisabled
+	}
+
+	if err := s.checkEmailVerified(user); err != nil {
+		return nil, err
+	}
+
+	// Refreshing keeps the session alive, unless it has been revoked
+	session, err := s.activeSession(ctx, record.SessionID, user.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return nil, err
 	}
 
-	newRefreshToken, err := s.jwtService.GenerateRefreshToken(user.ID)
+	next, nextToken, err := newRefreshTokenRecord(user.ID, session.ID, record.FamilyID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
-	return &AuthResult{
-		User:         user,
-		Token:        newToken,
-		RefreshToken: newRefreshToken,
-		ExpiresAt:    time.Now().Add(s.jwtService.expiryHours),
-	}, nil
+	if err := s.refreshRepo.Rotate(ctx, record.ID, next); err != nil {
+		if errors.Is(err, ErrRefreshTokenReused) {
+			// Lost a race with another refresh using the same token
+			s.revokeReusedFamily(ctx, record)
+			return nil, ErrRefreshTokenReused
+		}
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	return s.issueAccessToken(user, session, nextToken)
+}
+
+// Logout revokes the current access token, ends its session and revokes the session's refresh tokens
+func (s *AuthService) Logout(ctx context.Context, principal *Principal) error {
+	if principal.TokenID != "" {
+		if err := s.revocations.Revoke(ctx, principal.TokenID, principal.TokenExpiresAt); err != nil {
+			return fmt.Errorf("failed to revoke access token: %w", err)
+		}
+	}
+
+	// Tokens issued before sessions existed have no session to end
+	userID, sessionID := principal.User.ID, principal.SessionID
+	if sessionID == "" {
+		return nil
+	}
+
+	if err := s.refreshRepo.RevokeBySession(ctx, sessionID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	if err := s.sessionRepo.Revoke(ctx, sessionID, userID); err != nil && !errors.Is(err, ErrSessionNotFound) {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	// Other tabs sharing the session drop their credentials too
+	s.publishStatus(ctx, StatusEvent{
+		Reason:    StatusSessionRevoked,
+		UserID:    userID,
+		SessionID: sessionID,
+	})
+
+	return nil
 }
 
 // GetUserFromToken extracts user from JWT token
 func (a *AuthService) GetUserFromToken(ctx context.Context, token string) (*User, error) {
+	principal, err := a.AuthenticateToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return principal.User, nil
+}
+
+// AuthenticateToken validates an access token or personal access token and returns the user and session behind it
+func (a *AuthService) AuthenticateToken(ctx context.Context, token string) (*Principal, error) {
+	if strings.HasPrefix(token, PersonalAccessTokenPrefix) {
+		return a.authenticatePersonalAccessToken(ctx, token)
+	}
+
 	claims, err := a.jwtService.ValidateToken(token)
 	if err != nil {
 		return nil, fmt.Errorf("invalid token: %w", err)
 	}
-	return a.userRepo.GetByID(ctx, claims.UserID)
+
+	user, err := a.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsDisabled() {
+		return nil, ErrAccountDisabled
+	}
+
+	if err := a.checkEmailVerified(user); err != nil {
+		return nil, err
+	}
+
+	if claims.ID != "" {
+		revoked, err := a.revocations.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check token revocation: %w", err)
+		}
+		if revoked {
+			return nil, ErrTokenRevoked
+		}
+	}
+
+	// Tokens issued before sessions existed carry no session ID
+	if claims.SessionID != "" {
+		if _, err := a.activeSession(ctx, claims.SessionID, user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	principal := &Principal{
+		User:      user,
+		SessionID: claims.SessionID,
+		TokenID:   claims.ID,
+		ReadOnly:  a.unverifiedPolicy == UnverifiedReadOnly && !user.IsEmailVerified(),
+	}
+	if claims.ExpiresAt != nil {
+		principal.TokenExpiresAt = claims.ExpiresAt.Time
+	}
+
+	return principal, nil
+}
+
+// RevokeAccessToken invalidates a single access token immediately, e.g. one known to be stolen
+func (a *AuthService) RevokeAccessToken(ctx context.Context, token string) error {
+	claims, err := a.jwtService.ValidateToken(token)
+	if err != nil {
+		return fmt.Errorf("invalid token: %w", err)
+	}
+
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return fmt.Errorf("token cannot be revoked: missing jti or exp")
+	}
+
+	if err := a.revocations.Revoke(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	return nil
 }
 
 // GetUserByID retrieves user by ID
@@ -163,3 +505,921 @@ func (s *AuthService) GetUserByID(ctx context.Context, userID int) (*User, error
 
 	return user, nil
 }
+
+// GetAuthInfo returns the user's sign-in activity and active sessions
+func (s *AuthService) GetAuthInfo(ctx context.Context, userID int) (*AuthInfo, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	sessions, err := s.sessionRepo.ListActiveByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	twoFactorEnabled, err := s.twoFactorEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &AuthInfo{
+		LoginCount:       user.LoginCount,
+		LastLoginAt:      user.LastLoginAt,
+		ActiveSessions:   sessions,
+		TwoFactorEnabled: twoFactorEnabled,
+	}
+
+	if twoFactorEnabled {
+		info.RecoveryCodesRemaining, err = s.twoFactorRepo.CountRecoveryCodes(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count recovery codes: %w", err)
+		}
+	}
+
+	return info, nil
+}
+
+// RevokeSession signs one of the user's sessions out
+func (s *AuthService) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	if err := s.sessionRepo.Revoke(ctx, sessionID, userID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	if err := s.refreshRepo.RevokeBySession(ctx, sessionID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	s.publishStatus(ctx, StatusEvent{
+		Reason:    StatusSessionRevoked,
+		UserID:    userID,
+		SessionID: sessionID,
+	})
+
+	return nil
+}
+
+// RevokeOtherSessions signs out every session of the user except currentSessionID
+// and returns how many sessions were revoked
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, userID int, currentSessionID string) (int, error) {
+	revoked, err := s.revokeSessionsExcept(ctx, userID, currentSessionID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, sessionID := range revoked {
+		s.publishStatus(ctx, StatusEvent{
+			Reason:    StatusSessionRevoked,
+			UserID:    userID,
+			SessionID: sessionID,
+		})
+	}
+
+	return len(revoked), nil
+}
+
+// RequestPasswordReset emails a single-use reset link if email belongs to an active account.
+// It returns nil whether or not the account exists so callers cannot probe for registered emails.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to request password reset: %w", err)
+	}
+
+	if user.IsDisabled() {
+		return nil
+	}
+
+	// Only the newest link works
+	if err := s.resetRepo.InvalidateForUser(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to request password reset: %w", err)
+	}
+
+	token, hash, err := generateOpaqueToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate reset token: %w", err)
+	}
+
+	if err := s.resetRepo.Create(ctx, &PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(PasswordResetTokenExpiry),
+	}); err != nil {
+		return fmt.Errorf("failed to store reset token: %w", err)
+	}
+
+	s.sendMail(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Someone asked to reset the password for your account.\n\n"+
+				"Open this link within %d minutes to choose a new password:\n%s/reset-password?token=%s\n\n"+
+				"If this wasn't you, you can ignore this email.\n",
+			int(PasswordResetTokenExpiry.Minutes()), s.appURL, token,
+		),
+	})
+
+	return nil
+}
+
+// ResetPassword sets a new password using a token from a reset email.
+// The token is consumed, every session of the user is signed out and their personal access tokens are revoked.
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	record, err := s.resetRepo.GetValid(ctx, hashToken(token))
+	if err != nil {
+		return fmt.Errorf("password reset failed: %w", err)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, record.UserID)
+	if err != nil {
+		return fmt.Errorf("password reset failed: %w", err)
+	}
+
+	if user.IsDisabled() {
+		return fmt.Errorf("password reset failed: %w", ErrAccountDisabled)
+	}
+
+	// Validate before consuming so a rejected password doesn't burn the link
+	if err := s.validatorService.ValidatePassword(newPassword, user.Email); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	if _, err := s.resetRepo.Consume(ctx, record.TokenHash); err != nil {
+		return fmt.Errorf("password reset failed: %w", err)
+	}
+
+	passwordHash, err := s.passwordService.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, passwordHash); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if err := s.resetRepo.InvalidateForUser(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to invalidate reset tokens: %w", err)
+	}
+
+	if err := s.accessTokenRepo.RevokeAllForUser(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to revoke personal access tokens: %w", err)
+	}
+
+	return s.revokeAllSessions(ctx, user.ID, StatusPasswordChanged)
+}
+
+// VerifyEmail marks the address a verification token was sent to as verified.
+// Tokens for an address the user has since changed are rejected.
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) (*User, error) {
+	record, err := s.verificationRepo.Consume(ctx, hashToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("email verification failed: %w", err)
+	}
+
+	if err := s.userRepo.MarkEmailVerified(ctx, record.UserID, record.Email); err != nil {
+		return nil, fmt.Errorf("email verification failed: %w", err)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, record.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("email verification failed: %w", err)
+	}
+
+	return user, nil
+}
+
+// ResendVerification emails a new verification link to the user, replacing earlier ones.
+// Verified users are left alone.
+func (s *AuthService) ResendVerification(ctx context.Context, userID int) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to resend verification: %w", err)
+	}
+
+	if user.IsEmailVerified() || user.IsDisabled() {
+		return nil
+	}
+
+	return s.sendVerificationEmail(ctx, user)
+}
+
+// ResendVerificationByEmail is ResendVerification for users who cannot sign in yet.
+// Like RequestPasswordReset, it returns nil whether or not the account exists.
+func (s *AuthService) ResendVerificationByEmail(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to resend verification: %w", err)
+	}
+
+	if user.IsEmailVerified() || user.IsDisabled() {
+		return nil
+	}
+
+	return s.sendVerificationEmail(ctx, user)
+}
+
+// ChangePassword replaces the password of a signed in user after checking their current one.
+// Every other session is signed out and personal access tokens are revoked; currentSessionID stays signed in.
+func (s *AuthService) ChangePassword(ctx context.Context, userID int, currentSessionID, currentPassword, newPassword string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to change password: %w", err)
+	}
+
+	if err := s.confirmPassword(ctx, user, currentPassword); err != nil {
+		return fmt.Errorf("failed to change password: %w", err)
+	}
+
+	if err := s.validatorService.ValidatePassword(newPassword, user.Email); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	passwordHash, err := s.passwordService.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, passwordHash); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	// Links requested for the old password must not override the new one
+	if err := s.resetRepo.InvalidateForUser(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to invalidate reset tokens: %w", err)
+	}
+
+	// Tokens issued under the old password may have leaked along with it
+	if err := s.accessTokenRepo.RevokeAllForUser(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to revoke personal access tokens: %w", err)
+	}
+
+	revoked, err := s.revokeSessionsExcept(ctx, user.ID, currentSessionID)
+	if err != nil {
+		return err
+	}
+
+	for _, sessionID := range revoked {
+		s.publishStatus(ctx, StatusEvent{
+			Reason:    StatusPasswordChanged,
+			UserID:    user.ID,
+			SessionID: sessionID,
+		})
+	}
+
+	return nil
+}
+
+// ChangeEmail moves a signed in user to newEmail after checking their password, or that
+// currentSessionID signed in recently for accounts without one. The new address has to be
+// verified again through the emailed link, and the old address is told about the change.
+func (s *AuthService) ChangeEmail(ctx context.Context, userID int, currentSessionID, newEmail, password string) (*User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to change email: %w", err)
+	}
+
+	if err := s.confirmIdentity(ctx, user, currentSessionID, password); err != nil {
+		return nil, fmt.Errorf("failed to change email: %w", err)
+	}
+
+	if err := s.validatorService.ValidateEmail(newEmail); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	if newEmail == user.Email {
+		return user, nil
+	}
+
+	oldEmail := user.Email
+	if err := s.userRepo.UpdateEmail(ctx, user.ID, newEmail); err != nil {
+		return nil, fmt.Errorf("failed to change email: %w", err)
+	}
+
+	// Reset links went to the old address
+	if err := s.resetRepo.InvalidateForUser(ctx, user.ID); err != nil {
+		return nil, fmt.Errorf("failed to invalidate reset tokens: %w", err)
+	}
+
+	user, err = s.userRepo.GetByID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to change email: %w", err)
+	}
+
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		return nil, err
+	}
+
+	s.sendMail(ctx, mail.Message{
+		To:      oldEmail,
+		Subject: "Your email address was changed",
+		Body: fmt.Sprintf(
+			"The email address of your account was changed to %s.\n\n"+
+				"If this wasn't you, reset your password and contact support.\n",
+			newEmail,
+		),
+	})
+
+	return user, nil
+}
+
+// UpdateTimeZone sets the IANA time zone used for the signed in user's calendar days
+func (s *AuthService) UpdateTimeZone(ctx context.Context, userID int, timeZone string) (*User, error) {
+	if err := s.validatorService.ValidateTimeZone(timeZone); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	if err := s.userRepo.UpdateTimeZone(ctx, userID, timeZone); err != nil {
+		return nil, fmt.Errorf("failed to update time zone: %w", err)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update time zone: %w", err)
+	}
+
+	return user, nil
+}
+
+// DeleteAccount permanently deletes the signed in user after checking their password, or that
+// the principal's session signed in recently for accounts without one. Their todos go with the account, and every session, refresh token and the current
+// access token are revoked.
+func (s *AuthService) DeleteAccount(ctx context.Context, principal *Principal, password string) error {
+	user, err := s.userRepo.GetByID(ctx, principal.User.ID)
+	if err != nil {
+		return fmt.Errorf("failed to delete account: %w", err)
+	}
+
+	if err := s.confirmIdentity(ctx, user, principal.SessionID, password); err != nil {
+		return fmt.Errorf("failed to delete account: %w", err)
+	}
+
+	if principal.TokenID != "" {
+		if err := s.revocations.Revoke(ctx, principal.TokenID, principal.TokenExpiresAt); err != nil {
+			return fmt.Errorf("failed to revoke access token: %w", err)
+		}
+	}
+
+	// Deleting the user removes these too, but revoking first cuts off clients even if deletion fails
+	if _, err := s.revokeSessionsExcept(ctx, user.ID, ""); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.Delete(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to delete account: %w", err)
+	}
+
+	// The address may be registered again, without inheriting failed logins
+	if err := s.loginLimiter.Reset(ctx, user.Email); err != nil {
+		fmt.Printf("AuthService: failed to reset login failures: %v\n", err)
+	}
+
+	s.publishStatus(ctx, StatusEvent{
+		Reason: StatusAccountDeleted,
+		UserID: user.ID,
+	})
+
+	return nil
+}
+
+// sendVerificationEmail emails the user a link that verifies their current address
+func (s *AuthService) sendVerificationEmail(ctx context.Context, user *User) error {
+	// Only the newest link works
+	if err := s.verificationRepo.InvalidateForUser(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to invalidate verification tokens: %w", err)
+	}
+
+	token, hash, err := generateOpaqueToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate verification token: %w", err)
+	}
+
+	if err := s.verificationRepo.Create(ctx, &EmailVerificationToken{
+		UserID:    user.ID,
+		Email:     user.Email,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(EmailVerificationTokenExpiry),
+	}); err != nil {
+		return fmt.Errorf("failed to store verification token: %w", err)
+	}
+
+	s.sendMail(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Open this link within %d hours to verify your email address:\n%s/verify-email?token=%s\n\n"+
+				"If you didn't create an account, you can ignore this email.\n",
+			int(EmailVerificationTokenExpiry.Hours()), s.appURL, token,
+		),
+	})
+
+	return nil
+}
+
+// EnrollTOTP generates a new authenticator app secret for the user. Two-factor authentication
+// only takes effect once ConfirmTOTP is called with a code from the app.
+func (s *AuthService) EnrollTOTP(ctx context.Context, userID int) (*TOTPEnrollment, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enroll two-factor: %w", err)
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate two-factor secret: %w", err)
+	}
+
+	if err := s.twoFactorRepo.SaveTOTP(ctx, user.ID, secret); err != nil {
+		return nil, fmt.Errorf("failed to enroll two-factor: %w", err)
+	}
+
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    totpURI(s.totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP turns two-factor authentication on once code proves the user's app is set up,
+// and returns recovery codes. They are only stored hashed, so this is the only time they can be shown.
+func (s *AuthService) ConfirmTOTP(ctx context.Context, userID int, code string) ([]string, error) {
+	credential, err := s.twoFactorRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm two-factor: %w", err)
+	}
+
+	if credential.IsConfirmed() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	step, ok := validateTOTP(credential.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrTwoFactorCodeInvalid
+	}
+
+	if err := s.twoFactorRepo.ConfirmTOTP(ctx, userID, step); err != nil {
+		return nil, fmt.Errorf("failed to confirm two-factor: %w", err)
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = generateRecoveryCode(); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+		}
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+
+	return codes, nil
+}
+
+// VerifyTwoFactor completes a login by exchanging the challenge from Login and a code from
+// the user's authenticator app, or one of their recovery codes, for tokens
+func (s *AuthService) VerifyTwoFactor(ctx context.Context, challenge, code string) (*AuthResult, error) {
+	record, err := s.twoFactorRepo.GetChallenge(ctx, hashToken(challenge))
+	if err != nil {
+		return nil, fmt.Errorf("two-factor verification failed: %w", err)
+	}
+
+	if time.Now().After(record.ExpiresAt) || record.Attempts >= maxTwoFactorAttempts {
+		if err := s.twoFactorRepo.DeleteChallenge(ctx, record.ID); err != nil && !errors.Is(err, ErrChallengeInvalid) {
+			fmt.Printf("AuthService: failed to delete two-factor challenge: %v\n", err)
+		}
+		return nil, fmt.Errorf("two-factor verification failed: %w", ErrChallengeInvalid)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, record.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("two-factor verification failed: %w", err)
+	}
+
+	if user.IsDisabled() {
+		return nil, fmt.Errorf("two-factor verification failed: %w", ErrAccountDisabled)
+	}
+
+	// Wrong codes count towards the same lockout as wrong passwords, whichever challenge they are sent with
+	ipAddress := ClientInfoFromContext(ctx).IPAddress
+	if err := s.loginLimiter.Reserve(ctx, user.Email, ipAddress); err != nil {
+		return nil, err
+	}
+
+	if err := s.verifySecondFactor(ctx, user.ID, code); err != nil {
+		if errors.Is(err, ErrTwoFactorCodeInvalid) {
+			if err := s.twoFactorRepo.RecordChallengeFailure(ctx, record.ID); err != nil {
+				fmt.Printf("AuthService: failed to record two-factor failure: %v\n", err)
+			}
+			if err := s.loginLimiter.RecordFailure(ctx, user.Email, ipAddress); err != nil {
+				fmt.Printf("AuthService: failed to record login failure: %v\n", err)
+			}
+		} else if err := s.loginLimiter.Release(ctx, user.Email, ipAddress); err != nil {
+			fmt.Printf("AuthService: failed to release login attempt: %v\n", err)
+		}
+		return nil, fmt.Errorf("two-factor verification failed: %w", err)
+	}
+
+	// Deleting is atomic, so a challenge completes at most once
+	if err := s.twoFactorRepo.DeleteChallenge(ctx, record.ID); err != nil {
+		return nil, fmt.Errorf("two-factor verification failed: %w", err)
+	}
+
+	// Both factors checked out, so the failed logins can be cleared
+	if err := s.loginLimiter.RecordSuccess(ctx, user.Email, ipAddress); err != nil {
+		fmt.Printf("AuthService: failed to reset login failures: %v\n", err)
+	}
+
+	return s.startSession(ctx, user)
+}
+
+// DisableTwoFactor turns two-factor authentication off after re-checking the user's password,
+// or that currentSessionID signed in recently for accounts without one
+func (s *AuthService) DisableTwoFactor(ctx context.Context, userID int, currentSessionID, password string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to disable two-factor: %w", err)
+	}
+
+	if err := s.confirmIdentity(ctx, user, currentSessionID, password); err != nil {
+		return fmt.Errorf("failed to disable two-factor: %w", err)
+	}
+
+	enabled, err := s.twoFactorEnabled(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return ErrTwoFactorNotEnrolled
+	}
+
+	if err := s.twoFactorRepo.DeleteTOTP(ctx, userID); err != nil {
+		return fmt.Errorf("failed to disable two-factor: %w", err)
+	}
+
+	return nil
+}
+
+// twoFactorEnabled reports whether the user has a confirmed authenticator app
+func (s *AuthService) twoFactorEnabled(ctx context.Context, userID int) (bool, error) {
+	credential, err := s.twoFactorRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrTwoFactorNotEnrolled) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to load two-factor settings: %w", err)
+	}
+
+	return credential.IsConfirmed(), nil
+}
+
+// startTwoFactorChallenge stores a challenge for the second login step and returns it
+// as a *TwoFactorRequiredError, or another error if it could not be created
+func (s *AuthService) startTwoFactorChallenge(ctx context.Context, userID int) error {
+	token, hash, err := generateOpaqueToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate two-factor challenge: %w", err)
+	}
+
+	challenge := &TwoFactorChallenge{
+		UserID:    userID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(TwoFactorChallengeExpiry),
+	}
+	if err := s.twoFactorRepo.CreateChallenge(ctx, challenge); err != nil {
+		return fmt.Errorf("failed to store two-factor challenge: %w", err)
+	}
+
+	return &TwoFactorRequiredError{
+		Challenge: token,
+		ExpiresAt: challenge.ExpiresAt,
+	}
+}
+
+// verifySecondFactor accepts a current authenticator code that hasn't been used yet, or an unused recovery code
+func (s *AuthService) verifySecondFactor(ctx context.Context, userID int, code string) error {
+	credential, err := s.twoFactorRepo.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrTwoFactorNotEnrolled) {
+			// Two-factor was turned off after the challenge was issued
+			return ErrChallengeInvalid
+		}
+		return err
+	}
+
+	if step, ok := validateTOTP(credential.Secret, code, time.Now()); ok {
+		return s.twoFactorRepo.UseTOTPStep(ctx, userID, step)
+	}
+
+	return s.twoFactorRepo.UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+}
+
+// checkEmailVerified rejects unverified users when the policy keeps them from signing in
+func (s *AuthService) checkEmailVerified(user *User) error {
+	if s.unverifiedPolicy == UnverifiedBlock && !user.IsEmailVerified() {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// revokeAllSessions signs out every session of the user and tells open clients why
+func (s *AuthService) revokeAllSessions(ctx context.Context, userID int, reason StatusReason) error {
+	if _, err := s.revokeSessionsExcept(ctx, userID, ""); err != nil {
+		return err
+	}
+
+	s.publishStatus(ctx, StatusEvent{
+		Reason: reason,
+		UserID: userID,
+	})
+
+	return nil
+}
+
+// revokeSessionsExcept signs out every session of the user except keepSessionID, along with
+// their refresh tokens, and returns the IDs of the revoked sessions. Clients are not notified.
+func (s *AuthService) revokeSessionsExcept(ctx context.Context, userID int, keepSessionID string) ([]string, error) {
+	revoked, err := s.sessionRepo.RevokeAllExcept(ctx, userID, keepSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	for _, sessionID := range revoked {
+		if err := s.refreshRepo.RevokeBySession(ctx, sessionID); err != nil {
+			return nil, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+		}
+	}
+
+	return revoked, nil
+}
+
+// confirmIdentity checks that a sensitive change comes from the user themselves. Accounts with a
+// password have to enter it again. Accounts that sign in through a provider or emailed links have
+// no password to check, so the session making the change has to have signed in recently instead.
+func (s *AuthService) confirmIdentity(ctx context.Context, user *User, sessionID, password string) error {
+	if user.HasPassword() {
+		return s.confirmPassword(ctx, user, password)
+	}
+
+	// Tokens not bound to a session, like personal access tokens, never count as a recent sign-in
+	if sessionID == "" {
+		return ErrReauthRequired
+	}
+
+	session, err := s.activeSession(ctx, sessionID, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if time.Since(session.CreatedAt) >= RecentSignInWindow {
+		return ErrReauthRequired
+	}
+
+	return nil
+}
+
+// confirmPassword re-checks the password of a signed in user before a sensitive change.
+// Wrong guesses count towards the same lockout as failed logins, so a stolen session
+// can't be used to brute-force the password.
+func (s *AuthService) confirmPassword(ctx context.Context, user *User, password string) error {
+	if !user.HasPassword() {
+		return ErrPasswordNotSet
+	}
+
+	ipAddress := ClientInfoFromContext(ctx).IPAddress
+	if err := s.loginLimiter.Reserve(ctx, user.Email, ipAddress); err != nil {
+		return err
+	}
+
+	if err := s.passwordService.VerifyPassword(user.PasswordHash, password); err != nil {
+		if err := s.loginLimiter.RecordFailure(ctx, user.Email, ipAddress); err != nil {
+			fmt.Printf("AuthService: failed to record password failure: %v\n", err)
+		}
+		return ErrInvalidCredentials
+	}
+
+	// Unlike a login, a confirmed password leaves earlier failures in place
+	if err := s.loginLimiter.Release(ctx, user.Email, ipAddress); err != nil {
+		fmt.Printf("AuthService: failed to release password attempt: %v\n", err)
+	}
+
+	return nil
+}
+
+// sendMail delivers msg in the background so responses take as long whether or not an email was sent.
+// Delivery failures are logged, never returned.
+func (s *AuthService) sendMail(ctx context.Context, msg mail.Message) {
+	if s.mailer == nil {
+		fmt.Printf("AuthService: no mailer configured, dropping %q for %s\n", msg.Subject, msg.To)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), mailSendTimeout)
+	go func() {
+		defer cancel()
+		if err := s.mailer.Send(ctx, msg); err != nil {
+			fmt.Printf("AuthService: failed to send %q to %s: %v\n", msg.Subject, msg.To, err)
+		}
+	}()
+}
+
+// startSession signs the user in once every check has passed, recording the login
+// before starting a new session
+func (s *AuthService) startSession(ctx context.Context, user *User) (*AuthResult, error) {
+	if err := s.userRepo.UpdateLastLogin(ctx, user.ID); err != nil {
+		fmt.Printf("AuthService: failed to record login for user %d: %v\n", user.ID, err)
+	}
+
+	return s.newSession(ctx, user)
+}
+
+// newSession records a new session for the user and issues tokens bound to it,
+// starting a new refresh token family
+func (s *AuthService) newSession(ctx context.Context, user *User) (*AuthResult, error) {
+	session, err := s.sessionRepo.Create(ctx, user.ID, ClientInfoFromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	familyID, err := newRandomID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	record, refreshToken, err := newRefreshTokenRecord(user.ID, session.ID, familyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	if err := s.refreshRepo.Create(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return s.issueAccessToken(user, session, refreshToken)
+}
+
+// issueAccessToken generates an access token bound to session and pairs it with refreshToken
+func (s *AuthService) issueAccessToken(user *User, session *Session, refreshToken string) (*AuthResult, error) {
+	token, err := s.jwtService.GenerateSessionToken(user.ID, user.Email, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return &AuthResult{
+		User:         user,
+		Session:      session,
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    time.Now().Add(s.jwtService.expiryHours),
+	}, nil
+}
+
+// newRefreshTokenRecord generates a refresh token and the record to store for it
+func newRefreshTokenRecord(userID int, sessionID, familyID string) (*RefreshToken, string, error) {
+	token, hash, err := generateOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &RefreshToken{
+		TokenHash: hash,
+		FamilyID:  familyID,
+		UserID:    userID,
+		SessionID: sessionID,
+		ExpiresAt: time.Now().Add(RefreshTokenExpiry),
+	}, token, nil
+}
+
+// revokeReusedFamily shuts down a refresh token family after one of its rotated tokens was replayed
+func (s *AuthService) revokeReusedFamily(ctx context.Context, record *RefreshToken) {
+	fmt.Printf("AuthService: refresh token reuse detected for user %d, revoking family %s\n", record.UserID, record.FamilyID)
+
+	if err := s.refreshRepo.RevokeFamily(ctx, record.FamilyID); err != nil {
+		fmt.Printf("AuthService: failed to revoke refresh token family: %v\n", err)
+	}
+
+	if err := s.sessionRepo.Revoke(ctx, record.SessionID, record.UserID); err != nil {
+		// Already revoked sessions need no further action
+		if errors.Is(err, ErrSessionNotFound) {
+			return
+		}
+		fmt.Printf("AuthService: failed to revoke session: %v\n", err)
+	}
+
+	s.publishStatus(ctx, StatusEvent{
+		Reason:    StatusSessionRevoked,
+		UserID:    record.UserID,
+		SessionID: record.SessionID,
+	})
+}
+
+// activeSession loads the user's session, failing if it was revoked or went idle, and records activity on it
+func (s *AuthService) activeSession(ctx context.Context, sessionID string, userID int) (*Session, error) {
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			return nil, ErrSessionRevoked
+		}
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+
+	if session.UserID != userID || !session.IsActive() {
+		return nil, ErrSessionRevoked
+	}
+
+	if err := s.sessionRepo.Touch(ctx, sessionID); err != nil {
+		// Activity tracking is best effort
+		fmt.Printf("AuthService: failed to touch session: %v\n", err)
+	}
+
+	return session, nil
+}
+
+// DisableUser disables an account and signs out every open client
+func (s *AuthService) DisableUser(ctx context.Context, userID int) error {
+	if err := s.userRepo.SetDisabled(ctx, userID, true); err != nil {
+		return fmt.Errorf("failed to disable user: %w", err)
+	}
+
+	s.publishStatus(ctx, StatusEvent{
+		Reason: StatusAccountDisabled,
+		UserID: userID,
+	})
+
+	return nil
+}
+
+// EnableUser re-enables a disabled account
+func (s *AuthService) EnableUser(ctx context.Context, userID int) error {
+	if err := s.userRepo.SetDisabled(ctx, userID, false); err != nil {
+		return fmt.Errorf("failed to enable user: %w", err)
+	}
+
+	return nil
+}
+
+// SubscribeAuthStatus streams events that invalidate the credentials of the user's session until ctx is done.
+// Revocations of the user's other sessions are filtered out.
+func (s *AuthService) SubscribeAuthStatus(ctx context.Context, userID int, sessionID string) (<-chan StatusEvent, error) {
+	if userID <= 0 {
+		return nil, ErrUserNotFound
+	}
+
+	events := s.statusEvents.Subscribe(ctx, userID)
+
+	ch := make(chan StatusEvent, 1)
+	go func() {
+		defer close(ch)
+		for event := range events {
+			if event.SessionID != "" && event.SessionID != sessionID {
+				continue
+			}
+			select {
+			case ch <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// RelayStatusEvent publishes an event received through Postgres NOTIFY to local subscribers
+func (s *AuthService) RelayStatusEvent(payload string) {
+	var event StatusEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		fmt.Printf("AuthService: failed to decode relayed status event: %v\n", err)
+		return
+	}
+
+	s.statusEvents.Publish(event.UserID, event)
+}
+
+// publishStatus notifies the user's open clients, either directly or through Postgres NOTIFY
+func (s *AuthService) publishStatus(ctx context.Context, event StatusEvent) {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	if s.notifier == nil {
+		s.statusEvents.Publish(event.UserID, event)
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		fmt.Printf("AuthService: failed to encode status event: %v\n", err)
+		return
+	}
+
+	// The state change is already committed, so a failed broadcast is only logged
+	if err := s.notifier.Notify(context.WithoutCancel(ctx), StatusChannel, string(payload)); err != nil {
+		fmt.Printf("AuthService: failed to broadcast status event: %v\n", err)
+	}
+}
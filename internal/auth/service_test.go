@@ -6,6 +6,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/jayk0001/my-go-next-todo/internal/pubsub"
 )
 
 // MockUserRepository embeds UserRepository and overrides methods for testing
@@ -38,10 +40,13 @@ func (m *MockUserRepository) Create(ctx context.Context, input CreateUserInput)
 	}
 
 	// Hash password using real password service for testing
-	passwordService := NewPasswordService()
-	hashedPassword, err := passwordService.HashPassword(input.Password)
-	if err != nil {
-		return nil, err
+	var hashedPassword string
+	if input.Password != "" {
+		var err error
+		hashedPassword, err = NewPasswordService().HashPassword(input.Password)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	user := &User{
@@ -50,6 +55,11 @@ func (m *MockUserRepository) Create(ctx context.Context, input CreateUserInput)
 		PasswordHash: hashedPassword,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
+		Role:         RoleUser,
+		TimeZone:     "UTC",
+	}
+	if input.EmailVerified {
+		user.EmailVerifiedAt = &user.CreatedAt
 	}
 
 	m.users[user.ID] = user
@@ -110,6 +120,7 @@ func (m *MockUserRepository) UpdateLastLogin(ctx context.Context, userID int) er
 
 	now := time.Now()
 	user.LastLoginAt = &now
+	user.LoginCount++
 	user.UpdatedAt = now
 
 	return nil
@@ -134,12 +145,118 @@ func (m *MockUserRepository) Authenticate(ctx context.Context, email, password s
 		return nil, ErrInvalidCredentials
 	}
 
-	// Update last login
-	_ = m.UpdateLastLogin(ctx, user.ID)
-
 	return user, nil
 }
 
+func (m *MockUserRepository) SetDisabled(ctx context.Context, userID int, disabled bool) error {
+	if m.shouldFailOnDB {
+		return errors.New("database error")
+	}
+
+	user, exists := m.users[userID]
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	if disabled {
+		now := time.Now()
+		user.DisabledAt = &now
+	} else {
+		user.DisabledAt = nil
+	}
+
+	return nil
+}
+
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, userID int, passwordHash string) error {
+	if m.shouldFailOnDB {
+		return errors.New("database error")
+	}
+
+	user, exists := m.users[userID]
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	user.PasswordHash = passwordHash
+	user.UpdatedAt = time.Now()
+
+	return nil
+}
+
+func (m *MockUserRepository) MarkEmailVerified(ctx context.Context, userID int, email string) error {
+	if m.shouldFailOnDB {
+		return errors.New("database error")
+	}
+
+	user, exists := m.users[userID]
+	if !exists || user.Email != email {
+		return ErrVerificationTokenInvalid
+	}
+
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	return nil
+}
+
+func (m *MockUserRepository) UpdateEmail(ctx context.Context, userID int, email string) error {
+	if m.shouldFailOnDB {
+		return errors.New("database error")
+	}
+
+	user, exists := m.users[userID]
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	if m.usersByEmail[email] != nil {
+		return ErrEmailExits
+	}
+
+	delete(m.usersByEmail, user.Email)
+	user.Email = email
+	user.EmailVerifiedAt = nil
+	user.UpdatedAt = time.Now()
+	m.usersByEmail[email] = user
+
+	return nil
+}
+
+func (m *MockUserRepository) UpdateTimeZone(ctx context.Context, userID int, timeZone string) error {
+	if m.shouldFailOnDB {
+		return errors.New("database error")
+	}
+
+	user, exists := m.users[userID]
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	user.TimeZone = timeZone
+	user.UpdatedAt = time.Now()
+
+	return nil
+}
+
+func (m *MockUserRepository) Delete(ctx context.Context, userID int) error {
+	if m.shouldFailOnDB {
+		return errors.New("database error")
+	}
+
+	user, exists := m.users[userID]
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	delete(m.users, userID)
+	delete(m.usersByEmail, user.Email)
+
+	return nil
+}
+
 // Mock control methods
 func (m *MockUserRepository) SetShouldFailOnDB(shouldFail bool) {
 	m.shouldFailOnDB = shouldFail
@@ -197,6 +314,20 @@ func createTestAuthServiceWithMock() (*AuthService, *MockUserRepository) {
 		jwtService:       NewJWTService(serviceTestData.jwtSecret, serviceTestData.tokenExpiry),
 		passwordService:  NewPasswordService(),
 		validatorService: NewValidatorService(),
+		sessionRepo:      NewMockSessionRepository(),
+		refreshRepo:      NewMockRefreshTokenRepository(),
+		revocations:      NewMemoryRevocationStore(),
+		statusEvents:     pubsub.NewHub[StatusEvent](),
+		resetRepo:        NewMockPasswordResetRepository(),
+		verificationRepo: NewMockEmailVerificationRepository(),
+		twoFactorRepo:    NewMockTwoFactorRepository(),
+		loginLimiter:     NewLoginLimiter(NewMemoryLoginAttemptStore(), DefaultLockoutPolicy()),
+		dataExportRepo:   NewMockDataExportTokenRepository(),
+		accessTokenRepo:  NewMockPersonalAccessTokenRepository(),
+		identityRepo:     NewMockIdentityRepository(),
+		oidcStateRepo:    NewMockOIDCLoginStateRepository(),
+		magicLinkRepo:    NewMockMagicLinkRepository(),
+		totpIssuer:       DefaultTOTPIssuer,
 	}
 
 	return authService, mockRepo
@@ -296,6 +427,34 @@ func TestRegisterEmailExists(t *testing.T) {
 	}
 }
 
+// TestRegisterRunsUserCreatedHook tests that new accounts run the hook and a failing hook doesn't block them
+func TestRegisterRunsUserCreatedHook(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx := context.Background()
+
+	var created []int
+	authService.SetUserCreatedHook(func(ctx context.Context, user *User) error {
+		created = append(created, user.ID)
+		return errors.New("hook failed")
+	})
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed despite the hook failing: %v", err)
+	}
+	if len(created) != 1 || created[0] != result.User.ID {
+		t.Errorf("Expected the hook to run once for user %d, got %v", result.User.ID, created)
+	}
+
+	// Failed registrations don't run it
+	if _, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword); err == nil {
+		t.Fatal("Second registration should fail")
+	}
+	if len(created) != 1 {
+		t.Errorf("Expected no hook for a failed registration, got %v", created)
+	}
+}
+
 // TestLoginSuccess tests successful user login
 func TestLoginSuccess(t *testing.T) {
 	authService, _ := createTestAuthServiceWithMock()
@@ -352,3 +511,400 @@ func TestGetUserFromTokenSuccess(t *testing.T) {
 		t.Errorf("Expected user ID %d, got %d", result.User.ID, user.ID)
 	}
 }
+
+// fakeStatusNotifier records status events and loops them back like Postgres NOTIFY would
+type fakeStatusNotifier struct {
+	relay    func(payload string)
+	payloads []string
+}
+
+func (n *fakeStatusNotifier) Notify(ctx context.Context, channel, payload string) error {
+	if channel != StatusChannel {
+		return errors.New("unexpected channel " + channel)
+	}
+	n.payloads = append(n.payloads, payload)
+	n.relay(payload)
+	return nil
+}
+
+// nextStatusEvent waits for the next status event on ch
+func nextStatusEvent(t *testing.T, ch <-chan StatusEvent) StatusEvent {
+	t.Helper()
+
+	select {
+	case event := <-ch:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for status event")
+		return StatusEvent{}
+	}
+}
+
+// TestDisableUserPublishesStatus tests that disabling an account notifies open clients
+func TestDisableUserPublishesStatus(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	events, err := authService.SubscribeAuthStatus(ctx, result.User.ID, "")
+	if err != nil {
+		t.Fatalf("SubscribeAuthStatus should succeed: %v", err)
+	}
+
+	if err := authService.DisableUser(ctx, result.User.ID); err != nil {
+		t.Fatalf("DisableUser should succeed: %v", err)
+	}
+
+	event := nextStatusEvent(t, events)
+	if event.Reason != StatusAccountDisabled {
+		t.Errorf("Expected reason %s, got %s", StatusAccountDisabled, event.Reason)
+	}
+	if event.UserID != result.User.ID {
+		t.Errorf("Expected user ID %d, got %d", result.User.ID, event.UserID)
+	}
+	if event.OccurredAt.IsZero() {
+		t.Error("OccurredAt should be set")
+	}
+}
+
+// TestDisabledUserRejected tests that a disabled account can no longer authenticate
+func TestDisabledUserRejected(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx := context.Background()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	if err := authService.DisableUser(ctx, result.User.ID); err != nil {
+		t.Fatalf("DisableUser should succeed: %v", err)
+	}
+
+	if _, err := authService.GetUserFromToken(ctx, result.Token); !errors.Is(err, ErrAccountDisabled) {
+		t.Errorf("Expected ErrAccountDisabled from GetUserFromToken, got: %v", err)
+	}
+
+	if _, err := authService.Login(ctx, serviceTestData.testEmail, serviceTestData.testPassword); !errors.Is(err, ErrAccountDisabled) {
+		t.Errorf("Expected ErrAccountDisabled from Login, got: %v", err)
+	}
+	if info, _ := authService.GetAuthInfo(ctx, result.User.ID); info == nil || info.LoginCount != 0 || info.LastLoginAt != nil {
+		t.Errorf("Rejected logins should not be recorded, got %+v", info)
+	}
+
+	if _, err := authService.RefreshToken(ctx, result.RefreshToken); !errors.Is(err, ErrAccountDisabled) {
+		t.Errorf("Expected ErrAccountDisabled from RefreshToken, got: %v", err)
+	}
+
+	// Re-enabling restores access
+	if err := authService.EnableUser(ctx, result.User.ID); err != nil {
+		t.Fatalf("EnableUser should succeed: %v", err)
+	}
+
+	if _, err := authService.GetUserFromToken(ctx, result.Token); err != nil {
+		t.Errorf("GetUserFromToken should succeed after EnableUser: %v", err)
+	}
+}
+
+// TestStatusEventsIsolatedPerUser tests that users only receive their own status events
+func TestStatusEventsIsolatedPerUser(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	alice, err := authService.Register(ctx, "alice@example.com", serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+	bob, err := authService.Register(ctx, "bob@example.com", serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	bobEvents, _ := authService.SubscribeAuthStatus(ctx, bob.User.ID, "")
+
+	if err := authService.DisableUser(ctx, alice.User.ID); err != nil {
+		t.Fatalf("DisableUser should succeed: %v", err)
+	}
+
+	select {
+	case event := <-bobEvents:
+		t.Errorf("Bob should not receive Alice's event: %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// TestStatusEventsRelayedThroughNotifier tests that events go through the notifier when one is configured
+func TestStatusEventsRelayedThroughNotifier(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	notifier := &fakeStatusNotifier{relay: authService.RelayStatusEvent}
+	authService.notifier = notifier
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	events, _ := authService.SubscribeAuthStatus(ctx, result.User.ID, "")
+
+	if err := authService.DisableUser(ctx, result.User.ID); err != nil {
+		t.Fatalf("DisableUser should succeed: %v", err)
+	}
+
+	if len(notifier.payloads) != 1 {
+		t.Fatalf("Expected 1 notify payload, got %d", len(notifier.payloads))
+	}
+
+	event := nextStatusEvent(t, events)
+	if event.Reason != StatusAccountDisabled || event.UserID != result.User.ID {
+		t.Errorf("Unexpected relayed event: %+v", event)
+	}
+}
+
+// TestSubscribeAuthStatusInvalidUser tests subscribing without a valid user
+func TestSubscribeAuthStatusInvalidUser(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+
+	if _, err := authService.SubscribeAuthStatus(context.Background(), 0, ""); err == nil {
+		t.Error("Expected error for invalid user ID")
+	}
+}
+
+// TestChangePassword tests that changing the password checks the current one and signs out other sessions
+func TestChangePassword(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	current, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+	other, err := authService.Login(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Login should succeed: %v", err)
+	}
+	userID, sessionID := current.User.ID, current.Session.ID
+
+	_, accessToken, err := authService.CreatePersonalAccessToken(ctx, userID, CreatePersonalAccessTokenInput{Name: "script"})
+	if err != nil {
+		t.Fatalf("CreatePersonalAccessToken should succeed: %v", err)
+	}
+
+	otherEvents, _ := authService.SubscribeAuthStatus(ctx, userID, other.Session.ID)
+
+	if err := authService.ChangePassword(ctx, userID, sessionID, "wrong-password", "new-password-456"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials for a wrong current password, got: %v", err)
+	}
+
+	var ve ValidationErrors
+	if err := authService.ChangePassword(ctx, userID, sessionID, serviceTestData.testPassword, "short"); !errors.As(err, &ve) {
+		t.Errorf("Expected ValidationErrors for a short password, got: %v", err)
+	}
+
+	if err := authService.ChangePassword(ctx, userID, sessionID, serviceTestData.testPassword, "new-password-456"); err != nil {
+		t.Fatalf("ChangePassword should succeed: %v", err)
+	}
+
+	event := nextStatusEvent(t, otherEvents)
+	if event.Reason != StatusPasswordChanged || event.SessionID != other.Session.ID {
+		t.Errorf("Expected PASSWORD_CHANGED for the other session, got %+v", event)
+	}
+
+	if _, err := authService.GetUserFromToken(ctx, current.Token); err != nil {
+		t.Errorf("Current session should still be valid: %v", err)
+	}
+	if _, err := authService.GetUserFromToken(ctx, other.Token); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("Expected ErrSessionRevoked for other session, got: %v", err)
+	}
+	if _, err := authService.AuthenticateToken(ctx, accessToken); !errors.Is(err, ErrPersonalAccessTokenInvalid) {
+		t.Errorf("Expected ErrPersonalAccessTokenInvalid for the personal access token, got: %v", err)
+	}
+
+	if _, err := authService.Login(ctx, serviceTestData.testEmail, serviceTestData.testPassword); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Old password should no longer work, got: %v", err)
+	}
+	if _, err := authService.Login(ctx, serviceTestData.testEmail, "new-password-456"); err != nil {
+		t.Errorf("New password should work: %v", err)
+	}
+}
+
+// TestChangePasswordCountsFailures tests that wrong current passwords count towards the login lockout
+func TestChangePasswordCountsFailures(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx := context.Background()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	for i := 0; i < DefaultLockoutPolicy().Account.BackoffAfter; i++ {
+		if err := authService.ChangePassword(ctx, result.User.ID, result.Session.ID, "wrong-password", "new-password-456"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("Expected ErrInvalidCredentials, got: %v", err)
+		}
+	}
+
+	if err := authService.ChangePassword(ctx, result.User.ID, result.Session.ID, serviceTestData.testPassword, "new-password-456"); !errors.Is(err, ErrAccountLocked) {
+		t.Errorf("Expected ErrAccountLocked after repeated failures, got: %v", err)
+	}
+}
+
+// TestChangeEmail tests that changing the email requires the password and re-verification
+func TestChangeEmail(t *testing.T) {
+	authService, mockRepo, mailer := createTestAuthServiceWithMailer()
+	ctx := context.Background()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+	token := tokenFromLink(t, waitForMail(t, mailer, verifySubject, 1).Body, "/verify-email")
+	if _, err := authService.VerifyEmail(ctx, token); err != nil {
+		t.Fatalf("VerifyEmail should succeed: %v", err)
+	}
+	if _, err := authService.Register(ctx, "taken@example.com", serviceTestData.testPassword); err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+	userID := result.User.ID
+
+	if _, err := authService.ChangeEmail(ctx, userID, "", "new@example.com", "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got: %v", err)
+	}
+
+	var ve ValidationErrors
+	if _, err := authService.ChangeEmail(ctx, userID, "", "not-an-email", serviceTestData.testPassword); !errors.As(err, &ve) || !ve.Has("email", CodeInvalidFormat) {
+		t.Errorf("Expected invalid_format validation error, got: %v", err)
+	}
+
+	if _, err := authService.ChangeEmail(ctx, userID, "", "taken@example.com", serviceTestData.testPassword); !errors.Is(err, ErrEmailExits) {
+		t.Errorf("Expected ErrEmailExits, got: %v", err)
+	}
+
+	user, err := authService.ChangeEmail(ctx, userID, "", "new@example.com", serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("ChangeEmail should succeed: %v", err)
+	}
+	if user.Email != "new@example.com" || user.IsEmailVerified() {
+		t.Errorf("Expected unverified new address, got %s (verified %v)", user.Email, user.IsEmailVerified())
+	}
+
+	notice := waitForMail(t, mailer, "Your email address was changed", 1)
+	if notice.To != serviceTestData.testEmail || !strings.Contains(notice.Body, "new@example.com") {
+		t.Errorf("Old address should be told about the change, got %+v", notice)
+	}
+
+	msg := waitForMail(t, mailer, verifySubject, 3)
+	if msg.To != "new@example.com" {
+		t.Errorf("Expected verification mail to new address, got %s", msg.To)
+	}
+	if _, err := authService.VerifyEmail(ctx, tokenFromLink(t, msg.Body, "/verify-email")); err != nil {
+		t.Fatalf("New address should verify: %v", err)
+	}
+	if !mockRepo.users[userID].IsEmailVerified() {
+		t.Error("New address should be verified")
+	}
+}
+
+// TestUpdateTimeZone tests that only real IANA time zones are stored
+func TestUpdateTimeZone(t *testing.T) {
+	authService, mockRepo := createTestAuthServiceWithMock()
+	ctx := context.Background()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+	userID := result.User.ID
+
+	if result.User.TimeZone != "UTC" {
+		t.Errorf("Expected new users in UTC, got %q", result.User.TimeZone)
+	}
+
+	for _, timeZone := range []string{"", "Local", "Mars/Olympus_Mons"} {
+		var ve ValidationErrors
+		if _, err := authService.UpdateTimeZone(ctx, userID, timeZone); !errors.As(err, &ve) {
+			t.Errorf("Expected validation error for %q, got: %v", timeZone, err)
+		}
+	}
+
+	user, err := authService.UpdateTimeZone(ctx, userID, "Asia/Seoul")
+	if err != nil {
+		t.Fatalf("UpdateTimeZone should succeed: %v", err)
+	}
+	if user.TimeZone != "Asia/Seoul" || mockRepo.users[userID].TimeZone != "Asia/Seoul" {
+		t.Errorf("Expected Asia/Seoul to be stored, got %q", user.TimeZone)
+	}
+}
+
+// TestDeleteAccount tests that deleting the account checks the password and revokes every credential
+func TestDeleteAccount(t *testing.T) {
+	authService, mockRepo := createTestAuthServiceWithMock()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	current, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+	other, err := authService.Login(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Login should succeed: %v", err)
+	}
+
+	principal, err := authService.AuthenticateToken(ctx, current.Token)
+	if err != nil {
+		t.Fatalf("AuthenticateToken should succeed: %v", err)
+	}
+
+	otherEvents, _ := authService.SubscribeAuthStatus(ctx, other.User.ID, other.Session.ID)
+
+	if err := authService.DeleteAccount(ctx, principal, "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got: %v", err)
+	}
+	if _, exists := mockRepo.users[current.User.ID]; !exists {
+		t.Fatal("User should not be deleted with a wrong password")
+	}
+
+	if err := authService.DeleteAccount(ctx, principal, serviceTestData.testPassword); err != nil {
+		t.Fatalf("DeleteAccount should succeed: %v", err)
+	}
+
+	event := nextStatusEvent(t, otherEvents)
+	if event.Reason != StatusAccountDeleted {
+		t.Errorf("Expected ACCOUNT_DELETED, got %+v", event)
+	}
+
+	if _, exists := mockRepo.users[current.User.ID]; exists {
+		t.Error("User should be deleted")
+	}
+
+	revoked, err := authService.revocations.IsRevoked(ctx, principal.TokenID)
+	if err != nil || !revoked {
+		t.Errorf("Current access token should be revoked (err %v)", err)
+	}
+
+	for _, token := range []string{current.Token, other.Token} {
+		if _, err := authService.GetUserFromToken(ctx, token); err == nil {
+			t.Error("Access tokens should stop working")
+		}
+	}
+	for _, refreshToken := range []string{current.RefreshToken, other.RefreshToken} {
+		if _, err := authService.RefreshToken(ctx, refreshToken); err == nil {
+			t.Error("Refresh tokens should stop working")
+		}
+	}
+
+	// The address can be used for a new account
+	if _, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword); err != nil {
+		t.Errorf("Email should be free after deletion: %v", err)
+	}
+}
@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SessionIdleTimeout is how long a session stays active without being used (matches the refresh token lifetime)
+const SessionIdleTimeout = 24 * 7 * time.Hour
+
+// RecentSignInWindow is how long after signing in a session may make sensitive changes
+// to an account without a password
+const RecentSignInWindow = 10 * time.Minute
+
+// sessionTouchInterval limits how often last_active_at is written for a busy session
+const sessionTouchInterval = time.Minute
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionRevoked  = errors.New("session has been revoked")
+)
+
+// sessionColumns is the column list scanned by scanSession
+const sessionColumns = `id, user_id, user_agent, device_type, ip_address, created_at, last_active_at, revoked_at`
+
+// Session represents a signed-in device
+type Session struct {
+	ID           string     `db:"id" json:"id"`
+	UserID       int        `db:"user_id" json:"user_id"`
+	UserAgent    string     `db:"user_agent" json:"user_agent"`
+	DeviceType   string     `db:"device_type" json:"device_type"`
+	IPAddress    string     `db:"ip_address" json:"ip_address"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	LastActiveAt time.Time  `db:"last_active_at" json:"last_active_at"`
+	RevokedAt    *time.Time `db:"revoked_at" json:"revoked_at,omitempty"`
+}
+
+// IsActive returns true if the session has not been revoked or gone idle
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Since(s.LastActiveAt) < SessionIdleTimeout
+}
+
+// ClientInfo describes the client making a request
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+type clientInfoKey struct{}
+
+// WithClientInfo attaches the requesting client's details to ctx
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// ClientInfoFromContext returns the requesting client's details, if known
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
+
+// deviceTypeFromUserAgent roughly classifies a User-Agent as mobile, tablet or desktop
+func deviceTypeFromUserAgent(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	switch {
+	case ua == "":
+		return ""
+	case strings.Contains(ua, "ipad") || strings.Contains(ua, "tablet"):
+		return "tablet"
+	case strings.Contains(ua, "mobi") || strings.Contains(ua, "iphone") || strings.Contains(ua, "android"):
+		return "mobile"
+	case strings.Contains(ua, "windows") || strings.Contains(ua, "macintosh") || strings.Contains(ua, "linux") || strings.Contains(ua, "cros"):
+		return "desktop"
+	default:
+		return "other"
+	}
+}
+
+// SessionRepositoryInterface defines session persistence used by AuthService
+type SessionRepositoryInterface interface {
+	Create(ctx context.Context, userID int, client ClientInfo) (*Session, error)
+	GetByID(ctx context.Context, id string) (*Session, error)
+	ListActiveByUser(ctx context.Context, userID int) ([]*Session, error)
+	Touch(ctx context.Context, id string) error
+	Revoke(ctx context.Context, id string, userID int) error
+	RevokeAllExcept(ctx context.Context, userID int, keepID string) ([]string, error)
+}
+
+// SessionRepository handles session database operations
+type SessionRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewSessionRepository creates a new session repository
+func NewSessionRepository(db *pgxpool.Pool) *SessionRepository {
+	return &SessionRepository{
+		db: db,
+	}
+}
+
+// Create starts a new session for the user
+func (r *SessionRepository) Create(ctx context.Context, userID int, client ClientInfo) (*Session, error) {
+	id, err := newRandomID()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO sessions (id, user_id, user_agent, device_type, ip_address, created_at, last_active_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		RETURNING ` + sessionColumns
+
+	return scanSession(r.db.QueryRow(ctx, query, id, userID, client.UserAgent, deviceTypeFromUserAgent(client.UserAgent), client.IPAddress))
+}
+
+// GetByID retrieves a session by ID
+func (r *SessionRepository) GetByID(ctx context.Context, id string) (*Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id = $1`
+
+	return scanSession(r.db.QueryRow(ctx, query, id))
+}
+
+// ListActiveByUser returns the user's sessions that are neither revoked nor idle, most recent first
+func (r *SessionRepository) ListActiveByUser(ctx context.Context, userID int) ([]*Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND last_active_at > $2
+		ORDER BY last_active_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, time.Now().Add(-SessionIdleTimeout))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// ListByUser returns all of the user's sessions, including revoked and idle ones, oldest first
+func (r *SessionRepository) ListByUser(ctx context.Context, userID int) ([]*Session, error) {
+	query := `
+		SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// Touch records activity on a session, at most once per sessionTouchInterval
+func (r *SessionRepository) Touch(ctx context.Context, id string) error {
+	query := `
+		UPDATE sessions
+		SET last_active_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL AND last_active_at < $2
+	`
+
+	_, err := r.db.Exec(ctx, query, id, time.Now().Add(-sessionTouchInterval))
+
+	return err
+}
+
+// Revoke ends one of the user's sessions
+func (r *SessionRepository) Revoke(ctx context.Context, id string, userID int) error {
+	query := `
+		UPDATE sessions
+		SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// RevokeAllExcept ends every session of the user except keepID and returns the revoked IDs
+func (r *SessionRepository) RevokeAllExcept(ctx context.Context, userID int, keepID string) ([]string, error) {
+	query := `
+		UPDATE sessions
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+		RETURNING id
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, keepID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// scanSession scans a row selected with sessionColumns
+func scanSession(row pgx.Row) (*Session, error) {
+	var session Session
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.UserAgent,
+		&session.DeviceType,
+		&session.IPAddress,
+		&session.CreatedAt,
+		&session.LastActiveAt,
+		&session.RevokedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+
+	return &session, nil
+}
@@ -0,0 +1,326 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+// MockSessionRepository implements SessionRepositoryInterface in memory for testing
+type MockSessionRepository struct {
+	sessions   map[string]*Session
+	nextID     int
+	shouldFail bool
+}
+
+// NewMockSessionRepository creates a new mock session repository
+func NewMockSessionRepository() *MockSessionRepository {
+	return &MockSessionRepository{
+		sessions: make(map[string]*Session),
+		nextID:   1,
+	}
+}
+
+func (m *MockSessionRepository) Create(ctx context.Context, userID int, client ClientInfo) (*Session, error) {
+	if m.shouldFail {
+		return nil, errors.New("database error")
+	}
+
+	now := time.Now()
+	session := &Session{
+		ID:           fmt.Sprintf("session-%d", m.nextID),
+		UserID:       userID,
+		UserAgent:    client.UserAgent,
+		DeviceType:   deviceTypeFromUserAgent(client.UserAgent),
+		IPAddress:    client.IPAddress,
+		CreatedAt:    now,
+		LastActiveAt: now,
+	}
+	m.nextID++
+	m.sessions[session.ID] = session
+
+	copied := *session
+	return &copied, nil
+}
+
+func (m *MockSessionRepository) GetByID(ctx context.Context, id string) (*Session, error) {
+	session, exists := m.sessions[id]
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+
+	copied := *session
+	return &copied, nil
+}
+
+func (m *MockSessionRepository) ListActiveByUser(ctx context.Context, userID int) ([]*Session, error) {
+	var sessions []*Session
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.IsActive() {
+			copied := *session
+			sessions = append(sessions, &copied)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].ID < sessions[j].ID })
+	return sessions, nil
+}
+
+func (m *MockSessionRepository) Touch(ctx context.Context, id string) error {
+	if session, exists := m.sessions[id]; exists && session.RevokedAt == nil {
+		session.LastActiveAt = time.Now()
+	}
+	return nil
+}
+
+func (m *MockSessionRepository) Revoke(ctx context.Context, id string, userID int) error {
+	session, exists := m.sessions[id]
+	if !exists || session.UserID != userID || session.RevokedAt != nil {
+		return ErrSessionNotFound
+	}
+
+	now := time.Now()
+	session.RevokedAt = &now
+	return nil
+}
+
+func (m *MockSessionRepository) RevokeAllExcept(ctx context.Context, userID int, keepID string) ([]string, error) {
+	now := time.Now()
+	var revoked []string
+	for id, session := range m.sessions {
+		if session.UserID == userID && id != keepID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+			revoked = append(revoked, id)
+		}
+	}
+
+	sort.Strings(revoked)
+	return revoked, nil
+}
+
+// sessionMock returns the mock session repository behind a test auth service
+func sessionMock(authService *AuthService) *MockSessionRepository {
+	return authService.sessionRepo.(*MockSessionRepository)
+}
+
+// TestDeviceTypeFromUserAgent tests User-Agent classification
+func TestDeviceTypeFromUserAgent(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		expected  string
+	}{
+		{"", ""},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148", "mobile"},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) Mobile Safari/537.36", "mobile"},
+		{"Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X)", "tablet"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0", "desktop"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) Safari/605.1.15", "desktop"},
+		{"curl/8.4.0", "other"},
+	}
+
+	for _, tt := range tests {
+		if got := deviceTypeFromUserAgent(tt.userAgent); got != tt.expected {
+			t.Errorf("deviceTypeFromUserAgent(%q) = %q, expected %q", tt.userAgent, got, tt.expected)
+		}
+	}
+}
+
+// TestLoginCreatesSession tests that login records a session from the client info
+func TestLoginCreatesSession(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx := WithClientInfo(context.Background(), ClientInfo{
+		UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148",
+		IPAddress: "203.0.113.7",
+	})
+
+	if _, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword); err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	result, err := authService.Login(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Login should succeed: %v", err)
+	}
+
+	if result.Session == nil {
+		t.Fatal("Login should return a session")
+	}
+	if result.Session.DeviceType != "mobile" || result.Session.IPAddress != "203.0.113.7" {
+		t.Errorf("Unexpected session details: %+v", result.Session)
+	}
+
+	principal, err := authService.AuthenticateToken(ctx, result.Token)
+	if err != nil {
+		t.Fatalf("AuthenticateToken should succeed: %v", err)
+	}
+	if principal.SessionID != result.Session.ID {
+		t.Errorf("Expected session ID %s, got %s", result.Session.ID, principal.SessionID)
+	}
+
+	info, err := authService.GetAuthInfo(ctx, result.User.ID)
+	if err != nil {
+		t.Fatalf("GetAuthInfo should succeed: %v", err)
+	}
+	if info.LoginCount != 1 {
+		t.Errorf("Expected login count 1, got %d", info.LoginCount)
+	}
+	if info.LastLoginAt == nil {
+		t.Error("LastLoginAt should be set")
+	}
+	if len(info.ActiveSessions) != 2 {
+		t.Errorf("Expected 2 active sessions (register and login), got %d", len(info.ActiveSessions))
+	}
+}
+
+// TestRevokeSession tests that a revoked session can no longer authenticate or refresh
+func TestRevokeSession(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	events, _ := authService.SubscribeAuthStatus(ctx, result.User.ID, result.Session.ID)
+
+	if err := authService.RevokeSession(ctx, result.User.ID, result.Session.ID); err != nil {
+		t.Fatalf("RevokeSession should succeed: %v", err)
+	}
+
+	event := nextStatusEvent(t, events)
+	if event.Reason != StatusSessionRevoked || event.SessionID != result.Session.ID {
+		t.Errorf("Unexpected status event: %+v", event)
+	}
+
+	if _, err := authService.GetUserFromToken(ctx, result.Token); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("Expected ErrSessionRevoked from GetUserFromToken, got: %v", err)
+	}
+
+	if _, err := authService.RefreshToken(ctx, result.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Expected ErrRefreshTokenInvalid from RefreshToken, got: %v", err)
+	}
+
+	// Revoking twice reports the session as gone
+	if err := authService.RevokeSession(ctx, result.User.ID, result.Session.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, got: %v", err)
+	}
+}
+
+// TestRevokeSessionOtherUser tests that users cannot revoke each other's sessions
+func TestRevokeSessionOtherUser(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx := context.Background()
+
+	alice, _ := authService.Register(ctx, "alice@example.com", serviceTestData.testPassword)
+	bob, _ := authService.Register(ctx, "bob@example.com", serviceTestData.testPassword)
+
+	if err := authService.RevokeSession(ctx, bob.User.ID, alice.Session.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, got: %v", err)
+	}
+
+	if _, err := authService.GetUserFromToken(ctx, alice.Token); err != nil {
+		t.Errorf("Alice's session should still be valid: %v", err)
+	}
+}
+
+// TestRevokeOtherSessions tests signing out every other device
+func TestRevokeOtherSessions(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	current, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+	other, err := authService.Login(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Login should succeed: %v", err)
+	}
+
+	currentEvents, _ := authService.SubscribeAuthStatus(ctx, current.User.ID, current.Session.ID)
+	otherEvents, _ := authService.SubscribeAuthStatus(ctx, other.User.ID, other.Session.ID)
+
+	revoked, err := authService.RevokeOtherSessions(ctx, current.User.ID, current.Session.ID)
+	if err != nil {
+		t.Fatalf("RevokeOtherSessions should succeed: %v", err)
+	}
+	if revoked != 1 {
+		t.Errorf("Expected 1 revoked session, got %d", revoked)
+	}
+
+	event := nextStatusEvent(t, otherEvents)
+	if event.SessionID != other.Session.ID {
+		t.Errorf("Expected event for session %s, got %+v", other.Session.ID, event)
+	}
+
+	// The current session does not hear about other sessions being revoked
+	select {
+	case event := <-currentEvents:
+		t.Errorf("Current session should not receive event: %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if _, err := authService.GetUserFromToken(ctx, current.Token); err != nil {
+		t.Errorf("Current session should still be valid: %v", err)
+	}
+	if _, err := authService.GetUserFromToken(ctx, other.Token); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("Expected ErrSessionRevoked for other session, got: %v", err)
+	}
+}
+
+// TestRefreshTokenKeepsSession tests that refreshed tokens stay bound to the same session
+func TestRefreshTokenKeepsSession(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx := context.Background()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	refreshed, err := authService.RefreshToken(ctx, result.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshToken should succeed: %v", err)
+	}
+
+	principal, err := authService.AuthenticateToken(ctx, refreshed.Token)
+	if err != nil {
+		t.Fatalf("AuthenticateToken should succeed: %v", err)
+	}
+	if principal.SessionID != result.Session.ID {
+		t.Errorf("Expected session ID %s, got %s", result.Session.ID, principal.SessionID)
+	}
+}
+
+// TestIdleSessionRejected tests that sessions unused for longer than SessionIdleTimeout expire
+func TestIdleSessionRejected(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx := context.Background()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	sessionMock(authService).sessions[result.Session.ID].LastActiveAt = time.Now().Add(-SessionIdleTimeout - time.Minute)
+
+	if _, err := authService.GetUserFromToken(ctx, result.Token); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("Expected ErrSessionRevoked for idle session, got: %v", err)
+	}
+}
+
+// TestSessionCreationFailure tests that login fails when the session cannot be recorded
+func TestSessionCreationFailure(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	sessionMock(authService).shouldFail = true
+
+	if _, err := authService.Register(context.Background(), serviceTestData.testEmail, serviceTestData.testPassword); err == nil {
+		t.Error("Expected error when session creation fails")
+	}
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// opaqueTokenBytes is the amount of randomness in tokens handed to clients
+const opaqueTokenBytes = 32
+
+// generateOpaqueToken returns a random URL-safe token and the hash to store for it
+func generateOpaqueToken() (token, hash string, err error) {
+	b := make([]byte, opaqueTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+// hashToken returns the SHA-256 hex digest stored in place of an opaque token.
+// Tokens carry enough entropy that a fast unsalted hash is sufficient.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newRandomID returns a random 128-bit hex identifier
+func newRandomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
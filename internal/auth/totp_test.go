@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key used by the RFC 6238 test vectors
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+// TestTOTPCodeRFC6238 checks codes against the RFC 6238 test vectors, truncated to 6 digits
+func TestTOTPCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	key := []byte("12345678901234567890")
+	for _, tt := range tests {
+		if got := totpCode(key, totpStep(time.Unix(tt.unix, 0))); got != tt.code {
+			t.Errorf("At %d expected code %s, got %s", tt.unix, tt.code, got)
+		}
+	}
+}
+
+// TestValidateTOTP tests the accepted clock drift window and malformed input
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(59, 0)
+
+	step, ok := validateTOTP(rfc6238Secret, "287082", now)
+	if !ok || step != 1 {
+		t.Errorf("Expected current code to match step 1, got %d, %v", step, ok)
+	}
+
+	// One period of drift either way is tolerated
+	if _, ok := validateTOTP(rfc6238Secret, "287082", now.Add(totpPeriod)); !ok {
+		t.Error("Code from the previous period should be accepted")
+	}
+	if _, ok := validateTOTP(rfc6238Secret, "287082", now.Add(-totpPeriod)); !ok {
+		t.Error("Code from the next period should be accepted")
+	}
+	if _, ok := validateTOTP(rfc6238Secret, "287082", now.Add(3*totpPeriod)); ok {
+		t.Error("Code from three periods ago should be rejected")
+	}
+
+	for _, code := range []string{"", "28708", "2870820", "abcdef", "287083"} {
+		if _, ok := validateTOTP(rfc6238Secret, code, now); ok {
+			t.Errorf("Code %q should be rejected", code)
+		}
+	}
+
+	if _, ok := validateTOTP("not base32!", "287082", now); ok {
+		t.Error("Invalid secret should reject every code")
+	}
+}
+
+// TestGenerateTOTPSecret tests that secrets are random and decode to the expected length
+func TestGenerateTOTPSecret(t *testing.T) {
+	first, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatalf("generateTOTPSecret should succeed: %v", err)
+	}
+	second, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatalf("generateTOTPSecret should succeed: %v", err)
+	}
+
+	if first == second {
+		t.Error("Secrets should be random")
+	}
+
+	key, err := totpEncoding.DecodeString(first)
+	if err != nil || len(key) != totpSecretBytes {
+		t.Errorf("Expected %d byte key, got %d (%v)", totpSecretBytes, len(key), err)
+	}
+}
+
+// TestTOTPURI tests the otpauth URI imported by authenticator apps
+func TestTOTPURI(t *testing.T) {
+	uri := totpURI("Todo App", "user@example.com", "JBSWY3DPEHPK3PXP")
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("URI should parse: %v", err)
+	}
+
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" {
+		t.Errorf("Unexpected URI prefix: %s", uri)
+	}
+	if !strings.HasPrefix(parsed.Path, "/Todo App:user@example.com") {
+		t.Errorf("Unexpected label: %s", parsed.Path)
+	}
+
+	query := parsed.Query()
+	if query.Get("secret") != "JBSWY3DPEHPK3PXP" || query.Get("issuer") != "Todo App" {
+		t.Errorf("Unexpected query: %v", query)
+	}
+	if query.Get("digits") != "6" || query.Get("period") != "30" {
+		t.Errorf("Unexpected code parameters: %v", query)
+	}
+}
@@ -0,0 +1,349 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// DefaultTOTPIssuer is the name authenticator apps show unless SetTOTPIssuer is called
+	DefaultTOTPIssuer = "Todo App"
+	// TwoFactorChallengeExpiry is how long the second login step may take after the password was accepted
+	TwoFactorChallengeExpiry = 5 * time.Minute
+	// maxTwoFactorAttempts is how many wrong codes a challenge tolerates before it is discarded
+	maxTwoFactorAttempts = 5
+	// recoveryCodeCount is how many recovery codes are issued when 2FA is turned on
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrTwoFactorRequired       = errors.New("two-factor authentication required")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor authentication is not set up")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorCodeInvalid    = errors.New("invalid two-factor code")
+	ErrChallengeInvalid        = errors.New("invalid or expired two-factor challenge")
+)
+
+// TwoFactorRequiredError is returned by Login when the password was correct but a second factor is needed.
+// The challenge is exchanged for tokens with VerifyTwoFactor. It matches ErrTwoFactorRequired with errors.Is.
+type TwoFactorRequiredError struct {
+	Challenge string
+	ExpiresAt time.Time
+}
+
+func (e *TwoFactorRequiredError) Error() string {
+	return ErrTwoFactorRequired.Error()
+}
+
+func (e *TwoFactorRequiredError) Is(target error) bool {
+	return target == ErrTwoFactorRequired
+}
+
+// TOTPCredential is a user's authenticator app secret. It only protects logins once confirmed.
+type TOTPCredential struct {
+	UserID      int        `db:"user_id" json:"user_id"`
+	Secret      string     `db:"secret" json:"-"`
+	ConfirmedAt *time.Time `db:"confirmed_at" json:"confirmed_at,omitempty"`
+	// LastUsedStep is the time step of the last accepted code, so no code is accepted twice
+	LastUsedStep int64     `db:"last_used_step" json:"-"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// IsConfirmed returns true once the user proved their app generates valid codes
+func (c *TOTPCredential) IsConfirmed() bool {
+	return c.ConfirmedAt != nil
+}
+
+// TOTPEnrollment is what a user needs to add the secret to an authenticator app
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// TwoFactorChallenge is the stored record of a pending second login step
+type TwoFactorChallenge struct {
+	ID        int       `db:"id" json:"id"`
+	UserID    int       `db:"user_id" json:"user_id"`
+	TokenHash string    `db:"token_hash" json:"-"`
+	Attempts  int       `db:"attempts" json:"attempts"`
+	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// TwoFactorRepositoryInterface defines two-factor persistence used by AuthService
+type TwoFactorRepositoryInterface interface {
+	GetTOTP(ctx context.Context, userID int) (*TOTPCredential, error)
+	SaveTOTP(ctx context.Context, userID int, secret string) error
+	ConfirmTOTP(ctx context.Context, userID int, step int64) error
+	UseTOTPStep(ctx context.Context, userID int, step int64) error
+	DeleteTOTP(ctx context.Context, userID int) error
+
+	ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) error
+	CountRecoveryCodes(ctx context.Context, userID int) (int, error)
+
+	CreateChallenge(ctx context.Context, challenge *TwoFactorChallenge) error
+	GetChallenge(ctx context.Context, tokenHash string) (*TwoFactorChallenge, error)
+	RecordChallengeFailure(ctx context.Context, id int) error
+	DeleteChallenge(ctx context.Context, id int) error
+}
+
+// TwoFactorRepository handles two-factor database operations
+type TwoFactorRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewTwoFactorRepository creates a new two-factor repository
+func NewTwoFactorRepository(db *pgxpool.Pool) *TwoFactorRepository {
+	return &TwoFactorRepository{
+		db: db,
+	}
+}
+
+// GetTOTP retrieves the user's TOTP credential
+func (r *TwoFactorRepository) GetTOTP(ctx context.Context, userID int) (*TOTPCredential, error) {
+	query := `SELECT user_id, secret, confirmed_at, last_used_step, created_at FROM user_totp WHERE user_id = $1`
+
+	var credential TOTPCredential
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&credential.UserID,
+		&credential.Secret,
+		&credential.ConfirmedAt,
+		&credential.LastUsedStep,
+		&credential.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTwoFactorNotEnrolled
+		}
+		return nil, err
+	}
+
+	return &credential, nil
+}
+
+// SaveTOTP stores a new unconfirmed secret, replacing any earlier unconfirmed one.
+// It returns ErrTwoFactorAlreadyEnabled if the user has a confirmed secret.
+func (r *TwoFactorRepository) SaveTOTP(ctx context.Context, userID int, secret string) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret, created_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+		WHERE user_totp.confirmed_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, userID, secret)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrTwoFactorAlreadyEnabled
+	}
+
+	return nil
+}
+
+// ConfirmTOTP turns 2FA on, recording step as used
+func (r *TwoFactorRepository) ConfirmTOTP(ctx context.Context, userID int, step int64) error {
+	query := `
+		UPDATE user_totp
+		SET confirmed_at = NOW(), last_used_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, userID, step)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrTwoFactorNotEnrolled
+	}
+
+	return nil
+}
+
+// UseTOTPStep records step as used. It returns ErrTwoFactorCodeInvalid if the step,
+// or a later one, was already used, which also stops two concurrent logins with the same code.
+func (r *TwoFactorRepository) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	query := `UPDATE user_totp SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`
+
+	result, err := r.db.Exec(ctx, query, userID, step)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrTwoFactorCodeInvalid
+	}
+
+	return nil
+}
+
+// DeleteTOTP turns 2FA off, removing the secret and recovery codes
+func (r *TwoFactorRepository) DeleteTOTP(ctx context.Context, userID int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ReplaceRecoveryCodes discards the user's recovery codes and stores new ones
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		query := `INSERT INTO recovery_codes (user_id, code_hash, created_at) VALUES ($1, $2, NOW())`
+		if _, err := tx.Exec(ctx, query, userID, hash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UseRecoveryCode marks an unused recovery code as used, returning ErrTwoFactorCodeInvalid if there is none
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	query := `
+		UPDATE recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrTwoFactorCodeInvalid
+	}
+
+	return nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes the user has left
+func (r *TwoFactorRepository) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	query := `SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+
+	var count int
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// CreateChallenge stores a pending second login step, filling in its ID and creation time
+func (r *TwoFactorRepository) CreateChallenge(ctx context.Context, challenge *TwoFactorChallenge) error {
+	query := `
+		INSERT INTO two_factor_challenges (user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, NOW())
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRow(ctx, query, challenge.UserID, challenge.TokenHash, challenge.ExpiresAt).
+		Scan(&challenge.ID, &challenge.CreatedAt)
+}
+
+// GetChallenge retrieves a challenge by the hash of its token
+func (r *TwoFactorRepository) GetChallenge(ctx context.Context, tokenHash string) (*TwoFactorChallenge, error) {
+	query := `
+		SELECT id, user_id, token_hash, attempts, expires_at, created_at
+		FROM two_factor_challenges
+		WHERE token_hash = $1
+	`
+
+	var challenge TwoFactorChallenge
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+		&challenge.ID,
+		&challenge.UserID,
+		&challenge.TokenHash,
+		&challenge.Attempts,
+		&challenge.ExpiresAt,
+		&challenge.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrChallengeInvalid
+		}
+		return nil, err
+	}
+
+	return &challenge, nil
+}
+
+// RecordChallengeFailure counts a wrong code against the challenge
+func (r *TwoFactorRepository) RecordChallengeFailure(ctx context.Context, id int) error {
+	_, err := r.db.Exec(ctx, `UPDATE two_factor_challenges SET attempts = attempts + 1 WHERE id = $1`, id)
+	return err
+}
+
+// DeleteChallenge removes a challenge. It returns ErrChallengeInvalid if it was already removed,
+// so a challenge can only be completed once.
+func (r *TwoFactorRepository) DeleteChallenge(ctx context.Context, id int) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM two_factor_challenges WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrChallengeInvalid
+	}
+
+	return nil
+}
+
+// recoveryCodeAlphabet avoids characters that are easy to confuse when copied by hand
+const recoveryCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// generateRecoveryCode returns a random code formatted as XXXX-XXXX-XXXX-XXXX (80 bits)
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	var code strings.Builder
+	for i, v := range b {
+		if i > 0 && i%4 == 0 {
+			code.WriteByte('-')
+		}
+		code.WriteByte(recoveryCodeAlphabet[int(v)%len(recoveryCodeAlphabet)])
+	}
+
+	return code.String(), nil
+}
+
+// hashRecoveryCode normalizes a recovery code as typed by the user and hashes it
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return hashToken(normalized)
+}
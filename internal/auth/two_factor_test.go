@@ -0,0 +1,460 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// MockTwoFactorRepository implements TwoFactorRepositoryInterface in memory for testing
+type MockTwoFactorRepository struct {
+	mu            sync.Mutex
+	credentials   map[int]*TOTPCredential
+	recoveryCodes map[int]map[string]bool // user ID -> code hash -> used
+	challenges    map[int]*TwoFactorChallenge
+	nextID        int
+}
+
+// NewMockTwoFactorRepository creates a new mock two-factor repository
+func NewMockTwoFactorRepository() *MockTwoFactorRepository {
+	return &MockTwoFactorRepository{
+		credentials:   make(map[int]*TOTPCredential),
+		recoveryCodes: make(map[int]map[string]bool),
+		challenges:    make(map[int]*TwoFactorChallenge),
+		nextID:        1,
+	}
+}
+
+func (m *MockTwoFactorRepository) GetTOTP(ctx context.Context, userID int) (*TOTPCredential, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	credential, ok := m.credentials[userID]
+	if !ok {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	copied := *credential
+	return &copied, nil
+}
+
+func (m *MockTwoFactorRepository) SaveTOTP(ctx context.Context, userID int, secret string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if credential, ok := m.credentials[userID]; ok && credential.IsConfirmed() {
+		return ErrTwoFactorAlreadyEnabled
+	}
+	m.credentials[userID] = &TOTPCredential{UserID: userID, Secret: secret, CreatedAt: time.Now()}
+	return nil
+}
+
+func (m *MockTwoFactorRepository) ConfirmTOTP(ctx context.Context, userID int, step int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	credential, ok := m.credentials[userID]
+	if !ok || credential.IsConfirmed() {
+		return ErrTwoFactorNotEnrolled
+	}
+	now := time.Now()
+	credential.ConfirmedAt = &now
+	credential.LastUsedStep = step
+	return nil
+}
+
+func (m *MockTwoFactorRepository) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	credential, ok := m.credentials[userID]
+	if !ok || credential.LastUsedStep >= step {
+		return ErrTwoFactorCodeInvalid
+	}
+	credential.LastUsedStep = step
+	return nil
+}
+
+func (m *MockTwoFactorRepository) DeleteTOTP(ctx context.Context, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.credentials, userID)
+	delete(m.recoveryCodes, userID)
+	return nil
+}
+
+func (m *MockTwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	codes := make(map[string]bool, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes[hash] = false
+	}
+	m.recoveryCodes[userID] = codes
+	return nil
+}
+
+func (m *MockTwoFactorRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	used, ok := m.recoveryCodes[userID][codeHash]
+	if !ok || used {
+		return ErrTwoFactorCodeInvalid
+	}
+	m.recoveryCodes[userID][codeHash] = true
+	return nil
+}
+
+func (m *MockTwoFactorRepository) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, used := range m.recoveryCodes[userID] {
+		if !used {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MockTwoFactorRepository) CreateChallenge(ctx context.Context, challenge *TwoFactorChallenge) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	challenge.ID = m.nextID
+	challenge.CreatedAt = time.Now()
+	m.nextID++
+
+	copied := *challenge
+	m.challenges[challenge.ID] = &copied
+	return nil
+}
+
+func (m *MockTwoFactorRepository) GetChallenge(ctx context.Context, tokenHash string) (*TwoFactorChallenge, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, challenge := range m.challenges {
+		if challenge.TokenHash == tokenHash {
+			copied := *challenge
+			return &copied, nil
+		}
+	}
+	return nil, ErrChallengeInvalid
+}
+
+func (m *MockTwoFactorRepository) RecordChallengeFailure(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if challenge, ok := m.challenges[id]; ok {
+		challenge.Attempts++
+	}
+	return nil
+}
+
+func (m *MockTwoFactorRepository) DeleteChallenge(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.challenges[id]; !ok {
+		return ErrChallengeInvalid
+	}
+	delete(m.challenges, id)
+	return nil
+}
+
+// totpCodeAt returns the code for secret offset periods from now
+func totpCodeAt(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("Secret should decode: %v", err)
+	}
+	return totpCode(key, totpStep(time.Now())+offset)
+}
+
+// enableTwoFactor registers a user with two-factor authentication on and returns the secret and recovery codes
+func enableTwoFactor(t *testing.T, authService *AuthService) (*AuthResult, string, []string) {
+	t.Helper()
+	ctx := context.Background()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	enrollment, err := authService.EnrollTOTP(ctx, result.User.ID)
+	if err != nil {
+		t.Fatalf("EnrollTOTP should succeed: %v", err)
+	}
+
+	codes, err := authService.ConfirmTOTP(ctx, result.User.ID, totpCodeAt(t, enrollment.Secret, -1))
+	if err != nil {
+		t.Fatalf("ConfirmTOTP should succeed: %v", err)
+	}
+
+	return result, enrollment.Secret, codes
+}
+
+// loginChallenge logs in with the test credentials and returns the two-factor challenge
+func loginChallenge(t *testing.T, authService *AuthService) string {
+	t.Helper()
+
+	_, err := authService.Login(context.Background(), serviceTestData.testEmail, serviceTestData.testPassword)
+
+	var required *TwoFactorRequiredError
+	if !errors.As(err, &required) {
+		t.Fatalf("Expected TwoFactorRequiredError, got: %v", err)
+	}
+	if !errors.Is(err, ErrTwoFactorRequired) {
+		t.Error("TwoFactorRequiredError should match ErrTwoFactorRequired")
+	}
+	return required.Challenge
+}
+
+// TestEnrollTOTP tests that enrolling alone doesn't turn two-factor authentication on
+func TestEnrollTOTP(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx := context.Background()
+
+	result, err := authService.Register(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+	if err != nil {
+		t.Fatalf("Registration should succeed: %v", err)
+	}
+
+	enrollment, err := authService.EnrollTOTP(ctx, result.User.ID)
+	if err != nil {
+		t.Fatalf("EnrollTOTP should succeed: %v", err)
+	}
+	if !strings.HasPrefix(enrollment.URI, "otpauth://totp/") || !strings.Contains(enrollment.URI, "secret="+enrollment.Secret) {
+		t.Errorf("Unexpected otpauth URI: %s", enrollment.URI)
+	}
+
+	// Unconfirmed secrets don't affect logins
+	if _, err := authService.Login(ctx, serviceTestData.testEmail, serviceTestData.testPassword); err != nil {
+		t.Errorf("Login should succeed before confirming: %v", err)
+	}
+
+	if _, err := authService.ConfirmTOTP(ctx, result.User.ID, "000000"); !errors.Is(err, ErrTwoFactorCodeInvalid) {
+		t.Errorf("Expected ErrTwoFactorCodeInvalid for a wrong code, got: %v", err)
+	}
+
+	codes, err := authService.ConfirmTOTP(ctx, result.User.ID, totpCodeAt(t, enrollment.Secret, 0))
+	if err != nil {
+		t.Fatalf("ConfirmTOTP should succeed: %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Errorf("Expected %d recovery codes, got %d", recoveryCodeCount, len(codes))
+	}
+
+	info, err := authService.GetAuthInfo(ctx, result.User.ID)
+	if err != nil {
+		t.Fatalf("GetAuthInfo should succeed: %v", err)
+	}
+	if !info.TwoFactorEnabled || info.RecoveryCodesRemaining != recoveryCodeCount {
+		t.Errorf("Unexpected auth info: enabled %v, %d codes", info.TwoFactorEnabled, info.RecoveryCodesRemaining)
+	}
+
+	// Recovery codes are only stored hashed
+	stored := authService.twoFactorRepo.(*MockTwoFactorRepository).recoveryCodes[result.User.ID]
+	if _, ok := stored[hashRecoveryCode(codes[0])]; !ok {
+		t.Error("Recovery code hash should be stored")
+	}
+	if _, ok := stored[codes[0]]; ok {
+		t.Error("Plaintext recovery code should not be stored")
+	}
+
+	if _, err := authService.EnrollTOTP(ctx, result.User.ID); !errors.Is(err, ErrTwoFactorAlreadyEnabled) {
+		t.Errorf("Expected ErrTwoFactorAlreadyEnabled when enrolling again, got: %v", err)
+	}
+}
+
+// TestLoginWithTwoFactor tests the two-step login with an authenticator code
+func TestLoginWithTwoFactor(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx := context.Background()
+
+	registered, secret, _ := enableTwoFactor(t, authService)
+	challenge := loginChallenge(t, authService)
+
+	if _, err := authService.VerifyTwoFactor(ctx, challenge, "000000"); !errors.Is(err, ErrTwoFactorCodeInvalid) {
+		t.Errorf("Expected ErrTwoFactorCodeInvalid for a wrong code, got: %v", err)
+	}
+
+	// The code used to confirm can't be replayed
+	key, _ := totpEncoding.DecodeString(secret)
+	usedStep := authService.twoFactorRepo.(*MockTwoFactorRepository).credentials[registered.User.ID].LastUsedStep
+	if _, err := authService.VerifyTwoFactor(ctx, challenge, totpCode(key, usedStep)); !errors.Is(err, ErrTwoFactorCodeInvalid) {
+		t.Errorf("Expected ErrTwoFactorCodeInvalid for a used code, got: %v", err)
+	}
+
+	result, err := authService.VerifyTwoFactor(ctx, challenge, totpCodeAt(t, secret, 0))
+	if err != nil {
+		t.Fatalf("VerifyTwoFactor should succeed: %v", err)
+	}
+	if result.Token == "" || result.RefreshToken == "" {
+		t.Error("VerifyTwoFactor should issue tokens")
+	}
+
+	// Challenges are single-use
+	if _, err := authService.VerifyTwoFactor(ctx, challenge, totpCodeAt(t, secret, 1)); !errors.Is(err, ErrChallengeInvalid) {
+		t.Errorf("Expected ErrChallengeInvalid on reuse, got: %v", err)
+	}
+}
+
+// TestLoginWithRecoveryCode tests that each recovery code works once
+func TestLoginWithRecoveryCode(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx := context.Background()
+
+	registered, _, codes := enableTwoFactor(t, authService)
+
+	// Codes are accepted however they are typed
+	typed := strings.ToLower(strings.ReplaceAll(codes[0], "-", " "))
+	if _, err := authService.VerifyTwoFactor(ctx, loginChallenge(t, authService), typed); err != nil {
+		t.Fatalf("VerifyTwoFactor should accept a recovery code: %v", err)
+	}
+
+	if _, err := authService.VerifyTwoFactor(ctx, loginChallenge(t, authService), codes[0]); !errors.Is(err, ErrTwoFactorCodeInvalid) {
+		t.Errorf("Expected ErrTwoFactorCodeInvalid for a used recovery code, got: %v", err)
+	}
+
+	info, err := authService.GetAuthInfo(ctx, registered.User.ID)
+	if err != nil {
+		t.Fatalf("GetAuthInfo should succeed: %v", err)
+	}
+	if info.RecoveryCodesRemaining != recoveryCodeCount-1 {
+		t.Errorf("Expected %d recovery codes left, got %d", recoveryCodeCount-1, info.RecoveryCodesRemaining)
+	}
+}
+
+// TestVerifyTwoFactorLimitsAttempts tests that a challenge is discarded after too many wrong codes or when expired
+func TestVerifyTwoFactorLimitsAttempts(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	// Leave the login lockout out of the way to reach the per-challenge limit
+	authService.SetLoginLimiter(NewLoginLimiter(NewMemoryLoginAttemptStore(), LockoutPolicy{LockoutDuration: time.Minute}))
+	ctx := context.Background()
+
+	_, secret, _ := enableTwoFactor(t, authService)
+	challenge := loginChallenge(t, authService)
+
+	for i := 0; i < maxTwoFactorAttempts; i++ {
+		if _, err := authService.VerifyTwoFactor(ctx, challenge, "000000"); !errors.Is(err, ErrTwoFactorCodeInvalid) {
+			t.Fatalf("Attempt %d: expected ErrTwoFactorCodeInvalid, got: %v", i+1, err)
+		}
+	}
+
+	if _, err := authService.VerifyTwoFactor(ctx, challenge, totpCodeAt(t, secret, 0)); !errors.Is(err, ErrChallengeInvalid) {
+		t.Errorf("Expected ErrChallengeInvalid after too many attempts, got: %v", err)
+	}
+
+	expired := loginChallenge(t, authService)
+	repo := authService.twoFactorRepo.(*MockTwoFactorRepository)
+	for _, c := range repo.challenges {
+		c.ExpiresAt = time.Now().Add(-time.Second)
+	}
+
+	if _, err := authService.VerifyTwoFactor(ctx, expired, totpCodeAt(t, secret, 0)); !errors.Is(err, ErrChallengeInvalid) {
+		t.Errorf("Expected ErrChallengeInvalid for an expired challenge, got: %v", err)
+	}
+}
+
+// TestVerifyTwoFactorCountsFailures tests that wrong codes sent with fresh challenges lock the account out
+func TestVerifyTwoFactorCountsFailures(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	authService.SetLoginLimiter(NewLoginLimiter(NewMemoryLoginAttemptStore(), LockoutPolicy{
+		Account:         LockoutThreshold{LockAfter: 3},
+		BackoffBase:     time.Second,
+		BackoffMax:      time.Second,
+		LockoutDuration: time.Minute,
+	}))
+	ctx := WithClientInfo(context.Background(), ClientInfo{IPAddress: "10.0.0.1"})
+
+	enableTwoFactor(t, authService)
+
+	wrongCodes := 0
+	for ; wrongCodes < 10; wrongCodes++ {
+		_, err := authService.Login(ctx, serviceTestData.testEmail, serviceTestData.testPassword)
+		if errors.Is(err, ErrAccountLocked) {
+			break
+		}
+
+		var required *TwoFactorRequiredError
+		if !errors.As(err, &required) {
+			t.Fatalf("Expected TwoFactorRequiredError, got: %v", err)
+		}
+
+		if _, err := authService.VerifyTwoFactor(ctx, required.Challenge, "000000"); !errors.Is(err, ErrTwoFactorCodeInvalid) {
+			t.Fatalf("Expected ErrTwoFactorCodeInvalid, got: %v", err)
+		}
+	}
+
+	if wrongCodes != 3 {
+		t.Errorf("Expected the account to lock after 3 wrong codes, got %d", wrongCodes)
+	}
+
+	// The right password doesn't clear the failures on its own
+	if _, err := authService.Login(ctx, serviceTestData.testEmail, serviceTestData.testPassword); !errors.Is(err, ErrAccountLocked) {
+		t.Errorf("Expected ErrAccountLocked, got: %v", err)
+	}
+}
+
+// TestDisableTwoFactor tests that turning two-factor off requires the password
+func TestDisableTwoFactor(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx := context.Background()
+
+	registered, _, _ := enableTwoFactor(t, authService)
+	userID := registered.User.ID
+
+	if err := authService.DisableTwoFactor(ctx, userID, "", "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials for a wrong password, got: %v", err)
+	}
+
+	if err := authService.DisableTwoFactor(ctx, userID, "", serviceTestData.testPassword); err != nil {
+		t.Fatalf("DisableTwoFactor should succeed: %v", err)
+	}
+
+	if _, err := authService.Login(ctx, serviceTestData.testEmail, serviceTestData.testPassword); err != nil {
+		t.Errorf("Login should skip the second step once disabled: %v", err)
+	}
+
+	if err := authService.DisableTwoFactor(ctx, userID, "", serviceTestData.testPassword); !errors.Is(err, ErrTwoFactorNotEnrolled) {
+		t.Errorf("Expected ErrTwoFactorNotEnrolled when already off, got: %v", err)
+	}
+
+	info, err := authService.GetAuthInfo(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetAuthInfo should succeed: %v", err)
+	}
+	if info.TwoFactorEnabled || info.RecoveryCodesRemaining != 0 {
+		t.Errorf("Unexpected auth info: enabled %v, %d codes", info.TwoFactorEnabled, info.RecoveryCodesRemaining)
+	}
+}
+
+// TestDisableTwoFactorCountsFailures tests that wrong passwords count towards the login lockout
+func TestDisableTwoFactorCountsFailures(t *testing.T) {
+	authService, _ := createTestAuthServiceWithMock()
+	ctx := context.Background()
+
+	registered, _, _ := enableTwoFactor(t, authService)
+	userID := registered.User.ID
+
+	for i := 0; i < DefaultLockoutPolicy().Account.BackoffAfter; i++ {
+		if err := authService.DisableTwoFactor(ctx, userID, "", "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("Expected ErrInvalidCredentials, got: %v", err)
+		}
+	}
+
+	if err := authService.DisableTwoFactor(ctx, userID, "", serviceTestData.testPassword); !errors.Is(err, ErrAccountLocked) {
+		t.Errorf("Expected ErrAccountLocked after repeated failures, got: %v", err)
+	}
+}
@@ -1,7 +1,9 @@
 package auth
 
 import (
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jayk0001/my-go-next-todo/internal/graphql/model"
@@ -10,10 +12,13 @@ import (
 // ToGraphQLUser converts auth.User to GraphQL User model
 func (u *User) ToGraphQLUser() *model.User {
 	user := &model.User{
-		ID:        strconv.Itoa(u.ID),
-		Email:     u.Email,
-		CreatedAt: u.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: u.UpdatedAt.Format(time.RFC3339),
+		ID:            strconv.Itoa(u.ID),
+		Email:         u.Email,
+		EmailVerified: u.IsEmailVerified(),
+		Role:          model.Role(strings.ToUpper(u.Role)),
+		TimeZone:      u.TimeZone,
+		CreatedAt:     u.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:     u.UpdatedAt.Format(time.RFC3339),
 	}
 
 	if u.LastLoginAt != nil {
@@ -33,3 +38,96 @@ func (ar *AuthResult) ToGraphQLAuthPayload() *model.AuthPayload {
 		ExpiresAt:    ar.ExpiresAt.Format(time.RFC3339),
 	}
 }
+
+// ToGraphQLTwoFactorChallenge converts TwoFactorRequiredError to GraphQL TwoFactorChallenge
+func (e *TwoFactorRequiredError) ToGraphQLTwoFactorChallenge() *model.TwoFactorChallenge {
+	return &model.TwoFactorChallenge{
+		Challenge: e.Challenge,
+		ExpiresAt: e.ExpiresAt,
+	}
+}
+
+// ToGraphQLTwoFactorEnrollment converts TOTPEnrollment to GraphQL TwoFactorEnrollment
+func (e *TOTPEnrollment) ToGraphQLTwoFactorEnrollment() *model.TwoFactorEnrollment {
+	return &model.TwoFactorEnrollment{
+		Secret:     e.Secret,
+		OtpauthURI: e.URI,
+	}
+}
+
+// ToGraphQLAuthStatusEvent converts StatusEvent to GraphQL AuthStatusEvent
+func (e StatusEvent) ToGraphQLAuthStatusEvent() *model.AuthStatusEvent {
+	event := &model.AuthStatusEvent{
+		Reason:     model.AuthStatusReason(e.Reason),
+		OccurredAt: e.OccurredAt.Format(time.RFC3339),
+	}
+
+	if e.SessionID != "" {
+		sessionID := e.SessionID
+		event.SessionID = &sessionID
+	}
+
+	return event
+}
+
+// ToGraphQLDataExport converts DataExportGrant to GraphQL DataExport, linking to the archive served at downloadPath
+func (g *DataExportGrant) ToGraphQLDataExport(downloadPath string) *model.DataExport {
+	return &model.DataExport{
+		DownloadURL: downloadPath + "?token=" + url.QueryEscape(g.Token),
+		ExpiresAt:   g.ExpiresAt,
+	}
+}
+
+// ToGraphQLPersonalAccessToken converts PersonalAccessToken to GraphQL PersonalAccessToken model
+func (t *PersonalAccessToken) ToGraphQLPersonalAccessToken() *model.PersonalAccessToken {
+	return &model.PersonalAccessToken{
+		ID:         strconv.Itoa(t.ID),
+		Name:       t.Name,
+		Scopes:     t.Scopes,
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
+// ToGraphQLSession converts Session to GraphQL Session model
+func (s *Session) ToGraphQLSession() *model.Session {
+	session := &model.Session{
+		ID:           s.ID,
+		LastActiveAt: s.LastActiveAt.Format(time.RFC3339),
+		IsActive:     s.IsActive(),
+	}
+
+	if s.DeviceType != "" {
+		deviceType := s.DeviceType
+		session.DeviceType = &deviceType
+	}
+
+	if s.IPAddress != "" {
+		ipAddress := s.IPAddress
+		session.IPAddress = &ipAddress
+	}
+
+	return session
+}
+
+// ToGraphQLAuthInfo converts AuthInfo to GraphQL AuthInfo model
+func (ai *AuthInfo) ToGraphQLAuthInfo() *model.AuthInfo {
+	info := &model.AuthInfo{
+		LoginCount:             ai.LoginCount,
+		ActiveSessions:         make([]*model.Session, len(ai.ActiveSessions)),
+		TwoFactorEnabled:       ai.TwoFactorEnabled,
+		RecoveryCodesRemaining: ai.RecoveryCodesRemaining,
+	}
+
+	if ai.LastLoginAt != nil {
+		lastLogin := ai.LastLoginAt.Format(time.RFC3339)
+		info.LastLoginAt = &lastLogin
+	}
+
+	for i, session := range ai.ActiveSessions {
+		info.ActiveSessions[i] = session.ToGraphQLSession()
+	}
+
+	return info
+}
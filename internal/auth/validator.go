@@ -4,6 +4,7 @@ import (
 	"errors"
 	"regexp"
 	"strings"
+	"time"
 )
 
 var (
@@ -11,35 +12,71 @@ var (
 	EmailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z0-9]{2,}$`)
 )
 
-// ValidationErrors contains validation error messages
-type ValidationErrors struct {
-	Email    string `json:"email,omitempty"`
-	Password string `json:"password,omitempty"`
+// Validation error codes identify the rule an input broke, so clients can react without parsing messages
+const (
+	CodeRequired         = "required"
+	CodeInvalidFormat    = "invalid_format"
+	CodeTooShort         = "too_short"
+	CodeTooLong          = "too_long"
+	CodeCharacterClasses = "character_classes"
+	CodeContainsEmail    = "contains_email"
+	CodeBreached         = "breached"
+)
+
+// ValidationError is one rule broken by one input field
+type ValidationError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
+// ValidationErrors contains every validation error found in the input
+type ValidationErrors []ValidationError
+
 // Error implements the error interface
 func (ve ValidationErrors) Error() string {
 	var errors []string
-	if ve.Email != "" {
-		errors = append(errors, "email: "+ve.Email)
-	}
-	if ve.Password != "" {
-		errors = append(errors, "password: "+ve.Password)
+	for _, e := range ve {
+		errors = append(errors, e.Field+": "+e.Message)
 	}
 	return strings.Join(errors, ", ")
 }
 
 // HasErrors returns treu if there are validation errors
 func (ve ValidationErrors) HasErrors() bool {
-	return ve.Email != "" || ve.Password != ""
+	return len(ve) > 0
+}
+
+// Has returns true if field broke the rule identified by code
+func (ve ValidationErrors) Has(field, code string) bool {
+	for _, e := range ve {
+		if e.Field == field && e.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// add records a broken rule
+func (ve *ValidationErrors) add(field, code, message string) {
+	*ve = append(*ve, ValidationError{Field: field, Code: code, Message: message})
 }
 
 // ValidatorService handles input validation
-type ValidatorService struct{}
+type ValidatorService struct {
+	passwordPolicy PasswordPolicy
+}
 
-// NewValidatorService created a new validator service
+// NewValidatorService created a new validator service enforcing DefaultPasswordPolicy
 func NewValidatorService() *ValidatorService {
-	return &ValidatorService{}
+	return &ValidatorService{
+		passwordPolicy: DefaultPasswordPolicy(),
+	}
+}
+
+// SetPasswordPolicy replaces the rules new passwords have to meet
+func (v *ValidatorService) SetPasswordPolicy(policy PasswordPolicy) {
+	v.passwordPolicy = policy
 }
 
 // ValidateRegisterInput validates user registration input
@@ -47,17 +84,11 @@ func (v *ValidatorService) ValidateRegisterInput(email, password string) error {
 	ve := ValidationErrors{}
 
 	// Validate email
-	if email == "" {
-		ve.Email = "email is required"
-	} else if !v.IsValidEmail(email) {
-		ve.Email = "email format is invalid"
-	}
+	v.checkEmail(&ve, email)
 
 	// Validate password
-	if password == "" {
-		ve.Password = "password is required"
-	} else if !v.IsValidPassword(password) {
-		ve.Password = "password must be at least 8 character long"
+	if err := v.passwordPolicy.check(&ve, password, email); err != nil {
+		return err
 	}
 
 	if ve.HasErrors() {
@@ -78,6 +109,60 @@ func (v *ValidatorService) ValidateLoginInput(email, password string) error {
 	return nil
 }
 
+// ValidateEmail validates a new email address, e.g. when changing it
+func (v *ValidatorService) ValidateEmail(email string) error {
+	ve := ValidationErrors{}
+	v.checkEmail(&ve, email)
+
+	if ve.HasErrors() {
+		return ve
+	}
+
+	return nil
+}
+
+// ValidateTimeZone validates an IANA time zone name such as "Europe/Berlin"
+func (v *ValidatorService) ValidateTimeZone(timeZone string) error {
+	ve := ValidationErrors{}
+
+	// LoadLocation maps "" to UTC and "Local" to the server's zone, neither of which is a choice a user makes
+	if timeZone == "" {
+		ve.add("timeZone", CodeRequired, "time zone is required")
+	} else if _, err := time.LoadLocation(timeZone); err != nil || timeZone == "Local" {
+		ve.add("timeZone", CodeInvalidFormat, "unknown time zone")
+	}
+
+	if ve.HasErrors() {
+		return ve
+	}
+
+	return nil
+}
+
+// ValidatePassword validates a new password for the account with email, e.g. when resetting it
+func (v *ValidatorService) ValidatePassword(password, email string) error {
+	ve := ValidationErrors{}
+
+	if err := v.passwordPolicy.check(&ve, password, email); err != nil {
+		return err
+	}
+
+	if ve.HasErrors() {
+		return ve
+	}
+
+	return nil
+}
+
+// checkEmail adds the rules email breaks to ve
+func (v *ValidatorService) checkEmail(ve *ValidationErrors, email string) {
+	if email == "" {
+		ve.add("email", CodeRequired, "email is required")
+	} else if !v.IsValidEmail(email) {
+		ve.add("email", CodeInvalidFormat, "email format is invalid")
+	}
+}
+
 // IsValidEmail checks if email format is valid
 func (v *ValidatorService) IsValidEmail(email string) bool {
 	if len(email) > 254 {
@@ -110,7 +195,7 @@ func (v *ValidatorService) IsValidEmail(email string) bool {
 	return true
 }
 
-// IsValidPassword checks if password meets requirements
+// IsValidPassword checks if password meets the password policy, ignoring rules that need the email
 func (v *ValidatorService) IsValidPassword(password string) bool {
-	return len(password) >= 8
+	return v.ValidatePassword(password, "") == nil
 }
@@ -194,7 +194,7 @@ func TestValidationErrors(t *testing.T) {
 	})
 
 	t.Run("email error only", func(t *testing.T) {
-		ve := ValidationErrors{Email: "email is invalid"}
+		ve := ValidationErrors{{Field: "email", Code: CodeInvalidFormat, Message: "email is invalid"}}
 
 		if !ve.HasErrors() {
 			t.Error("ValidationErrors with email error should have errors")
@@ -207,7 +207,7 @@ func TestValidationErrors(t *testing.T) {
 	})
 
 	t.Run("password error only", func(t *testing.T) {
-		ve := ValidationErrors{Password: "password too short"}
+		ve := ValidationErrors{{Field: "password", Code: CodeTooShort, Message: "password too short"}}
 
 		if !ve.HasErrors() {
 			t.Error("ValidationErrors with password error should have errors")
@@ -221,8 +221,8 @@ func TestValidationErrors(t *testing.T) {
 
 	t.Run("both errors", func(t *testing.T) {
 		ve := ValidationErrors{
-			Email:    "email is invalid",
-			Password: "password too short",
+			{Field: "email", Code: CodeInvalidFormat, Message: "email is invalid"},
+			{Field: "password", Code: CodeTooShort, Message: "password too short"},
 		}
 
 		if !ve.HasErrors() {
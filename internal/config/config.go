@@ -2,8 +2,12 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -14,6 +18,9 @@ type Config struct {
 	Database DatabaseConfig
 	Server   ServerConfig
 	JWT      JWTConfig
+	Auth     AuthConfig
+	Mail     MailConfig
+	OIDC     OIDCConfig
 	App      AppConfig
 }
 
@@ -26,24 +33,121 @@ type DatabaseConfig struct {
 	Database    string
 	SSLMode     string
 	DatabaseURL string // For Cloud providers like neon
+	// ListenNotify fans subscription events out across replicas via LISTEN/NOTIFY.
+	// Requires a session-mode connection (not a transaction pooler).
+	ListenNotify bool
 }
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
 	Host string
 	Port string
+	// TrustedProxies are the addresses or CIDR ranges of reverse proxies whose X-Forwarded-For
+	// header is believed. Without any, the client IP is the address of the connection.
+	TrustedProxies []string
 }
 
 // JWTConfig holds JWT configuration
 type JWTConfig struct {
 	Secret      string
 	ExpiryHours time.Duration
+	// RevocationStore selects where revoked access tokens are tracked: "memory" (per instance) or "postgres"
+	RevocationStore string
+	// SigningKeyFile is a PEM RSA or Ed25519 private key. When set, access tokens are signed
+	// with RS256/EdDSA instead of HS256 and the public keys are served at /.well-known/jwks.json.
+	SigningKeyFile string
+	// VerificationKeyFiles are PEM public keys (or certificates) of retired signing keys
+	// that are still accepted while tokens they signed expire
+	VerificationKeyFiles []string
+	// RejectHS256 stops accepting HS256 tokens signed with Secret. Turn it on once the tokens
+	// issued before switching to SigningKeyFile have expired to finish the rollover.
+	RejectHS256 bool
 }
 
+// AuthConfig holds account policy configuration
+type AuthConfig struct {
+	// UnverifiedPolicy decides what users with an unverified email may do:
+	// "allow" (everything), "read_only" (sign in, no changes) or "block" (no sign in)
+	UnverifiedPolicy string
+	// TOTPIssuer is the name authenticator apps show for two-factor codes
+	TOTPIssuer string
+	// MagicLinkAutoRegister lets sign-in links create accounts for addresses that are not registered
+	MagicLinkAutoRegister bool
+
+	// LoginAttemptStore keeps failed login counts: "memory" or "postgres" (shared by every replica)
+	LoginAttemptStore string
+	// Failed logins per account and per IP address after which each further attempt has to wait,
+	// doubling from LoginBackoffBase up to LoginBackoffMax. Zero disables backoff.
+	AccountBackoffAfter int
+	IPBackoffAfter      int
+	// Failed logins per account and per IP address that lock logins out for LockoutDuration.
+	// Zero disables lockout.
+	AccountLockAfter int
+	IPLockAfter      int
+	LoginBackoffBase time.Duration
+	LoginBackoffMax  time.Duration
+	// LockoutDuration is how long a lockout lasts; failures older than this are forgotten
+	LockoutDuration time.Duration
+
+	// PasswordHasher hashes new passwords: "argon2id" or "bcrypt". Existing hashes of either
+	// algorithm keep working and are rehashed on login when they differ from these settings.
+	PasswordHasher    string
+	Argon2MemoryKiB   int
+	Argon2Iterations  int
+	Argon2Parallelism int
+	BcryptCost        int
+
+	// Password policy for new passwords. PasswordMaxLength 0 means no limit.
+	PasswordMinLength           int
+	PasswordMaxLength           int
+	PasswordMinCharacterClasses int
+	PasswordRejectEmail         bool
+	// PasswordBreachedList rejects known passwords: "bundled" (a short list of the most common ones),
+	// "none", or the path of a SHA-1 hash file sorted by hash, such as the Pwned Passwords download
+	PasswordBreachedList string
+}
+
+// MailConfig holds outgoing email configuration
+type MailConfig struct {
+	// Driver selects how email is delivered: "smtp", "file" (one .eml file per message in FileDir)
+	// or "memory" (kept in process, for tests)
+	Driver       string
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	FileDir      string
+}
+
+// OIDCConfig holds the OpenID Connect providers users can sign in with
+type OIDCConfig struct {
+	Providers []OIDCProviderConfig
+}
+
+// OIDCProviderConfig holds the settings of one OpenID Connect provider. Each provider
+// named in OIDC_PROVIDERS reads OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET,
+// _REDIRECT_URL and _SCOPES.
+type OIDCProviderConfig struct {
+	// Name is used in the login and callback URLs, e.g. /auth/oidc/google/login
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL must point at /auth/oidc/<name>/callback on this API
+	RedirectURL string
+	Scopes      []string
+}
+
+// oidcProviderName restricts provider names to what fits in a URL path and an environment variable
+var oidcProviderName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
 // AppConfig holds general application configuration
 type AppConfig struct {
 	Environment string
 	LogLevel    string
+	// URL is the public frontend address used in links sent by email
+	URL string
 }
 
 // Load func loads configuration from enviroment variables
@@ -56,25 +160,72 @@ func Load() (*Config, error) {
 
 	cfg := &Config{
 		Database: DatabaseConfig{
-			Host:        getEnv("DB_HOST", "localhost"),
-			Port:        getEnv("DB_PORT", "5432"),
-			User:        getEnv("DB_USER", "postgres"),
-			Password:    getEnv("DB_PASSWORD", ""),
-			Database:    getEnv("DB_NAME", "todoapp"),
-			SSLMode:     getEnv("DB_SSL_MODE", "require"), // Default to 'require' for cloud DBs
-			DatabaseURL: getEnv("DATABASE_URL", ""),       // Neon connection string
+			Host:         getEnv("DB_HOST", "localhost"),
+			Port:         getEnv("DB_PORT", "5432"),
+			User:         getEnv("DB_USER", "postgres"),
+			Password:     getEnv("DB_PASSWORD", ""),
+			Database:     getEnv("DB_NAME", "todoapp"),
+			SSLMode:      getEnv("DB_SSL_MODE", "require"), // Default to 'require' for cloud DBs
+			DatabaseURL:  getEnv("DATABASE_URL", ""),       // Neon connection string
+			ListenNotify: getEnvAsBool("DB_LISTEN_NOTIFY", false),
 		},
 		Server: ServerConfig{
 			Host: getEnv("SERVER_HOST", "localhost"),
 			Port: getEnv("SERVER_PORT", "8080"),
+			// Client IPs key login lockouts, so forwarded headers are only trusted from known proxies
+			TrustedProxies: getEnvAsList("TRUSTED_PROXIES"),
 		},
 		JWT: JWTConfig{
-			Secret:      getEnv("JWT_SECRET", ""),
-			ExpiryHours: time.Duration(getEnvAsInt("JWT_EXPIRY_HOURS", 24)) * time.Hour,
+			Secret:               getEnv("JWT_SECRET", ""),
+			ExpiryHours:          time.Duration(getEnvAsInt("JWT_EXPIRY_HOURS", 24)) * time.Hour,
+			RevocationStore:      getEnv("JWT_REVOCATION_STORE", "memory"),
+			SigningKeyFile:       getEnv("JWT_SIGNING_KEY_FILE", ""),
+			VerificationKeyFiles: getEnvAsList("JWT_VERIFICATION_KEY_FILES"),
+			RejectHS256:          getEnvAsBool("JWT_REJECT_HS256", false),
+		},
+		Auth: AuthConfig{
+			UnverifiedPolicy: getEnv("UNVERIFIED_EMAIL_POLICY", "allow"),
+			TOTPIssuer:       getEnv("TOTP_ISSUER", "Todo App"),
+
+			MagicLinkAutoRegister: getEnvAsBool("MAGIC_LINK_AUTO_REGISTER", false),
+
+			LoginAttemptStore:   getEnv("LOGIN_ATTEMPT_STORE", "memory"),
+			AccountBackoffAfter: getEnvAsInt("LOGIN_ACCOUNT_BACKOFF_AFTER", 3),
+			AccountLockAfter:    getEnvAsInt("LOGIN_ACCOUNT_LOCK_AFTER", 10),
+			IPBackoffAfter:      getEnvAsInt("LOGIN_IP_BACKOFF_AFTER", 20),
+			IPLockAfter:         getEnvAsInt("LOGIN_IP_LOCK_AFTER", 100),
+			LoginBackoffBase:    time.Duration(getEnvAsInt("LOGIN_BACKOFF_BASE_SECONDS", 1)) * time.Second,
+			LoginBackoffMax:     time.Duration(getEnvAsInt("LOGIN_BACKOFF_MAX_SECONDS", 300)) * time.Second,
+			LockoutDuration:     time.Duration(getEnvAsInt("LOGIN_LOCKOUT_MINUTES", 15)) * time.Minute,
+
+			PasswordHasher:    getEnv("PASSWORD_HASHER", "argon2id"),
+			Argon2MemoryKiB:   getEnvAsInt("ARGON2_MEMORY_KIB", 19456),
+			Argon2Iterations:  getEnvAsInt("ARGON2_ITERATIONS", 2),
+			Argon2Parallelism: getEnvAsInt("ARGON2_PARALLELISM", 1),
+			BcryptCost:        getEnvAsInt("BCRYPT_COST", 12),
+
+			PasswordMinLength:           getEnvAsInt("PASSWORD_MIN_LENGTH", 8),
+			PasswordMaxLength:           getEnvAsInt("PASSWORD_MAX_LENGTH", 128),
+			PasswordMinCharacterClasses: getEnvAsInt("PASSWORD_MIN_CHARACTER_CLASSES", 0),
+			PasswordRejectEmail:         getEnvAsBool("PASSWORD_REJECT_EMAIL", true),
+			PasswordBreachedList:        getEnv("PASSWORD_BREACHED_LIST", "bundled"),
+		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "file"),
+			From:         getEnv("MAIL_FROM", "no-reply@localhost"),
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     getEnv("SMTP_PORT", "1025"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			FileDir:      getEnv("MAIL_FILE_DIR", "tmp/mail"),
+		},
+		OIDC: OIDCConfig{
+			Providers: getOIDCProviders(),
 		},
 		App: AppConfig{
 			Environment: getEnv("ENVIRONMENT", "development"),
 			LogLevel:    getEnv("LOG_LEVEL", "info"),
+			URL:         getEnv("APP_URL", "http://localhost:3000"),
 		},
 	}
 
@@ -88,6 +239,94 @@ func Load() (*Config, error) {
 
 // Validate validates the configuration
 func (c *Config) Validate() error {
+	if c.JWT.RevocationStore != "memory" && c.JWT.RevocationStore != "postgres" {
+		return fmt.Errorf("JWT revocation store must be memory or postgres, got %q", c.JWT.RevocationStore)
+	}
+
+	if len(c.JWT.VerificationKeyFiles) > 0 && c.JWT.SigningKeyFile == "" {
+		return fmt.Errorf("JWT verification keys require a signing key file")
+	}
+
+	if c.JWT.RejectHS256 && c.JWT.SigningKeyFile == "" {
+		return fmt.Errorf("rejecting HS256 tokens requires a signing key file")
+	}
+
+	switch c.Auth.UnverifiedPolicy {
+	case "allow", "read_only", "block":
+	default:
+		return fmt.Errorf("unverified email policy must be allow, read_only or block, got %q", c.Auth.UnverifiedPolicy)
+	}
+
+	if c.Auth.LoginAttemptStore != "memory" && c.Auth.LoginAttemptStore != "postgres" {
+		return fmt.Errorf("login attempt store must be memory or postgres, got %q", c.Auth.LoginAttemptStore)
+	}
+
+	if c.Auth.AccountBackoffAfter < 0 || c.Auth.AccountLockAfter < 0 || c.Auth.IPBackoffAfter < 0 || c.Auth.IPLockAfter < 0 {
+		return fmt.Errorf("login failure thresholds must not be negative")
+	}
+
+	if c.Auth.LoginBackoffBase <= 0 || c.Auth.LoginBackoffMax < c.Auth.LoginBackoffBase {
+		return fmt.Errorf("login backoff must be positive with a maximum of at least the base")
+	}
+
+	if c.Auth.LockoutDuration <= 0 {
+		return fmt.Errorf("login lockout duration must be positive")
+	}
+
+	switch c.Auth.PasswordHasher {
+	case "argon2id":
+		if c.Auth.Argon2MemoryKiB < 8*c.Auth.Argon2Parallelism || c.Auth.Argon2Iterations < 1 ||
+			c.Auth.Argon2Parallelism < 1 || c.Auth.Argon2Parallelism > 255 {
+			return fmt.Errorf("argon2id needs at least 1 iteration, 1 to 255 threads and 8 KiB of memory per thread")
+		}
+	case "bcrypt":
+		if c.Auth.BcryptCost < 10 || c.Auth.BcryptCost > 31 {
+			return fmt.Errorf("bcrypt cost must be between 10 and 31, got %d", c.Auth.BcryptCost)
+		}
+	default:
+		return fmt.Errorf("password hasher must be argon2id or bcrypt, got %q", c.Auth.PasswordHasher)
+	}
+
+	if c.Auth.PasswordMinLength < 1 {
+		return fmt.Errorf("password min length must be at least 1, got %d", c.Auth.PasswordMinLength)
+	}
+	if c.Auth.PasswordMaxLength != 0 && c.Auth.PasswordMaxLength < c.Auth.PasswordMinLength {
+		return fmt.Errorf("password max length must be 0 or at least the min length, got %d", c.Auth.PasswordMaxLength)
+	}
+	if c.Auth.PasswordMinCharacterClasses < 0 || c.Auth.PasswordMinCharacterClasses > 4 {
+		return fmt.Errorf("password min character classes must be between 0 and 4, got %d", c.Auth.PasswordMinCharacterClasses)
+	}
+	if c.Auth.PasswordBreachedList == "" {
+		return fmt.Errorf("password breached list must be bundled, none or a file path")
+	}
+
+	switch c.Mail.Driver {
+	case "smtp", "file", "memory":
+	default:
+		return fmt.Errorf("mail driver must be smtp, file or memory, got %q", c.Mail.Driver)
+	}
+
+	seen := make(map[string]bool)
+	for _, provider := range c.OIDC.Providers {
+		if !oidcProviderName.MatchString(provider.Name) {
+			return fmt.Errorf("OIDC provider name must be lowercase letters, digits and underscores, got %q", provider.Name)
+		}
+		if seen[provider.Name] {
+			return fmt.Errorf("OIDC provider %q is configured twice", provider.Name)
+		}
+		seen[provider.Name] = true
+
+		if !isAbsoluteURL(provider.Issuer) || !isAbsoluteURL(provider.RedirectURL) {
+			return fmt.Errorf("OIDC provider %q needs an absolute issuer and redirect URL", provider.Name)
+		}
+		if provider.ClientID == "" || provider.ClientSecret == "" {
+			return fmt.Errorf("OIDC provider %q needs a client ID and secret", provider.Name)
+		}
+		if !slices.Contains(provider.Scopes, "openid") {
+			return fmt.Errorf("OIDC provider %q scopes must include openid", provider.Name)
+		}
+	}
+
 	// If DATABASE_URL is provided, skip individual field validation
 	if c.Database.DatabaseURL != "" {
 		// Just validate JWT Secret
@@ -142,3 +381,50 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvAsList gets a comma-separated environment variable as a list, skipping empty entries
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getOIDCProviders reads the settings of every provider named in OIDC_PROVIDERS
+func getOIDCProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, name := range getEnvAsList("OIDC_PROVIDERS") {
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+
+		scopes := strings.Fields(getEnv(prefix+"SCOPES", "openid email profile"))
+
+		providers = append(providers, OIDCProviderConfig{
+			Name:         strings.ToLower(name),
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", ""),
+			Scopes:       scopes,
+		})
+	}
+	return providers
+}
+
+// isAbsoluteURL reports whether value is an http or https URL with a host
+func isAbsoluteURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "https" || parsed.Scheme == "http") && parsed.Host != ""
+}
+
+// getEnvAsBool gets an environment variable as bool or returns a default value
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
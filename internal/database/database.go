@@ -89,18 +89,40 @@ func RunMigrations(pool *pgxpool.Pool) error {
 		CREATE TABLE IF NOT EXISTS users (
 			id SERIAL PRIMARY KEY,
 			email TEXT UNIQUE NOT NULL,
-			password_hash TEXT NOT NULL,
+			password_hash TEXT,
 			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-			last_login_at TIMESTAMP WITH TIME ZONE
+			last_login_at TIMESTAMP WITH TIME ZONE,
+			login_count INTEGER NOT NULL DEFAULT 0,
+			disabled_at TIMESTAMP WITH TIME ZONE,
+			email_verified_at TIMESTAMP WITH TIME ZONE,
+			role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin')),
+			time_zone TEXT NOT NULL DEFAULT 'UTC'
 		);
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP WITH TIME ZONE;
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS login_count INTEGER NOT NULL DEFAULT 0;
+		-- Accounts created before verification existed are treated as verified. This only runs
+		-- when the column is added, so later unverified sign-ups are left alone.
+		DO $$
+		BEGIN
+			IF NOT EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_schema = current_schema() AND table_name = 'users' AND column_name = 'email_verified_at'
+			) THEN
+				ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP WITH TIME ZONE;
+				UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
+			END IF;
+		END $$;
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin'));
+		ALTER TABLE users ALTER COLUMN password_hash DROP NOT NULL;
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS time_zone TEXT NOT NULL DEFAULT 'UTC';
 		CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to create users table: %w", err)
 	}
 
-	// Create todos table (unchanged)
+	// Create todos table
 	_, err = pool.Exec(ctx, `
 		CREATE TABLE IF NOT EXISTS todos (
 			id SERIAL PRIMARY KEY,
@@ -108,14 +130,313 @@ func RunMigrations(pool *pgxpool.Pool) error {
 			title VARCHAR(500) NOT NULL,
 			description TEXT,
 			completed BOOLEAN DEFAULT FALSE,
+			due_at TIMESTAMP WITH TIME ZONE,
+			all_day BOOLEAN NOT NULL DEFAULT FALSE,
+			priority SMALLINT NOT NULL DEFAULT 0 CHECK (priority BETWEEN 0 AND 4),
 			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 		);
+		ALTER TABLE todos ADD COLUMN IF NOT EXISTS due_at TIMESTAMP WITH TIME ZONE;
+		ALTER TABLE todos ADD COLUMN IF NOT EXISTS all_day BOOLEAN NOT NULL DEFAULT FALSE;
+		ALTER TABLE todos ADD COLUMN IF NOT EXISTS priority SMALLINT NOT NULL DEFAULT 0 CHECK (priority BETWEEN 0 AND 4);
 		CREATE INDEX IF NOT EXISTS idx_todos_user_id ON todos(user_id);
+		CREATE INDEX IF NOT EXISTS idx_todos_user_id_due_at ON todos(user_id, due_at);
+		CREATE INDEX IF NOT EXISTS idx_todos_user_id_priority ON todos(user_id, priority);
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to create todos table: %w", err)
 	}
 
+	// Create sessions table
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS sessions (
+			id TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			user_agent TEXT NOT NULL DEFAULT '',
+			device_type TEXT NOT NULL DEFAULT '',
+			ip_address TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			last_active_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			revoked_at TIMESTAMP WITH TIME ZONE
+		);
+		CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create sessions table: %w", err)
+	}
+
+	// Create refresh tokens table
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS refresh_tokens (
+			id SERIAL PRIMARY KEY,
+			token_hash TEXT UNIQUE NOT NULL,
+			family_id TEXT NOT NULL,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			session_id TEXT NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			rotated_at TIMESTAMP WITH TIME ZONE,
+			revoked_at TIMESTAMP WITH TIME ZONE
+		);
+		CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+		CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens(session_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh tokens table: %w", err)
+	}
+
+	// Create revoked access tokens table
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS revoked_tokens (
+			jti TEXT PRIMARY KEY,
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			revoked_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create revoked tokens table: %w", err)
+	}
+
+	// Create password reset tokens table
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS password_reset_tokens (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			token_hash TEXT UNIQUE NOT NULL,
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			used_at TIMESTAMP WITH TIME ZONE
+		);
+		CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create password reset tokens table: %w", err)
+	}
+
+	// Create email verification tokens table
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS email_verification_tokens (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			email TEXT NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL,
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			used_at TIMESTAMP WITH TIME ZONE
+		);
+		CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_user_id ON email_verification_tokens(user_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create email verification tokens table: %w", err)
+	}
+
+	// Create two-factor authentication tables
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS user_totp (
+			user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			secret TEXT NOT NULL,
+			confirmed_at TIMESTAMP WITH TIME ZONE,
+			last_used_step BIGINT NOT NULL DEFAULT 0,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS recovery_codes (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			code_hash TEXT NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			used_at TIMESTAMP WITH TIME ZONE
+		);
+		CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes(user_id);
+		CREATE TABLE IF NOT EXISTS two_factor_challenges (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			token_hash TEXT UNIQUE NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_two_factor_challenges_user_id ON two_factor_challenges(user_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create two-factor tables: %w", err)
+	}
+
+	// Create failed login attempts table
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS login_attempts (
+			key TEXT PRIMARY KEY,
+			failures INTEGER NOT NULL DEFAULT 0,
+			last_failure_at TIMESTAMP WITH TIME ZONE NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_login_attempts_last_failure_at ON login_attempts(last_failure_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create login attempts table: %w", err)
+	}
+
+	// Create data export tokens table
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS data_export_tokens (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			token_hash TEXT UNIQUE NOT NULL,
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			used_at TIMESTAMP WITH TIME ZONE
+		);
+		CREATE INDEX IF NOT EXISTS idx_data_export_tokens_user_id ON data_export_tokens(user_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create data export tokens table: %w", err)
+	}
+
+	// Create personal access tokens table
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS personal_access_tokens (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name TEXT NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL,
+			scopes TEXT[] NOT NULL DEFAULT '{}',
+			expires_at TIMESTAMP WITH TIME ZONE,
+			last_used_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			revoked_at TIMESTAMP WITH TIME ZONE
+		);
+		CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_user_id ON personal_access_tokens(user_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create personal access tokens table: %w", err)
+	}
+
+	// Create user identities table, linking users to identity provider accounts
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS user_identities (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			provider TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			email TEXT NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			last_login_at TIMESTAMP WITH TIME ZONE,
+			UNIQUE (provider, subject)
+		);
+		CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create user identities table: %w", err)
+	}
+
+	// Create OIDC login states table
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS oidc_login_states (
+			id SERIAL PRIMARY KEY,
+			provider TEXT NOT NULL,
+			state_hash TEXT UNIQUE NOT NULL,
+			code_verifier TEXT NOT NULL,
+			nonce TEXT NOT NULL,
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create OIDC login states table: %w", err)
+	}
+
+	// Create magic link tokens table
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS magic_link_tokens (
+			id SERIAL PRIMARY KEY,
+			email TEXT NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL,
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			used_at TIMESTAMP WITH TIME ZONE
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_magic_link_tokens_email ON magic_link_tokens(email);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create magic link tokens table: %w", err)
+	}
+
+	// Create tags tables
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS tags (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name VARCHAR(50) NOT NULL,
+			color VARCHAR(7) NOT NULL DEFAULT '#6B7280',
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_id_name ON tags(user_id, LOWER(name));
+
+		CREATE TABLE IF NOT EXISTS todo_tags (
+			todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+			tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+			PRIMARY KEY (todo_id, tag_id)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_todo_tags_tag_id ON todo_tags(tag_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create tags tables: %w", err)
+	}
+
+	// Create projects table and move existing todos into each user's inbox
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS projects (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name VARCHAR(100) NOT NULL,
+			color VARCHAR(7) NOT NULL DEFAULT '#6B7280',
+			archived BOOLEAN NOT NULL DEFAULT FALSE,
+			position INTEGER NOT NULL DEFAULT 0,
+			is_inbox BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_projects_user_id_position ON projects(user_id, position);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_projects_user_id_inbox ON projects(user_id) WHERE is_inbox;
+
+		ALTER TABLE todos ADD COLUMN IF NOT EXISTS project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE;
+
+		INSERT INTO projects (user_id, name, is_inbox)
+		SELECT DISTINCT user_id, 'Inbox', TRUE FROM todos WHERE project_id IS NULL
+		ON CONFLICT (user_id) WHERE is_inbox DO NOTHING;
+
+		UPDATE todos SET project_id = projects.id
+		FROM projects
+		WHERE projects.user_id = todos.user_id AND projects.is_inbox AND todos.project_id IS NULL;
+
+		ALTER TABLE todos ALTER COLUMN project_id SET NOT NULL;
+		CREATE INDEX IF NOT EXISTS idx_todos_project_id ON todos(project_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create projects table: %w", err)
+	}
+
+	// Add parent_id for subtasks
+	_, err = pool.Exec(ctx, `
+		ALTER TABLE todos ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES todos(id) ON DELETE CASCADE;
+		CREATE INDEX IF NOT EXISTS idx_todos_parent_id ON todos(parent_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add parent_id to todos: %w", err)
+	}
+
+	// Track logins in progress apart from confirmed failures
+	_, err = pool.Exec(ctx, `
+		ALTER TABLE login_attempts ADD COLUMN IF NOT EXISTS pending INTEGER NOT NULL DEFAULT 0;
+		ALTER TABLE login_attempts ADD COLUMN IF NOT EXISTS reserved_at TIMESTAMP WITH TIME ZONE;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add pending to login attempts: %w", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// MaxNotifyPayload is the largest payload Postgres accepts for NOTIFY (8000 bytes minus terminator)
+	MaxNotifyPayload = 7999
+
+	defaultMinBackoff = 500 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+)
+
+// NotificationHandler receives the payload of a NOTIFY message
+type NotificationHandler func(payload string)
+
+// Notifier sends Postgres NOTIFY messages through the pool and relays
+// LISTEN notifications from a dedicated connection to local handlers.
+// Notifications sent while the listen connection is down are not replayed.
+type Notifier struct {
+	pool       *pgxpool.Pool
+	mu         sync.RWMutex
+	handlers   map[string][]NotificationHandler
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// NewNotifier creates a notifier on top of an existing connection pool
+func NewNotifier(pool *pgxpool.Pool) *Notifier {
+	return &Notifier{
+		pool:       pool,
+		handlers:   make(map[string][]NotificationHandler),
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+	}
+}
+
+// Notify sends payload on channel to every listening instance (including this one)
+func (n *Notifier) Notify(ctx context.Context, channel, payload string) error {
+	if len(payload) > MaxNotifyPayload {
+		return fmt.Errorf("notify payload too large: %d bytes", len(payload))
+	}
+
+	if _, err := n.pool.Exec(ctx, "SELECT pg_notify($1, $2)", channel, payload); err != nil {
+		return fmt.Errorf("failed to notify %s: %w", channel, err)
+	}
+
+	return nil
+}
+
+// Subscribe registers a handler for a channel. Handlers must be registered before Run.
+func (n *Notifier) Subscribe(channel string, handler NotificationHandler) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.handlers[channel] = append(n.handlers[channel], handler)
+}
+
+// Run listens for notifications until ctx is done, reconnecting with exponential backoff
+func (n *Notifier) Run(ctx context.Context) error {
+	backoff := n.minBackoff
+
+	for {
+		err := n.listen(ctx, func() { backoff = n.minBackoff })
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		fmt.Printf("Notifier: listen connection lost: %v (reconnecting in %s)\n", err, backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		backoff *= 2
+		if backoff > n.maxBackoff {
+			backoff = n.maxBackoff
+		}
+	}
+}
+
+// listen opens a dedicated connection, LISTENs on all channels and dispatches notifications
+func (n *Notifier) listen(ctx context.Context, onConnected func()) error {
+	// A connection outside the pool, so it is never recycled or shared with queries
+	conn, err := pgx.ConnectConfig(ctx, n.pool.Config().ConnConfig.Copy())
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	for _, channel := range n.channels() {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", channel, err)
+		}
+	}
+
+	onConnected()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to wait for notification: %w", err)
+		}
+
+		n.dispatch(notification.Channel, notification.Payload)
+	}
+}
+
+// dispatch calls every handler registered for the channel
+func (n *Notifier) dispatch(channel, payload string) {
+	n.mu.RLock()
+	handlers := n.handlers[channel]
+	n.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(payload)
+	}
+}
+
+// channels returns the channels that have handlers
+func (n *Notifier) channels() []string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	channels := make([]string, 0, len(n.handlers))
+	for channel := range n.handlers {
+		channels = append(channels, channel)
+	}
+	return channels
+}
@@ -0,0 +1,274 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/jayk0001/my-go-next-todo/internal/auth"
+	"github.com/jayk0001/my-go-next-todo/internal/todo"
+)
+
+// DownloadPath is where the HTTP server serves data export archives
+const DownloadPath = "/api/v1/account/export"
+
+// pageSize is how many todos are read per query while streaming an archive
+const pageSize = 100
+
+// Files in an export archive
+const (
+	ProfileFile     = "profile.json"
+	TodosFile       = "todos.json"
+	SessionsFile    = "sessions.json"
+	AuditEventsFile = "audit_events.json"
+)
+
+// Audit event types
+const (
+	EventAccountCreated  = "account_created"
+	EventEmailVerified   = "email_verified"
+	EventAccountDisabled = "account_disabled"
+	EventSessionStarted  = "session_started"
+	EventSessionRevoked  = "session_revoked"
+)
+
+// UserSource loads the account being exported
+type UserSource interface {
+	GetByID(ctx context.Context, id int) (*auth.User, error)
+}
+
+// SessionSource lists every session of the account being exported
+type SessionSource interface {
+	ListByUser(ctx context.Context, userID int) ([]*auth.Session, error)
+}
+
+// TodoSource pages through the todos of the account being exported
+type TodoSource interface {
+	GetByUserID(ctx context.Context, userID int, filter todo.TodoFilter) (*todo.TodoListResponse, error)
+}
+
+// Profile is the exported account. It deliberately leaves out the password hash.
+type Profile struct {
+	ID              int        `json:"id"`
+	Email           string     `json:"email"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	LastLoginAt     *time.Time `json:"last_login_at,omitempty"`
+	LoginCount      int        `json:"login_count"`
+	DisabledAt      *time.Time `json:"disabled_at,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+}
+
+// NewProfile copies the exportable fields of user
+func NewProfile(user *auth.User) Profile {
+	return Profile{
+		ID:              user.ID,
+		Email:           user.Email,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+		LastLoginAt:     user.LastLoginAt,
+		LoginCount:      user.LoginCount,
+		DisabledAt:      user.DisabledAt,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+	}
+}
+
+// AuditEvent is something that happened to the account
+type AuditEvent struct {
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	SessionID  string    `json:"session_id,omitempty"`
+	IPAddress  string    `json:"ip_address,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+}
+
+// Exporter builds personal data export archives
+type Exporter struct {
+	users    UserSource
+	sessions SessionSource
+	todos    TodoSource
+}
+
+// NewExporter creates a new exporter reading from the given sources
+func NewExporter(users UserSource, sessions SessionSource, todos TodoSource) *Exporter {
+	return &Exporter{
+		users:    users,
+		sessions: sessions,
+		todos:    todos,
+	}
+}
+
+// NewExporterWithDB creates a new exporter with database connection
+func NewExporterWithDB(db *pgxpool.Pool) *Exporter {
+	return NewExporter(
+		auth.NewUserRepository(db),
+		auth.NewSessionRepository(db),
+		todo.NewTodoRepository(db),
+	)
+}
+
+// WriteArchive streams a zip of the user's profile, todos, sessions and audit events to w.
+// Todos are read a page at a time, so large accounts are never held in memory at once.
+func (e *Exporter) WriteArchive(ctx context.Context, w io.Writer, userID int) error {
+	user, err := e.users.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+
+	sessions, err := e.sessions.ListByUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+	if sessions == nil {
+		sessions = []*auth.Session{}
+	}
+
+	zw := zip.NewWriter(w)
+	now := time.Now()
+
+	if err := writeJSONFile(zw, ProfileFile, now, NewProfile(user)); err != nil {
+		return err
+	}
+
+	todos, err := createFile(zw, TodosFile, now)
+	if err != nil {
+		return err
+	}
+	if err := e.writeTodos(ctx, todos, userID); err != nil {
+		return fmt.Errorf("failed to write %s: %w", TodosFile, err)
+	}
+
+	if err := writeJSONFile(zw, SessionsFile, now, sessions); err != nil {
+		return err
+	}
+
+	if err := writeJSONFile(zw, AuditEventsFile, now, AuditEvents(user, sessions)); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// writeTodos writes the user's todos to w as a JSON array, one page at a time
+func (e *Exporter) writeTodos(ctx context.Context, w io.Writer, userID int) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	count := 0
+	for offset := 0; ; offset += pageSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		page, err := e.todos.GetByUserID(ctx, userID, todo.TodoFilter{Limit: pageSize, Offset: offset})
+		if err != nil {
+			return err
+		}
+
+		for _, t := range page.Todos {
+			separator := ",\n  "
+			if count == 0 {
+				separator = "\n  "
+			}
+
+			data, err := json.MarshalIndent(t, "  ", "  ")
+			if err != nil {
+				return err
+			}
+
+			if _, err := io.WriteString(w, separator); err != nil {
+				return err
+			}
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+			count++
+		}
+
+		if !page.HasMore || len(page.Todos) == 0 {
+			break
+		}
+	}
+
+	end := "\n]\n"
+	if count == 0 {
+		end = "]\n"
+	}
+	_, err := io.WriteString(w, end)
+	return err
+}
+
+// AuditEvents lists what happened to the account, oldest first.
+// There is no separate audit log; events are reconstructed from the timestamps stored with the account and its sessions.
+func AuditEvents(user *auth.User, sessions []*auth.Session) []AuditEvent {
+	events := []AuditEvent{{Type: EventAccountCreated, OccurredAt: user.CreatedAt}}
+
+	if user.EmailVerifiedAt != nil {
+		events = append(events, AuditEvent{Type: EventEmailVerified, OccurredAt: *user.EmailVerifiedAt})
+	}
+
+	if user.DisabledAt != nil {
+		events = append(events, AuditEvent{Type: EventAccountDisabled, OccurredAt: *user.DisabledAt})
+	}
+
+	for _, session := range sessions {
+		events = append(events, AuditEvent{
+			Type:       EventSessionStarted,
+			OccurredAt: session.CreatedAt,
+			SessionID:  session.ID,
+			IPAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+		})
+
+		if session.RevokedAt != nil {
+			events = append(events, AuditEvent{
+				Type:       EventSessionRevoked,
+				OccurredAt: *session.RevokedAt,
+				SessionID:  session.ID,
+			})
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].OccurredAt.Before(events[j].OccurredAt)
+	})
+
+	return events
+}
+
+// createFile starts a new compressed file in the archive
+func createFile(zw *zip.Writer, name string, modified time.Time) (io.Writer, error) {
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", name, err)
+	}
+
+	return w, nil
+}
+
+// writeJSONFile adds a file containing v as indented JSON to the archive
+func writeJSONFile(zw *zip.Writer, name string, modified time.Time, v any) error {
+	w, err := createFile(zw, name, modified)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	return nil
+}
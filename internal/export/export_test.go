@@ -0,0 +1,275 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jayk0001/my-go-next-todo/internal/auth"
+	"github.com/jayk0001/my-go-next-todo/internal/todo"
+)
+
+// mockUsers implements UserSource for testing
+type mockUsers struct {
+	users map[int]*auth.User
+}
+
+func (m *mockUsers) GetByID(ctx context.Context, id int) (*auth.User, error) {
+	user, exists := m.users[id]
+	if !exists {
+		return nil, auth.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// mockSessions implements SessionSource for testing
+type mockSessions struct {
+	sessions []*auth.Session
+}
+
+func (m *mockSessions) ListByUser(ctx context.Context, userID int) ([]*auth.Session, error) {
+	var sessions []*auth.Session
+	for _, session := range m.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+// mockTodos implements TodoSource for testing, recording the pages requested
+type mockTodos struct {
+	todos   []*todo.Todo
+	filters []todo.TodoFilter
+	err     error
+}
+
+func (m *mockTodos) GetByUserID(ctx context.Context, userID int, filter todo.TodoFilter) (*todo.TodoListResponse, error) {
+	m.filters = append(m.filters, filter)
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	var owned []*todo.Todo
+	for _, t := range m.todos {
+		if t.UserID == userID {
+			owned = append(owned, t)
+		}
+	}
+
+	start := min(filter.Offset, len(owned))
+	end := min(start+filter.Limit, len(owned))
+
+	return &todo.TodoListResponse{
+		Todos:   owned[start:end],
+		Total:   len(owned),
+		Limit:   filter.Limit,
+		Offset:  filter.Offset,
+		HasMore: end < len(owned),
+	}, nil
+}
+
+// newTestExporter creates an exporter for user 1 with todoCount todos and two sessions, one revoked
+func newTestExporter(todoCount int) (*Exporter, *mockTodos) {
+	created := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	verified := created.Add(time.Hour)
+	revoked := created.Add(3 * time.Hour)
+
+	users := &mockUsers{users: map[int]*auth.User{
+		1: {
+			ID:              1,
+			Email:           "test@example.com",
+			PasswordHash:    "$argon2id$secret-hash",
+			CreatedAt:       created,
+			UpdatedAt:       created,
+			EmailVerifiedAt: &verified,
+		},
+	}}
+
+	sessions := &mockSessions{sessions: []*auth.Session{
+		{ID: "session-1", UserID: 1, IPAddress: "192.0.2.1", CreatedAt: created.Add(2 * time.Hour), RevokedAt: &revoked},
+		{ID: "session-2", UserID: 1, IPAddress: "192.0.2.2", CreatedAt: created.Add(4 * time.Hour)},
+		{ID: "session-3", UserID: 2, CreatedAt: created},
+	}}
+
+	todos := &mockTodos{}
+	for i := 1; i <= todoCount; i++ {
+		todos.todos = append(todos.todos, &todo.Todo{ID: i, UserID: 1, Title: fmt.Sprintf("Todo %d", i)})
+	}
+	todos.todos = append(todos.todos, &todo.Todo{ID: 1000, UserID: 2, Title: "Someone else's todo"})
+
+	return NewExporter(users, sessions, todos), todos
+}
+
+// readArchive returns the contents of every file in a zip archive
+func readArchive(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", f.Name, err)
+		}
+		files[f.Name] = content
+	}
+
+	return files
+}
+
+// TestWriteArchive tests the contents of an export archive
+func TestWriteArchive(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("contains every file", func(t *testing.T) {
+		exporter, _ := newTestExporter(3)
+
+		var buf bytes.Buffer
+		if err := exporter.WriteArchive(ctx, &buf, 1); err != nil {
+			t.Fatalf("WriteArchive failed: %v", err)
+		}
+
+		files := readArchive(t, buf.Bytes())
+		for _, name := range []string{ProfileFile, TodosFile, SessionsFile, AuditEventsFile} {
+			if _, ok := files[name]; !ok {
+				t.Errorf("Expected %s in the archive", name)
+			}
+		}
+
+		var profile map[string]any
+		if err := json.Unmarshal(files[ProfileFile], &profile); err != nil {
+			t.Fatalf("Failed to parse profile: %v", err)
+		}
+		if profile["email"] != "test@example.com" {
+			t.Errorf("Expected email test@example.com, got %v", profile["email"])
+		}
+		for key := range profile {
+			if strings.Contains(strings.ToLower(key), "password") {
+				t.Errorf("Expected no password field in profile, got %q", key)
+			}
+		}
+		if bytes.Contains(buf.Bytes(), []byte("secret-hash")) {
+			t.Error("Expected the password hash to be left out of the archive")
+		}
+
+		var sessions []auth.Session
+		if err := json.Unmarshal(files[SessionsFile], &sessions); err != nil {
+			t.Fatalf("Failed to parse sessions: %v", err)
+		}
+		if len(sessions) != 2 {
+			t.Errorf("Expected 2 sessions, got %d", len(sessions))
+		}
+	})
+
+	t.Run("todos are read in pages", func(t *testing.T) {
+		exporter, todos := newTestExporter(2*pageSize + 5)
+
+		var buf bytes.Buffer
+		if err := exporter.WriteArchive(ctx, &buf, 1); err != nil {
+			t.Fatalf("WriteArchive failed: %v", err)
+		}
+
+		if len(todos.filters) != 3 {
+			t.Fatalf("Expected 3 page queries, got %d", len(todos.filters))
+		}
+		for i, filter := range todos.filters {
+			if filter.Limit != pageSize || filter.Offset != i*pageSize {
+				t.Errorf("Page %d: expected limit %d offset %d, got %d/%d", i, pageSize, i*pageSize, filter.Limit, filter.Offset)
+			}
+		}
+
+		var exported []todo.Todo
+		if err := json.Unmarshal(readArchive(t, buf.Bytes())[TodosFile], &exported); err != nil {
+			t.Fatalf("Failed to parse todos: %v", err)
+		}
+		if len(exported) != 2*pageSize+5 {
+			t.Fatalf("Expected %d todos, got %d", 2*pageSize+5, len(exported))
+		}
+		for i, td := range exported {
+			if td.ID != i+1 {
+				t.Fatalf("Expected todo %d at position %d, got %d", i+1, i, td.ID)
+			}
+		}
+	})
+
+	t.Run("no todos", func(t *testing.T) {
+		exporter, _ := newTestExporter(0)
+
+		var buf bytes.Buffer
+		if err := exporter.WriteArchive(ctx, &buf, 1); err != nil {
+			t.Fatalf("WriteArchive failed: %v", err)
+		}
+
+		var exported []todo.Todo
+		if err := json.Unmarshal(readArchive(t, buf.Bytes())[TodosFile], &exported); err != nil {
+			t.Fatalf("Failed to parse todos: %v", err)
+		}
+		if exported == nil || len(exported) != 0 {
+			t.Errorf("Expected an empty array, got %v", exported)
+		}
+	})
+
+	t.Run("unknown user writes nothing", func(t *testing.T) {
+		exporter, _ := newTestExporter(1)
+
+		var buf bytes.Buffer
+		if err := exporter.WriteArchive(ctx, &buf, 999); !errors.Is(err, auth.ErrUserNotFound) {
+			t.Errorf("Expected ErrUserNotFound, got %v", err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("Expected nothing written, got %d bytes", buf.Len())
+		}
+	})
+
+	t.Run("todo query failure", func(t *testing.T) {
+		exporter, todos := newTestExporter(1)
+		todos.err = errors.New("database error")
+
+		if err := exporter.WriteArchive(ctx, io.Discard, 1); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
+
+// TestAuditEvents tests reconstructing account history
+func TestAuditEvents(t *testing.T) {
+	exporter, _ := newTestExporter(0)
+	user, _ := exporter.users.GetByID(context.Background(), 1)
+	sessions, _ := exporter.sessions.ListByUser(context.Background(), 1)
+
+	events := AuditEvents(user, sessions)
+
+	expected := []string{EventAccountCreated, EventEmailVerified, EventSessionStarted, EventSessionRevoked, EventSessionStarted}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d: %+v", len(expected), len(events), events)
+	}
+	for i, event := range events {
+		if event.Type != expected[i] {
+			t.Errorf("Event %d: expected %s, got %s", i, expected[i], event.Type)
+		}
+		if i > 0 && event.OccurredAt.Before(events[i-1].OccurredAt) {
+			t.Errorf("Event %d is out of order", i)
+		}
+	}
+
+	if events[3].SessionID != "session-1" {
+		t.Errorf("Expected the revocation of session-1, got %q", events[3].SessionID)
+	}
+}
@@ -2,10 +2,24 @@
 
 package model
 
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+type LoginResult interface {
+	IsLoginResult()
+}
+
 type AuthInfo struct {
-	LoginCount     int        `json:"loginCount"`
-	LastLoginAt    *string    `json:"lastLoginAt,omitempty"`
-	ActiveSessions []*Session `json:"activeSessions"`
+	LoginCount             int        `json:"loginCount"`
+	LastLoginAt            *string    `json:"lastLoginAt,omitempty"`
+	ActiveSessions         []*Session `json:"activeSessions"`
+	TwoFactorEnabled       bool       `json:"twoFactorEnabled"`
+	RecoveryCodesRemaining int        `json:"recoveryCodesRemaining"`
 }
 
 type AuthPayload struct {
@@ -15,14 +29,49 @@ type AuthPayload struct {
 	ExpiresAt    string `json:"expiresAt"`
 }
 
+func (AuthPayload) IsLoginResult() {}
+
+type AuthStatusEvent struct {
+	Reason     AuthStatusReason `json:"reason"`
+	SessionID  *string          `json:"sessionId,omitempty"`
+	OccurredAt string           `json:"occurredAt"`
+}
+
 type BatchUpdateInput struct {
 	TodoIds []string         `json:"todoIds"`
 	Updates *UpdateTodoInput `json:"updates"`
 }
 
+type CreatePersonalAccessTokenInput struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+type CreateProjectInput struct {
+	Name     string  `json:"name"`
+	Color    *string `json:"color,omitempty"`
+	Position *int    `json:"position,omitempty"`
+}
+
+type CreateTagInput struct {
+	Name  string  `json:"name"`
+	Color *string `json:"color,omitempty"`
+}
+
 type CreateTodoInput struct {
-	Title       string  `json:"title"`
-	Description *string `json:"description,omitempty"`
+	Title       string        `json:"title"`
+	Description *string       `json:"description,omitempty"`
+	DueAt       *time.Time    `json:"dueAt,omitempty"`
+	AllDay      *bool         `json:"allDay,omitempty"`
+	Priority    *TodoPriority `json:"priority,omitempty"`
+	ProjectID   *string       `json:"projectId,omitempty"`
+	ParentID    *string       `json:"parentId,omitempty"`
+}
+
+type DataExport struct {
+	DownloadURL string    `json:"downloadUrl"`
+	ExpiresAt   time.Time `json:"expiresAt"`
 }
 
 type LoginInput struct {
@@ -33,6 +82,33 @@ type LoginInput struct {
 type Mutation struct {
 }
 
+type PersonalAccessToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+type PersonalAccessTokenPayload struct {
+	Token               string               `json:"token"`
+	PersonalAccessToken *PersonalAccessToken `json:"personalAccessToken"`
+}
+
+type Project struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Color     string            `json:"color"`
+	Archived  bool              `json:"archived"`
+	Position  int               `json:"position"`
+	IsInbox   bool              `json:"isInbox"`
+	Todos     *TodoListResponse `json:"todos"`
+	Stats     *TodoStats        `json:"stats"`
+	CreatedAt string            `json:"createdAt"`
+	UpdatedAt string            `json:"updatedAt"`
+}
+
 type Query struct {
 }
 
@@ -52,21 +128,53 @@ type Session struct {
 type Subscription struct {
 }
 
+type Tag struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Color     string `json:"color"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
 type Todo struct {
-	ID          string  `json:"id"`
-	Title       string  `json:"title"`
-	Description *string `json:"description,omitempty"`
-	Completed   bool    `json:"completed"`
-	CreatedAt   string  `json:"createdAt"`
-	UpdatedAt   string  `json:"updatedAt"`
-	User        *User   `json:"user"`
+	ID          string        `json:"id"`
+	Title       string        `json:"title"`
+	Description *string       `json:"description,omitempty"`
+	Completed   bool          `json:"completed"`
+	DueAt       *time.Time    `json:"dueAt,omitempty"`
+	AllDay      bool          `json:"allDay"`
+	Priority    TodoPriority  `json:"priority"`
+	Project     *Project      `json:"project"`
+	Tags        []*Tag        `json:"tags"`
+	ParentID    *string       `json:"parentId,omitempty"`
+	Parent      *Todo         `json:"parent,omitempty"`
+	Children    []*Todo       `json:"children"`
+	Progress    *TodoProgress `json:"progress"`
+	CreatedAt   string        `json:"createdAt"`
+	UpdatedAt   string        `json:"updatedAt"`
+	User        *User         `json:"user"`
+}
+
+type TodoEvent struct {
+	Kind   TodoEventKind `json:"kind"`
+	TodoID string        `json:"todoId"`
+	Todo   *Todo         `json:"todo,omitempty"`
 }
 
 type TodoFilter struct {
-	Completed *bool   `json:"completed,omitempty"`
-	Search    *string `json:"search,omitempty"`
-	Limit     *int    `json:"limit,omitempty"`
-	Offset    *int    `json:"offset,omitempty"`
+	ProjectID  *string        `json:"projectId,omitempty"`
+	Completed  *bool          `json:"completed,omitempty"`
+	Search     *string        `json:"search,omitempty"`
+	DueBefore  *time.Time     `json:"dueBefore,omitempty"`
+	DueAfter   *time.Time     `json:"dueAfter,omitempty"`
+	Overdue    *bool          `json:"overdue,omitempty"`
+	DueToday   *bool          `json:"dueToday,omitempty"`
+	Priorities []TodoPriority `json:"priorities,omitempty"`
+	TagsAny    []string       `json:"tagsAny,omitempty"`
+	TagsAll    []string       `json:"tagsAll,omitempty"`
+	TopLevel   *bool          `json:"topLevel,omitempty"`
+	Limit      *int           `json:"limit,omitempty"`
+	Offset     *int           `json:"offset,omitempty"`
 }
 
 type TodoListResponse struct {
@@ -77,23 +185,477 @@ type TodoListResponse struct {
 	HasMore bool    `json:"hasMore"`
 }
 
+type TodoProgress struct {
+	Completed int `json:"completed"`
+	Total     int `json:"total"`
+}
+
+type TodoSort struct {
+	Field     TodoSortField `json:"field"`
+	Direction SortDirection `json:"direction"`
+}
+
 type TodoStats struct {
 	Total     int `json:"total"`
 	Completed int `json:"completed"`
 	Pending   int `json:"pending"`
+	Overdue   int `json:"overdue"`
+}
+
+type TwoFactorChallenge struct {
+	Challenge string    `json:"challenge"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (TwoFactorChallenge) IsLoginResult() {}
+
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauthUri"`
+}
+
+type UpdateProjectInput struct {
+	Name     *string `json:"name,omitempty"`
+	Color    *string `json:"color,omitempty"`
+	Archived *bool   `json:"archived,omitempty"`
+	Position *int    `json:"position,omitempty"`
+}
+
+type UpdateTagInput struct {
+	Name  *string `json:"name,omitempty"`
+	Color *string `json:"color,omitempty"`
 }
 
 type UpdateTodoInput struct {
-	Title       *string `json:"title,omitempty"`
-	Description *string `json:"description,omitempty"`
-	Completed   *bool   `json:"completed,omitempty"`
+	Title        *string       `json:"title,omitempty"`
+	Description  *string       `json:"description,omitempty"`
+	Completed    *bool         `json:"completed,omitempty"`
+	DueAt        *time.Time    `json:"dueAt,omitempty"`
+	AllDay       *bool         `json:"allDay,omitempty"`
+	ClearDueAt   *bool         `json:"clearDueAt,omitempty"`
+	Priority     *TodoPriority `json:"priority,omitempty"`
+	ProjectID    *string       `json:"projectId,omitempty"`
+	ParentID     *string       `json:"parentId,omitempty"`
+	ClearParent  *bool         `json:"clearParent,omitempty"`
+	AddTagIds    []string      `json:"addTagIds,omitempty"`
+	RemoveTagIds []string      `json:"removeTagIds,omitempty"`
 }
 
 type User struct {
-	ID          string    `json:"id"`
-	Email       string    `json:"email"`
-	CreatedAt   string    `json:"createdAt"`
-	UpdatedAt   string    `json:"updatedAt"`
-	LastLoginAt *string   `json:"lastLoginAt,omitempty"`
-	AuthInfo    *AuthInfo `json:"authInfo,omitempty"`
+	ID            string    `json:"id"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"emailVerified"`
+	Role          Role      `json:"role"`
+	TimeZone      string    `json:"timeZone"`
+	CreatedAt     string    `json:"createdAt"`
+	UpdatedAt     string    `json:"updatedAt"`
+	LastLoginAt   *string   `json:"lastLoginAt,omitempty"`
+	AuthInfo      *AuthInfo `json:"authInfo,omitempty"`
+}
+
+type AuthStatusReason string
+
+const (
+	AuthStatusReasonSessionRevoked  AuthStatusReason = "SESSION_REVOKED"
+	AuthStatusReasonPasswordChanged AuthStatusReason = "PASSWORD_CHANGED"
+	AuthStatusReasonAccountDisabled AuthStatusReason = "ACCOUNT_DISABLED"
+	AuthStatusReasonAccountDeleted  AuthStatusReason = "ACCOUNT_DELETED"
+)
+
+var AllAuthStatusReason = []AuthStatusReason{
+	AuthStatusReasonSessionRevoked,
+	AuthStatusReasonPasswordChanged,
+	AuthStatusReasonAccountDisabled,
+	AuthStatusReasonAccountDeleted,
+}
+
+func (e AuthStatusReason) IsValid() bool {
+	switch e {
+	case AuthStatusReasonSessionRevoked, AuthStatusReasonPasswordChanged, AuthStatusReasonAccountDisabled, AuthStatusReasonAccountDeleted:
+		return true
+	}
+	return false
+}
+
+func (e AuthStatusReason) String() string {
+	return string(e)
+}
+
+func (e *AuthStatusReason) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AuthStatusReason(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AuthStatusReason", str)
+	}
+	return nil
+}
+
+func (e AuthStatusReason) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *AuthStatusReason) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e AuthStatusReason) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type DeleteMode string
+
+const (
+	DeleteModeCascade  DeleteMode = "CASCADE"
+	DeleteModeReparent DeleteMode = "REPARENT"
+)
+
+var AllDeleteMode = []DeleteMode{
+	DeleteModeCascade,
+	DeleteModeReparent,
+}
+
+func (e DeleteMode) IsValid() bool {
+	switch e {
+	case DeleteModeCascade, DeleteModeReparent:
+		return true
+	}
+	return false
+}
+
+func (e DeleteMode) String() string {
+	return string(e)
+}
+
+func (e *DeleteMode) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = DeleteMode(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid DeleteMode", str)
+	}
+	return nil
+}
+
+func (e DeleteMode) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *DeleteMode) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e DeleteMode) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type Role string
+
+const (
+	RoleUser  Role = "USER"
+	RoleAdmin Role = "ADMIN"
+)
+
+var AllRole = []Role{
+	RoleUser,
+	RoleAdmin,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RoleUser, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *Role) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e Role) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type SortDirection string
+
+const (
+	SortDirectionAsc  SortDirection = "ASC"
+	SortDirectionDesc SortDirection = "DESC"
+)
+
+var AllSortDirection = []SortDirection{
+	SortDirectionAsc,
+	SortDirectionDesc,
+}
+
+func (e SortDirection) IsValid() bool {
+	switch e {
+	case SortDirectionAsc, SortDirectionDesc:
+		return true
+	}
+	return false
+}
+
+func (e SortDirection) String() string {
+	return string(e)
+}
+
+func (e *SortDirection) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SortDirection(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SortDirection", str)
+	}
+	return nil
+}
+
+func (e SortDirection) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *SortDirection) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e SortDirection) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type TodoEventKind string
+
+const (
+	TodoEventKindCreated      TodoEventKind = "CREATED"
+	TodoEventKindUpdated      TodoEventKind = "UPDATED"
+	TodoEventKindDeleted      TodoEventKind = "DELETED"
+	TodoEventKindToggled      TodoEventKind = "TOGGLED"
+	TodoEventKindBatchUpdated TodoEventKind = "BATCH_UPDATED"
+)
+
+var AllTodoEventKind = []TodoEventKind{
+	TodoEventKindCreated,
+	TodoEventKindUpdated,
+	TodoEventKindDeleted,
+	TodoEventKindToggled,
+	TodoEventKindBatchUpdated,
+}
+
+func (e TodoEventKind) IsValid() bool {
+	switch e {
+	case TodoEventKindCreated, TodoEventKindUpdated, TodoEventKindDeleted, TodoEventKindToggled, TodoEventKindBatchUpdated:
+		return true
+	}
+	return false
+}
+
+func (e TodoEventKind) String() string {
+	return string(e)
+}
+
+func (e *TodoEventKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TodoEventKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TodoEventKind", str)
+	}
+	return nil
+}
+
+func (e TodoEventKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *TodoEventKind) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e TodoEventKind) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type TodoPriority string
+
+const (
+	TodoPriorityNone   TodoPriority = "NONE"
+	TodoPriorityLow    TodoPriority = "LOW"
+	TodoPriorityMedium TodoPriority = "MEDIUM"
+	TodoPriorityHigh   TodoPriority = "HIGH"
+	TodoPriorityUrgent TodoPriority = "URGENT"
+)
+
+var AllTodoPriority = []TodoPriority{
+	TodoPriorityNone,
+	TodoPriorityLow,
+	TodoPriorityMedium,
+	TodoPriorityHigh,
+	TodoPriorityUrgent,
+}
+
+func (e TodoPriority) IsValid() bool {
+	switch e {
+	case TodoPriorityNone, TodoPriorityLow, TodoPriorityMedium, TodoPriorityHigh, TodoPriorityUrgent:
+		return true
+	}
+	return false
+}
+
+func (e TodoPriority) String() string {
+	return string(e)
+}
+
+func (e *TodoPriority) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TodoPriority(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TodoPriority", str)
+	}
+	return nil
+}
+
+func (e TodoPriority) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *TodoPriority) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e TodoPriority) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type TodoSortField string
+
+const (
+	TodoSortFieldPriority TodoSortField = "PRIORITY"
+	TodoSortFieldDue      TodoSortField = "DUE"
+	TodoSortFieldCreated  TodoSortField = "CREATED"
+	TodoSortFieldUpdated  TodoSortField = "UPDATED"
+	TodoSortFieldTitle    TodoSortField = "TITLE"
+)
+
+var AllTodoSortField = []TodoSortField{
+	TodoSortFieldPriority,
+	TodoSortFieldDue,
+	TodoSortFieldCreated,
+	TodoSortFieldUpdated,
+	TodoSortFieldTitle,
+}
+
+func (e TodoSortField) IsValid() bool {
+	switch e {
+	case TodoSortFieldPriority, TodoSortFieldDue, TodoSortFieldCreated, TodoSortFieldUpdated, TodoSortFieldTitle:
+		return true
+	}
+	return false
+}
+
+func (e TodoSortField) String() string {
+	return string(e)
+}
+
+func (e *TodoSortField) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TodoSortField(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TodoSortField", str)
+	}
+	return nil
+}
+
+func (e TodoSortField) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *TodoSortField) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e TodoSortField) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
 }
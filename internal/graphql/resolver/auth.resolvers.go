@@ -2,13 +2,16 @@ package resolver
 
 // This file will be automatically regenerated based on the schema, any resolver implementations
 // will be copied through when generating and any unknown code will be moved to the end.
-// Code generated by github.com/99designs/gqlgen version v0.17.81
+// Code generated by github.com/99designs/gqlgen version v0.17.82
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 
+	"github.com/jayk0001/my-go-next-todo/internal/auth"
+	"github.com/jayk0001/my-go-next-todo/internal/export"
 	"github.com/jayk0001/my-go-next-todo/internal/graphql/generated"
 	"github.com/jayk0001/my-go-next-todo/internal/graphql/model"
 	"github.com/jayk0001/my-go-next-todo/internal/middleware"
@@ -19,6 +22,10 @@ func (r *mutationResolver) Register(ctx context.Context, input model.RegisterInp
 	// Use the auth service to register a new user
 	authResult, err := r.AuthService.Register(ctx, input.Email, input.Password)
 	if err != nil {
+		// The account was created but has to be verified before signing in
+		if errors.Is(err, auth.ErrEmailNotVerified) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("registration failed: %w", err)
 	}
 
@@ -27,10 +34,15 @@ func (r *mutationResolver) Register(ctx context.Context, input model.RegisterInp
 }
 
 // Login is the resolver for the login field.
-func (r *mutationResolver) Login(ctx context.Context, input model.LoginInput) (*model.AuthPayload, error) {
+func (r *mutationResolver) Login(ctx context.Context, input model.LoginInput) (model.LoginResult, error) {
 	// Use the auth service to authenticate user
 	authResult, err := r.AuthService.Login(ctx, input.Email, input.Password)
 	if err != nil {
+		// The password was right; the client continues with verifyTwoFactor
+		var required *auth.TwoFactorRequiredError
+		if errors.As(err, &required) {
+			return required.ToGraphQLTwoFactorChallenge(), nil
+		}
 		return nil, fmt.Errorf("login failed: %w", err)
 	}
 
@@ -38,6 +50,39 @@ func (r *mutationResolver) Login(ctx context.Context, input model.LoginInput) (*
 	return authResult.ToGraphQLAuthPayload(), nil
 }
 
+// VerifyTwoFactor is the resolver for the verifyTwoFactor field.
+func (r *mutationResolver) VerifyTwoFactor(ctx context.Context, challenge string, code string) (*model.AuthPayload, error) {
+	authResult, err := r.AuthService.VerifyTwoFactor(ctx, challenge, code)
+	if err != nil {
+		return nil, fmt.Errorf("login failed: %w", err)
+	}
+
+	return authResult.ToGraphQLAuthPayload(), nil
+}
+
+// RequestMagicLink is the resolver for the requestMagicLink field.
+func (r *mutationResolver) RequestMagicLink(ctx context.Context, email string) (bool, error) {
+	if err := r.AuthService.RequestMagicLink(ctx, email); err != nil {
+		return false, fmt.Errorf("failed to request sign-in link: %w", err)
+	}
+
+	return true, nil
+}
+
+// ConsumeMagicLink is the resolver for the consumeMagicLink field.
+func (r *mutationResolver) ConsumeMagicLink(ctx context.Context, token string) (model.LoginResult, error) {
+	authResult, err := r.AuthService.ConsumeMagicLink(ctx, token)
+	if err != nil {
+		var required *auth.TwoFactorRequiredError
+		if errors.As(err, &required) {
+			return required.ToGraphQLTwoFactorChallenge(), nil
+		}
+		return nil, fmt.Errorf("login failed: %w", err)
+	}
+
+	return authResult.ToGraphQLAuthPayload(), nil
+}
+
 // RefreshToken is the resolver for the refreshToken field.
 func (r *mutationResolver) RefreshToken(ctx context.Context, token string) (*model.AuthPayload, error) {
 	// Use the auth service to refresh token
@@ -52,11 +97,311 @@ func (r *mutationResolver) RefreshToken(ctx context.Context, token string) (*mod
 
 // Logout is the resolver for the logout field.
 func (r *mutationResolver) Logout(ctx context.Context) (bool, error) {
-	// For now, logous is just a simple success response
-	// TODO: Implement actual logout logic (token blacklist, session invalidation)
+	principal, ok := middleware.GetPrincipalFromContext(ctx)
+	if !ok {
+		return false, fmt.Errorf("user not authenticated")
+	}
+
+	if err := r.AuthService.Logout(ctx, principal); err != nil {
+		return false, fmt.Errorf("logout failed: %w", err)
+	}
+
+	return true, nil
+}
+
+// RevokeSession is the resolver for the revokeSession field.
+func (r *mutationResolver) RevokeSession(ctx context.Context, id string) (bool, error) {
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return false, fmt.Errorf("user not authenticated")
+	}
+
+	if err := r.AuthService.RevokeSession(ctx, user.ID, id); err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return true, nil
+}
+
+// RevokeOtherSessions is the resolver for the revokeOtherSessions field.
+func (r *mutationResolver) RevokeOtherSessions(ctx context.Context) (int, error) {
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return 0, fmt.Errorf("user not authenticated")
+	}
+
+	// Tokens without a session keep nothing, so every session is revoked
+	sessionID, _ := middleware.GetSessionIDFromContext(ctx)
+
+	revoked, err := r.AuthService.RevokeOtherSessions(ctx, user.ID, sessionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return revoked, nil
+}
+
+// RequestPasswordReset is the resolver for the requestPasswordReset field.
+func (r *mutationResolver) RequestPasswordReset(ctx context.Context, email string) (bool, error) {
+	if err := r.AuthService.RequestPasswordReset(ctx, email); err != nil {
+		return false, fmt.Errorf("failed to request password reset: %w", err)
+	}
+
+	return true, nil
+}
+
+// ResetPassword is the resolver for the resetPassword field.
+func (r *mutationResolver) ResetPassword(ctx context.Context, token string, newPassword string) (bool, error) {
+	if err := r.AuthService.ResetPassword(ctx, token, newPassword); err != nil {
+		return false, fmt.Errorf("password reset failed: %w", err)
+	}
+
+	return true, nil
+}
+
+// VerifyEmail is the resolver for the verifyEmail field.
+func (r *mutationResolver) VerifyEmail(ctx context.Context, token string) (*model.User, error) {
+	user, err := r.AuthService.VerifyEmail(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	return user.ToGraphQLUser(), nil
+}
+
+// ResendVerification is the resolver for the resendVerification field.
+func (r *mutationResolver) ResendVerification(ctx context.Context, email *string) (bool, error) {
+	if user, ok := middleware.GetUserFromContext(ctx); ok {
+		if err := r.AuthService.ResendVerification(ctx, user.ID); err != nil {
+			return false, fmt.Errorf("failed to resend verification: %w", err)
+		}
+		return true, nil
+	}
+
+	if email == nil {
+		return false, fmt.Errorf("email is required when not authenticated")
+	}
+
+	if err := r.AuthService.ResendVerificationByEmail(ctx, *email); err != nil {
+		return false, fmt.Errorf("failed to resend verification: %w", err)
+	}
+
+	return true, nil
+}
+
+// EnrollTwoFactor is the resolver for the enrollTwoFactor field.
+func (r *mutationResolver) EnrollTwoFactor(ctx context.Context) (*model.TwoFactorEnrollment, error) {
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("user not authenticated")
+	}
+
+	enrollment, err := r.AuthService.EnrollTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enroll two-factor: %w", err)
+	}
+
+	return enrollment.ToGraphQLTwoFactorEnrollment(), nil
+}
+
+// ConfirmTwoFactor is the resolver for the confirmTwoFactor field.
+func (r *mutationResolver) ConfirmTwoFactor(ctx context.Context, code string) ([]string, error) {
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("user not authenticated")
+	}
+
+	codes, err := r.AuthService.ConfirmTOTP(ctx, user.ID, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm two-factor: %w", err)
+	}
+
+	return codes, nil
+}
+
+// DisableTwoFactor is the resolver for the disableTwoFactor field.
+func (r *mutationResolver) DisableTwoFactor(ctx context.Context, password *string) (bool, error) {
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return false, fmt.Errorf("user not authenticated")
+	}
+
+	sessionID, _ := middleware.GetSessionIDFromContext(ctx)
+
+	if err := r.AuthService.DisableTwoFactor(ctx, user.ID, sessionID, stringValue(password)); err != nil {
+		return false, fmt.Errorf("failed to disable two-factor: %w", err)
+	}
+
+	return true, nil
+}
+
+// ChangePassword is the resolver for the changePassword field.
+func (r *mutationResolver) ChangePassword(ctx context.Context, currentPassword string, newPassword string) (bool, error) {
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return false, fmt.Errorf("user not authenticated")
+	}
+
+	// The session making the change stays signed in
+	sessionID, _ := middleware.GetSessionIDFromContext(ctx)
+
+	if err := r.AuthService.ChangePassword(ctx, user.ID, sessionID, currentPassword, newPassword); err != nil {
+		return false, fmt.Errorf("failed to change password: %w", err)
+	}
+
+	return true, nil
+}
+
+// ChangeEmail is the resolver for the changeEmail field.
+func (r *mutationResolver) ChangeEmail(ctx context.Context, newEmail string, password *string) (*model.User, error) {
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("user not authenticated")
+	}
+
+	sessionID, _ := middleware.GetSessionIDFromContext(ctx)
+
+	updated, err := r.AuthService.ChangeEmail(ctx, user.ID, sessionID, newEmail, stringValue(password))
+	if err != nil {
+		return nil, fmt.Errorf("failed to change email: %w", err)
+	}
+
+	return updated.ToGraphQLUser(), nil
+}
+
+// UpdateTimeZone is the resolver for the updateTimeZone field.
+func (r *mutationResolver) UpdateTimeZone(ctx context.Context, timeZone string) (*model.User, error) {
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("user not authenticated")
+	}
+
+	updated, err := r.AuthService.UpdateTimeZone(ctx, user.ID, timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update time zone: %w", err)
+	}
+
+	return updated.ToGraphQLUser(), nil
+}
+
+// DeleteAccount is the resolver for the deleteAccount field.
+func (r *mutationResolver) DeleteAccount(ctx context.Context, password *string) (bool, error) {
+	principal, ok := middleware.GetPrincipalFromContext(ctx)
+	if !ok {
+		return false, fmt.Errorf("user not authenticated")
+	}
+
+	if err := r.AuthService.DeleteAccount(ctx, principal, stringValue(password)); err != nil {
+		return false, fmt.Errorf("failed to delete account: %w", err)
+	}
+
 	return true, nil
 }
 
+// RequestDataExport is the resolver for the requestDataExport field.
+func (r *mutationResolver) RequestDataExport(ctx context.Context) (*model.DataExport, error) {
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("user not authenticated")
+	}
+
+	grant, err := r.AuthService.RequestDataExport(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request data export: %w", err)
+	}
+
+	return grant.ToGraphQLDataExport(export.DownloadPath), nil
+}
+
+// CreatePersonalAccessToken is the resolver for the createPersonalAccessToken field.
+func (r *mutationResolver) CreatePersonalAccessToken(ctx context.Context, input model.CreatePersonalAccessTokenInput) (*model.PersonalAccessTokenPayload, error) {
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("user not authenticated")
+	}
+
+	record, token, err := r.AuthService.CreatePersonalAccessToken(ctx, user.ID, auth.CreatePersonalAccessTokenInput{
+		Name:      input.Name,
+		Scopes:    input.Scopes,
+		ExpiresAt: input.ExpiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create personal access token: %w", err)
+	}
+
+	return &model.PersonalAccessTokenPayload{
+		Token:               token,
+		PersonalAccessToken: record.ToGraphQLPersonalAccessToken(),
+	}, nil
+}
+
+// RevokePersonalAccessToken is the resolver for the revokePersonalAccessToken field.
+func (r *mutationResolver) RevokePersonalAccessToken(ctx context.Context, id string) (bool, error) {
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return false, fmt.Errorf("user not authenticated")
+	}
+
+	tokenID, err := strconv.Atoi(id)
+	if err != nil {
+		return false, fmt.Errorf("invalid personal access token ID: %w", err)
+	}
+
+	if err := r.AuthService.RevokePersonalAccessToken(ctx, user.ID, tokenID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// DisableUser is the resolver for the disableUser field.
+func (r *mutationResolver) DisableUser(ctx context.Context, id string) (*model.User, error) {
+	admin, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("user not authenticated")
+	}
+
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid User ID: %w", err)
+	}
+
+	// An admin locking themselves out would need another admin to undo it
+	if userID == admin.ID {
+		return nil, fmt.Errorf("cannot disable your own account")
+	}
+
+	if err := r.AuthService.DisableUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	user, err := r.AuthService.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return user.ToGraphQLUser(), nil
+}
+
+// EnableUser is the resolver for the enableUser field.
+func (r *mutationResolver) EnableUser(ctx context.Context, id string) (*model.User, error) {
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid User ID: %w", err)
+	}
+
+	if err := r.AuthService.EnableUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	user, err := r.AuthService.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return user.ToGraphQLUser(), nil
+}
+
 // CurrentUser is the resolver for the currentUser field.
 func (r *queryResolver) CurrentUser(ctx context.Context) (*model.User, error) {
 	user, ok := middleware.GetUserFromContext(ctx)
@@ -66,6 +411,26 @@ func (r *queryResolver) CurrentUser(ctx context.Context) (*model.User, error) {
 	return user.ToGraphQLUser(), nil
 }
 
+// PersonalAccessTokens is the resolver for the personalAccessTokens field.
+func (r *queryResolver) PersonalAccessTokens(ctx context.Context) ([]*model.PersonalAccessToken, error) {
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("user not authenticated")
+	}
+
+	tokens, err := r.AuthService.ListPersonalAccessTokens(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.PersonalAccessToken, len(tokens))
+	for i, token := range tokens {
+		result[i] = token.ToGraphQLPersonalAccessToken()
+	}
+
+	return result, nil
+}
+
 // UserProfile is the resolver for the userProfile field.
 func (r *queryResolver) UserProfile(ctx context.Context, id string) (*model.User, error) {
 	// Convert string ID to int
@@ -74,6 +439,15 @@ func (r *queryResolver) UserProfile(ctx context.Context, id string) (*model.User
 		return nil, fmt.Errorf("invalid User ID: %w", err)
 	}
 
+	// Users can only look themselves up, admins can look up anyone
+	viewer, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("user not authenticated")
+	}
+	if viewer.ID != userID && !viewer.IsAdmin() {
+		return nil, fmt.Errorf("failed to get user profile: %w", auth.ErrForbidden)
+	}
+
 	user, err := r.AuthService.GetUserByID(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user profile: %w", err)
@@ -89,18 +463,48 @@ func (r *queryResolver) Health(ctx context.Context) (string, error) {
 }
 
 // AuthStatusChanged is the resolver for the authStatusChanged field.
-func (r *subscriptionResolver) AuthStatusChanged(ctx context.Context) (<-chan *model.User, error) {
-	// Create a channel for user updates
-	userChan := make(chan *model.User, 1)
+func (r *subscriptionResolver) AuthStatusChanged(ctx context.Context) (<-chan *model.AuthStatusEvent, error) {
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("user not authenticated")
+	}
 
-	// TODO: implement real-time auth status subscription
-	// For now, return an empty channel that closes when context is done
+	// Subscribe to the user's auth status events (closed when ctx is done)
+	sessionID, _ := middleware.GetSessionIDFromContext(ctx)
+	events, err := r.AuthService.SubscribeAuthStatus(ctx, user.ID, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to auth status: %w", err)
+	}
+
+	ch := make(chan *model.AuthStatusEvent, 1)
 	go func() {
-		defer close(userChan)
-		<-ctx.Done()
+		defer close(ch)
+		for event := range events {
+			select {
+			case ch <- event.ToGraphQLAuthStatusEvent():
+			case <-ctx.Done():
+				return
+			}
+		}
 	}()
 
-	return userChan, nil
+	return ch, nil
+}
+
+// AuthInfo is the resolver for the authInfo field.
+func (r *userResolver) AuthInfo(ctx context.Context, obj *model.User) (*model.AuthInfo, error) {
+	// Sign-in activity is only visible to the user it belongs to
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok || strconv.Itoa(user.ID) != obj.ID {
+		return nil, nil
+	}
+
+	info, err := r.AuthService.GetAuthInfo(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get auth info: %w", err)
+	}
+
+	return info.ToGraphQLAuthInfo(), nil
 }
 
 // Mutation returns generated.MutationResolver implementation.
@@ -112,6 +516,10 @@ func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 // Subscription returns generated.SubscriptionResolver implementation.
 func (r *Resolver) Subscription() generated.SubscriptionResolver { return &subscriptionResolver{r} }
 
+// User returns generated.UserResolver implementation.
+func (r *Resolver) User() generated.UserResolver { return &userResolver{r} }
+
 type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
 type subscriptionResolver struct{ *Resolver }
+type userResolver struct{ *Resolver }
@@ -0,0 +1,308 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/jayk0001/my-go-next-todo/internal/auth"
+	"github.com/jayk0001/my-go-next-todo/internal/graphql/generated"
+	"github.com/jayk0001/my-go-next-todo/internal/middleware"
+	"github.com/jayk0001/my-go-next-todo/internal/todo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAuthWebsocketTestClient creates a test client backed by authService whose websocket connections are authenticated as userID
+func newAuthWebsocketTestClient(authService *auth.AuthService, userID int) *client.Client {
+	resolver := NewResolver(authService, &MockTodoService{})
+	server := handler.NewDefaultServer(generated.NewExecutableSchema(NewConfig(resolver)))
+	return client.New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), middleware.UserContextKey, &auth.User{ID: userID})
+		server.ServeHTTP(w, r.WithContext(ctx))
+	}))
+}
+
+// relayStatusUntilDone keeps relaying event until ctx is done, since the subscription
+// is registered asynchronously and events published before that are not buffered
+func relayStatusUntilDone(ctx context.Context, t *testing.T, authService *auth.AuthService, event auth.StatusEvent) {
+	payload, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			authService.RelayStatusEvent(string(payload))
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func TestSubscription_AuthStatusChanged(t *testing.T) {
+	authService := auth.NewAuthService(nil, "test-secret-key-32-chars-long-for-testing", time.Hour)
+	c := newAuthWebsocketTestClient(authService, 1)
+
+	sub := c.Websocket(`subscription { authStatusChanged { reason sessionId occurredAt } }`)
+	defer sub.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Events for other users must not leak into the subscription
+	relayStatusUntilDone(ctx, t, authService, auth.StatusEvent{
+		Reason:     auth.StatusPasswordChanged,
+		UserID:     2,
+		OccurredAt: time.Now(),
+	})
+	relayStatusUntilDone(ctx, t, authService, auth.StatusEvent{
+		Reason:     auth.StatusAccountDisabled,
+		UserID:     1,
+		OccurredAt: time.Now(),
+	})
+
+	var resp struct {
+		AuthStatusChanged struct {
+			Reason     string
+			SessionID  *string
+			OccurredAt string
+		}
+	}
+	require.NoError(t, sub.Next(&resp))
+	assert.Equal(t, "ACCOUNT_DISABLED", resp.AuthStatusChanged.Reason)
+	assert.Nil(t, resp.AuthStatusChanged.SessionID)
+	assert.NotEmpty(t, resp.AuthStatusChanged.OccurredAt)
+}
+
+func TestSubscription_AuthStatusChanged_Unauthorized(t *testing.T) {
+	c := newTestClient(&MockTodoService{})
+
+	sub := c.Websocket(`subscription { authStatusChanged { reason } }`)
+	defer sub.Close()
+
+	var resp struct{ AuthStatusChanged struct{ Reason string } }
+	err := sub.Next(&resp)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "user not authenticated")
+}
+
+func TestMutation_Logout_Unauthorized(t *testing.T) {
+	c := newTestClient(&MockTodoService{})
+
+	var resp struct{ Logout bool }
+	err := c.Post(`mutation { logout }`, &resp)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "user not authenticated")
+}
+
+func TestMutation_AccountManagement_Unauthorized(t *testing.T) {
+	c := newTestClient(&MockTodoService{})
+
+	mutations := map[string]string{
+		"changePassword":            `mutation { changePassword(currentPassword: "password123", newPassword: "new-password-456") }`,
+		"changeEmail":               `mutation { changeEmail(newEmail: "new@example.com", password: "password123") { id } }`,
+		"deleteAccount":             `mutation { deleteAccount(password: "password123") }`,
+		"requestDataExport":         `mutation { requestDataExport { downloadUrl expiresAt } }`,
+		"createPersonalAccessToken": `mutation { createPersonalAccessToken(input: {name: "ci"}) { token } }`,
+		"revokePersonalAccessToken": `mutation { revokePersonalAccessToken(id: "1") }`,
+	}
+
+	for name, mutation := range mutations {
+		t.Run(name, func(t *testing.T) {
+			var resp map[string]any
+			err := c.Post(mutation, &resp)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "user not authenticated")
+		})
+	}
+}
+
+func TestQuery_PersonalAccessTokens_Unauthorized(t *testing.T) {
+	c := newTestClient(&MockTodoService{})
+
+	var resp struct{ PersonalAccessTokens []struct{ ID string } }
+	err := c.Post(`query { personalAccessTokens { id } }`, &resp)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "user not authenticated")
+}
+
+func TestDirectives_HasScope(t *testing.T) {
+	created := false
+	mockSvc := &MockTodoService{
+		CreateTodoFn: func(ctx context.Context, userID int, input todo.CreateTodoInput) (*todo.Todo, error) {
+			created = true
+			return &todo.Todo{ID: 1, UserID: userID, Title: input.Title}, nil
+		},
+		GetUserTodoStatsFn: func(ctx context.Context, userID int) (*todo.TodoStats, error) {
+			return &todo.TodoStats{Total: 1}, nil
+		},
+	}
+
+	server := handler.NewDefaultServer(generated.NewExecutableSchema(NewConfig(NewResolver(nil, mockSvc))))
+
+	newClient := func(scopes []string) *client.Client {
+		return client.New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := &auth.User{ID: 1, Role: auth.RoleUser}
+			ctx := context.WithValue(r.Context(), middleware.UserContextKey, user)
+			ctx = context.WithValue(ctx, middleware.PrincipalContextKey, &auth.Principal{User: user, Scopes: scopes})
+			server.ServeHTTP(w, r.WithContext(ctx))
+		}))
+	}
+
+	reader := newClient([]string{auth.ScopeTodosRead})
+
+	// Fields within the token's scopes work
+	var statsResp struct{ TodoStats struct{ Total int } }
+	require.NoError(t, reader.Post(`query { todoStats { total } }`, &statsResp))
+	assert.Equal(t, 1, statsResp.TodoStats.Total)
+
+	// Fields needing another scope are rejected before reaching the resolver
+	var createResp struct{ CreateTodo struct{ ID string } }
+	err := reader.Post(`mutation { createTodo(input: {title: "Scripted"}) { id } }`, &createResp)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "todos:write")
+	assert.False(t, created)
+
+	// Fields without a declared scope are never available to tokens
+	var tokensResp struct{ PersonalAccessTokens []struct{ ID string } }
+	err = reader.Post(`query { personalAccessTokens { id } }`, &tokensResp)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not available to personal access tokens")
+
+	// Interactive sign-ins are unaffected
+	require.NoError(t, newClient(nil).Post(`mutation { createTodo(input: {title: "Typed"}) { id } }`, &createResp))
+	assert.True(t, created)
+}
+
+func TestScopeGuard(t *testing.T) {
+	server := handler.NewDefaultServer(generated.NewExecutableSchema(NewConfig(NewResolver(nil, &MockTodoService{}))))
+	server.AroundRootFields(middleware.ScopeGuard())
+
+	newClient := func(scopes []string) *client.Client {
+		return client.New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := &auth.User{ID: 1, Role: auth.RoleUser}
+			ctx := context.WithValue(r.Context(), middleware.UserContextKey, user)
+			ctx = context.WithValue(ctx, middleware.PrincipalContextKey, &auth.Principal{User: user, Scopes: scopes})
+			server.ServeHTTP(w, r.WithContext(ctx))
+		}))
+	}
+
+	token := newClient(auth.Scopes)
+
+	// Root fields without @hasScope are rejected for tokens, even those open to anyone
+	var healthResp struct{ Health string }
+	err := token.Post(`query { health }`, &healthResp)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "health is not allowed: not available to personal access tokens")
+
+	var resendResp struct{ ResendVerification bool }
+	err = token.Post(`mutation { resendVerification }`, &resendResp)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "resendVerification is not allowed")
+
+	// Introspection still works
+	var schemaResp struct {
+		Schema struct{ QueryType struct{ Name string } } `json:"__schema"`
+	}
+	require.NoError(t, token.Post(`query { __schema { queryType { name } } }`, &schemaResp))
+	assert.Equal(t, "Query", schemaResp.Schema.QueryType.Name)
+
+	// Interactive sign-ins are unaffected
+	require.NoError(t, newClient(nil).Post(`query { health }`, &healthResp))
+	assert.NotEmpty(t, healthResp.Health)
+}
+
+func TestDirectives_Roles(t *testing.T) {
+	server := handler.NewDefaultServer(generated.NewExecutableSchema(NewConfig(NewResolver(nil, &MockTodoService{}))))
+
+	newClient := func(role string) *client.Client {
+		return client.New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := &auth.User{ID: 1, Role: role}
+			ctx := context.WithValue(r.Context(), middleware.UserContextKey, user)
+			ctx = context.WithValue(ctx, middleware.PrincipalContextKey, &auth.Principal{User: user})
+			server.ServeHTTP(w, r.WithContext(ctx))
+		}))
+	}
+
+	var resp map[string]any
+
+	// Anonymous callers are rejected
+	err := newTestClient(&MockTodoService{}).Post(`mutation { disableUser(id: "2") { id } }`, &resp)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "user not authenticated")
+
+	err = newTestClient(&MockTodoService{}).Post(`query { userProfile(id: "2") { email } }`, &resp)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "user not authenticated")
+
+	// Users need the admin role
+	err = newClient(auth.RoleUser).Post(`mutation { disableUser(id: "2") { id } }`, &resp)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "forbidden: requires the ADMIN role")
+
+	// and can only see their own profile
+	err = newClient(auth.RoleUser).Post(`query { userProfile(id: "2") { email } }`, &resp)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "forbidden")
+
+	// Admins get through to the resolver
+	err = newClient(auth.RoleAdmin).Post(`mutation { disableUser(id: "1") { id } }`, &resp)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot disable your own account")
+}
+
+func TestReadOnlyGuard(t *testing.T) {
+	deleted := false
+	mockSvc := &MockTodoService{
+		DeleteTodoFn: func(ctx context.Context, todoID, userID int, mode todo.DeleteMode) error {
+			deleted = true
+			return nil
+		},
+		GetUserTodoStatsFn: func(ctx context.Context, userID int) (*todo.TodoStats, error) {
+			return &todo.TodoStats{Total: 1}, nil
+		},
+	}
+
+	server := handler.NewDefaultServer(generated.NewExecutableSchema(NewConfig(NewResolver(nil, mockSvc))))
+	server.AroundRootFields(middleware.ReadOnlyGuard("logout"))
+
+	newClient := func(readOnly bool) *client.Client {
+		return client.New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := &auth.User{ID: 1}
+			ctx := context.WithValue(r.Context(), middleware.UserContextKey, user)
+			ctx = context.WithValue(ctx, middleware.PrincipalContextKey, &auth.Principal{User: user, ReadOnly: readOnly})
+			server.ServeHTTP(w, r.WithContext(ctx))
+		}))
+	}
+
+	readOnly := newClient(true)
+
+	// Queries still work
+	var statsResp struct{ TodoStats struct{ Total int } }
+	require.NoError(t, readOnly.Post(`query { todoStats { total } }`, &statsResp))
+	assert.Equal(t, 1, statsResp.TodoStats.Total)
+
+	// Mutations are rejected before reaching the resolver
+	var deleteResp struct{ DeleteTodo bool }
+	err := readOnly.Post(`mutation { deleteTodo(id: "1") }`, &deleteResp)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "email address has not been verified")
+	assert.False(t, deleted)
+
+	// Allowed mutations reach the resolver (a principal without token or session has nothing to revoke)
+	var logoutResp struct{ Logout bool }
+	require.NoError(t, readOnly.Post(`mutation { logout }`, &logoutResp))
+	assert.True(t, logoutResp.Logout)
+
+	// Principals that are not read-only are unaffected
+	require.NoError(t, newClient(false).Post(`mutation { deleteTodo(id: "1") }`, &deleteResp))
+	assert.True(t, deleted)
+}
@@ -0,0 +1,291 @@
+package resolver
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jayk0001/my-go-next-todo/internal/graphql/model"
+	"github.com/jayk0001/my-go-next-todo/internal/todo"
+)
+
+// convertTodoToGraphQL converts service Todo to graphQL Todo
+func convertTodoToGraphQL(t *todo.Todo) *model.Todo {
+	converted := &model.Todo{
+		ID:          strconv.Itoa(t.ID),
+		Title:       t.Title,
+		Description: t.Description,
+		Completed:   t.Completed,
+		DueAt:       t.DueAt,
+		AllDay:      t.AllDay,
+		Priority:    model.TodoPriority(strings.ToUpper(t.Priority.String())),
+		Tags:        convertTagsToGraphQL(t.Tags),
+		Progress:    convertTodoProgressToGraphQL(t.Progress),
+		CreatedAt:   t.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   t.UpdatedAt.Format(time.RFC3339),
+	}
+
+	if t.Project != nil {
+		converted.Project = convertProjectToGraphQL(t.Project)
+	}
+
+	if t.ParentID != nil {
+		parentID := strconv.Itoa(*t.ParentID)
+		converted.ParentID = &parentID
+	}
+
+	return converted
+}
+
+// convertTodosToGraphQL converts service Todos to graphQL Todos, never returning nil
+func convertTodosToGraphQL(todos []*todo.Todo) []*model.Todo {
+	converted := make([]*model.Todo, len(todos))
+	for i, t := range todos {
+		converted[i] = convertTodoToGraphQL(t)
+	}
+	return converted
+}
+
+// convertTodoProgressToGraphQL converts service TodoProgress to graphQL TodoProgress
+func convertTodoProgressToGraphQL(p todo.TodoProgress) *model.TodoProgress {
+	return &model.TodoProgress{
+		Completed: p.Completed,
+		Total:     p.Total,
+	}
+}
+
+// convertProjectToGraphQL converts service Project to graphQL Project.
+// Todos and stats are left to the Project field resolvers.
+func convertProjectToGraphQL(p *todo.Project) *model.Project {
+	return &model.Project{
+		ID:        strconv.Itoa(p.ID),
+		Name:      p.Name,
+		Color:     p.Color,
+		Archived:  p.Archived,
+		Position:  p.Position,
+		IsInbox:   p.IsInbox,
+		CreatedAt: p.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: p.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// convertProjectsToGraphQL converts service Projects to graphQL Projects, never returning nil
+func convertProjectsToGraphQL(projects []*todo.Project) []*model.Project {
+	converted := make([]*model.Project, len(projects))
+	for i, project := range projects {
+		converted[i] = convertProjectToGraphQL(project)
+	}
+	return converted
+}
+
+// convertTagToGraphQL converts service Tag to graphQL Tag
+func convertTagToGraphQL(t *todo.Tag) *model.Tag {
+	return &model.Tag{
+		ID:        strconv.Itoa(t.ID),
+		Name:      t.Name,
+		Color:     t.Color,
+		CreatedAt: t.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: t.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// convertTagsToGraphQL converts service Tags to graphQL Tags, never returning nil
+func convertTagsToGraphQL(tags []*todo.Tag) []*model.Tag {
+	converted := make([]*model.Tag, len(tags))
+	for i, tag := range tags {
+		converted[i] = convertTagToGraphQL(tag)
+	}
+	return converted
+}
+
+// convertTodoEventToGraphQL converts service Event to graphQL TodoEvent
+func convertTodoEventToGraphQL(e todo.Event) *model.TodoEvent {
+	event := &model.TodoEvent{
+		Kind:   model.TodoEventKind(e.Kind),
+		TodoID: strconv.Itoa(e.TodoID),
+	}
+
+	if e.Todo != nil {
+		event.Todo = convertTodoToGraphQL(e.Todo)
+	}
+
+	return event
+}
+
+// convertTodoStatsToGraphQL converts service TodoStats to graphQL TodoStats
+func convertTodoStatsToGraphQL(s *todo.TodoStats) *model.TodoStats {
+	return &model.TodoStats{
+		Total:     s.Total,
+		Completed: s.Completed,
+		Pending:   s.Pending,
+		Overdue:   s.Overdue,
+	}
+}
+
+// convertUpdateTodoInput converts graphQL UpdateTodoInput to service UpdateTodoInput
+func convertUpdateTodoInput(input model.UpdateTodoInput) (todo.UpdateTodoInput, error) {
+	addTagIDs, err := convertIDs(input.AddTagIds)
+	if err != nil {
+		return todo.UpdateTodoInput{}, err
+	}
+
+	removeTagIDs, err := convertIDs(input.RemoveTagIds)
+	if err != nil {
+		return todo.UpdateTodoInput{}, err
+	}
+
+	projectID, err := convertOptionalID(input.ProjectID)
+	if err != nil {
+		return todo.UpdateTodoInput{}, err
+	}
+
+	parentID, err := convertOptionalID(input.ParentID)
+	if err != nil {
+		return todo.UpdateTodoInput{}, err
+	}
+
+	return todo.UpdateTodoInput{
+		Title:        input.Title,
+		Description:  input.Description,
+		Completed:    input.Completed,
+		DueAt:        input.DueAt,
+		AllDay:       boolValue(input.AllDay),
+		ClearDueAt:   boolValue(input.ClearDueAt),
+		Priority:     convertOptionalPriority(input.Priority),
+		AddTagIDs:    addTagIDs,
+		RemoveTagIDs: removeTagIDs,
+		ProjectID:    projectID,
+		ParentID:     parentID,
+		ClearParent:  boolValue(input.ClearParent),
+	}, nil
+}
+
+// convertTodoFilter converts graphQL TodoFilter and TodoSort to service TodoFilter
+func convertTodoFilter(filter *model.TodoFilter, sort *model.TodoSort) (todo.TodoFilter, error) {
+	serviceFilter := todo.TodoFilter{Sort: convertTodoSort(sort)}
+	if filter == nil {
+		return serviceFilter, nil
+	}
+
+	var err error
+	if serviceFilter.ProjectID, err = convertOptionalID(filter.ProjectID); err != nil {
+		return todo.TodoFilter{}, err
+	}
+	serviceFilter.Completed = filter.Completed
+	serviceFilter.TopLevel = filter.TopLevel
+	serviceFilter.Search = filter.Search
+	serviceFilter.DueBefore = filter.DueBefore
+	serviceFilter.DueAfter = filter.DueAfter
+	serviceFilter.Overdue = filter.Overdue
+	serviceFilter.DueToday = filter.DueToday
+	for _, priority := range filter.Priorities {
+		serviceFilter.Priorities = append(serviceFilter.Priorities, convertPriority(priority))
+	}
+	if serviceFilter.TagsAny, err = convertIDs(filter.TagsAny); err != nil {
+		return todo.TodoFilter{}, err
+	}
+	if serviceFilter.TagsAll, err = convertIDs(filter.TagsAll); err != nil {
+		return todo.TodoFilter{}, err
+	}
+	if filter.Limit != nil {
+		serviceFilter.Limit = *filter.Limit
+	}
+	if filter.Offset != nil {
+		serviceFilter.Offset = *filter.Offset
+	}
+
+	return serviceFilter, nil
+}
+
+// convertTodoListToGraphQL converts service TodoListResponse to graphQL TodoListResponse
+func convertTodoListToGraphQL(result *todo.TodoListResponse) *model.TodoListResponse {
+	return &model.TodoListResponse{
+		Todos:   convertTodosToGraphQL(result.Todos),
+		Total:   result.Total,
+		Limit:   result.Limit,
+		Offset:  result.Offset,
+		HasMore: result.HasMore,
+	}
+}
+
+// convertIDs converts graphQL IDs to integers
+func convertIDs(ids []string) ([]int, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	converted := make([]int, len(ids))
+	for i, idStr := range ids {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			return nil, todo.ErrInvalidTodoInput
+		}
+		converted[i] = id
+	}
+	return converted, nil
+}
+
+// convertOptionalID converts an optional graphQL ID, nil when it is omitted
+func convertOptionalID(idStr *string) (*int, error) {
+	if idStr == nil {
+		return nil, nil
+	}
+
+	id, err := strconv.Atoi(*idStr)
+	if err != nil {
+		return nil, todo.ErrInvalidTodoInput
+	}
+	return &id, nil
+}
+
+// convertPriority converts graphQL TodoPriority to service Priority.
+// Unknown values become an invalid priority, which the service rejects.
+func convertPriority(p model.TodoPriority) todo.Priority {
+	priority, err := todo.ParsePriority(string(p))
+	if err != nil {
+		return -1
+	}
+	return priority
+}
+
+// convertOptionalPriority converts an optional graphQL TodoPriority, nil when it is omitted
+func convertOptionalPriority(p *model.TodoPriority) *todo.Priority {
+	if p == nil {
+		return nil
+	}
+	priority := convertPriority(*p)
+	return &priority
+}
+
+// convertDeleteMode converts an optional graphQL DeleteMode to service DeleteMode,
+// empty (cascade) when it is omitted
+func convertDeleteMode(m *model.DeleteMode) todo.DeleteMode {
+	if m == nil {
+		return ""
+	}
+	return todo.DeleteMode(strings.ToLower(string(*m)))
+}
+
+// convertTodoSort converts graphQL TodoSort to service TodoSort, the default order when it is omitted
+func convertTodoSort(s *model.TodoSort) todo.TodoSort {
+	if s == nil {
+		return todo.TodoSort{}
+	}
+	return todo.TodoSort{
+		Field:      todo.SortField(strings.ToLower(string(s.Field))),
+		Descending: s.Direction == model.SortDirectionDesc,
+	}
+}
+
+// boolValue returns the value of an optional graphQL boolean, false when it is omitted
+func boolValue(b *bool) bool {
+	return b != nil && *b
+}
+
+// stringValue returns the value of an optional graphQL string, empty when it is omitted
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,100 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+
+	"github.com/jayk0001/my-go-next-todo/internal/auth"
+	"github.com/jayk0001/my-go-next-todo/internal/graphql/generated"
+	"github.com/jayk0001/my-go-next-todo/internal/graphql/model"
+	"github.com/jayk0001/my-go-next-todo/internal/middleware"
+)
+
+// NewConfig creates the executable schema config for r, including the authorization directives
+func NewConfig(r *Resolver) generated.Config {
+	return generated.Config{
+		Resolvers: r,
+		Directives: generated.DirectiveRoot{
+			Auth:     authDirective,
+			HasRole:  hasRoleDirective,
+			HasScope: hasScopeDirective,
+		},
+	}
+}
+
+// userIDKey is the context key under which the directives record the authorized user's ID
+type userIDKey struct{}
+
+// authorizedUserID returns the ID of the user authorized by the @auth, @hasRole or @hasScope
+// directive of the field being resolved, or 0 for fields without one
+func authorizedUserID(ctx context.Context) int {
+	userID, _ := ctx.Value(userIDKey{}).(int)
+	return userID
+}
+
+// authDirective implements @auth
+func authDirective(ctx context.Context, obj any, next graphql.Resolver) (any, error) {
+	user, err := authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return next(context.WithValue(ctx, userIDKey{}, user.ID))
+}
+
+// hasRoleDirective implements @hasRole(role)
+func hasRoleDirective(ctx context.Context, obj any, next graphql.Resolver, role model.Role) (any, error) {
+	user, err := authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if model.Role(strings.ToUpper(user.Role)) != role {
+		return nil, fmt.Errorf("%w: requires the %s role", auth.ErrForbidden, role)
+	}
+
+	return next(context.WithValue(ctx, userIDKey{}, user.ID))
+}
+
+// hasScopeDirective implements @hasScope(scope)
+func hasScopeDirective(ctx context.Context, obj any, next graphql.Resolver, scope string) (any, error) {
+	user, err := authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if principal, ok := middleware.GetPrincipalFromContext(ctx); ok && !principal.HasScope(scope) {
+		return nil, fmt.Errorf("%w %s", auth.ErrInsufficientScope, scope)
+	}
+
+	return next(context.WithValue(ctx, userIDKey{}, user.ID))
+}
+
+// authorize returns the signed-in user. Personal access tokens are rejected
+// unless the field declares the scope it needs with @hasScope.
+func authorize(ctx context.Context) (*auth.User, error) {
+	user, ok := middleware.GetUserFromContext(ctx)
+	if !ok {
+		return nil, errNotAuthenticated
+	}
+
+	principal, ok := middleware.GetPrincipalFromContext(ctx)
+	if ok && principal.Scopes != nil && !declaresScope(ctx) {
+		return nil, auth.ErrPersonalAccessTokenNotAllowed
+	}
+
+	return user, nil
+}
+
+// declaresScope returns true if the field being resolved has a @hasScope directive
+func declaresScope(ctx context.Context) bool {
+	field := graphql.GetFieldContext(ctx)
+	if field == nil || field.Field.Definition == nil {
+		return false
+	}
+
+	return field.Field.Definition.Directives.ForName("hasScope") != nil
+}
@@ -1,11 +1,9 @@
 package resolver
 
 import (
-	"context"
 	"errors"
 
 	"github.com/jayk0001/my-go-next-todo/internal/auth"
-	"github.com/jayk0001/my-go-next-todo/internal/middleware"
 	"github.com/jayk0001/my-go-next-todo/internal/todo"
 )
 
@@ -26,11 +24,5 @@ func NewResolver(authService *auth.AuthService, todoService todo.TodoServiceInte
 	}
 }
 
-// getUserIDFromContext Helper to extract user ID from context
-func getUserIDFromContext(ctx context.Context) (int, error) {
-	user, ok := middleware.GetUserFromContext(ctx)
-	if !ok {
-		return 0, errors.New("user not authenticated")
-	}
-	return user.ID, nil
-}
+// errNotAuthenticated is returned to callers who are not signed in
+var errNotAuthenticated = errors.New("user not authenticated")
@@ -2,21 +2,28 @@ package resolver
 
 // This file will be automatically regenerated based on the schema, any resolver implementations
 // will be copied through when generating and any unknown code will be moved to the end.
-// Code generated by github.com/99designs/gqlgen version v0.17.81
+// Code generated by github.com/99designs/gqlgen version v0.17.82
 
 import (
 	"context"
 	"strconv"
-	"time"
 
+	"github.com/jayk0001/my-go-next-todo/internal/graphql/generated"
 	"github.com/jayk0001/my-go-next-todo/internal/graphql/model"
 	"github.com/jayk0001/my-go-next-todo/internal/todo"
 )
 
 // CreateTodo is the resolver for the createTodo field.
 func (r *mutationResolver) CreateTodo(ctx context.Context, input model.CreateTodoInput) (*model.Todo, error) {
-	// Extract user ID from context (set by auth middleware)
-	userID, err := getUserIDFromContext(ctx)
+	// User ID of the caller authorized by the @hasScope directive
+	userID := authorizedUserID(ctx)
+
+	projectID, err := convertOptionalID(input.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	parentID, err := convertOptionalID(input.ParentID)
 	if err != nil {
 		return nil, err
 	}
@@ -25,6 +32,13 @@ func (r *mutationResolver) CreateTodo(ctx context.Context, input model.CreateTod
 	serviceInput := todo.CreateTodoInput{
 		Title:       input.Title,
 		Description: input.Description,
+		DueAt:       input.DueAt,
+		AllDay:      boolValue(input.AllDay),
+		ProjectID:   projectID,
+		ParentID:    parentID,
+	}
+	if input.Priority != nil {
+		serviceInput.Priority = convertPriority(*input.Priority)
 	}
 
 	// Call service layer
@@ -39,10 +53,7 @@ func (r *mutationResolver) CreateTodo(ctx context.Context, input model.CreateTod
 
 // UpdateTodo is the resolver for the updateTodo field.
 func (r *mutationResolver) UpdateTodo(ctx context.Context, id string, input model.UpdateTodoInput) (*model.Todo, error) {
-	userID, err := getUserIDFromContext(ctx)
-	if err != nil {
-		return nil, err
-	}
+	userID := authorizedUserID(ctx)
 
 	todoId, err := strconv.Atoi(id)
 	if err != nil {
@@ -50,10 +61,9 @@ func (r *mutationResolver) UpdateTodo(ctx context.Context, id string, input mode
 	}
 
 	// Convert GraphQL input to service input
-	serviceInput := todo.UpdateTodoInput{
-		Title:       input.Title,
-		Description: input.Description,
-		Completed:   input.Completed,
+	serviceInput, err := convertUpdateTodoInput(input)
+	if err != nil {
+		return nil, err
 	}
 
 	// Call service layer
@@ -66,11 +76,8 @@ func (r *mutationResolver) UpdateTodo(ctx context.Context, id string, input mode
 }
 
 // DeleteTodo is the resolver for the deleteTodo field.
-func (r *mutationResolver) DeleteTodo(ctx context.Context, id string) (bool, error) {
-	userID, err := getUserIDFromContext(ctx)
-	if err != nil {
-		return false, err
-	}
+func (r *mutationResolver) DeleteTodo(ctx context.Context, id string, mode *model.DeleteMode) (bool, error) {
+	userID := authorizedUserID(ctx)
 
 	todoID, err := strconv.Atoi(id)
 	if err != nil {
@@ -78,7 +85,7 @@ func (r *mutationResolver) DeleteTodo(ctx context.Context, id string) (bool, err
 	}
 
 	// Call service layer
-	err = r.TodoService.DeleteTodo(ctx, todoID, userID)
+	err = r.TodoService.DeleteTodo(ctx, todoID, userID, convertDeleteMode(mode))
 	if err != nil {
 		return false, err
 	}
@@ -87,11 +94,8 @@ func (r *mutationResolver) DeleteTodo(ctx context.Context, id string) (bool, err
 }
 
 // ToggleTodo is the resolver for the toggleTodo field.
-func (r *mutationResolver) ToggleTodo(ctx context.Context, id string) (*model.Todo, error) {
-	userID, err := getUserIDFromContext(ctx)
-	if err != nil {
-		return nil, err
-	}
+func (r *mutationResolver) ToggleTodo(ctx context.Context, id string, cascade *bool) (*model.Todo, error) {
+	userID := authorizedUserID(ctx)
 
 	todoID, err := strconv.Atoi(id)
 	if err != nil {
@@ -99,7 +103,7 @@ func (r *mutationResolver) ToggleTodo(ctx context.Context, id string) (*model.To
 	}
 
 	// Call service layer
-	todoResult, err := r.TodoService.ToggleTodoComplete(ctx, todoID, userID)
+	todoResult, err := r.TodoService.ToggleTodoComplete(ctx, todoID, userID, boolValue(cascade))
 	if err != nil {
 		return nil, err
 	}
@@ -109,10 +113,7 @@ func (r *mutationResolver) ToggleTodo(ctx context.Context, id string) (*model.To
 
 // BatchUpdateTodos is the resolver for the batchUpdateTodos field.
 func (r *mutationResolver) BatchUpdateTodos(ctx context.Context, input model.BatchUpdateInput) ([]*model.Todo, error) {
-	userID, err := getUserIDFromContext(ctx)
-	if err != nil {
-		return nil, err
-	}
+	userID := authorizedUserID(ctx)
 
 	// Convert graphQL todo IDs to integers
 	todoIds := make([]int, len(input.TodoIds))
@@ -125,10 +126,9 @@ func (r *mutationResolver) BatchUpdateTodos(ctx context.Context, input model.Bat
 	}
 
 	// Convert GraphQL input to service input
-	serviceInput := todo.UpdateTodoInput{
-		Title:       input.Updates.Title,
-		Description: input.Updates.Description,
-		Completed:   input.Updates.Completed,
+	serviceInput, err := convertUpdateTodoInput(*input.Updates)
+	if err != nil {
+		return nil, err
 	}
 
 	// Call service layer
@@ -146,24 +146,240 @@ func (r *mutationResolver) BatchUpdateTodos(ctx context.Context, input model.Bat
 	return graphQLTodos, nil
 }
 
+// CreateProject is the resolver for the createProject field.
+func (r *mutationResolver) CreateProject(ctx context.Context, input model.CreateProjectInput) (*model.Project, error) {
+	userID := authorizedUserID(ctx)
+
+	serviceInput := todo.CreateProjectInput{Name: input.Name, Position: input.Position}
+	if input.Color != nil {
+		serviceInput.Color = *input.Color
+	}
+
+	// Call service layer
+	project, err := r.TodoService.CreateProject(ctx, userID, serviceInput)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertProjectToGraphQL(project), nil
+}
+
+// UpdateProject is the resolver for the updateProject field.
+func (r *mutationResolver) UpdateProject(ctx context.Context, id string, input model.UpdateProjectInput) (*model.Project, error) {
+	userID := authorizedUserID(ctx)
+
+	projectID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, todo.ErrInvalidTodoInput
+	}
+
+	// Call service layer
+	project, err := r.TodoService.UpdateProject(ctx, projectID, userID, todo.UpdateProjectInput{
+		Name:     input.Name,
+		Color:    input.Color,
+		Archived: input.Archived,
+		Position: input.Position,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return convertProjectToGraphQL(project), nil
+}
+
+// DeleteProject is the resolver for the deleteProject field.
+func (r *mutationResolver) DeleteProject(ctx context.Context, id string) (bool, error) {
+	userID := authorizedUserID(ctx)
+
+	projectID, err := strconv.Atoi(id)
+	if err != nil {
+		return false, todo.ErrInvalidTodoInput
+	}
+
+	// Call service layer
+	if err := r.TodoService.DeleteProject(ctx, projectID, userID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// MoveTodos is the resolver for the moveTodos field.
+func (r *mutationResolver) MoveTodos(ctx context.Context, todoIds []string, projectID string) ([]*model.Todo, error) {
+	userID := authorizedUserID(ctx)
+
+	serviceTodoIDs, err := convertIDs(todoIds)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.Atoi(projectID)
+	if err != nil {
+		return nil, todo.ErrInvalidTodoInput
+	}
+
+	// Call service layer
+	todoResults, err := r.TodoService.MoveTodos(ctx, userID, serviceTodoIDs, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertTodosToGraphQL(todoResults), nil
+}
+
+// CreateTag is the resolver for the createTag field.
+func (r *mutationResolver) CreateTag(ctx context.Context, input model.CreateTagInput) (*model.Tag, error) {
+	userID := authorizedUserID(ctx)
+
+	serviceInput := todo.CreateTagInput{Name: input.Name}
+	if input.Color != nil {
+		serviceInput.Color = *input.Color
+	}
+
+	// Call service layer
+	tag, err := r.TodoService.CreateTag(ctx, userID, serviceInput)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertTagToGraphQL(tag), nil
+}
+
+// UpdateTag is the resolver for the updateTag field.
+func (r *mutationResolver) UpdateTag(ctx context.Context, id string, input model.UpdateTagInput) (*model.Tag, error) {
+	userID := authorizedUserID(ctx)
+
+	tagID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, todo.ErrInvalidTodoInput
+	}
+
+	// Call service layer
+	tag, err := r.TodoService.UpdateTag(ctx, tagID, userID, todo.UpdateTagInput{
+		Name:  input.Name,
+		Color: input.Color,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return convertTagToGraphQL(tag), nil
+}
+
+// DeleteTag is the resolver for the deleteTag field.
+func (r *mutationResolver) DeleteTag(ctx context.Context, id string) (bool, error) {
+	userID := authorizedUserID(ctx)
+
+	tagID, err := strconv.Atoi(id)
+	if err != nil {
+		return false, todo.ErrInvalidTodoInput
+	}
+
+	// Call service layer
+	if err := r.TodoService.DeleteTag(ctx, tagID, userID); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// AddTags is the resolver for the addTags field.
+func (r *mutationResolver) AddTags(ctx context.Context, todoID string, tagIds []string) (*model.Todo, error) {
+	userID := authorizedUserID(ctx)
+
+	id, err := strconv.Atoi(todoID)
+	if err != nil {
+		return nil, todo.ErrInvalidTodoInput
+	}
+
+	serviceTagIDs, err := convertIDs(tagIds)
+	if err != nil {
+		return nil, err
+	}
+
+	// Call service layer
+	todoResult, err := r.TodoService.AddTags(ctx, id, userID, serviceTagIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertTodoToGraphQL(todoResult), nil
+}
+
+// RemoveTags is the resolver for the removeTags field.
+func (r *mutationResolver) RemoveTags(ctx context.Context, todoID string, tagIds []string) (*model.Todo, error) {
+	userID := authorizedUserID(ctx)
+
+	id, err := strconv.Atoi(todoID)
+	if err != nil {
+		return nil, todo.ErrInvalidTodoInput
+	}
+
+	serviceTagIDs, err := convertIDs(tagIds)
+	if err != nil {
+		return nil, err
+	}
+
+	// Call service layer
+	todoResult, err := r.TodoService.RemoveTags(ctx, id, userID, serviceTagIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertTodoToGraphQL(todoResult), nil
+}
+
 // Todos is the resolver for the todos field.
-func (r *queryResolver) Todos(ctx context.Context, filter *model.TodoFilter) (*model.TodoListResponse, error) {
-	userID, err := getUserIDFromContext(ctx)
+func (r *projectResolver) Todos(ctx context.Context, obj *model.Project, filter *model.TodoFilter, sort *model.TodoSort) (*model.TodoListResponse, error) {
+	userID := authorizedUserID(ctx)
+
+	projectID, err := strconv.Atoi(obj.ID)
+	if err != nil {
+		return nil, todo.ErrInvalidTodoInput
+	}
+
+	// Always scope the filter to this project
+	serviceFilter, err := convertTodoFilter(filter, sort)
 	if err != nil {
 		return nil, err
 	}
+	serviceFilter.ProjectID = &projectID
+
+	// Call service layer
+	result, err := r.TodoService.GetUserTodos(ctx, userID, serviceFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertTodoListToGraphQL(result), nil
+}
+
+// Stats is the resolver for the stats field.
+func (r *projectResolver) Stats(ctx context.Context, obj *model.Project) (*model.TodoStats, error) {
+	userID := authorizedUserID(ctx)
+
+	projectID, err := strconv.Atoi(obj.ID)
+	if err != nil {
+		return nil, todo.ErrInvalidTodoInput
+	}
+
+	// Call service layer
+	stats, err := r.TodoService.GetProjectTodoStats(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertTodoStatsToGraphQL(stats), nil
+}
+
+// Todos is the resolver for the todos field.
+func (r *queryResolver) Todos(ctx context.Context, filter *model.TodoFilter, sort *model.TodoSort) (*model.TodoListResponse, error) {
+	userID := authorizedUserID(ctx)
 
 	// Convert GraphQL filter to service filter
-	serviceFilter := todo.TodoFilter{}
-	if filter != nil {
-		serviceFilter.Completed = filter.Completed
-		serviceFilter.Search = filter.Search
-		if filter.Limit != nil {
-			serviceFilter.Limit = *filter.Limit
-		}
-		if filter.Offset != nil {
-			serviceFilter.Offset = *filter.Offset
-		}
+	serviceFilter, err := convertTodoFilter(filter, sort)
+	if err != nil {
+		return nil, err
 	}
 
 	// Call service layer
@@ -172,27 +388,12 @@ func (r *queryResolver) Todos(ctx context.Context, filter *model.TodoFilter) (*m
 		return nil, err
 	}
 
-	// Convert results to GraphQL models
-	var graphqlTodos []*model.Todo
-	for _, todoResult := range result.Todos {
-		graphqlTodos = append(graphqlTodos, convertTodoToGraphQL(todoResult))
-	}
-
-	return &model.TodoListResponse{
-		Todos:   graphqlTodos,
-		Total:   result.Total,
-		Limit:   result.Limit,
-		Offset:  result.Offset,
-		HasMore: result.HasMore,
-	}, nil
+	return convertTodoListToGraphQL(result), nil
 }
 
 // Todo is the resolver for the todo field.
 func (r *queryResolver) Todo(ctx context.Context, id string) (*model.Todo, error) {
-	userID, err := getUserIDFromContext(ctx)
-	if err != nil {
-		return nil, err
-	}
+	userID := authorizedUserID(ctx)
 
 	todoID, err := strconv.Atoi(id)
 	if err != nil {
@@ -210,70 +411,157 @@ func (r *queryResolver) Todo(ctx context.Context, id string) (*model.Todo, error
 
 // TodoStats is the resolver for the todoStats field.
 func (r *queryResolver) TodoStats(ctx context.Context) (*model.TodoStats, error) {
-	userID, err := getUserIDFromContext(ctx)
+	userID := authorizedUserID(ctx)
+
+	// Call service layer
+	stats, err := r.TodoService.GetUserTodoStats(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
+	return convertTodoStatsToGraphQL(stats), nil
+}
+
+// Projects is the resolver for the projects field.
+func (r *queryResolver) Projects(ctx context.Context, includeArchived *bool) ([]*model.Project, error) {
+	userID := authorizedUserID(ctx)
+
 	// Call service layer
-	stats, err := r.TodoService.GetUserTodoStats(ctx, userID)
+	projects, err := r.TodoService.GetUserProjects(ctx, userID, boolValue(includeArchived))
 	if err != nil {
 		return nil, err
 	}
 
-	return &model.TodoStats{
-		Total:     stats.Total,
-		Completed: stats.Completed,
-		Pending:   stats.Pending,
-	}, nil
+	return convertProjectsToGraphQL(projects), nil
+}
+
+// Project is the resolver for the project field.
+func (r *queryResolver) Project(ctx context.Context, id string) (*model.Project, error) {
+	userID := authorizedUserID(ctx)
+
+	projectID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, todo.ErrInvalidTodoInput
+	}
+
+	// Call service layer
+	project, err := r.TodoService.GetProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertProjectToGraphQL(project), nil
+}
+
+// Tags is the resolver for the tags field.
+func (r *queryResolver) Tags(ctx context.Context) ([]*model.Tag, error) {
+	userID := authorizedUserID(ctx)
+
+	// Call service layer
+	tags, err := r.TodoService.GetUserTags(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertTagsToGraphQL(tags), nil
 }
 
 // TodoChanged is the resolver for the todoChanged field.
-func (r *subscriptionResolver) TodoChanged(ctx context.Context) (<-chan *model.Todo, error) {
-	// TODO: Implement real-time subscriptions in future
-	// For now, return a simple channel
-	ch := make(chan *model.Todo)
-	close(ch)
+func (r *subscriptionResolver) TodoChanged(ctx context.Context) (<-chan *model.TodoEvent, error) {
+	userID := authorizedUserID(ctx)
+
+	// Subscribe to the user's todo events (closed when ctx is done)
+	events, err := r.TodoService.SubscribeEvents(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan *model.TodoEvent, 1)
+	go func() {
+		defer close(ch)
+		for event := range events {
+			select {
+			case ch <- convertTodoEventToGraphQL(event):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
 	return ch, nil
 }
 
 // TodoStatsChanged is the resolver for the todoStatsChanged field.
 func (r *subscriptionResolver) TodoStatsChanged(ctx context.Context) (<-chan *model.TodoStats, error) {
-	// TODO: Implement real-time subscriptions in future
-	// For now, return a simple channel
-	ch := make(chan *model.TodoStats)
-	close(ch)
+	userID := authorizedUserID(ctx)
+
+	// Subscribe to debounced stats pushes (closed when ctx is done)
+	updates, err := r.TodoService.SubscribeStats(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan *model.TodoStats, 1)
+	go func() {
+		defer close(ch)
+		for stats := range updates {
+			select {
+			case ch <- convertTodoStatsToGraphQL(stats):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
 	return ch, nil
 }
 
-// Helper function to convert service Todo to graphQL Todo
-func convertTodoToGraphQL(t *todo.Todo) *model.Todo {
-	return &model.Todo{
-		ID:          strconv.Itoa(t.ID),
-		Title:       t.Title,
-		Description: t.Description,
-		Completed:   t.Completed,
-		CreatedAt:   t.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:   t.UpdatedAt.Format(time.RFC3339),
+// Parent is the resolver for the parent field.
+func (r *todoResolver) Parent(ctx context.Context, obj *model.Todo) (*model.Todo, error) {
+	// Top-level todos have no parent
+	if obj.ParentID == nil {
+		return nil, nil
 	}
-}
 
-// !!! WARNING !!!
-// The code below was going to be deleted when updating resolvers. It has been copied here so you have
-// one last chance to move it out of harms way if you want. There are two reasons this happens:
-//  - When renaming or deleting a resolver the old code will be put in here. You can safely delete
-//    it when you're done.
-//  - You have helper methods in this file. Move them out to keep these resolver files clean.
-/*
-	func (r *todoResolver) CreatedAt(ctx context.Context, obj *todo.Todo) (string, error) {
-	panic(fmt.Errorf("not implemented: CreatedAt - createdAt"))
-}
-func (r *todoResolver) UpdatedAt(ctx context.Context, obj *todo.Todo) (string, error) {
-	panic(fmt.Errorf("not implemented: UpdatedAt - updatedAt"))
+	userID := authorizedUserID(ctx)
+
+	parentID, err := strconv.Atoi(*obj.ParentID)
+	if err != nil {
+		return nil, todo.ErrInvalidTodoInput
+	}
+
+	// Call service layer
+	parent, err := r.TodoService.GetTodo(ctx, parentID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertTodoToGraphQL(parent), nil
 }
-func (r *todoResolver) User(ctx context.Context, obj *todo.Todo) (*model.User, error) {
-	panic(fmt.Errorf("not implemented: User - user"))
+
+// Children is the resolver for the children field.
+func (r *todoResolver) Children(ctx context.Context, obj *model.Todo) ([]*model.Todo, error) {
+	userID := authorizedUserID(ctx)
+
+	todoID, err := strconv.Atoi(obj.ID)
+	if err != nil {
+		return nil, todo.ErrInvalidTodoInput
+	}
+
+	// Call service layer
+	children, err := r.TodoService.GetSubtasks(ctx, todoID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertTodosToGraphQL(children), nil
 }
+
+// Project returns generated.ProjectResolver implementation.
+func (r *Resolver) Project() generated.ProjectResolver { return &projectResolver{r} }
+
+// Todo returns generated.TodoResolver implementation.
 func (r *Resolver) Todo() generated.TodoResolver { return &todoResolver{r} }
+
+type projectResolver struct{ *Resolver }
 type todoResolver struct{ *Resolver }
-*/
@@ -3,6 +3,7 @@ package resolver
 import (
 	"context"
 	"errors"
+	"net/http"
 	"testing"
 	"time"
 
@@ -18,14 +19,30 @@ import (
 
 // MockTodoService implements TodoServiceInterface for testing
 type MockTodoService struct {
-	CreateTodoFn         func(ctx context.Context, userID int, input todo.CreateTodoInput) (*todo.Todo, error)
-	GetUserTodosFn       func(ctx context.Context, userID int, filter todo.TodoFilter) (*todo.TodoListResponse, error)
-	GetTodoFn            func(ctx context.Context, todoID, userID int) (*todo.Todo, error)
-	UpdateTodoFn         func(ctx context.Context, todoID, userID int, input todo.UpdateTodoInput) (*todo.Todo, error)
-	DeleteTodoFn         func(ctx context.Context, todoID, userID int) error
-	ToggleTodoCompleteFn func(ctx context.Context, todoID, userID int) (*todo.Todo, error)
-	BatchUpdateTodosFn   func(ctx context.Context, userID int, todoIDs []int, input todo.UpdateTodoInput) ([]*todo.Todo, error)
-	GetUserTodoStatsFn   func(ctx context.Context, userID int) (*todo.TodoStats, error)
+	CreateTodoFn          func(ctx context.Context, userID int, input todo.CreateTodoInput) (*todo.Todo, error)
+	GetUserTodosFn        func(ctx context.Context, userID int, filter todo.TodoFilter) (*todo.TodoListResponse, error)
+	GetTodoFn             func(ctx context.Context, todoID, userID int) (*todo.Todo, error)
+	UpdateTodoFn          func(ctx context.Context, todoID, userID int, input todo.UpdateTodoInput) (*todo.Todo, error)
+	DeleteTodoFn          func(ctx context.Context, todoID, userID int, mode todo.DeleteMode) error
+	ToggleTodoCompleteFn  func(ctx context.Context, todoID, userID int, cascade bool) (*todo.Todo, error)
+	GetSubtasksFn         func(ctx context.Context, todoID, userID int) ([]*todo.Todo, error)
+	BatchUpdateTodosFn    func(ctx context.Context, userID int, todoIDs []int, input todo.UpdateTodoInput) ([]*todo.Todo, error)
+	GetUserTodoStatsFn    func(ctx context.Context, userID int) (*todo.TodoStats, error)
+	SubscribeEventsFn     func(ctx context.Context, userID int) (<-chan todo.Event, error)
+	SubscribeStatsFn      func(ctx context.Context, userID int) (<-chan *todo.TodoStats, error)
+	CreateTagFn           func(ctx context.Context, userID int, input todo.CreateTagInput) (*todo.Tag, error)
+	GetUserTagsFn         func(ctx context.Context, userID int) ([]*todo.Tag, error)
+	UpdateTagFn           func(ctx context.Context, tagID, userID int, input todo.UpdateTagInput) (*todo.Tag, error)
+	DeleteTagFn           func(ctx context.Context, tagID, userID int) error
+	AddTagsFn             func(ctx context.Context, todoID, userID int, tagIDs []int) (*todo.Todo, error)
+	RemoveTagsFn          func(ctx context.Context, todoID, userID int, tagIDs []int) (*todo.Todo, error)
+	CreateProjectFn       func(ctx context.Context, userID int, input todo.CreateProjectInput) (*todo.Project, error)
+	GetProjectFn          func(ctx context.Context, projectID, userID int) (*todo.Project, error)
+	GetUserProjectsFn     func(ctx context.Context, userID int, includeArchived bool) ([]*todo.Project, error)
+	UpdateProjectFn       func(ctx context.Context, projectID, userID int, input todo.UpdateProjectInput) (*todo.Project, error)
+	DeleteProjectFn       func(ctx context.Context, projectID, userID int) error
+	MoveTodosFn           func(ctx context.Context, userID int, todoIDs []int, projectID int) ([]*todo.Todo, error)
+	GetProjectTodoStatsFn func(ctx context.Context, projectID, userID int) (*todo.TodoStats, error)
 }
 
 // CreateTodo mock
@@ -61,17 +78,25 @@ func (m *MockTodoService) UpdateTodo(ctx context.Context, todoID, userID int, in
 }
 
 // DeleteTodo mock
-func (m *MockTodoService) DeleteTodo(ctx context.Context, todoID, userID int) error {
+func (m *MockTodoService) DeleteTodo(ctx context.Context, todoID, userID int, mode todo.DeleteMode) error {
 	if m.DeleteTodoFn != nil {
-		return m.DeleteTodoFn(ctx, todoID, userID)
+		return m.DeleteTodoFn(ctx, todoID, userID, mode)
 	}
 	return errors.New("not implemented")
 }
 
 // ToggleTodoComplete mock
-func (m *MockTodoService) ToggleTodoComplete(ctx context.Context, todoID, userID int) (*todo.Todo, error) {
+func (m *MockTodoService) ToggleTodoComplete(ctx context.Context, todoID, userID int, cascade bool) (*todo.Todo, error) {
 	if m.ToggleTodoCompleteFn != nil {
-		return m.ToggleTodoCompleteFn(ctx, todoID, userID)
+		return m.ToggleTodoCompleteFn(ctx, todoID, userID, cascade)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// GetSubtasks mock
+func (m *MockTodoService) GetSubtasks(ctx context.Context, todoID, userID int) ([]*todo.Todo, error) {
+	if m.GetSubtasksFn != nil {
+		return m.GetSubtasksFn(ctx, todoID, userID)
 	}
 	return nil, errors.New("not implemented")
 }
@@ -92,15 +117,147 @@ func (m *MockTodoService) GetUserTodoStats(ctx context.Context, userID int) (*to
 	return nil, errors.New("not implemented")
 }
 
+// SubscribeEvents mock
+func (m *MockTodoService) SubscribeEvents(ctx context.Context, userID int) (<-chan todo.Event, error) {
+	if m.SubscribeEventsFn != nil {
+		return m.SubscribeEventsFn(ctx, userID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// SubscribeStats mock
+func (m *MockTodoService) SubscribeStats(ctx context.Context, userID int) (<-chan *todo.TodoStats, error) {
+	if m.SubscribeStatsFn != nil {
+		return m.SubscribeStatsFn(ctx, userID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// CreateTag mock
+func (m *MockTodoService) CreateTag(ctx context.Context, userID int, input todo.CreateTagInput) (*todo.Tag, error) {
+	if m.CreateTagFn != nil {
+		return m.CreateTagFn(ctx, userID, input)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// GetUserTags mock
+func (m *MockTodoService) GetUserTags(ctx context.Context, userID int) ([]*todo.Tag, error) {
+	if m.GetUserTagsFn != nil {
+		return m.GetUserTagsFn(ctx, userID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// UpdateTag mock
+func (m *MockTodoService) UpdateTag(ctx context.Context, tagID, userID int, input todo.UpdateTagInput) (*todo.Tag, error) {
+	if m.UpdateTagFn != nil {
+		return m.UpdateTagFn(ctx, tagID, userID, input)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// DeleteTag mock
+func (m *MockTodoService) DeleteTag(ctx context.Context, tagID, userID int) error {
+	if m.DeleteTagFn != nil {
+		return m.DeleteTagFn(ctx, tagID, userID)
+	}
+	return errors.New("not implemented")
+}
+
+// AddTags mock
+func (m *MockTodoService) AddTags(ctx context.Context, todoID, userID int, tagIDs []int) (*todo.Todo, error) {
+	if m.AddTagsFn != nil {
+		return m.AddTagsFn(ctx, todoID, userID, tagIDs)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// RemoveTags mock
+func (m *MockTodoService) RemoveTags(ctx context.Context, todoID, userID int, tagIDs []int) (*todo.Todo, error) {
+	if m.RemoveTagsFn != nil {
+		return m.RemoveTagsFn(ctx, todoID, userID, tagIDs)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// CreateProject mock
+func (m *MockTodoService) CreateProject(ctx context.Context, userID int, input todo.CreateProjectInput) (*todo.Project, error) {
+	if m.CreateProjectFn != nil {
+		return m.CreateProjectFn(ctx, userID, input)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// GetProject mock
+func (m *MockTodoService) GetProject(ctx context.Context, projectID, userID int) (*todo.Project, error) {
+	if m.GetProjectFn != nil {
+		return m.GetProjectFn(ctx, projectID, userID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// GetUserProjects mock
+func (m *MockTodoService) GetUserProjects(ctx context.Context, userID int, includeArchived bool) ([]*todo.Project, error) {
+	if m.GetUserProjectsFn != nil {
+		return m.GetUserProjectsFn(ctx, userID, includeArchived)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// UpdateProject mock
+func (m *MockTodoService) UpdateProject(ctx context.Context, projectID, userID int, input todo.UpdateProjectInput) (*todo.Project, error) {
+	if m.UpdateProjectFn != nil {
+		return m.UpdateProjectFn(ctx, projectID, userID, input)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// DeleteProject mock
+func (m *MockTodoService) DeleteProject(ctx context.Context, projectID, userID int) error {
+	if m.DeleteProjectFn != nil {
+		return m.DeleteProjectFn(ctx, projectID, userID)
+	}
+	return errors.New("not implemented")
+}
+
+// MoveTodos mock
+func (m *MockTodoService) MoveTodos(ctx context.Context, userID int, todoIDs []int, projectID int) ([]*todo.Todo, error) {
+	if m.MoveTodosFn != nil {
+		return m.MoveTodosFn(ctx, userID, todoIDs, projectID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// GetProjectTodoStats mock
+func (m *MockTodoService) GetProjectTodoStats(ctx context.Context, projectID, userID int) (*todo.TodoStats, error) {
+	if m.GetProjectTodoStatsFn != nil {
+		return m.GetProjectTodoStatsFn(ctx, projectID, userID)
+	}
+	return nil, errors.New("not implemented")
+}
+
 // newTestClient creates a gqlgen test client with the mock service
 func newTestClient(mockTodoSvc todo.TodoServiceInterface) *client.Client {
 	resolver := NewResolver(nil, mockTodoSvc) // AuthService nil as not used in tests
-	cfg := generated.Config{Resolvers: resolver}
+	cfg := NewConfig(resolver)
 	schema := generated.NewExecutableSchema(cfg)
 	server := handler.NewDefaultServer(schema)
 	return client.New(server)
 }
 
+// newWebsocketTestClient creates a gqlgen test client whose websocket connections are authenticated as userID.
+// Websocket requests go through a real test server, so the user is injected by wrapping the handler.
+func newWebsocketTestClient(mockTodoSvc todo.TodoServiceInterface, userID int) *client.Client {
+	resolver := NewResolver(nil, mockTodoSvc)
+	cfg := NewConfig(resolver)
+	server := handler.NewDefaultServer(generated.NewExecutableSchema(cfg))
+	return client.New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), middleware.UserContextKey, &auth.User{ID: userID})
+		server.ServeHTTP(w, r.WithContext(ctx))
+	}))
+}
+
 // withAuthUserModifier returns a client.Option to add a mock user to the context
 func withAuthUserModifier(userID int) client.Option {
 	return func(bd *client.Request) {
@@ -194,6 +351,41 @@ func TestQuery_Todos_WithFilter(t *testing.T) {
 	assert.True(t, resp.Todos.Todos[0].Completed)
 }
 
+func TestQuery_Todos_WithPrioritiesAndSort(t *testing.T) {
+	mockSvc := &MockTodoService{
+		GetUserTodosFn: func(ctx context.Context, userID int, filter todo.TodoFilter) (*todo.TodoListResponse, error) {
+			assert.Equal(t, []todo.Priority{todo.PriorityHigh, todo.PriorityUrgent}, filter.Priorities)
+			assert.Equal(t, todo.TodoSort{Field: todo.SortByPriority, Descending: true}, filter.Sort)
+			return &todo.TodoListResponse{
+				Todos: []*todo.Todo{
+					{ID: 1, Title: "Urgent Todo", Priority: todo.PriorityUrgent, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+				},
+				Total: 1,
+			}, nil
+		},
+	}
+
+	c := newTestClient(mockSvc)
+
+	var resp struct {
+		Todos struct {
+			Todos []struct {
+				ID       string
+				Priority string
+			}
+		}
+	}
+
+	err := c.Post(
+		`query { todos(filter: {priorities: [HIGH, URGENT]}, sort: {field: PRIORITY, direction: DESC}) { todos { id priority } } }`,
+		&resp,
+		withAuthUserModifier(1),
+	)
+	require.NoError(t, err)
+	require.Len(t, resp.Todos.Todos, 1)
+	assert.Equal(t, "URGENT", resp.Todos.Todos[0].Priority)
+}
+
 func TestQuery_Todos_Unauthorized(t *testing.T) {
 	mockSvc := &MockTodoService{}
 
@@ -338,9 +530,10 @@ func TestMutation_UpdateTodo(t *testing.T) {
 
 func TestMutation_DeleteTodo(t *testing.T) {
 	mockSvc := &MockTodoService{
-		DeleteTodoFn: func(ctx context.Context, todoID, userID int) error {
+		DeleteTodoFn: func(ctx context.Context, todoID, userID int, mode todo.DeleteMode) error {
 			assert.Equal(t, 1, userID)
 			assert.Equal(t, 1, todoID)
+			assert.Equal(t, todo.DeleteCascade, mode)
 			return nil
 		},
 	}
@@ -362,7 +555,7 @@ func TestMutation_DeleteTodo(t *testing.T) {
 
 func TestMutation_DeleteTodo_NotFound(t *testing.T) {
 	mockSvc := &MockTodoService{
-		DeleteTodoFn: func(ctx context.Context, todoID, userID int) error {
+		DeleteTodoFn: func(ctx context.Context, todoID, userID int, mode todo.DeleteMode) error {
 			return todo.ErrTodoNotFound
 		},
 	}
@@ -381,9 +574,10 @@ func TestMutation_DeleteTodo_NotFound(t *testing.T) {
 
 func TestMutation_ToggleTodo(t *testing.T) {
 	mockSvc := &MockTodoService{
-		ToggleTodoCompleteFn: func(ctx context.Context, todoID, userID int) (*todo.Todo, error) {
+		ToggleTodoCompleteFn: func(ctx context.Context, todoID, userID int, cascade bool) (*todo.Todo, error) {
 			assert.Equal(t, 1, userID)
 			assert.Equal(t, 1, todoID)
+			assert.False(t, cascade)
 			return &todo.Todo{ID: 1, Title: "Toggled", Completed: true, CreatedAt: time.Now(), UpdatedAt: time.Now()}, nil
 		},
 	}
@@ -458,6 +652,386 @@ func TestMutation_BatchUpdateTodos_PartialFailure(t *testing.T) {
 	assert.Contains(t, err.Error(), "partial error")
 }
 
+func TestQuery_Todos_WithTags(t *testing.T) {
+	mockSvc := &MockTodoService{
+		GetUserTodosFn: func(ctx context.Context, userID int, filter todo.TodoFilter) (*todo.TodoListResponse, error) {
+			assert.Equal(t, []int{1, 2}, filter.TagsAny)
+			assert.Equal(t, []int{3}, filter.TagsAll)
+			return &todo.TodoListResponse{
+				Todos: []*todo.Todo{{
+					ID:    1,
+					Title: "Tagged",
+					Tags:  []*todo.Tag{{ID: 3, Name: "work", Color: "#3B82F6"}},
+				}, {
+					ID:    2,
+					Title: "Untagged",
+				}},
+				Total: 2,
+			}, nil
+		},
+	}
+
+	c := newTestClient(mockSvc)
+
+	var resp struct {
+		Todos struct {
+			Todos []struct {
+				ID   string
+				Tags []struct {
+					ID    string
+					Name  string
+					Color string
+				}
+			}
+		}
+	}
+
+	err := c.Post(
+		`query { todos(filter: {tagsAny: ["1", "2"], tagsAll: ["3"]}) { todos { id tags { id name color } } } }`,
+		&resp,
+		withAuthUserModifier(1),
+	)
+	require.NoError(t, err)
+	require.Len(t, resp.Todos.Todos, 2)
+	require.Len(t, resp.Todos.Todos[0].Tags, 1)
+	assert.Equal(t, "3", resp.Todos.Todos[0].Tags[0].ID)
+	assert.Equal(t, "work", resp.Todos.Todos[0].Tags[0].Name)
+	assert.NotNil(t, resp.Todos.Todos[1].Tags)
+	assert.Empty(t, resp.Todos.Todos[1].Tags)
+}
+
+func TestMutation_Tags(t *testing.T) {
+	mockSvc := &MockTodoService{
+		CreateTagFn: func(ctx context.Context, userID int, input todo.CreateTagInput) (*todo.Tag, error) {
+			assert.Equal(t, "work", input.Name)
+			assert.Equal(t, "", input.Color)
+			return &todo.Tag{ID: 7, Name: "work", Color: todo.DefaultColor}, nil
+		},
+		AddTagsFn: func(ctx context.Context, todoID, userID int, tagIDs []int) (*todo.Todo, error) {
+			assert.Equal(t, 1, todoID)
+			assert.Equal(t, []int{7}, tagIDs)
+			return &todo.Todo{ID: 1, Tags: []*todo.Tag{{ID: 7, Name: "work"}}}, nil
+		},
+		BatchUpdateTodosFn: func(ctx context.Context, userID int, todoIDs []int, input todo.UpdateTodoInput) ([]*todo.Todo, error) {
+			assert.Equal(t, []int{7}, input.RemoveTagIDs)
+			return []*todo.Todo{{ID: 1}, {ID: 2}}, nil
+		},
+	}
+
+	c := newTestClient(mockSvc)
+
+	var created struct {
+		CreateTag struct {
+			ID    string
+			Color string
+		}
+	}
+	err := c.Post(`mutation { createTag(input: {name: "work"}) { id color } }`, &created, withAuthUserModifier(1))
+	require.NoError(t, err)
+	assert.Equal(t, "7", created.CreateTag.ID)
+	assert.Equal(t, todo.DefaultColor, created.CreateTag.Color)
+
+	var added struct {
+		AddTags struct {
+			Tags []struct{ Name string }
+		}
+	}
+	err = c.Post(`mutation { addTags(todoId: "1", tagIds: ["7"]) { tags { name } } }`, &added, withAuthUserModifier(1))
+	require.NoError(t, err)
+	require.Len(t, added.AddTags.Tags, 1)
+	assert.Equal(t, "work", added.AddTags.Tags[0].Name)
+
+	var batch struct {
+		BatchUpdateTodos []struct{ ID string }
+	}
+	err = c.Post(
+		`mutation { batchUpdateTodos(input: {todoIds: ["1", "2"], updates: {removeTagIds: ["7"]}}) { id } }`,
+		&batch,
+		withAuthUserModifier(1),
+	)
+	require.NoError(t, err)
+	assert.Len(t, batch.BatchUpdateTodos, 2)
+
+	err = c.Post(`mutation { addTags(todoId: "1", tagIds: ["abc"]) { id } }`, &added, withAuthUserModifier(1))
+	require.Error(t, err)
+}
+
+func TestQuery_Projects(t *testing.T) {
+	inbox := &todo.Project{ID: 1, Name: todo.InboxName, Color: todo.DefaultColor, IsInbox: true}
+	work := &todo.Project{ID: 2, Name: "Work", Color: "#3B82F6", Position: 1}
+
+	mockSvc := &MockTodoService{
+		GetUserProjectsFn: func(ctx context.Context, userID int, includeArchived bool) ([]*todo.Project, error) {
+			assert.True(t, includeArchived)
+			return []*todo.Project{inbox, work}, nil
+		},
+		GetUserTodosFn: func(ctx context.Context, userID int, filter todo.TodoFilter) (*todo.TodoListResponse, error) {
+			require.NotNil(t, filter.ProjectID)
+			require.NotNil(t, filter.Completed)
+			return &todo.TodoListResponse{
+				Todos: []*todo.Todo{{ID: *filter.ProjectID * 10, ProjectID: *filter.ProjectID}},
+				Total: 1,
+			}, nil
+		},
+		GetProjectTodoStatsFn: func(ctx context.Context, projectID, userID int) (*todo.TodoStats, error) {
+			return &todo.TodoStats{Total: projectID, Pending: projectID}, nil
+		},
+	}
+
+	c := newTestClient(mockSvc)
+
+	var resp struct {
+		Projects []struct {
+			ID      string
+			Name    string
+			IsInbox bool
+			Todos   struct {
+				Todos []struct{ ID string }
+			}
+			Stats struct{ Total int }
+		}
+	}
+
+	// A projectId in the field filter is overridden by the parent project
+	err := c.Post(
+		`query { projects(includeArchived: true) { id name isInbox todos(filter: {completed: false, projectId: "9"}) { todos { id } } stats { total } } }`,
+		&resp,
+		withAuthUserModifier(1),
+	)
+	require.NoError(t, err)
+	require.Len(t, resp.Projects, 2)
+	assert.True(t, resp.Projects[0].IsInbox)
+	assert.Equal(t, "Work", resp.Projects[1].Name)
+	require.Len(t, resp.Projects[1].Todos.Todos, 1)
+	assert.Equal(t, "20", resp.Projects[1].Todos.Todos[0].ID)
+	assert.Equal(t, 2, resp.Projects[1].Stats.Total)
+}
+
+func TestQuery_Project_NotFound(t *testing.T) {
+	mockSvc := &MockTodoService{
+		GetProjectFn: func(ctx context.Context, projectID, userID int) (*todo.Project, error) {
+			return nil, todo.ErrProjectNotFound
+		},
+	}
+
+	c := newTestClient(mockSvc)
+
+	var resp struct {
+		Project *struct{ ID string }
+	}
+	err := c.Post(`query { project(id: "99") { id } }`, &resp, withAuthUserModifier(1))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "project not found")
+}
+
+func TestMutation_Projects(t *testing.T) {
+	work := &todo.Project{ID: 2, Name: "Work", Color: "#3B82F6", Position: 1}
+
+	mockSvc := &MockTodoService{
+		CreateProjectFn: func(ctx context.Context, userID int, input todo.CreateProjectInput) (*todo.Project, error) {
+			assert.Equal(t, "Work", input.Name)
+			assert.Equal(t, "#3B82F6", input.Color)
+			assert.Nil(t, input.Position)
+			return work, nil
+		},
+		UpdateProjectFn: func(ctx context.Context, projectID, userID int, input todo.UpdateProjectInput) (*todo.Project, error) {
+			assert.Equal(t, 2, projectID)
+			require.NotNil(t, input.Archived)
+			assert.Nil(t, input.Name)
+			return &todo.Project{ID: 2, Name: "Work", Archived: *input.Archived}, nil
+		},
+		DeleteProjectFn: func(ctx context.Context, projectID, userID int) error {
+			assert.Equal(t, 2, projectID)
+			return nil
+		},
+		MoveTodosFn: func(ctx context.Context, userID int, todoIDs []int, projectID int) ([]*todo.Todo, error) {
+			assert.Equal(t, []int{1, 2}, todoIDs)
+			todos := make([]*todo.Todo, len(todoIDs))
+			for i, id := range todoIDs {
+				todos[i] = &todo.Todo{ID: id, ProjectID: projectID, Project: work}
+			}
+			return todos, nil
+		},
+		CreateTodoFn: func(ctx context.Context, userID int, input todo.CreateTodoInput) (*todo.Todo, error) {
+			require.NotNil(t, input.ProjectID)
+			assert.Equal(t, 2, *input.ProjectID)
+			return &todo.Todo{ID: 3, Title: input.Title, ProjectID: 2, Project: work}, nil
+		},
+	}
+
+	c := newTestClient(mockSvc)
+
+	var created struct {
+		CreateProject struct {
+			ID   string
+			Name string
+		}
+	}
+	err := c.Post(`mutation { createProject(input: {name: "Work", color: "#3B82F6"}) { id name } }`, &created, withAuthUserModifier(1))
+	require.NoError(t, err)
+	assert.Equal(t, "2", created.CreateProject.ID)
+
+	var updated struct {
+		UpdateProject struct{ Archived bool }
+	}
+	err = c.Post(`mutation { updateProject(id: "2", input: {archived: true}) { archived } }`, &updated, withAuthUserModifier(1))
+	require.NoError(t, err)
+	assert.True(t, updated.UpdateProject.Archived)
+
+	var moved struct {
+		MoveTodos []struct {
+			ID      string
+			Project struct{ Name string }
+		}
+	}
+	err = c.Post(`mutation { moveTodos(todoIds: ["1", "2"], projectId: "2") { id project { name } } }`, &moved, withAuthUserModifier(1))
+	require.NoError(t, err)
+	require.Len(t, moved.MoveTodos, 2)
+	assert.Equal(t, "Work", moved.MoveTodos[1].Project.Name)
+
+	var todoResp struct {
+		CreateTodo struct {
+			Project struct{ ID string }
+		}
+	}
+	err = c.Post(`mutation { createTodo(input: {title: "Plan", projectId: "2"}) { project { id } } }`, &todoResp, withAuthUserModifier(1))
+	require.NoError(t, err)
+	assert.Equal(t, "2", todoResp.CreateTodo.Project.ID)
+
+	var deleted struct{ DeleteProject bool }
+	err = c.Post(`mutation { deleteProject(id: "2") }`, &deleted, withAuthUserModifier(1))
+	require.NoError(t, err)
+	assert.True(t, deleted.DeleteProject)
+
+	err = c.Post(`mutation { moveTodos(todoIds: ["1"], projectId: "abc") { id } }`, &moved, withAuthUserModifier(1))
+	require.Error(t, err)
+}
+
+func TestQuery_Todo_Subtasks(t *testing.T) {
+	parentID := 1
+	mockSvc := &MockTodoService{
+		GetTodoFn: func(ctx context.Context, todoID, userID int) (*todo.Todo, error) {
+			if todoID == 1 {
+				return &todo.Todo{ID: 1, Title: "Launch", Progress: todo.TodoProgress{Completed: 1, Total: 2}}, nil
+			}
+			return &todo.Todo{ID: todoID, Title: "Write copy", ParentID: &parentID}, nil
+		},
+		GetSubtasksFn: func(ctx context.Context, todoID, userID int) ([]*todo.Todo, error) {
+			if todoID != 1 {
+				return []*todo.Todo{}, nil
+			}
+			return []*todo.Todo{
+				{ID: 2, Title: "Write copy", ParentID: &parentID, Completed: true},
+				{ID: 3, Title: "Ship it", ParentID: &parentID},
+			}, nil
+		},
+	}
+
+	c := newTestClient(mockSvc)
+
+	var resp struct {
+		Todo struct {
+			ParentID *string
+			Progress struct {
+				Completed int
+				Total     int
+			}
+			Children []struct {
+				ID       string
+				ParentID string
+				Children []struct{ ID string }
+			}
+		}
+	}
+	err := c.Post(
+		`query { todo(id: "1") { parentId progress { completed total } children { id parentId children { id } } } }`,
+		&resp,
+		withAuthUserModifier(1),
+	)
+	require.NoError(t, err)
+	assert.Nil(t, resp.Todo.ParentID)
+	assert.Equal(t, 1, resp.Todo.Progress.Completed)
+	assert.Equal(t, 2, resp.Todo.Progress.Total)
+	require.Len(t, resp.Todo.Children, 2)
+	assert.Equal(t, "1", resp.Todo.Children[0].ParentID)
+	assert.NotNil(t, resp.Todo.Children[0].Children)
+	assert.Empty(t, resp.Todo.Children[0].Children)
+
+	var child struct {
+		Todo struct {
+			Parent *struct{ Title string }
+		}
+	}
+	err = c.Post(`query { todo(id: "2") { parent { title } } }`, &child, withAuthUserModifier(1))
+	require.NoError(t, err)
+	require.NotNil(t, child.Todo.Parent)
+	assert.Equal(t, "Launch", child.Todo.Parent.Title)
+}
+
+func TestMutation_Subtasks(t *testing.T) {
+	mockSvc := &MockTodoService{
+		CreateTodoFn: func(ctx context.Context, userID int, input todo.CreateTodoInput) (*todo.Todo, error) {
+			require.NotNil(t, input.ParentID)
+			assert.Equal(t, 1, *input.ParentID)
+			return &todo.Todo{ID: 2, Title: input.Title, ParentID: input.ParentID}, nil
+		},
+		UpdateTodoFn: func(ctx context.Context, todoID, userID int, input todo.UpdateTodoInput) (*todo.Todo, error) {
+			assert.Nil(t, input.ParentID)
+			assert.True(t, input.ClearParent)
+			return &todo.Todo{ID: todoID}, nil
+		},
+		ToggleTodoCompleteFn: func(ctx context.Context, todoID, userID int, cascade bool) (*todo.Todo, error) {
+			assert.True(t, cascade)
+			return &todo.Todo{ID: todoID, Completed: true, Progress: todo.TodoProgress{Completed: 2, Total: 2}}, nil
+		},
+		DeleteTodoFn: func(ctx context.Context, todoID, userID int, mode todo.DeleteMode) error {
+			assert.Equal(t, todo.DeleteReparent, mode)
+			return nil
+		},
+		GetUserTodosFn: func(ctx context.Context, userID int, filter todo.TodoFilter) (*todo.TodoListResponse, error) {
+			require.NotNil(t, filter.TopLevel)
+			assert.True(t, *filter.TopLevel)
+			return &todo.TodoListResponse{}, nil
+		},
+	}
+
+	c := newTestClient(mockSvc)
+
+	var created struct {
+		CreateTodo struct{ ParentID string }
+	}
+	err := c.Post(`mutation { createTodo(input: {title: "Write copy", parentId: "1"}) { parentId } }`, &created, withAuthUserModifier(1))
+	require.NoError(t, err)
+	assert.Equal(t, "1", created.CreateTodo.ParentID)
+
+	var updated struct {
+		UpdateTodo struct{ ParentID *string }
+	}
+	err = c.Post(`mutation { updateTodo(id: "2", input: {clearParent: true}) { parentId } }`, &updated, withAuthUserModifier(1))
+	require.NoError(t, err)
+	assert.Nil(t, updated.UpdateTodo.ParentID)
+
+	var toggled struct {
+		ToggleTodo struct {
+			Progress struct{ Completed int }
+		}
+	}
+	err = c.Post(`mutation { toggleTodo(id: "1", cascade: true) { progress { completed } } }`, &toggled, withAuthUserModifier(1))
+	require.NoError(t, err)
+	assert.Equal(t, 2, toggled.ToggleTodo.Progress.Completed)
+
+	var deleted struct{ DeleteTodo bool }
+	err = c.Post(`mutation { deleteTodo(id: "1", mode: REPARENT) }`, &deleted, withAuthUserModifier(1))
+	require.NoError(t, err)
+	assert.True(t, deleted.DeleteTodo)
+
+	var list struct {
+		Todos struct{ Total int }
+	}
+	err = c.Post(`query { todos(filter: {topLevel: true}) { total } }`, &list, withAuthUserModifier(1))
+	require.NoError(t, err)
+}
+
 func TestQuery_TodoStats(t *testing.T) {
 	mockSvc := &MockTodoService{
 		GetUserTodoStatsFn: func(ctx context.Context, userID int) (*todo.TodoStats, error) {
@@ -488,35 +1062,105 @@ func TestQuery_TodoStats(t *testing.T) {
 }
 
 func TestSubscription_TodoChanged(t *testing.T) {
+	events := make(chan todo.Event, 2)
+	mockSvc := &MockTodoService{
+		SubscribeEventsFn: func(ctx context.Context, userID int) (<-chan todo.Event, error) {
+			assert.Equal(t, 1, userID)
+			return events, nil
+		},
+	}
+
+	c := newWebsocketTestClient(mockSvc, 1)
+
+	sub := c.Websocket(`subscription { todoChanged { kind todoId todo { id title } } }`)
+	defer sub.Close()
+
+	events <- todo.Event{
+		Kind:   todo.EventCreated,
+		UserID: 1,
+		TodoID: 7,
+		Todo:   &todo.Todo{ID: 7, Title: "Live Todo", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	}
+	events <- todo.Event{Kind: todo.EventDeleted, UserID: 1, TodoID: 7}
+
+	var resp struct {
+		TodoChanged struct {
+			Kind   string
+			TodoID string
+			Todo   *struct {
+				ID    string
+				Title string
+			}
+		}
+	}
+	require.NoError(t, sub.Next(&resp))
+	assert.Equal(t, "CREATED", resp.TodoChanged.Kind)
+	assert.Equal(t, "7", resp.TodoChanged.TodoID)
+	require.NotNil(t, resp.TodoChanged.Todo)
+	assert.Equal(t, "Live Todo", resp.TodoChanged.Todo.Title)
+
+	resp.TodoChanged.Todo = nil
+	require.NoError(t, sub.Next(&resp))
+	assert.Equal(t, "DELETED", resp.TodoChanged.Kind)
+	assert.Equal(t, "7", resp.TodoChanged.TodoID)
+	assert.Nil(t, resp.TodoChanged.Todo)
+}
+
+func TestSubscription_TodoChanged_Unauthorized(t *testing.T) {
 	mockSvc := &MockTodoService{}
 
 	c := newTestClient(mockSvc)
 
-	sub := c.Websocket(
-		`subscription { todoChanged { id } }`,
-		withAuthUserModifier(1),
-	)
+	sub := c.Websocket(`subscription { todoChanged { kind } }`)
 	defer sub.Close()
 
-	var resp struct{ TodoChanged struct{ ID string } }
+	var resp struct{ TodoChanged struct{ Kind string } }
 	err := sub.Next(&resp)
-	assert.Error(t, err) // Expect error for closed channel
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "user not authenticated")
 }
 
 func TestSubscription_TodoStatsChanged(t *testing.T) {
+	updates := make(chan *todo.TodoStats, 1)
+	mockSvc := &MockTodoService{
+		SubscribeStatsFn: func(ctx context.Context, userID int) (<-chan *todo.TodoStats, error) {
+			assert.Equal(t, 1, userID)
+			return updates, nil
+		},
+	}
+
+	c := newWebsocketTestClient(mockSvc, 1)
+
+	sub := c.Websocket(`subscription { todoStatsChanged { total completed pending } }`)
+	defer sub.Close()
+
+	updates <- &todo.TodoStats{Total: 3, Completed: 1, Pending: 2}
+
+	var resp struct {
+		TodoStatsChanged struct {
+			Total     int
+			Completed int
+			Pending   int
+		}
+	}
+	require.NoError(t, sub.Next(&resp))
+	assert.Equal(t, 3, resp.TodoStatsChanged.Total)
+	assert.Equal(t, 1, resp.TodoStatsChanged.Completed)
+	assert.Equal(t, 2, resp.TodoStatsChanged.Pending)
+}
+
+func TestSubscription_TodoStatsChanged_Unauthorized(t *testing.T) {
 	mockSvc := &MockTodoService{}
 
 	c := newTestClient(mockSvc)
 
-	sub := c.Websocket(
-		`subscription { todoStatsChanged { total } }`,
-		withAuthUserModifier(1),
-	)
+	sub := c.Websocket(`subscription { todoStatsChanged { total } }`)
 	defer sub.Close()
 
 	var resp struct{ TodoStatsChanged struct{ Total int } }
 	err := sub.Next(&resp)
 	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "user not authenticated")
 }
 
 // Helper for string pointers
@@ -0,0 +1,48 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// FileMailer writes each message to its own .eml file in a directory instead of sending it.
+// It is meant for local development, where links in the messages can be opened by hand.
+type FileMailer struct {
+	dir  string
+	from string
+	seq  atomic.Int64
+}
+
+// NewFileMailer creates a mailer that writes messages into dir, creating it if needed
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	return &FileMailer{
+		dir:  dir,
+		from: from,
+	}, nil
+}
+
+// Send writes msg to a new file named after the current time
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	if err := validate(msg); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%s-%d.eml", now.UTC().Format("20060102T150405.000000000"), m.seq.Add(1))
+
+	if err := os.WriteFile(filepath.Join(m.dir, name), format(m.from, msg, now), 0o600); err != nil {
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+
+	fmt.Printf("Mail: wrote %q for %s to %s\n", msg.Subject, msg.To, name)
+
+	return nil
+}
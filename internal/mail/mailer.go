@@ -0,0 +1,47 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// format renders msg as an RFC 5322 message from the given sender
+func format(from string, msg Message, date time.Time) []byte {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return []byte(b.String())
+}
+
+// validate rejects messages whose headers could inject extra headers or recipients
+func validate(msg Message) error {
+	if msg.To == "" {
+		return fmt.Errorf("message has no recipient")
+	}
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("message headers must not contain line breaks")
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMessage = Message{
+	To:      "user@example.com",
+	Subject: "Reset your password",
+	Body:    "Open this link:\nhttps://example.com/reset?token=abc",
+}
+
+func TestMemoryMailer(t *testing.T) {
+	mailer := NewMemoryMailer()
+
+	require.NoError(t, mailer.Send(context.Background(), testMessage))
+
+	select {
+	case <-mailer.Sent():
+	default:
+		t.Fatal("Sent should be signalled")
+	}
+
+	messages := mailer.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, testMessage, messages[0])
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer, err := NewFileMailer(dir, "noreply@example.com")
+	require.NoError(t, err)
+
+	require.NoError(t, mailer.Send(context.Background(), testMessage))
+	require.NoError(t, mailer.Send(context.Background(), testMessage))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "each message should get its own file")
+
+	data, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "From: noreply@example.com\r\n")
+	assert.Contains(t, string(data), "To: user@example.com\r\n")
+	assert.Contains(t, string(data), "Subject: Reset your password\r\n")
+	assert.Contains(t, string(data), "https://example.com/reset?token=abc")
+}
+
+func TestRejectsHeaderInjection(t *testing.T) {
+	mailer := NewMemoryMailer()
+
+	err := mailer.Send(context.Background(), Message{To: "user@example.com\r\nBcc: other@example.com", Subject: "Hi"})
+	assert.Error(t, err)
+
+	err = mailer.Send(context.Background(), Message{To: "user@example.com", Subject: "Hi\nBcc: other@example.com"})
+	assert.Error(t, err)
+
+	err = mailer.Send(context.Background(), Message{Subject: "Hi"})
+	assert.Error(t, err)
+
+	assert.Empty(t, mailer.Messages())
+}
+
+// startFakeSMTPServer accepts one SMTP conversation and sends the DATA payload on the returned channel
+func startFakeSMTPServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "DATA"):
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				received <- data.String()
+				reply("250 queued")
+			case strings.HasPrefix(cmd, "QUIT"):
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), received
+}
+
+func TestSMTPMailer(t *testing.T) {
+	addr, received := startFakeSMTPServer(t)
+	host, port, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+
+	mailer := NewSMTPMailer(host, port, "", "", "noreply@example.com")
+	require.NoError(t, mailer.Send(context.Background(), testMessage))
+
+	data := <-received
+	assert.Contains(t, data, "To: user@example.com\r\n")
+	assert.Contains(t, data, "Subject: Reset your password\r\n")
+	assert.Contains(t, data, "Open this link:\r\nhttps://example.com/reset?token=abc")
+}
+
+func TestSMTPMailerCancelledContext(t *testing.T) {
+	mailer := NewSMTPMailer("127.0.0.1", "1", "", "", "noreply@example.com")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, mailer.Send(ctx, testMessage), context.Canceled)
+}
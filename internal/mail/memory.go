@@ -0,0 +1,51 @@
+package mail
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryMailer records messages in memory. It is used by tests to inspect what would have been sent.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+	sent     chan struct{}
+}
+
+// NewMemoryMailer creates an empty in-memory mailer
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{
+		sent: make(chan struct{}, 1),
+	}
+}
+
+// Send records msg
+func (m *MemoryMailer) Send(ctx context.Context, msg Message) error {
+	if err := validate(msg); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.messages = append(m.messages, msg)
+	m.mu.Unlock()
+
+	select {
+	case m.sent <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Messages returns a copy of every message sent so far
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Message(nil), m.messages...)
+}
+
+// Sent is signalled after a message is recorded, for callers waiting on asynchronous sends
+func (m *MemoryMailer) Sent() <-chan struct{} {
+	return m.sent
+}
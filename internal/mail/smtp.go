@@ -0,0 +1,48 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer sends messages through an SMTP server, authenticating with PLAIN auth
+// when a username is configured. A local stand-in such as MailHog or Mailpit works for development.
+type SMTPMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer creates a mailer that sends through host:port as from
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPMailer{
+		addr: net.JoinHostPort(host, port),
+		from: from,
+		auth: auth,
+	}
+}
+
+// Send delivers msg. net/smtp has no context support, so ctx is only checked before sending.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := validate(msg); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, format(m.from, msg, time.Now())); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return nil
+}
@@ -2,23 +2,33 @@ package middleware
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"net/http"
 	"strings"
 
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/gin-gonic/gin"
 	"github.com/jayk0001/my-go-next-todo/internal/auth"
+	"github.com/vektah/gqlparser/v2/ast"
 )
 
 type ContextKey string
 
 const (
-	UserContextKey ContextKey = "github.com/jayk0001/my-go-next-todo/middleware.user"
+	UserContextKey      ContextKey = "github.com/jayk0001/my-go-next-todo/middleware.user"
+	SessionContextKey   ContextKey = "github.com/jayk0001/my-go-next-todo/middleware.session"
+	PrincipalContextKey ContextKey = "github.com/jayk0001/my-go-next-todo/middleware.principal"
 )
 
 // AuthMiddleware creates authentication middleware
 func AuthMiddleware(authService *auth.AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Record the client so sessions started by this request can describe the device
+		c.Request = c.Request.WithContext(auth.WithClientInfo(c.Request.Context(), auth.ClientInfo{
+			UserAgent: c.Request.UserAgent(),
+			IPAddress: c.ClientIP(),
+		}))
 
 		// Get token from Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -28,36 +38,125 @@ func AuthMiddleware(authService *auth.AuthService) gin.HandlerFunc {
 		}
 
 		// Extract token from "Bearer <token>" format
-		tokenParts := strings.Split(authHeader, " ")
-		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+		token, err := extractBearerToken(authHeader)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
 			c.Abort()
 			return
 		}
 
-		token := tokenParts[1]
-		if len(token) > 20 {
-			fmt.Printf("AuthMiddleware: Extracted token: %s...\n", token[:20])
-		} else {
-			fmt.Printf("AuthMiddleware: Extracted token: %s\n", token)
-		}
-
 		// Validate token and get user
-		user, err := authService.GetUserFromToken(c.Request.Context(), token)
+		principal, err := authService.AuthenticateToken(c.Request.Context(), token)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 
-		// Add user to context
-		ctx := context.WithValue(c.Request.Context(), UserContextKey, user)
-		c.Request = c.Request.WithContext(ctx)
+		// Add user and session to context
+		c.Request = c.Request.WithContext(withPrincipal(c.Request.Context(), principal))
 
 		c.Next()
 	}
 }
 
+// WebsocketInit authenticates graphql-ws connections from the connection_init payload.
+// Browsers cannot set headers on websocket upgrades, so clients send
+// {"Authorization": "Bearer <token>"} as the init payload instead.
+func WebsocketInit(authService *auth.AuthService) transport.WebsocketInitFunc {
+	return func(ctx context.Context, initPayload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		// Already authenticated through the upgrade request header
+		if _, ok := GetUserFromContext(ctx); ok {
+			return ctx, nil, nil
+		}
+
+		authHeader := initPayload.Authorization()
+		if authHeader == "" {
+			return ctx, nil, nil
+		}
+
+		token, err := extractBearerToken(authHeader)
+		if err != nil {
+			return ctx, nil, err
+		}
+
+		principal, err := authService.AuthenticateToken(ctx, token)
+		if err != nil {
+			return ctx, nil, errors.New("invalid or expired token")
+		}
+
+		return withPrincipal(ctx, principal), nil, nil
+	}
+}
+
+// ReadOnlyGuard rejects mutations from read-only principals, such as users who have not verified
+// their email yet, except for the named mutations they need to manage their account
+func ReadOnlyGuard(allowedMutations ...string) graphql.RootFieldMiddleware {
+	allowed := make(map[string]bool, len(allowedMutations))
+	for _, name := range allowedMutations {
+		allowed[name] = true
+	}
+
+	return func(ctx context.Context, next graphql.RootResolver) graphql.Marshaler {
+		principal, ok := GetPrincipalFromContext(ctx)
+		if !ok || !principal.ReadOnly {
+			return next(ctx)
+		}
+
+		if graphql.GetOperationContext(ctx).Operation.Operation != ast.Mutation {
+			return next(ctx)
+		}
+
+		field := graphql.GetRootFieldContext(ctx)
+		if allowed[field.Field.Name] {
+			return next(ctx)
+		}
+
+		graphql.AddErrorf(ctx, "%s is not allowed: %v", field.Field.Name, auth.ErrEmailNotVerified)
+		return graphql.Null
+	}
+}
+
+// ScopeGuard rejects principals with scopes, i.e. personal access tokens, on every root field
+// that doesn't declare the scope it needs with @hasScope, including fields open to anonymous
+// callers. Introspection is always allowed.
+func ScopeGuard() graphql.RootFieldMiddleware {
+	return func(ctx context.Context, next graphql.RootResolver) graphql.Marshaler {
+		principal, ok := GetPrincipalFromContext(ctx)
+		if !ok || principal.Scopes == nil {
+			return next(ctx)
+		}
+
+		field := graphql.GetRootFieldContext(ctx)
+		if strings.HasPrefix(field.Field.Name, "__") {
+			return next(ctx)
+		}
+
+		if field.Field.Definition == nil || field.Field.Definition.Directives.ForName("hasScope") == nil {
+			graphql.AddErrorf(ctx, "%s is not allowed: %v", field.Field.Name, auth.ErrPersonalAccessTokenNotAllowed)
+			return graphql.Null
+		}
+
+		return next(ctx)
+	}
+}
+
+// withPrincipal adds the authenticated user, session and token to ctx
+func withPrincipal(ctx context.Context, principal *auth.Principal) context.Context {
+	ctx = context.WithValue(ctx, UserContextKey, principal.User)
+	ctx = context.WithValue(ctx, SessionContextKey, principal.SessionID)
+	return context.WithValue(ctx, PrincipalContextKey, principal)
+}
+
+// extractBearerToken extracts the token from a "Bearer <token>" value
+func extractBearerToken(authHeader string) (string, error) {
+	tokenParts := strings.Split(authHeader, " ")
+	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+		return "", errors.New("invalid authorization header format")
+	}
+	return tokenParts[1], nil
+}
+
 // CORS middleware for development
 func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -79,3 +178,15 @@ func GetUserFromContext(ctx context.Context) (*auth.User, bool) {
 	user, ok := ctx.Value(UserContextKey).(*auth.User)
 	return user, ok
 }
+
+// GetSessionIDFromContext helper function to extract the current session ID from context
+func GetSessionIDFromContext(ctx context.Context) (string, bool) {
+	sessionID, ok := ctx.Value(SessionContextKey).(string)
+	return sessionID, ok && sessionID != ""
+}
+
+// GetPrincipalFromContext helper function to extract the authenticated principal from context
+func GetPrincipalFromContext(ctx context.Context) (*auth.Principal, bool) {
+	principal, ok := ctx.Value(PrincipalContextKey).(*auth.Principal)
+	return principal, ok
+}
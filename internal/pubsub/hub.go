@@ -0,0 +1,78 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultBufferSize is the number of messages buffered per subscriber
+const DefaultBufferSize = 16
+
+// Hub fans out messages to in-process subscribers grouped by user ID
+type Hub[T any] struct {
+	mu          sync.RWMutex
+	subscribers map[int]map[chan T]struct{}
+	bufferSize  int
+}
+
+// NewHub creates a new hub with the default buffer size
+func NewHub[T any]() *Hub[T] {
+	return &Hub[T]{
+		subscribers: make(map[int]map[chan T]struct{}),
+		bufferSize:  DefaultBufferSize,
+	}
+}
+
+// Subscribe registers a subscriber for the user's messages.
+// The returned channel is closed once ctx is done.
+func (h *Hub[T]) Subscribe(ctx context.Context, userID int) <-chan T {
+	ch := make(chan T, h.bufferSize)
+
+	h.mu.Lock()
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[chan T]struct{})
+	}
+	h.subscribers[userID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.unsubscribe(userID, ch)
+	}()
+
+	return ch
+}
+
+// Publish delivers msg to every subscriber of the user.
+// Slow subscribers whose buffer is full miss the message instead of blocking the publisher.
+func (h *Hub[T]) Publish(userID int, msg T) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch := range h.subscribers[userID] {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+// SubscriberCount returns the number of active subscribers for a user
+func (h *Hub[T]) SubscriberCount(userID int) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.subscribers[userID])
+}
+
+// unsubscribe removes and closes a subscriber channel
+func (h *Hub[T]) unsubscribe(userID int, ch chan T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.subscribers[userID], ch)
+	if len(h.subscribers[userID]) == 0 {
+		delete(h.subscribers, userID)
+	}
+	close(ch)
+}
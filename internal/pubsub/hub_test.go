@@ -0,0 +1,87 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHubPublishToSubscriber(t *testing.T) {
+	hub := NewHub[string]()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := hub.Subscribe(ctx, 1)
+	hub.Publish(1, "hello")
+
+	select {
+	case msg := <-ch:
+		assert.Equal(t, "hello", msg)
+	case <-time.After(time.Second):
+		t.Fatal("expected message")
+	}
+}
+
+func TestHubIsolatesUsers(t *testing.T) {
+	hub := NewHub[string]()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := hub.Subscribe(ctx, 1)
+	hub.Publish(2, "not for user 1")
+
+	select {
+	case msg := <-ch:
+		t.Fatalf("unexpected message: %s", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestHubFanOut(t *testing.T) {
+	hub := NewHub[int]()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := hub.Subscribe(ctx, 1)
+	second := hub.Subscribe(ctx, 1)
+	assert.Equal(t, 2, hub.SubscriberCount(1))
+
+	hub.Publish(1, 42)
+	assert.Equal(t, 42, <-first)
+	assert.Equal(t, 42, <-second)
+}
+
+func TestHubUnsubscribeOnContextDone(t *testing.T) {
+	hub := NewHub[string]()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ch := hub.Subscribe(ctx, 1)
+	cancel()
+
+	select {
+	case _, ok := <-ch:
+		require.False(t, ok, "channel should be closed")
+	case <-time.After(time.Second):
+		t.Fatal("channel was not closed")
+	}
+	assert.Equal(t, 0, hub.SubscriberCount(1))
+
+	// Publishing after unsubscribe must not panic
+	hub.Publish(1, "late")
+}
+
+func TestHubDropsForSlowSubscriber(t *testing.T) {
+	hub := NewHub[int]()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := hub.Subscribe(ctx, 1)
+	for i := 0; i < DefaultBufferSize+5; i++ {
+		hub.Publish(1, i)
+	}
+
+	assert.Len(t, ch, DefaultBufferSize)
+}
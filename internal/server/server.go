@@ -2,23 +2,40 @@ package server
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/vektah/gqlparser/v2/ast"
 
 	"github.com/jayk0001/my-go-next-todo/internal/auth"
 	"github.com/jayk0001/my-go-next-todo/internal/config"
 	"github.com/jayk0001/my-go-next-todo/internal/database"
+	"github.com/jayk0001/my-go-next-todo/internal/export"
 	"github.com/jayk0001/my-go-next-todo/internal/graphql/generated"
 	"github.com/jayk0001/my-go-next-todo/internal/graphql/resolver"
+	"github.com/jayk0001/my-go-next-todo/internal/mail"
 	"github.com/jayk0001/my-go-next-todo/internal/middleware"
 	"github.com/jayk0001/my-go-next-todo/internal/todo"
 )
 
+// oidcStateCookie holds the state of a sign-in started in the browser
+const oidcStateCookie = "oidc_state"
+
+// oidcAppCallbackPath is the frontend page that finishes a provider sign-in
+const oidcAppCallbackPath = "/auth/callback"
+
 // Server holds the HTTP server and dependencies
 type Server struct {
 	router      *gin.Engine
@@ -26,20 +43,100 @@ type Server struct {
 	config      *config.Config
 	AuthService *auth.AuthService
 	TodoService *todo.TodoService
+	Exporter    *export.Exporter
+
+	// cancel stops background workers started by New
+	cancel context.CancelFunc
 }
 
 // New creates a new server instance
-func New(cfg *config.Config, db *database.DB) *Server {
+func New(cfg *config.Config, db *database.DB) (*Server, error) {
 	// Set gin mode based on environment
 	if cfg.IsProduction() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// Load asymmetric signing keys before starting any background work
+	var signingKeys *auth.KeySet
+	if cfg.JWT.SigningKeyFile != "" {
+		keys, err := auth.LoadKeySet(cfg.JWT.SigningKeyFile, cfg.JWT.VerificationKeyFiles)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load JWT keys: %w", err)
+		}
+		signingKeys = keys
+	}
+
+	mailer, err := newMailer(cfg.Mail)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := newPasswordPolicy(cfg.Auth)
+	if err != nil {
+		return nil, err
+	}
+
 	router := gin.New()
 
-	// Initialize auth service
-	authService := auth.NewAuthService(db.Pool, cfg.JWT.Secret, cfg.JWT.ExpiryHours)
-	todoService := todo.NewTodoServiceWithDB(db.Pool)
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
+	// Background workers live until Close is called
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Initialize auth and todo services, fanning events out across replicas when enabled
+	var authService *auth.AuthService
+	var todoService *todo.TodoService
+	if cfg.Database.ListenNotify {
+		notifier := database.NewNotifier(db.Pool)
+		authService = auth.NewAuthServiceWithNotifier(db.Pool, cfg.JWT.Secret, cfg.JWT.ExpiryHours, notifier)
+		todoService = todo.NewTodoServiceWithNotifier(db.Pool, notifier)
+		go notifier.Run(ctx)
+	} else {
+		authService = auth.NewAuthService(db.Pool, cfg.JWT.Secret, cfg.JWT.ExpiryHours)
+		todoService = todo.NewTodoServiceWithDB(db.Pool)
+	}
+
+	// Share access-token revocations between instances when configured
+	if cfg.JWT.RevocationStore == "postgres" {
+		authService.SetRevocationStore(auth.NewPostgresRevocationStore(db.Pool))
+	}
+
+	if signingKeys != nil {
+		authService.SetSigningKeys(signingKeys)
+		authService.SetRejectHS256(cfg.JWT.RejectHS256)
+	}
+
+	authService.SetMailer(mailer, cfg.App.URL)
+
+	if cfg.Auth.UnverifiedPolicy != "" {
+		authService.SetUnverifiedPolicy(auth.UnverifiedPolicy(cfg.Auth.UnverifiedPolicy))
+	}
+
+	if cfg.Auth.TOTPIssuer != "" {
+		authService.SetTOTPIssuer(cfg.Auth.TOTPIssuer)
+	}
+
+	authService.SetMagicLinkAutoRegister(cfg.Auth.MagicLinkAutoRegister)
+
+	authService.SetLoginLimiter(newLoginLimiter(cfg.Auth, db))
+
+	if hasher := newPasswordHasher(cfg.Auth); hasher != nil {
+		authService.SetPasswordHasher(hasher)
+	}
+
+	if policy != nil {
+		authService.SetPasswordPolicy(*policy)
+	}
+
+	authService.SetOIDCProviders(newOIDCProviders(cfg.OIDC)...)
+
+	// Every new account starts with an inbox project
+	authService.SetUserCreatedHook(func(ctx context.Context, user *auth.User) error {
+		_, err := todoService.CreateInbox(ctx, user.ID)
+		return err
+	})
 
 	server := &Server{
 		router:      router,
@@ -47,6 +144,8 @@ func New(cfg *config.Config, db *database.DB) *Server {
 		config:      cfg,
 		AuthService: authService, // save to struct
 		TodoService: todoService,
+		Exporter:    export.NewExporterWithDB(db.Pool),
+		cancel:      cancel,
 	}
 
 	// Middleware (get authService from struct)
@@ -56,8 +155,105 @@ func New(cfg *config.Config, db *database.DB) *Server {
 	router.Use(middleware.AuthMiddleware(server.AuthService))
 
 	server.setupRoutes()
-	return server
+	return server, nil
+
+}
+
+// newMailer creates the mailer selected by the mail driver setting
+func newMailer(cfg config.MailConfig) (mail.Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From), nil
+	case "file":
+		mailer, err := mail.NewFileMailer(cfg.FileDir, cfg.From)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create mailer: %w", err)
+		}
+		return mailer, nil
+	default:
+		return mail.NewMemoryMailer(), nil
+	}
+}
+
+// newLoginLimiter creates the login brute-force protection configured by the auth settings
+func newLoginLimiter(cfg config.AuthConfig, db *database.DB) *auth.LoginLimiter {
+	var store auth.LoginAttemptStore = auth.NewMemoryLoginAttemptStore()
+	if cfg.LoginAttemptStore == "postgres" {
+		store = auth.NewPostgresLoginAttemptStore(db.Pool)
+	}
+
+	// Configs built without Load (e.g. in tests) get the defaults
+	if cfg.LockoutDuration == 0 {
+		return auth.NewLoginLimiter(store, auth.DefaultLockoutPolicy())
+	}
+
+	return auth.NewLoginLimiter(store, auth.LockoutPolicy{
+		Account:         auth.LockoutThreshold{BackoffAfter: cfg.AccountBackoffAfter, LockAfter: cfg.AccountLockAfter},
+		IP:              auth.LockoutThreshold{BackoffAfter: cfg.IPBackoffAfter, LockAfter: cfg.IPLockAfter},
+		BackoffBase:     cfg.LoginBackoffBase,
+		BackoffMax:      cfg.LoginBackoffMax,
+		LockoutDuration: cfg.LockoutDuration,
+	})
+}
 
+// newPasswordHasher creates the hasher for new passwords, or nil to keep the default
+func newPasswordHasher(cfg config.AuthConfig) auth.PasswordHasher {
+	switch cfg.PasswordHasher {
+	case "argon2id":
+		params := auth.DefaultArgon2Params()
+		params.Memory = uint32(cfg.Argon2MemoryKiB)
+		params.Iterations = uint32(cfg.Argon2Iterations)
+		params.Parallelism = uint8(cfg.Argon2Parallelism)
+		return auth.NewArgon2idHasher(params)
+	case "bcrypt":
+		return auth.NewBcryptHasher(cfg.BcryptCost)
+	default:
+		return nil
+	}
+}
+
+// newOIDCProviders creates a client for every configured OpenID Connect provider
+func newOIDCProviders(cfg config.OIDCConfig) []*auth.OIDCProvider {
+	var providers []*auth.OIDCProvider
+	for _, provider := range cfg.Providers {
+		providers = append(providers, auth.NewOIDCProvider(auth.OIDCProviderConfig{
+			Name:         provider.Name,
+			Issuer:       provider.Issuer,
+			ClientID:     provider.ClientID,
+			ClientSecret: provider.ClientSecret,
+			RedirectURL:  provider.RedirectURL,
+			Scopes:       provider.Scopes,
+		}, nil))
+	}
+	return providers
+}
+
+// newPasswordPolicy creates the policy for new passwords, or nil to keep the default
+func newPasswordPolicy(cfg config.AuthConfig) (*auth.PasswordPolicy, error) {
+	if cfg.PasswordMinLength == 0 {
+		return nil, nil
+	}
+
+	policy := auth.PasswordPolicy{
+		MinLength:            cfg.PasswordMinLength,
+		MaxLength:            cfg.PasswordMaxLength,
+		MinCharacterClasses:  cfg.PasswordMinCharacterClasses,
+		RejectEmailLocalPart: cfg.PasswordRejectEmail,
+	}
+
+	switch cfg.PasswordBreachedList {
+	case "none":
+	case "bundled":
+		policy.Breached = auth.NewCommonPasswordList()
+	default:
+		list, err := auth.OpenSHA1PasswordFile(cfg.PasswordBreachedList)
+		if err != nil {
+			return nil, err
+		}
+		policy.Breached = list
+	}
+
+	return &policy, nil
 }
 
 // setupRoutes configures all the routes
@@ -70,9 +266,22 @@ func (s *Server) setupRoutes() {
 		health.GET("live", s.livenessCheck)
 	}
 
+	// Public keys for verifying our access tokens
+	s.router.GET("/.well-known/jwks.json", s.jwks)
+
 	// GraphQL routes
 	s.setupGraphQL()
 
+	// Personal data export, authenticated by a bearer token or a link from requestDataExport
+	s.router.GET(export.DownloadPath, s.exportData)
+
+	// Sign in through OpenID Connect providers
+	oidc := s.router.Group("/auth/oidc/:provider")
+	{
+		oidc.GET("login", s.oidcLogin)
+		oidc.GET("callback", s.oidcCallback)
+	}
+
 	// API version 1
 	v1 := s.router.Group("/api/v1")
 	{
@@ -88,6 +297,14 @@ func (s *Server) Start() error {
 	return s.router.Run(addr)
 }
 
+// Close stops background workers such as the LISTEN/NOTIFY relay
+func (s *Server) Close() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.TodoService.Close()
+}
+
 // Router returns the gin router (useful for testing)
 func (s *Server) Router() *gin.Engine {
 	return s.router
@@ -99,17 +316,47 @@ func (s *Server) setupGraphQL() {
 	resolverInstance := resolver.NewResolver(s.AuthService, s.TodoService)
 
 	// create GraphQL server
-	gqlServer := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{
-		Resolvers: resolverInstance,
-	}))
+	gqlServer := handler.New(generated.NewExecutableSchema(resolver.NewConfig(resolverInstance)))
+
+	// Websocket transport (graphql-ws) for subscriptions
+	gqlServer.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		Upgrader: websocket.Upgrader{
+			// CORS allows any origin, so the websocket upgrade does too
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		InitFunc: middleware.WebsocketInit(s.AuthService),
+	})
+	gqlServer.AddTransport(transport.Options{})
+	gqlServer.AddTransport(transport.GET{})
+	gqlServer.AddTransport(transport.POST{})
+	gqlServer.AddTransport(transport.MultipartForm{})
+
+	gqlServer.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+
+	// Read-only users can still manage their credentials and account and verify their email
+	gqlServer.AroundRootFields(middleware.ReadOnlyGuard(
+		"register", "login", "requestMagicLink", "consumeMagicLink", "refreshToken", "logout", "revokeSession", "revokeOtherSessions",
+		"requestPasswordReset", "resetPassword", "verifyEmail", "resendVerification", "verifyTwoFactor",
+		"changePassword", "changeEmail", "updateTimeZone", "deleteAccount", "requestDataExport",
+	))
+
+	// Personal access tokens only reach root fields that declare a scope with @hasScope
+	gqlServer.AroundRootFields(middleware.ScopeGuard())
+
+	gqlServer.Use(extension.Introspection{})
+	gqlServer.Use(extension.AutomaticPersistedQuery{
+		Cache: lru.New[string](100),
+	})
 
 	// Playground only available in Development mode
 	if s.config.IsDevelopment() {
 		s.router.GET("/graphql", gin.WrapH(playground.Handler("GraphQL Playground", "/graphql/query")))
 	}
 
-	// GraphQL endpoint
+	// GraphQL endpoint (GET handles websocket upgrades for subscriptions)
 	s.router.POST("/graphql/query", gin.WrapH(gqlServer))
+	s.router.GET("/graphql/query", gin.WrapH(gqlServer))
 }
 
 // healthCheck handles GET /health
@@ -152,6 +399,162 @@ func (s *Server) readinessCheck(c *gin.Context) {
 	})
 }
 
+// jwks handles GET /.well-known/jwks.json
+func (s *Server) jwks(c *gin.Context) {
+	// Verifiers may cache keys briefly; rotations keep the old key listed until its tokens expire
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, s.AuthService.JWKS())
+}
+
+// exportData handles GET /api/v1/account/export
+func (s *Server) exportData(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var userID int
+	if token := c.Query("token"); token != "" {
+		user, err := s.AuthService.RedeemDataExportToken(ctx, token)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "invalid or expired download link",
+			})
+			return
+		}
+		userID = user.ID
+	} else if principal, ok := middleware.GetPrincipalFromContext(ctx); ok {
+		if principal.Scopes != nil {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": auth.ErrPersonalAccessTokenNotAllowed.Error(),
+			})
+			return
+		}
+		userID = principal.User.ID
+	} else {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("data-export-%s.zip", time.Now().UTC().Format("2006-01-02"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	if err := s.Exporter.WriteArchive(ctx, c.Writer, userID); err != nil {
+		fmt.Printf("Server: data export for user %d failed: %v\n", userID, err)
+
+		// Nothing has been sent yet, so the client can still be told it failed
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "failed to export data",
+			})
+		}
+	}
+}
+
+// oidcLogin handles GET /auth/oidc/:provider/login
+func (s *Server) oidcLogin(c *gin.Context) {
+	provider := c.Param("provider")
+
+	authURL, state, err := s.AuthService.StartOIDCLogin(c.Request.Context(), provider)
+	if err != nil {
+		if errors.Is(err, auth.ErrOIDCProviderUnknown) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		fmt.Printf("Server: failed to start %s sign-in: %v\n", provider, err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": "failed to start sign-in",
+		})
+		return
+	}
+
+	// The callback must come back to the browser that started the sign-in, so an attacker
+	// can't get a victim signed in to the attacker's account with their own callback URL
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, int(auth.OIDCLoginStateExpiry.Seconds()), oidcCookiePath(provider), "", s.config.IsProduction(), true)
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// oidcCallback handles GET /auth/oidc/:provider/callback, sending the browser back to the
+// frontend with tokens, a two-factor challenge or an error code in the URL fragment
+func (s *Server) oidcCallback(c *gin.Context) {
+	provider := c.Param("provider")
+
+	cookieState, _ := c.Cookie(oidcStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, oidcCookiePath(provider), "", s.config.IsProduction(), true)
+
+	if providerError := c.Query("error"); providerError != "" {
+		s.redirectToApp(c, url.Values{"error": {"provider_error"}})
+		return
+	}
+
+	state := c.Query("state")
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(cookieState)) != 1 {
+		s.redirectToApp(c, url.Values{"error": {"invalid_state"}})
+		return
+	}
+
+	result, err := s.AuthService.CompleteOIDCLogin(c.Request.Context(), provider, state, c.Query("code"))
+
+	var required *auth.TwoFactorRequiredError
+	switch {
+	case errors.As(err, &required):
+		s.redirectToApp(c, url.Values{
+			"two_factor_challenge": {required.Challenge},
+			"expires_at":           {required.ExpiresAt.UTC().Format(time.RFC3339)},
+		})
+	case err != nil:
+		fmt.Printf("Server: %s sign-in failed: %v\n", provider, err)
+		s.redirectToApp(c, url.Values{"error": {oidcErrorCode(err)}})
+	default:
+		s.redirectToApp(c, url.Values{
+			"access_token":  {result.Token},
+			"refresh_token": {result.RefreshToken},
+			"expires_at":    {strconv.FormatInt(result.ExpiresAt.Unix(), 10)},
+		})
+	}
+}
+
+// redirectToApp sends the browser to the frontend's sign-in callback page. Values go in the
+// fragment, which browsers don't send to servers or put in Referer headers.
+func (s *Server) redirectToApp(c *gin.Context, values url.Values) {
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, s.config.App.URL+oidcAppCallbackPath+"#"+values.Encode())
+}
+
+// oidcErrorCode maps a failed sign-in to an error code the frontend can show a message for
+func oidcErrorCode(err error) string {
+	switch {
+	case errors.Is(err, auth.ErrOIDCStateInvalid):
+		return "invalid_state"
+	case errors.Is(err, auth.ErrOIDCProviderUnknown):
+		return "unknown_provider"
+	case errors.Is(err, auth.ErrOIDCEmailNotVerified):
+		return "provider_email_not_verified"
+	case errors.Is(err, auth.ErrIdentityLinkUnverified):
+		return "account_not_verified"
+	case errors.Is(err, auth.ErrEmailNotVerified):
+		return "email_not_verified"
+	case errors.Is(err, auth.ErrAccountDisabled):
+		return "account_disabled"
+	default:
+		return "sign_in_failed"
+	}
+}
+
+// oidcCookiePath limits the state cookie to one provider's sign-in routes
+func oidcCookiePath(provider string) string {
+	return "/auth/oidc/" + provider
+}
+
 // livenessCheck handles get /ealth/live
 func (s *Server) livenessCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
@@ -1,6 +1,7 @@
 package server_test
 
 import (
+	"archive/zip"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -70,7 +71,8 @@ func TestE2E_TodoOperations(t *testing.T) {
 	require.NoError(t, database.RunMigrations(pool))
 
 	// Create Server instance (now initializes all services internally)
-	srv := server.New(testCfg, db)
+	srv, err := server.New(testCfg, db)
+	require.NoError(t, err)
 
 	// Use the router for httptest
 	ginRouter := srv.Router()
@@ -336,6 +338,49 @@ func TestE2E_TodoOperations(t *testing.T) {
 	assert.Equal(t, 2, statsResult.Data.TodoStats.Completed)
 	assert.Equal(t, 0, statsResult.Data.TodoStats.Pending)
 
+	// Step 7b: Export account data through a one-time download link
+	resp = makeGQLRequest(`mutation { requestDataExport { downloadUrl expiresAt } }`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var exportResult struct {
+		Data struct {
+			RequestDataExport struct {
+				DownloadURL string `json:"downloadUrl"`
+				ExpiresAt   string `json:"expiresAt"`
+			} `json:"requestDataExport"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&exportResult)
+	require.NoError(t, err)
+	if len(exportResult.Errors) > 0 {
+		t.Fatalf("GraphQL export errors: %v", exportResult.Errors)
+	}
+	downloadURL := testServer.URL + exportResult.Data.RequestDataExport.DownloadURL
+
+	resp, err = httpClient.Get(downloadURL)
+	require.NoError(t, err)
+	archive, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/zip", resp.Header.Get("Content-Type"))
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.ElementsMatch(t, []string{"profile.json", "todos.json", "sessions.json", "audit_events.json"}, names)
+
+	// The link only works once
+	resp, err = httpClient.Get(downloadURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
 	// Step 8: Delete Todo
 	deleteQuery := `mutation { deleteTodo(id: "` + todoID + `") }`
 	resp = makeGQLRequest(deleteQuery)
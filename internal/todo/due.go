@@ -0,0 +1,67 @@
+package todo
+
+import "time"
+
+// calendarDay is the current day in a user's time zone. It decides which todos are
+// overdue or due today, for the mock repository as well as the SQL in TodoRepository.
+type calendarDay struct {
+	now time.Time
+	// start and end are the midnights that begin this day and the next in the user's time zone
+	start time.Time
+	end   time.Time
+	// date is the day at midnight UTC, the way all-day due dates are stored
+	date time.Time
+}
+
+// newCalendarDay returns the day containing now in loc
+func newCalendarDay(now time.Time, loc *time.Location) *calendarDay {
+	local := now.In(loc)
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+	return &calendarDay{
+		now:   now,
+		start: start,
+		end:   start.AddDate(0, 0, 1),
+		date:  dateOf(local),
+	}
+}
+
+// isOverdue reports whether an open todo is past its due time.
+// All-day todos only become overdue once their date has ended.
+func (d *calendarDay) isOverdue(todo *Todo) bool {
+	if todo.DueAt == nil || todo.Completed {
+		return false
+	}
+
+	if todo.AllDay {
+		return todo.DueAt.Before(d.date)
+	}
+	return todo.DueAt.Before(d.now)
+}
+
+// isDueToday reports whether a todo is due at some point during the day
+func (d *calendarDay) isDueToday(todo *Todo) bool {
+	if todo.DueAt == nil {
+		return false
+	}
+
+	if todo.AllDay {
+		return todo.DueAt.Equal(d.date)
+	}
+	return !todo.DueAt.Before(d.start) && todo.DueAt.Before(d.end)
+}
+
+// dateOf returns the calendar date of t, in t's own offset, at midnight UTC
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// normalizeDueAt reduces all-day due times to their date
+func normalizeDueAt(dueAt *time.Time, allDay bool) *time.Time {
+	if dueAt == nil || !allDay {
+		return dueAt
+	}
+
+	date := dateOf(*dueAt)
+	return &date
+}
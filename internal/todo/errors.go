@@ -20,4 +20,58 @@ var (
 
 	// ErrTodoDescriptionTooLoing is return when description exceeds max lengths
 	ErrTodoDescriptionTooLong = errors.New("todo description too long (max 2000 characters)")
+
+	// ErrTodoAllDayWithoutDueDate is returned when a todo is marked all-day without a due date
+	ErrTodoAllDayWithoutDueDate = errors.New("all-day todos need a due date")
+
+	// ErrInvalidTodoPriority is returned for a priority that is not one of the defined levels
+	ErrInvalidTodoPriority = errors.New("invalid todo priority (must be none, low, medium, high or urgent)")
+
+	// ErrInvalidTodoSort is returned when todos are sorted by an unknown field
+	ErrInvalidTodoSort = errors.New("invalid todo sort field")
+
+	// ErrTodoCycle is returned when a todo would be nested under itself or one of its subtasks
+	ErrTodoCycle = errors.New("a todo can't be nested under itself or its subtasks")
+
+	// ErrTodoTooDeep is returned when subtasks would be nested deeper than MaxTodoDepth
+	ErrTodoTooDeep = errors.New("todos can't be nested more than 3 levels deep")
+
+	// ErrInvalidDeleteMode is returned for an unknown way of handling subtasks on delete
+	ErrInvalidDeleteMode = errors.New("invalid delete mode (must be cascade or reparent)")
+
+	// ErrTagNotFound is returned when a tag doesn't exist or belongs to another user
+	ErrTagNotFound = errors.New("tag not found")
+
+	// ErrTagNameRequired is returned when a tag name is empty
+	ErrTagNameRequired = errors.New("tag name is required")
+
+	// ErrTagNameTooLong is returned when a tag name exceeds max length
+	ErrTagNameTooLong = errors.New("tag name too long (max 50 characters)")
+
+	// ErrTagNameTaken is returned when the user already has a tag with the same name
+	ErrTagNameTaken = errors.New("a tag with this name already exists")
+
+	// ErrInvalidTagColor is returned when a tag color is not a #RRGGBB hex color
+	ErrInvalidTagColor = errors.New("invalid tag color (must be a hex color like #3B82F6)")
+
+	// ErrProjectNotFound is returned when a project doesn't exist or belongs to another user
+	ErrProjectNotFound = errors.New("project not found")
+
+	// ErrProjectNameRequired is returned when a project name is empty
+	ErrProjectNameRequired = errors.New("project name is required")
+
+	// ErrProjectNameTooLong is returned when a project name exceeds max length
+	ErrProjectNameTooLong = errors.New("project name too long (max 100 characters)")
+
+	// ErrInvalidProjectColor is returned when a project color is not a #RRGGBB hex color
+	ErrInvalidProjectColor = errors.New("invalid project color (must be a hex color like #3B82F6)")
+
+	// ErrInvalidProjectPosition is returned for a negative sort position
+	ErrInvalidProjectPosition = errors.New("project position can't be negative")
+
+	// ErrProjectArchived is returned when todos are added to or moved into an archived project
+	ErrProjectArchived = errors.New("project is archived")
+
+	// ErrInboxProject is returned when the inbox would be archived or deleted
+	ErrInboxProject = errors.New("the inbox can't be archived or deleted")
 )
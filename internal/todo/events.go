@@ -0,0 +1,55 @@
+package todo
+
+import (
+	"context"
+
+	"github.com/jayk0001/my-go-next-todo/internal/database"
+)
+
+// EventChannel is the Postgres NOTIFY channel used to fan todo events out across instances
+const EventChannel = "todo_events"
+
+// EventKind identifies the mutation that produced an Event
+type EventKind string
+
+const (
+	EventCreated      EventKind = "CREATED"
+	EventUpdated      EventKind = "UPDATED"
+	EventDeleted      EventKind = "DELETED"
+	EventToggled      EventKind = "TOGGLED"
+	EventBatchUpdated EventKind = "BATCH_UPDATED"
+)
+
+// Event describes a change to one of a user's todos
+type Event struct {
+	Kind   EventKind `json:"kind"`
+	UserID int       `json:"user_id"`
+	TodoID int       `json:"todo_id"`
+	Todo   *Todo     `json:"todo,omitempty"` // nil for deleted todos
+}
+
+// Notifier broadcasts payloads on a Postgres NOTIFY channel
+type Notifier interface {
+	Notify(ctx context.Context, channel, payload string) error
+}
+
+// EventRelay is a Notifier that also delivers the payloads broadcast on a channel
+type EventRelay interface {
+	Notifier
+	Subscribe(channel string, handler database.NotificationHandler)
+}
+
+type eventKindKey struct{}
+
+// withEventKind overrides the event kind reported by repository writes (e.g. updates made by a batch)
+func withEventKind(ctx context.Context, kind EventKind) context.Context {
+	return context.WithValue(ctx, eventKindKey{}, kind)
+}
+
+// eventKindFromContext returns the overridden event kind or fallback
+func eventKindFromContext(ctx context.Context, fallback EventKind) EventKind {
+	if kind, ok := ctx.Value(eventKindKey{}).(EventKind); ok {
+		return kind
+	}
+	return fallback
+}
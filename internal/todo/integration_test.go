@@ -3,8 +3,10 @@ package todo
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jayk0001/my-go-next-todo/internal/auth"
 	"github.com/jayk0001/my-go-next-todo/internal/database"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -13,7 +15,9 @@ import (
 	"github.com/testcontainers/testcontainers-go/wait"
 )
 
-func TestTodoCRUD_Integration(t *testing.T) {
+// setupIntegrationDB starts a Postgres test container, runs migrations and returns a pool
+func setupIntegrationDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
 	ctx := context.Background()
 
 	// Start test DB container
@@ -37,8 +41,15 @@ func TestTodoCRUD_Integration(t *testing.T) {
 	// Run migrations (assume a func RunMigrations(pool))
 	require.NoError(t, database.RunMigrations(pool)) // Implement this to create todos table
 
+	return pool
+}
+
+func TestTodoCRUD_Integration(t *testing.T) {
+	ctx := context.Background()
+	pool := setupIntegrationDB(t)
+
 	// Create a test user (since todos has foreign key to users)
-	_, err = pool.Exec(ctx, `
+	_, err := pool.Exec(ctx, `
 		INSERT INTO users (email, password_hash) VALUES ('test@example.com', 'test_hash')
 	`)
 	require.NoError(t, err)
@@ -64,10 +75,398 @@ func TestTodoCRUD_Integration(t *testing.T) {
 	assert.True(t, updated.Completed)
 
 	// Test Delete
-	err = service.DeleteTodo(ctx, created.ID, 1)
+	err = service.DeleteTodo(ctx, created.ID, 1, DeleteCascade)
 	assert.NoError(t, err)
 
 	// Verify deleted
 	_, err = service.GetTodo(ctx, created.ID, 1)
 	assert.ErrorIs(t, err, ErrTodoNotFound)
 }
+
+// TestDeleteUserCascadesTodos_Integration checks that deleting an account removes its todos and sessions
+// but leaves other users' todos alone
+func TestDeleteUserCascadesTodos_Integration(t *testing.T) {
+	ctx := context.Background()
+	pool := setupIntegrationDB(t)
+
+	users := auth.NewUserRepository(pool)
+	owner, err := users.Create(ctx, auth.CreateUserInput{Email: "owner@example.com", Password: "password123"})
+	require.NoError(t, err)
+	other, err := users.Create(ctx, auth.CreateUserInput{Email: "other@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	_, err = auth.NewSessionRepository(pool).Create(ctx, owner.ID, auth.ClientInfo{})
+	require.NoError(t, err)
+
+	service := NewTodoService(NewTodoRepository(pool), NewValidatorService())
+	for _, userID := range []int{owner.ID, owner.ID, other.ID} {
+		_, err := service.CreateTodo(ctx, userID, CreateTodoInput{Title: "Todo"})
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, users.Delete(ctx, owner.ID))
+
+	var todos, sessions int
+	require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM todos WHERE user_id = $1`, owner.ID).Scan(&todos))
+	require.NoError(t, pool.QueryRow(ctx, `SELECT COUNT(*) FROM sessions WHERE user_id = $1`, owner.ID).Scan(&sessions))
+	assert.Zero(t, todos)
+	assert.Zero(t, sessions)
+
+	stats, err := service.GetUserTodoStats(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Total)
+
+	assert.ErrorIs(t, users.Delete(ctx, owner.ID), auth.ErrUserNotFound)
+}
+
+// TestTodoDueFilters_Integration checks the overdue and due-today SQL against the user's time zone
+func TestTodoDueFilters_Integration(t *testing.T) {
+	ctx := context.Background()
+	pool := setupIntegrationDB(t)
+
+	users := auth.NewUserRepository(pool)
+	user, err := users.Create(ctx, auth.CreateUserInput{Email: "due@example.com", Password: "password123"})
+	require.NoError(t, err)
+	require.NoError(t, users.UpdateTimeZone(ctx, user.ID, "Pacific/Kiritimati"))
+
+	repo := NewTodoRepository(pool)
+	service := NewTodoService(repo, NewValidatorService())
+
+	loc, err := repo.GetUserLocation(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Pacific/Kiritimati", loc.String())
+
+	today := newCalendarDay(time.Now(), loc)
+	yesterday := today.date.AddDate(0, 0, -1)
+	pastHour := time.Now().Add(-time.Hour)
+	laterToday := today.end.Add(-time.Second)
+
+	for _, input := range []CreateTodoInput{
+		{Title: "Late", DueAt: &pastHour},
+		{Title: "Yesterday", DueAt: &yesterday, AllDay: true},
+		{Title: "Today", DueAt: &today.date, AllDay: true},
+		{Title: "Later today", DueAt: &laterToday},
+		{Title: "Someday"},
+	} {
+		_, err := service.CreateTodo(ctx, user.ID, input)
+		require.NoError(t, err)
+	}
+
+	overdue, err := service.GetUserTodos(ctx, user.ID, TodoFilter{Overdue: boolPtr(true)})
+	require.NoError(t, err)
+	assert.Equal(t, 2, overdue.Total)
+
+	notOverdue, err := service.GetUserTodos(ctx, user.ID, TodoFilter{Overdue: boolPtr(false)})
+	require.NoError(t, err)
+	assert.Equal(t, 3, notOverdue.Total)
+
+	dueToday, err := service.GetUserTodos(ctx, user.ID, TodoFilter{DueToday: boolPtr(true)})
+	require.NoError(t, err)
+	var titles []string
+	for _, todo := range dueToday.Todos {
+		titles = append(titles, todo.Title)
+	}
+	assert.Contains(t, titles, "Today")
+	assert.Contains(t, titles, "Later today")
+	assert.NotContains(t, titles, "Yesterday")
+
+	stats, err := service.GetUserTodoStats(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Overdue)
+}
+
+func TestTodoTags_Integration(t *testing.T) {
+	ctx := context.Background()
+	pool := setupIntegrationDB(t)
+
+	users := auth.NewUserRepository(pool)
+	user, err := users.Create(ctx, auth.CreateUserInput{Email: "tags@example.com", Password: "password123"})
+	require.NoError(t, err)
+	other, err := users.Create(ctx, auth.CreateUserInput{Email: "other@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	service := NewTodoService(NewTodoRepository(pool), NewValidatorService())
+
+	work, err := service.CreateTag(ctx, user.ID, CreateTagInput{Name: "work"})
+	require.NoError(t, err)
+	home, err := service.CreateTag(ctx, user.ID, CreateTagInput{Name: "Home", Color: "#22c55e"})
+	require.NoError(t, err)
+	assert.Equal(t, "#22C55E", home.Color)
+
+	_, err = service.CreateTag(ctx, user.ID, CreateTagInput{Name: "WORK"})
+	assert.ErrorIs(t, err, ErrTagNameTaken)
+
+	theirs, err := service.CreateTag(ctx, other.ID, CreateTagInput{Name: "work"})
+	require.NoError(t, err)
+
+	report, err := service.CreateTodo(ctx, user.ID, CreateTodoInput{Title: "Report"})
+	require.NoError(t, err)
+	laundry, err := service.CreateTodo(ctx, user.ID, CreateTodoInput{Title: "Laundry"})
+	require.NoError(t, err)
+
+	tagged, err := service.AddTags(ctx, report.ID, user.ID, []int{work.ID, home.ID})
+	require.NoError(t, err)
+	require.Len(t, tagged.Tags, 2)
+	assert.Equal(t, "Home", tagged.Tags[0].Name)
+
+	_, err = service.AddTags(ctx, laundry.ID, user.ID, []int{theirs.ID})
+	assert.ErrorIs(t, err, ErrTagNotFound)
+
+	_, err = service.BatchUpdateTodos(ctx, user.ID, []int{report.ID, laundry.ID}, UpdateTodoInput{
+		AddTagIDs:    []int{home.ID},
+		RemoveTagIDs: []int{work.ID},
+	})
+	require.NoError(t, err)
+
+	list, err := service.GetUserTodos(ctx, user.ID, TodoFilter{TagsAll: []int{home.ID}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, list.Total)
+	for _, todo := range list.Todos {
+		require.Len(t, todo.Tags, 1)
+		assert.Equal(t, home.ID, todo.Tags[0].ID)
+	}
+
+	list, err = service.GetUserTodos(ctx, user.ID, TodoFilter{TagsAny: []int{work.ID}})
+	require.NoError(t, err)
+	assert.Equal(t, 0, list.Total)
+
+	// Deleting a tag detaches it everywhere
+	require.NoError(t, service.DeleteTag(ctx, home.ID, user.ID))
+	got, err := service.GetTodo(ctx, report.ID, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, got.Tags)
+}
+
+// TestTodoEvents_ListenNotify_Integration simulates two API replicas sharing one database:
+// a mutation handled by instance A must reach a subscriber connected to instance B.
+func TestTodoProjects_Integration(t *testing.T) {
+	ctx := context.Background()
+	pool := setupIntegrationDB(t)
+
+	users := auth.NewUserRepository(pool)
+	user, err := users.Create(ctx, auth.CreateUserInput{Email: "projects@example.com", Password: "password123"})
+	require.NoError(t, err)
+	other, err := users.Create(ctx, auth.CreateUserInput{Email: "other@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	service := NewTodoService(NewTodoRepository(pool), NewValidatorService())
+
+	// Todos without a project land in a lazily created inbox
+	loose, err := service.CreateTodo(ctx, user.ID, CreateTodoInput{Title: "Loose end"})
+	require.NoError(t, err)
+	require.NotNil(t, loose.Project)
+	assert.True(t, loose.Project.IsInbox)
+	inbox, err := service.CreateInbox(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, loose.ProjectID, inbox.ID)
+
+	work, err := service.CreateProject(ctx, user.ID, CreateProjectInput{Name: "Work"})
+	require.NoError(t, err)
+	home, err := service.CreateProject(ctx, user.ID, CreateProjectInput{Name: "Home"})
+	require.NoError(t, err)
+	assert.Greater(t, home.Position, work.Position)
+	theirs, err := service.CreateProject(ctx, other.ID, CreateProjectInput{Name: "Theirs"})
+	require.NoError(t, err)
+
+	report, err := service.CreateTodo(ctx, user.ID, CreateTodoInput{Title: "Report", ProjectID: &work.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "Work", report.Project.Name)
+
+	_, err = service.CreateTodo(ctx, user.ID, CreateTodoInput{Title: "Sneaky", ProjectID: &theirs.ID})
+	assert.ErrorIs(t, err, ErrProjectNotFound)
+
+	moved, err := service.MoveTodos(ctx, user.ID, []int{loose.ID}, work.ID)
+	require.NoError(t, err)
+	require.Len(t, moved, 1)
+	assert.Equal(t, work.ID, moved[0].ProjectID)
+
+	list, err := service.GetUserTodos(ctx, user.ID, TodoFilter{ProjectID: &work.ID})
+	require.NoError(t, err)
+	assert.Equal(t, 2, list.Total)
+
+	_, err = service.ToggleTodoComplete(ctx, report.ID, user.ID, false)
+	require.NoError(t, err)
+	stats, err := service.GetProjectTodoStats(ctx, work.ID, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Total)
+	assert.Equal(t, 1, stats.Completed)
+
+	// Archived projects are hidden and can't receive todos
+	archived := true
+	_, err = service.UpdateProject(ctx, home.ID, user.ID, UpdateProjectInput{Archived: &archived})
+	require.NoError(t, err)
+	_, err = service.MoveTodos(ctx, user.ID, []int{report.ID}, home.ID)
+	assert.ErrorIs(t, err, ErrProjectArchived)
+
+	projects, err := service.GetUserProjects(ctx, user.ID, false)
+	require.NoError(t, err)
+	require.Len(t, projects, 2)
+	assert.True(t, projects[0].IsInbox)
+	assert.Equal(t, "Work", projects[1].Name)
+
+	_, err = service.UpdateProject(ctx, inbox.ID, user.ID, UpdateProjectInput{Archived: &archived})
+	assert.ErrorIs(t, err, ErrInboxProject)
+	assert.ErrorIs(t, service.DeleteProject(ctx, inbox.ID, user.ID), ErrInboxProject)
+
+	// Deleting a project moves its todos to the inbox
+	require.NoError(t, service.DeleteProject(ctx, work.ID, user.ID))
+	got, err := service.GetTodo(ctx, report.ID, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, inbox.ID, got.ProjectID)
+	assert.True(t, got.Project.IsInbox)
+}
+
+func TestTodoSubtasks_Integration(t *testing.T) {
+	ctx := context.Background()
+	pool := setupIntegrationDB(t)
+
+	users := auth.NewUserRepository(pool)
+	user, err := users.Create(ctx, auth.CreateUserInput{Email: "subtasks@example.com", Password: "password123"})
+	require.NoError(t, err)
+
+	service := NewTodoService(NewTodoRepository(pool), NewValidatorService())
+
+	launch, err := service.CreateTodo(ctx, user.ID, CreateTodoInput{Title: "Launch"})
+	require.NoError(t, err)
+	draft, err := service.CreateTodo(ctx, user.ID, CreateTodoInput{Title: "Write copy", ParentID: &launch.ID})
+	require.NoError(t, err)
+	assert.Equal(t, launch.ProjectID, draft.ProjectID)
+	review, err := service.CreateTodo(ctx, user.ID, CreateTodoInput{Title: "Review copy", ParentID: &draft.ID})
+	require.NoError(t, err)
+	ship, err := service.CreateTodo(ctx, user.ID, CreateTodoInput{Title: "Ship", ParentID: &launch.ID})
+	require.NoError(t, err)
+
+	_, err = service.CreateTodo(ctx, user.ID, CreateTodoInput{Title: "Too deep", ParentID: &review.ID})
+	assert.ErrorIs(t, err, ErrTodoTooDeep)
+	_, err = service.UpdateTodo(ctx, launch.ID, user.ID, UpdateTodoInput{ParentID: &review.ID})
+	assert.ErrorIs(t, err, ErrTodoCycle)
+
+	children, err := service.GetSubtasks(ctx, launch.ID, user.ID)
+	require.NoError(t, err)
+	require.Len(t, children, 2)
+	assert.Equal(t, draft.ID, children[0].ID)
+	assert.Equal(t, TodoProgress{Completed: 0, Total: 1}, children[0].Progress)
+
+	list, err := service.GetUserTodos(ctx, user.ID, TodoFilter{TopLevel: boolPtr(true)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, list.Total)
+
+	// Cascading completion reaches every level
+	toggled, err := service.ToggleTodoComplete(ctx, launch.ID, user.ID, true)
+	require.NoError(t, err)
+	assert.True(t, toggled.Completed)
+	assert.Equal(t, TodoProgress{Completed: 2, Total: 2}, toggled.Progress)
+	got, err := service.GetTodo(ctx, review.ID, user.ID)
+	require.NoError(t, err)
+	assert.True(t, got.Completed)
+
+	// Reparenting moves the subtask up to the deleted todo's parent
+	require.NoError(t, service.DeleteTodo(ctx, draft.ID, user.ID, DeleteReparent))
+	got, err = service.GetTodo(ctx, review.ID, user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.ParentID)
+	assert.Equal(t, launch.ID, *got.ParentID)
+
+	// Cascading deletes the whole tree
+	require.NoError(t, service.DeleteTodo(ctx, launch.ID, user.ID, DeleteCascade))
+	for _, id := range []int{launch.ID, review.ID, ship.ID} {
+		_, err = service.GetTodo(ctx, id, user.ID)
+		assert.ErrorIs(t, err, ErrTodoNotFound)
+	}
+}
+
+func TestTodoEvents_ListenNotify_Integration(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	pool := setupIntegrationDB(t)
+
+	_, err := pool.Exec(ctx, `
+		INSERT INTO users (email, password_hash) VALUES ('test@example.com', 'test_hash')
+	`)
+	require.NoError(t, err)
+
+	notifierA := database.NewNotifier(pool)
+	serviceA := NewTodoServiceWithNotifier(pool, notifierA)
+	go notifierA.Run(ctx)
+
+	notifierB := database.NewNotifier(pool)
+	serviceB := NewTodoServiceWithNotifier(pool, notifierB)
+	go notifierB.Run(ctx)
+
+	events, err := serviceB.SubscribeEvents(ctx, 1)
+	require.NoError(t, err)
+
+	// Wait until both listeners are connected
+	require.Eventually(t, func() bool {
+		var listeners int
+		err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM pg_stat_activity WHERE query LIKE 'LISTEN%'`).Scan(&listeners)
+		return err == nil && listeners == 2
+	}, 10*time.Second, 100*time.Millisecond)
+
+	created, err := serviceA.CreateTodo(ctx, 1, CreateTodoInput{Title: "From A"})
+	require.NoError(t, err)
+
+	event := waitForEvent(t, events)
+	assert.Equal(t, EventCreated, event.Kind)
+	assert.Equal(t, created.ID, event.TodoID)
+	require.NotNil(t, event.Todo)
+	assert.Equal(t, "From A", event.Todo.Title)
+
+	_, err = serviceA.BatchUpdateTodos(ctx, 1, []int{created.ID}, UpdateTodoInput{Completed: boolPtr(true)})
+	require.NoError(t, err)
+	assert.Equal(t, EventBatchUpdated, waitForEvent(t, events).Kind)
+
+	require.NoError(t, serviceA.DeleteTodo(ctx, created.ID, 1, DeleteCascade))
+	event = waitForEvent(t, events)
+	assert.Equal(t, EventDeleted, event.Kind)
+	assert.Nil(t, event.Todo)
+}
+
+// TestNotifier_Reconnect_Integration kills the LISTEN connection and checks that the
+// notifier reconnects and resumes relaying notifications.
+func TestNotifier_Reconnect_Integration(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	pool := setupIntegrationDB(t)
+
+	received := make(chan string, 16)
+	notifier := database.NewNotifier(pool)
+	notifier.Subscribe("reconnect_test", func(payload string) { received <- payload })
+	go notifier.Run(ctx)
+
+	listenerCount := func() int {
+		var listeners int
+		_ = pool.QueryRow(ctx, `SELECT COUNT(*) FROM pg_stat_activity WHERE query LIKE 'LISTEN%'`).Scan(&listeners)
+		return listeners
+	}
+	require.Eventually(t, func() bool { return listenerCount() == 1 }, 10*time.Second, 100*time.Millisecond)
+
+	// Drop the listen connection from the server side
+	_, err := pool.Exec(ctx, `SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE query LIKE 'LISTEN%'`)
+	require.NoError(t, err)
+
+	// Keep notifying until the reconnected listener picks a message up
+	require.Eventually(t, func() bool {
+		if err := notifier.Notify(ctx, "reconnect_test", "after-reconnect"); err != nil {
+			return false
+		}
+		select {
+		case payload := <-received:
+			return payload == "after-reconnect"
+		case <-time.After(200 * time.Millisecond):
+			return false
+		}
+	}, 15*time.Second, 100*time.Millisecond)
+}
+
+// waitForEvent waits for the next relayed event or fails the test
+func waitForEvent(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a relayed event")
+		return Event{}
+	}
+}
@@ -7,19 +7,39 @@ import (
 
 // Todo represents a todo item in the database
 type Todo struct {
-	ID          int       `db:"id" json:"id"`
-	UserID      int       `db:"user_id" json:"user_id"`
-	Title       string    `db:"title" json:"title"`
-	Description *string   `db:"description" json:"description"`
-	Completed   bool      `db:"completed" json:"completed"`
-	CreatedAt   time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+	ID        int `db:"id" json:"id"`
+	UserID    int `db:"user_id" json:"user_id"`
+	ProjectID int `db:"project_id" json:"project_id"`
+	// ParentID is the todo this one is a subtask of, nil for top-level todos
+	ParentID    *int    `db:"parent_id" json:"parent_id,omitempty"`
+	Title       string  `db:"title" json:"title"`
+	Description *string `db:"description" json:"description"`
+	Completed   bool    `db:"completed" json:"completed"`
+	// DueAt is when the todo is due. For all-day todos it is the due date at midnight UTC.
+	DueAt     *time.Time `db:"due_at" json:"due_at,omitempty"`
+	AllDay    bool       `db:"all_day" json:"all_day"`
+	Priority  Priority   `db:"priority" json:"priority"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at" json:"updated_at"`
+	// Project, Tags and Progress are loaded alongside the todo; tags are ordered by name
+	Project  *Project     `json:"project,omitempty"`
+	Tags     []*Tag       `json:"tags,omitempty"`
+	Progress TodoProgress `json:"progress"`
 }
 
 // CreateTodoInput represents input for creating a new todo
 type CreateTodoInput struct {
-	Title       string  `json:"title" validate:"required,max=500"`
-	Description *string `json:"description,omitempty"`
+	Title       string     `json:"title" validate:"required,max=500"`
+	Description *string    `json:"description,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	// AllDay makes DueAt a date; only the calendar date of DueAt in its own offset is kept
+	AllDay   bool     `json:"all_day,omitempty"`
+	Priority Priority `json:"priority,omitempty"`
+	// ProjectID is the project the todo goes into. Subtasks default to their parent's
+	// project, other todos to the user's inbox.
+	ProjectID *int `json:"project_id,omitempty"`
+	// ParentID makes the todo a subtask of another of the user's todos
+	ParentID *int `json:"parent_id,omitempty"`
 }
 
 // UpdateTodoInput represents input for updating a todo
@@ -27,14 +47,46 @@ type UpdateTodoInput struct {
 	Title       *string `json:"title,omitempty" validate:"omitempty,max=500"`
 	Description *string `json:"description,omitempty"`
 	Completed   *bool   `json:"completed,omitempty"`
+	// DueAt and AllDay replace the due date together; ClearDueAt removes it
+	DueAt      *time.Time `json:"due_at,omitempty"`
+	AllDay     bool       `json:"all_day,omitempty"`
+	ClearDueAt bool       `json:"clear_due_at,omitempty"`
+	Priority   *Priority  `json:"priority,omitempty"`
+	// ProjectID moves the todo to another of the user's projects
+	ProjectID *int `json:"project_id,omitempty"`
+	// ParentID nests the todo under another of the user's todos; ClearParent makes it top-level
+	ParentID    *int `json:"parent_id,omitempty"`
+	ClearParent bool `json:"clear_parent,omitempty"`
+	// AddTagIDs and RemoveTagIDs attach and detach the user's tags
+	AddTagIDs    []int `json:"add_tag_ids,omitempty"`
+	RemoveTagIDs []int `json:"remove_tag_ids,omitempty"`
 }
 
 // TodoFilter represents filtering options for querying todos
 type TodoFilter struct {
+	ProjectID *int    `json:"project_id,omitempty"`
 	Completed *bool   `json:"completed,omitempty"`
 	Search    *string `json:"search,omitempty"`
-	Limit     int     `json:"limit,omitempty"`
-	Offset    int     `json:"offset,omitempty"`
+	// DueBefore and DueAfter match todos due before, or at or after, a point in time
+	DueBefore *time.Time `json:"due_before,omitempty"`
+	DueAfter  *time.Time `json:"due_after,omitempty"`
+	// Overdue and DueToday are decided in the user's time zone
+	Overdue  *bool `json:"overdue,omitempty"`
+	DueToday *bool `json:"due_today,omitempty"`
+	// Priorities matches todos with any of the listed priorities
+	Priorities []Priority `json:"priorities,omitempty"`
+	// TagsAny matches todos with at least one of the tags, TagsAll todos with every one of them
+	TagsAny []int `json:"tags_any,omitempty"`
+	TagsAll []int `json:"tags_all,omitempty"`
+	// TopLevel matches todos that aren't subtasks, or only subtasks when false
+	TopLevel *bool `json:"top_level,omitempty"`
+	// Sort orders the results; the zero value is DefaultSort
+	Sort   TodoSort `json:"sort,omitempty"`
+	Limit  int      `json:"limit,omitempty"`
+	Offset int      `json:"offset,omitempty"`
+
+	// day is the user's current day, resolved by TodoService when Overdue or DueToday is set
+	day *calendarDay
 }
 
 // TodoListResponse represents a paginated list of todos
@@ -51,6 +103,7 @@ type TodoStats struct {
 	Total     int `json:"total"`
 	Completed int `json:"completed"`
 	Pending   int `json:"pending"`
+	Overdue   int `json:"overdue"`
 }
 
 // TodoRepository interface defines the contract for todo data operations
@@ -59,7 +112,23 @@ type Repository interface {
 	GetByID(ctx context.Context, todoID, userID int) (*Todo, error)
 	GetByUserID(ctx context.Context, userID int, filter TodoFilter) (*TodoListResponse, error)
 	Update(ctx context.Context, todoID, userID int, input UpdateTodoInput) (*Todo, error)
-	Delete(ctx context.Context, todoID, userID int) error
+	Delete(ctx context.Context, todoID, userID int, mode DeleteMode) (*DeleteResult, error)
 	ToggleComplete(ctx context.Context, todoID, userID int) (*Todo, error)
 	CountByUserID(ctx context.Context, userID int) (int, error)
+	GetStats(ctx context.Context, userID int, projectID *int, day *calendarDay) (*TodoStats, error)
+	GetUserLocation(ctx context.Context, userID int) (*time.Location, error)
+	CreateTag(ctx context.Context, userID int, input CreateTagInput) (*Tag, error)
+	GetTagsByUserID(ctx context.Context, userID int) ([]*Tag, error)
+	UpdateTag(ctx context.Context, tagID, userID int, input UpdateTagInput) (*Tag, error)
+	DeleteTag(ctx context.Context, tagID, userID int) ([]*Todo, error)
+	EnsureInbox(ctx context.Context, userID int) (*Project, error)
+	CreateProject(ctx context.Context, userID int, input CreateProjectInput) (*Project, error)
+	GetProjectByID(ctx context.Context, projectID, userID int) (*Project, error)
+	GetProjectsByUserID(ctx context.Context, userID int, includeArchived bool) ([]*Project, error)
+	UpdateProject(ctx context.Context, projectID, userID int, input UpdateProjectInput) (*Project, error)
+	DeleteProject(ctx context.Context, projectID, userID int) ([]*Todo, error)
+	GetChildren(ctx context.Context, todoID, userID int) ([]*Todo, error)
+	GetAncestorIDs(ctx context.Context, todoID, userID int) ([]int, error)
+	GetSubtreeDepth(ctx context.Context, todoID, userID int) (int, error)
+	SetSubtasksCompleted(ctx context.Context, todoID, userID int, completed bool) ([]*Todo, error)
 }
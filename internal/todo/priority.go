@@ -0,0 +1,62 @@
+package todo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Priority is how urgent a todo is. Higher values are more urgent, so todos sort by the value directly.
+// Pass it to queries as an int: pgx would encode it by String, the way it encodes any fmt.Stringer.
+type Priority int
+
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+	PriorityUrgent
+)
+
+// priorityNames are the names of the priorities, indexed by value
+var priorityNames = [...]string{"none", "low", "medium", "high", "urgent"}
+
+// ParsePriority returns the priority with the given name, ignoring case
+func ParsePriority(name string) (Priority, error) {
+	for i, priorityName := range priorityNames {
+		if strings.EqualFold(name, priorityName) {
+			return Priority(i), nil
+		}
+	}
+	return PriorityNone, fmt.Errorf("%w: %q", ErrInvalidTodoPriority, name)
+}
+
+// IsValid returns true if p is one of the defined priorities
+func (p Priority) IsValid() bool {
+	return p >= PriorityNone && p <= PriorityUrgent
+}
+
+// String returns the name of the priority
+func (p Priority) String() string {
+	if !p.IsValid() {
+		return fmt.Sprintf("Priority(%d)", int(p))
+	}
+	return priorityNames[p]
+}
+
+// MarshalText encodes the priority by name, e.g. in events and data exports
+func (p Priority) MarshalText() ([]byte, error) {
+	if !p.IsValid() {
+		return nil, ErrInvalidTodoPriority
+	}
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText decodes a priority name
+func (p *Priority) UnmarshalText(text []byte) error {
+	priority, err := ParsePriority(string(text))
+	if err != nil {
+		return err
+	}
+	*p = priority
+	return nil
+}
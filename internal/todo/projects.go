@@ -0,0 +1,404 @@
+package todo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// InboxName is the name given to every user's inbox
+const InboxName = "Inbox"
+
+// Project groups a user's todos. Every todo belongs to exactly one project; todos
+// created without one go to the user's inbox.
+type Project struct {
+	ID       int    `db:"id" json:"id"`
+	UserID   int    `db:"user_id" json:"user_id"`
+	Name     string `db:"name" json:"name"`
+	Color    string `db:"color" json:"color"`
+	Archived bool   `db:"archived" json:"archived"`
+	// Position orders the user's projects; the inbox always comes first
+	Position  int       `db:"position" json:"position"`
+	IsInbox   bool      `db:"is_inbox" json:"is_inbox"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// CreateProjectInput represents input for creating a new project
+type CreateProjectInput struct {
+	Name string `json:"name"`
+	// Color is a #RRGGBB hex color, DefaultColor when empty
+	Color string `json:"color,omitempty"`
+	// Position defaults to after the user's other projects
+	Position *int `json:"position,omitempty"`
+}
+
+// UpdateProjectInput represents input for changing a project
+type UpdateProjectInput struct {
+	Name     *string `json:"name,omitempty"`
+	Color    *string `json:"color,omitempty"`
+	Archived *bool   `json:"archived,omitempty"`
+	Position *int    `json:"position,omitempty"`
+}
+
+// EnsureInbox returns the user's inbox, creating it if needed
+func (r *TodoRepository) EnsureInbox(ctx context.Context, userID int) (*Project, error) {
+	query := `
+		INSERT INTO projects (user_id, name, is_inbox, created_at, updated_at)
+		VALUES ($1, $2, TRUE, NOW(), NOW())
+		ON CONFLICT (user_id) WHERE is_inbox DO NOTHING
+	`
+	if _, err := r.db.Exec(ctx, query, userID, InboxName); err != nil {
+		return nil, fmt.Errorf("failed to create inbox: %w", err)
+	}
+
+	project, err := scanProject(r.db.QueryRow(ctx, `SELECT `+projectColumns+` FROM projects WHERE user_id = $1 AND is_inbox`, userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inbox: %w", err)
+	}
+
+	return project, nil
+}
+
+// CreateProject creates a new project for the user
+func (r *TodoRepository) CreateProject(ctx context.Context, userID int, input CreateProjectInput) (*Project, error) {
+	query := `
+		INSERT INTO projects (user_id, name, color, position, created_at, updated_at)
+		VALUES ($1, $2, $3, COALESCE($4::INTEGER, (SELECT COALESCE(MAX(position) + 1, 0) FROM projects WHERE user_id = $1)), NOW(), NOW())
+		RETURNING ` + projectColumns
+
+	project, err := scanProject(r.db.QueryRow(ctx, query, userID, input.Name, input.Color, input.Position))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create project: %w", err)
+	}
+
+	return project, nil
+}
+
+// GetProjectByID retrieves one of the user's projects
+func (r *TodoRepository) GetProjectByID(ctx context.Context, projectID, userID int) (*Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects WHERE id = $1 AND user_id = $2`
+
+	project, err := scanProject(r.db.QueryRow(ctx, query, projectID, userID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrProjectNotFound
+		}
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+
+	return project, nil
+}
+
+// GetProjectsByUserID lists the user's projects, inbox first and then by position
+func (r *TodoRepository) GetProjectsByUserID(ctx context.Context, userID int, includeArchived bool) ([]*Project, error) {
+	query := `
+		SELECT ` + projectColumns + ` FROM projects
+		WHERE user_id = $1 AND ($2 OR NOT archived)
+		ORDER BY is_inbox DESC, position, id
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, includeArchived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query projects: %w", err)
+	}
+	defer rows.Close()
+
+	projects := []*Project{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan project: %w", err)
+		}
+		projects = append(projects, project)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate projects: %w", err)
+	}
+
+	return projects, nil
+}
+
+// UpdateProject changes one of the user's projects
+func (r *TodoRepository) UpdateProject(ctx context.Context, projectID, userID int, input UpdateProjectInput) (*Project, error) {
+	query := `
+		UPDATE projects
+		SET name = COALESCE($3, name), color = COALESCE($4, color),
+			archived = COALESCE($5, archived), position = COALESCE($6, position), updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING ` + projectColumns
+
+	project, err := scanProject(r.db.QueryRow(ctx, query, projectID, userID, input.Name, input.Color, input.Archived, input.Position))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrProjectNotFound
+		}
+		return nil, fmt.Errorf("failed to update project: %w", err)
+	}
+
+	return project, nil
+}
+
+// DeleteProject deletes one of the user's projects, moving its todos to the inbox.
+// The inbox itself is never deleted. The moved todos are returned.
+func (r *TodoRepository) DeleteProject(ctx context.Context, projectID, userID int) ([]*Todo, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin project delete: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE todos
+		SET project_id = (SELECT id FROM projects WHERE user_id = $2 AND is_inbox), updated_at = NOW()
+		WHERE project_id = $1 AND user_id = $2
+		RETURNING ` + todoColumns
+
+	rows, err := tx.Query(ctx, query, projectID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move todos to inbox: %w", err)
+	}
+	moved, err := scanTodos(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM projects WHERE id = $1 AND user_id = $2 AND NOT is_inbox`, projectID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete project: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return nil, ErrProjectNotFound
+	}
+
+	if err := loadRelations(ctx, tx, moved...); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit project delete: %w", err)
+	}
+
+	for _, todo := range moved {
+		r.notify(ctx, EventUpdated, userID, todo.ID, todo)
+	}
+
+	return moved, nil
+}
+
+// loadProjects fills in the projects of todos with a single query
+func loadProjects(ctx context.Context, db querier, todos ...*Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	projectIDs := make([]int, 0, len(todos))
+	for _, todo := range todos {
+		projectIDs = append(projectIDs, todo.ProjectID)
+	}
+
+	rows, err := db.Query(ctx, `SELECT `+projectColumns+` FROM projects WHERE id = ANY($1)`, projectIDs)
+	if err != nil {
+		return fmt.Errorf("failed to query todo projects: %w", err)
+	}
+	defer rows.Close()
+
+	byID := make(map[int]*Project)
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan todo project: %w", err)
+		}
+		byID[project.ID] = project
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, todo := range todos {
+		todo.Project = byID[todo.ProjectID]
+	}
+
+	return nil
+}
+
+// projectColumns is the column list scanned by scanProject
+const projectColumns = `id, user_id, name, color, archived, position, is_inbox, created_at, updated_at`
+
+// scanProject scans a row selected with projectColumns
+func scanProject(row pgx.Row) (*Project, error) {
+	var project Project
+	err := row.Scan(
+		&project.ID,
+		&project.UserID,
+		&project.Name,
+		&project.Color,
+		&project.Archived,
+		&project.Position,
+		&project.IsInbox,
+		&project.CreatedAt,
+		&project.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &project, nil
+}
+
+// CreateInbox creates the user's inbox if they don't have one yet
+func (s *TodoService) CreateInbox(ctx context.Context, userID int) (*Project, error) {
+	inbox, err := s.repo.EnsureInbox(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create inbox: %w", err)
+	}
+
+	return inbox, nil
+}
+
+// CreateProject creates a new project with validation
+func (s *TodoService) CreateProject(ctx context.Context, userID int, input CreateProjectInput) (*Project, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Color == "" {
+		input.Color = DefaultColor
+	}
+	input.Color = strings.ToUpper(input.Color)
+
+	if err := s.validator.ValidateProjectInput(ctx, input); err != nil {
+		return nil, fmt.Errorf("failed to validate input: %w", err)
+	}
+
+	project, err := s.repo.CreateProject(ctx, userID, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create project: %w", err)
+	}
+
+	return project, nil
+}
+
+// GetProject retrieves a project by ID with ownership check
+func (s *TodoService) GetProject(ctx context.Context, projectID, userID int) (*Project, error) {
+	if projectID <= 0 {
+		return nil, ErrProjectNotFound
+	}
+
+	project, err := s.repo.GetProjectByID(ctx, projectID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+
+	return project, nil
+}
+
+// GetUserProjects lists the user's projects, inbox first and then by position
+func (s *TodoService) GetUserProjects(ctx context.Context, userID int, includeArchived bool) ([]*Project, error) {
+	projects, err := s.repo.GetProjectsByUserID(ctx, userID, includeArchived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get projects: %w", err)
+	}
+
+	// Users who never created a todo may not have an inbox yet
+	if len(projects) == 0 || !projects[0].IsInbox {
+		inbox, err := s.CreateInbox(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		projects = append([]*Project{inbox}, projects...)
+	}
+
+	return projects, nil
+}
+
+// UpdateProject changes a project with validation and ownership check
+func (s *TodoService) UpdateProject(ctx context.Context, projectID, userID int, input UpdateProjectInput) (*Project, error) {
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		input.Name = &name
+	}
+	if input.Color != nil {
+		color := strings.ToUpper(*input.Color)
+		input.Color = &color
+	}
+
+	if err := s.validator.ValidateProjectUpdateInput(ctx, input); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	project, err := s.GetProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if project.IsInbox && input.Archived != nil && *input.Archived {
+		return nil, ErrInboxProject
+	}
+
+	project, err = s.repo.UpdateProject(ctx, projectID, userID, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update project: %w", err)
+	}
+
+	return project, nil
+}
+
+// DeleteProject deletes a project with ownership check, moving its todos to the inbox
+func (s *TodoService) DeleteProject(ctx context.Context, projectID, userID int) error {
+	project, err := s.GetProject(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+
+	if project.IsInbox {
+		return ErrInboxProject
+	}
+
+	if _, err := s.CreateInbox(ctx, userID); err != nil {
+		return err
+	}
+
+	moved, err := s.repo.DeleteProject(ctx, projectID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete project: %w", err)
+	}
+
+	// Subscribers see the todos land in the inbox, and stats are pushed again
+	for _, todo := range moved {
+		s.publish(EventUpdated, userID, todo.ID, todo)
+	}
+
+	return nil
+}
+
+// MoveTodos moves the user's todos into one of their projects
+func (s *TodoService) MoveTodos(ctx context.Context, userID int, todoIDs []int, projectID int) ([]*Todo, error) {
+	return s.BatchUpdateTodos(ctx, userID, todoIDs, UpdateTodoInput{ProjectID: &projectID})
+}
+
+// GetProjectTodoStats returns statistics about the todos in one of the user's projects
+func (s *TodoService) GetProjectTodoStats(ctx context.Context, projectID, userID int) (*TodoStats, error) {
+	if _, err := s.GetProject(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.todoStats(ctx, userID, &projectID)
+}
+
+// checkProjectTarget returns an error unless todos can be put into the project
+func (s *TodoService) checkProjectTarget(ctx context.Context, userID, projectID int) error {
+	project, err := s.GetProject(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+
+	if project.Archived {
+		return ErrProjectArchived
+	}
+
+	return nil
+}
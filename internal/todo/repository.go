@@ -2,16 +2,22 @@ package todo
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/jayk0001/my-go-next-todo/internal/database"
 )
 
 // TodoRepository hanldes todo database operations
 type TodoRepository struct {
-	db *pgxpool.Pool
+	db       *pgxpool.Pool
+	notifier Notifier
 }
 
 // NewTodoRepository created a new todo repository
@@ -21,51 +27,41 @@ func NewTodoRepository(db *pgxpool.Pool) *TodoRepository {
 	}
 }
 
+// NewTodoRepositoryWithNotifier creates a todo repository that NOTIFYs EventChannel after every write
+func NewTodoRepositoryWithNotifier(db *pgxpool.Pool, notifier Notifier) *TodoRepository {
+	return &TodoRepository{
+		db:       db,
+		notifier: notifier,
+	}
+}
+
 // Create creates a new todo in the database
 func (r *TodoRepository) Create(ctx context.Context, userID int, input CreateTodoInput) (*Todo, error) {
 	query := `
-		INSERT INTO todos (user_id, title, description, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-		RETURNING id, user_id, title, description, created_at, updated_at
-	`
-
-	var todo Todo
-	err := r.db.QueryRow(ctx, query, userID, input.Title, input.Description).Scan(
-		&todo.ID,
-		&todo.UserID,
-		&todo.Title,
-		&todo.Description,
-		&todo.CreatedAt,
-		&todo.UpdatedAt,
-	)
+		INSERT INTO todos (user_id, project_id, parent_id, title, description, due_at, all_day, priority, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		RETURNING ` + todoColumns
 
+	todo, err := scanTodo(r.db.QueryRow(ctx, query, userID, input.ProjectID, input.ParentID, input.Title, input.Description, input.DueAt, input.AllDay, int(input.Priority)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create todo: %w", err)
 	}
 
-	return &todo, nil
+	if err := loadRelations(ctx, r.db, todo); err != nil {
+		return nil, err
+	}
+
+	r.notify(ctx, EventCreated, userID, todo.ID, todo)
+
+	return todo, nil
 }
 
 // GetByID retrieves a todo by ID for a specific user
 func (r *TodoRepository) GetByID(ctx context.Context, todoId, userID int) (*Todo, error) {
 	// Build query with filters
-	query := `
-		SELECT id, user_id, title, description, completed, created_at, updated_at
-		FROM todos
-		WHERE id = $1 and user_id = $2
-	`
-
-	var todo Todo
-	err := r.db.QueryRow(ctx, query, todoId, userID).Scan(
-		&todo.ID,
-		&todo.UserID,
-		&todo.Title,
-		&todo.Description,
-		&todo.Completed,
-		&todo.CreatedAt,
-		&todo.UpdatedAt,
-	)
+	query := `SELECT ` + todoColumns + ` FROM todos WHERE id = $1 and user_id = $2`
 
+	todo, err := scanTodo(r.db.QueryRow(ctx, query, todoId, userID))
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, ErrTodoNotFound
@@ -73,37 +69,23 @@ func (r *TodoRepository) GetByID(ctx context.Context, todoId, userID int) (*Todo
 		return nil, fmt.Errorf("failed to get todo: %w", err)
 	}
 
-	return &todo, nil
+	if err := loadRelations(ctx, r.db, todo); err != nil {
+		return nil, err
+	}
+
+	return todo, nil
 }
 
 // GetByUserID retrieves todos for a specific user with filtering
 func (r *TodoRepository) GetByUserID(ctx context.Context, userID int, filter TodoFilter) (*TodoListResponse, error) {
 	// Build query with filters
-	query := `
-		SELECT id, user_id, title, description, completed, created_at, updated_at
-		FROM todos
-		WHERE user_id = $1
-	`
-	args := []interface{}{userID}
-	argIndex := 2
-
-	// Add completed filter
-	if filter.Completed != nil {
-		query += fmt.Sprintf(" AND completed = $%d", argIndex)
-		args = append(args, *filter.Completed)
-		argIndex++
-	}
-
-	// Add search filter
-	if filter.Search != nil && *filter.Search != "" {
-		query += fmt.Sprintf(" AND (title ILIKE $%d OR description ILIKE $%d)", argIndex, argIndex)
-		searchTerm := "%" + *filter.Search + "%"
-		args = append(args, searchTerm)
-		argIndex++
-	}
+	where, whereArgs := filterConditions(userID, filter)
+	query := `SELECT ` + todoColumns + ` FROM todos WHERE ` + where
+	args := append([]interface{}{}, whereArgs...)
+	argIndex := len(args) + 1
 
 	// Add ordering
-	query += " ORDER BY created_at DESC"
+	query += " ORDER BY " + filter.Sort.orderBy()
 
 	// Add pagination
 	if filter.Limit > 0 {
@@ -129,29 +111,24 @@ func (r *TodoRepository) GetByUserID(ctx context.Context, userID int, filter Tod
 	// Create todos array from query and append to todos array from rows
 	var todos []*Todo
 	for rows.Next() {
-		var todo Todo
-		err := rows.Scan(
-			&todo.ID,
-			&todo.UserID,
-			&todo.Title,
-			&todo.Description,
-			&todo.Completed,
-			&todo.CreatedAt,
-			&todo.UpdatedAt,
-		)
+		todo, err := scanTodo(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan todo: %w", err)
 		}
-		todos = append(todos, &todo)
+		todos = append(todos, todo)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("failed to iterate todos: %w", err)
 	}
 
-	// Get total count of todos for paginations
-	total, err := r.CountByUserID(ctx, userID)
-	if err != nil {
+	if err := loadRelations(ctx, r.db, todos...); err != nil {
+		return nil, err
+	}
+
+	// Get total count of matching todos for paginations
+	total := 0
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM todos WHERE `+where, whereArgs...).Scan(&total); err != nil {
 		return nil, fmt.Errorf("failed to count todos: %w", err)
 	}
 
@@ -198,8 +175,41 @@ func (r *TodoRepository) Update(ctx context.Context, todoID, userID int, input U
 		argIndex++
 	}
 
-	// No fields to update, just return current todo
-	if len(setParts) == 0 {
+	if input.DueAt != nil {
+		setParts = append(setParts, fmt.Sprintf("due_at = $%d, all_day = $%d", argIndex, argIndex+1))
+		args = append(args, *input.DueAt, input.AllDay)
+		argIndex += 2
+	}
+
+	if input.ClearDueAt {
+		setParts = append(setParts, "due_at = NULL, all_day = FALSE")
+	}
+
+	if input.Priority != nil {
+		setParts = append(setParts, fmt.Sprintf("priority = $%d", argIndex))
+		args = append(args, int(*input.Priority))
+		argIndex++
+	}
+
+	if input.ProjectID != nil {
+		setParts = append(setParts, fmt.Sprintf("project_id = $%d", argIndex))
+		args = append(args, *input.ProjectID)
+		argIndex++
+	}
+
+	if input.ParentID != nil {
+		setParts = append(setParts, fmt.Sprintf("parent_id = $%d", argIndex))
+		args = append(args, *input.ParentID)
+		argIndex++
+	}
+
+	if input.ClearParent {
+		setParts = append(setParts, "parent_id = NULL")
+	}
+
+	// No fields or tags to update, just return current todo
+	tagsChanged := len(input.AddTagIDs) > 0 || len(input.RemoveTagIDs) > 0
+	if len(setParts) == 0 && !tagsChanged {
 		return r.GetByID(ctx, todoID, userID)
 	}
 
@@ -210,20 +220,17 @@ func (r *TodoRepository) Update(ctx context.Context, todoID, userID int, input U
 		UPDATE todos
 		SET %s
 		WHERE id = $1 and user_id = $2
-		RETURNING id, user_id, title, description, completed, created_at, updated_at
-		`, strings.Join(setParts, ", "))
+		RETURNING %s
+		`, strings.Join(setParts, ", "), todoColumns)
 
-	var todo Todo
-	err := r.db.QueryRow(ctx, query, args...).Scan(
-		&todo.ID,
-		&todo.UserID,
-		&todo.Title,
-		&todo.Description,
-		&todo.Completed,
-		&todo.CreatedAt,
-		&todo.UpdatedAt,
-	)
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin todo update: %w", err)
+	}
+	defer tx.Rollback(ctx)
 
+	// The update runs first so tags are only changed on todos the user owns
+	todo, err := scanTodo(tx.QueryRow(ctx, query, args...))
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, ErrTodoNotFound
@@ -231,28 +238,94 @@ func (r *TodoRepository) Update(ctx context.Context, todoID, userID int, input U
 		return nil, fmt.Errorf("failed to update todo: %w", err)
 	}
 
-	return &todo, nil
+	if tagsChanged {
+		if err := updateTodoTags(ctx, tx, todo.ID, userID, input.AddTagIDs, input.RemoveTagIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := loadRelations(ctx, tx, todo); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit todo update: %w", err)
+	}
+
+	r.notify(ctx, eventKindFromContext(ctx, EventUpdated), userID, todo.ID, todo)
+
+	return todo, nil
 }
 
-// Delete deletes a todo for a specific user
-func (r *TodoRepository) Delete(ctx context.Context, todoID, userID int) error {
+// Delete deletes a todo for a specific user. Its subtasks are deleted too, or moved up to
+// the todo's own parent with DeleteReparent.
+func (r *TodoRepository) Delete(ctx context.Context, todoID, userID int, mode DeleteMode) (*DeleteResult, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin todo delete: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result := &DeleteResult{}
+
+	if mode == DeleteReparent {
+		query := `
+			UPDATE todos
+			SET parent_id = (SELECT parent_id FROM todos WHERE id = $1 AND user_id = $2), updated_at = NOW()
+			WHERE parent_id = $1 AND user_id = $2
+			RETURNING ` + todoColumns
+
+		rows, err := tx.Query(ctx, query, todoID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reparent subtasks: %w", err)
+		}
+		if result.Reparented, err = scanTodos(rows); err != nil {
+			return nil, err
+		}
+	}
+
+	// After reparenting the subtree is just the todo itself
 	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM todos WHERE id = $1 AND user_id = $2
+			UNION
+			SELECT t.id FROM todos t JOIN subtree s ON t.parent_id = s.id
+		)
 		DELETE FROM todos
-		WHERE id = $1 and user_id = $2
+		WHERE id IN (SELECT id FROM subtree)
+		RETURNING id
 	`
 
-	result, err := r.db.Exec(ctx, query, todoID, userID)
-
+	rows, err := tx.Query(ctx, query, todoID, userID)
 	if err != nil {
-		return fmt.Errorf("failed to delete todo: %w", err)
+		return nil, fmt.Errorf("failed to delete todo: %w", err)
+	}
+	result.DeletedIDs, err = pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete todo: %w", err)
+	}
+
+	if len(result.DeletedIDs) == 0 {
+		return nil, ErrTodoNotFound
+	}
+	slices.Sort(result.DeletedIDs)
+
+	if err := loadRelations(ctx, tx, result.Reparented...); err != nil {
+		return nil, err
 	}
 
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		return ErrTodoNotFound
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit todo delete: %w", err)
 	}
 
-	return nil
+	for _, deletedID := range result.DeletedIDs {
+		r.notify(ctx, EventDeleted, userID, deletedID, nil)
+	}
+	for _, todo := range result.Reparented {
+		r.notify(ctx, EventUpdated, userID, todo.ID, todo)
+	}
+
+	return result, nil
 }
 
 // ToggleComplete toggles the completed status of a todo
@@ -261,19 +334,9 @@ func (r *TodoRepository) ToggleComplete(ctx context.Context, todoID, userID int)
 		UPDATE todos
 		SET completed = NOT completed, updated_at = NOW()
 		WHERE id = $1 and user_id = $2
-		RETURNING id, user_id, title, description, completed, created_at, updated_at
-	`
+		RETURNING ` + todoColumns
 
-	var todo Todo
-	err := r.db.QueryRow(ctx, query, todoID, userID).Scan(
-		&todo.ID,
-		&todo.UserID,
-		&todo.Title,
-		&todo.Description,
-		&todo.Completed,
-		&todo.CreatedAt,
-		&todo.UpdatedAt,
-	)
+	todo, err := scanTodo(r.db.QueryRow(ctx, query, todoID, userID))
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, ErrTodoNotFound
@@ -281,7 +344,13 @@ func (r *TodoRepository) ToggleComplete(ctx context.Context, todoID, userID int)
 		return nil, fmt.Errorf("failed to toggle complete todo: %w", err)
 	}
 
-	return &todo, nil
+	if err := loadRelations(ctx, r.db, todo); err != nil {
+		return nil, err
+	}
+
+	r.notify(ctx, EventToggled, userID, todo.ID, todo)
+
+	return todo, nil
 }
 
 // CountByUserID counts total todos for a user
@@ -296,5 +365,214 @@ func (r *TodoRepository) CountByUserID(ctx context.Context, userID int) (int, er
 	return count, nil
 }
 
+// GetStats counts the user's todos, completed, pending and overdue on day, with a single query.
+// Only the todos in the project are counted when projectID is set.
+func (r *TodoRepository) GetStats(ctx context.Context, userID int, projectID *int, day *calendarDay) (*TodoStats, error) {
+	where, args := filterConditions(userID, TodoFilter{ProjectID: projectID})
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	query := `
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE completed),
+			COUNT(*) FILTER (WHERE NOT completed),
+			COUNT(*) FILTER (WHERE ` + overdueCondition(arg, day) + `)
+		FROM todos
+		WHERE ` + where
+
+	var stats TodoStats
+	err := r.db.QueryRow(ctx, query, args...).Scan(&stats.Total, &stats.Completed, &stats.Pending, &stats.Overdue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get todo stats for user: %w", err)
+	}
+	return &stats, nil
+}
+
+// GetUserLocation returns the user's time zone, falling back to UTC for unknown users and zones
+func (r *TodoRepository) GetUserLocation(ctx context.Context, userID int) (*time.Location, error) {
+	var timeZone string
+	err := r.db.QueryRow(ctx, `SELECT time_zone FROM users WHERE id = $1`, userID).Scan(&timeZone)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return time.UTC, nil
+		}
+		return nil, fmt.Errorf("failed to get user time zone: %w", err)
+	}
+
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		fmt.Printf("TodoRepository: unknown time zone %q for user %d, using UTC\n", timeZone, userID)
+		return time.UTC, nil
+	}
+
+	return loc, nil
+}
+
+// filterConditions builds the WHERE clause matching the user's todos that pass filter,
+// along with its arguments. The user ID is always $1.
+func filterConditions(userID int, filter TodoFilter) (string, []interface{}) {
+	conditions := []string{"user_id = $1"}
+	args := []interface{}{userID}
+
+	// arg adds an argument and returns its placeholder
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	// Add project filter
+	if filter.ProjectID != nil {
+		conditions = append(conditions, "project_id = "+arg(*filter.ProjectID))
+	}
+
+	// Add subtask filter
+	if filter.TopLevel != nil {
+		conditions = append(conditions, negateUnless(*filter.TopLevel, "parent_id IS NULL"))
+	}
+
+	// Add completed filter
+	if filter.Completed != nil {
+		conditions = append(conditions, "completed = "+arg(*filter.Completed))
+	}
+
+	// Add search filter
+	if filter.Search != nil && *filter.Search != "" {
+		searchTerm := arg("%" + *filter.Search + "%")
+		conditions = append(conditions, fmt.Sprintf("(title ILIKE %s OR description ILIKE %s)", searchTerm, searchTerm))
+	}
+
+	// Add priority filter
+	if len(filter.Priorities) > 0 {
+		priorities := make([]int, len(filter.Priorities))
+		for i, priority := range filter.Priorities {
+			priorities[i] = int(priority)
+		}
+		conditions = append(conditions, "priority = ANY("+arg(priorities)+")")
+	}
+
+	// Add tag filters
+	if len(filter.TagsAny) > 0 {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM todo_tags WHERE todo_id = todos.id AND tag_id = ANY("+arg(filter.TagsAny)+"))")
+	}
+
+	if len(filter.TagsAll) > 0 {
+		// Each tag is attached at most once, so matching every tag means matching as many rows as there are tags
+		tagIDs := slices.Compact(slices.Sorted(slices.Values(filter.TagsAll)))
+		conditions = append(conditions, fmt.Sprintf(
+			"(SELECT COUNT(*) FROM todo_tags WHERE todo_id = todos.id AND tag_id = ANY(%s)) = %s",
+			arg(tagIDs), arg(len(tagIDs)),
+		))
+	}
+
+	// Add due date filters
+	if filter.DueBefore != nil {
+		conditions = append(conditions, "due_at < "+arg(*filter.DueBefore))
+	}
+
+	if filter.DueAfter != nil {
+		conditions = append(conditions, "due_at >= "+arg(*filter.DueAfter))
+	}
+
+	// These mirror calendarDay.isOverdue and calendarDay.isDueToday
+	if filter.Overdue != nil && filter.day != nil {
+		conditions = append(conditions, negateUnless(*filter.Overdue, overdueCondition(arg, filter.day)))
+	}
+
+	if filter.DueToday != nil && filter.day != nil {
+		dueToday := fmt.Sprintf(
+			"(due_at IS NOT NULL AND ((all_day AND due_at = %s) OR (NOT all_day AND due_at >= %s AND due_at < %s)))",
+			arg(filter.day.date), arg(filter.day.start), arg(filter.day.end),
+		)
+		conditions = append(conditions, negateUnless(*filter.DueToday, dueToday))
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// overdueCondition matches open todos past their due time on day, adding its arguments with arg.
+// It mirrors calendarDay.isOverdue.
+func overdueCondition(arg func(interface{}) string, day *calendarDay) string {
+	return fmt.Sprintf(
+		"(due_at IS NOT NULL AND NOT completed AND ((all_day AND due_at < %s) OR (NOT all_day AND due_at < %s)))",
+		arg(day.date), arg(day.now),
+	)
+}
+
+// loadRelations fills in the project, tags and subtask progress of todos, with one query each
+func loadRelations(ctx context.Context, db querier, todos ...*Todo) error {
+	if err := loadProjects(ctx, db, todos...); err != nil {
+		return err
+	}
+
+	if err := loadTags(ctx, db, todos...); err != nil {
+		return err
+	}
+
+	return loadProgress(ctx, db, todos...)
+}
+
+// negateUnless returns condition, or its negation when want is false
+func negateUnless(want bool, condition string) string {
+	if want {
+		return condition
+	}
+	return "NOT " + condition
+}
+
+// todoColumns is the column list scanned by scanTodo
+const todoColumns = `id, user_id, project_id, parent_id, title, description, completed, due_at, all_day, priority, created_at, updated_at`
+
+// scanTodo scans a row selected with todoColumns
+func scanTodo(row pgx.Row) (*Todo, error) {
+	var todo Todo
+	err := row.Scan(
+		&todo.ID,
+		&todo.UserID,
+		&todo.ProjectID,
+		&todo.ParentID,
+		&todo.Title,
+		&todo.Description,
+		&todo.Completed,
+		&todo.DueAt,
+		&todo.AllDay,
+		&todo.Priority,
+		&todo.CreatedAt,
+		&todo.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &todo, nil
+}
+
+// notify broadcasts a change event to every instance when a notifier is configured.
+// The write has already succeeded, so a failed NOTIFY is logged rather than returned.
+func (r *TodoRepository) notify(ctx context.Context, kind EventKind, userID, todoID int, todo *Todo) {
+	if r.notifier == nil {
+		return
+	}
+
+	event := Event{Kind: kind, UserID: userID, TodoID: todoID, Todo: todo}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		fmt.Printf("TodoRepository: failed to encode event: %v\n", err)
+		return
+	}
+
+	// NOTIFY payloads are limited in size; receivers reload the todo when it is omitted
+	if len(payload) > database.MaxNotifyPayload {
+		event.Todo = nil
+		payload, _ = json.Marshal(event)
+	}
+
+	if err := r.notifier.Notify(ctx, EventChannel, string(payload)); err != nil {
+		fmt.Printf("TodoRepository: failed to notify %s: %v\n", EventChannel, err)
+	}
+}
+
 // Ensure TodoRepository implements Repository interface
 var _ Repository = (*TodoRepository)(nil)
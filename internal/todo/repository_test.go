@@ -3,6 +3,8 @@ package todo
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -14,6 +16,12 @@ type MockTodoRepository struct {
 	nextID       int
 	shouldFail   bool
 	failureError error
+	locations    map[int]*time.Location
+	tags         map[int]*Tag
+	todoTags     map[int][]int // todo ID -> attached tag IDs
+	nextTagID    int
+	projects     map[int]*Project
+	nextProjID   int
 }
 
 // NewMockTodoRepository creates a new mock repository
@@ -22,6 +30,12 @@ func NewMockTodoRepository() *MockTodoRepository {
 		todos:       make(map[int]*Todo),
 		todosByUser: make(map[int][]*Todo),
 		nextID:      1,
+		locations:   make(map[int]*time.Location),
+		tags:        make(map[int]*Tag),
+		todoTags:    make(map[int][]int),
+		nextTagID:   1,
+		projects:    make(map[int]*Project),
+		nextProjID:  1,
 	}
 }
 
@@ -37,15 +51,23 @@ func (m *MockTodoRepository) Create(ctx context.Context, userID int, input Creat
 		Title:       input.Title,
 		Description: input.Description,
 		Completed:   false,
+		DueAt:       input.DueAt,
+		AllDay:      input.AllDay,
+		Priority:    input.Priority,
+		ParentID:    input.ParentID,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
+		Tags:        []*Tag{},
+	}
+	if input.ProjectID != nil {
+		todo.ProjectID = *input.ProjectID
 	}
 
 	m.todos[todo.ID] = todo
 	m.todosByUser[userID] = append(m.todosByUser[userID], todo)
 	m.nextID++ // ✅ ID 자동 증가
 
-	return todo, nil
+	return m.withRelations(todo), nil
 }
 
 // GetByID implements Repository interface
@@ -59,7 +81,7 @@ func (m *MockTodoRepository) GetByID(ctx context.Context, todoID, userID int) (*
 		return nil, ErrTodoNotFound
 	}
 
-	return todo, nil
+	return m.withRelations(todo), nil
 }
 
 // GetByUserID implements Repository interface
@@ -73,17 +95,58 @@ func (m *MockTodoRepository) GetByUserID(ctx context.Context, userID int, filter
 
 	// Apply filters
 	for _, todo := range userTodos {
+		if filter.ProjectID != nil && todo.ProjectID != *filter.ProjectID {
+			continue
+		}
 		if filter.Completed != nil && todo.Completed != *filter.Completed {
 			continue
 		}
+		if filter.TopLevel != nil && (todo.ParentID == nil) != *filter.TopLevel {
+			continue
+		}
 		if filter.Search != nil && *filter.Search != "" {
 			if !containString(todo.Title, *filter.Search) {
 				continue
 			}
 		}
-		filteredTodos = append(filteredTodos, todo)
+		if len(filter.Priorities) > 0 && !slices.Contains(filter.Priorities, todo.Priority) {
+			continue
+		}
+		if len(filter.TagsAny) > 0 && !slices.ContainsFunc(filter.TagsAny, func(tagID int) bool {
+			return slices.Contains(m.todoTags[todo.ID], tagID)
+		}) {
+			continue
+		}
+		if len(filter.TagsAll) > 0 && slices.ContainsFunc(filter.TagsAll, func(tagID int) bool {
+			return !slices.Contains(m.todoTags[todo.ID], tagID)
+		}) {
+			continue
+		}
+		if filter.DueBefore != nil && (todo.DueAt == nil || !todo.DueAt.Before(*filter.DueBefore)) {
+			continue
+		}
+		if filter.DueAfter != nil && (todo.DueAt == nil || todo.DueAt.Before(*filter.DueAfter)) {
+			continue
+		}
+		if filter.Overdue != nil && filter.day != nil && filter.day.isOverdue(todo) != *filter.Overdue {
+			continue
+		}
+		if filter.DueToday != nil && filter.day != nil && filter.day.isDueToday(todo) != *filter.DueToday {
+			continue
+		}
+		filteredTodos = append(filteredTodos, m.withRelations(todo))
 	}
 
+	slices.SortStableFunc(filteredTodos, func(a, b *Todo) int {
+		if filter.Sort.less(a, b) {
+			return -1
+		}
+		if filter.Sort.less(b, a) {
+			return 1
+		}
+		return 0
+	})
+
 	// Apply pagination
 	total := len(filteredTodos)
 	offset := filter.Offset
@@ -138,34 +201,90 @@ func (m *MockTodoRepository) Update(ctx context.Context, todoID, userID int, inp
 		todo.Completed = *input.Completed
 	}
 
+	if input.DueAt != nil {
+		todo.DueAt = input.DueAt
+		todo.AllDay = input.AllDay
+	}
+
+	if input.ClearDueAt {
+		todo.DueAt = nil
+		todo.AllDay = false
+	}
+
+	if input.Priority != nil {
+		todo.Priority = *input.Priority
+	}
+
+	if input.ProjectID != nil {
+		todo.ProjectID = *input.ProjectID
+	}
+
+	if input.ParentID != nil {
+		todo.ParentID = input.ParentID
+	}
+
+	if input.ClearParent {
+		todo.ParentID = nil
+	}
+
+	// Like the real repository, only the user's own tags are attached
+	for _, tagID := range input.AddTagIDs {
+		if tag, ok := m.tags[tagID]; ok && tag.UserID == userID && !slices.Contains(m.todoTags[todoID], tagID) {
+			m.todoTags[todoID] = append(m.todoTags[todoID], tagID)
+		}
+	}
+	m.todoTags[todoID] = slices.DeleteFunc(m.todoTags[todoID], func(tagID int) bool {
+		return slices.Contains(input.RemoveTagIDs, tagID)
+	})
+
 	todo.UpdatedAt = time.Now()
 
-	return todo, nil
+	return m.withRelations(todo), nil
 }
 
 // Delete implements Repository interface
-func (m *MockTodoRepository) Delete(ctx context.Context, todoID, userID int) error {
+func (m *MockTodoRepository) Delete(ctx context.Context, todoID, userID int, mode DeleteMode) (*DeleteResult, error) {
 	if m.shouldFail {
-		return m.failureError
+		return nil, m.failureError
 	}
 
 	todo, exists := m.todos[todoID]
 	if !exists || todo.UserID != userID {
-		return ErrTodoNotFound
+		return nil, ErrTodoNotFound
 	}
 
-	delete(m.todos, todoID)
+	result := &DeleteResult{}
+	if mode == DeleteReparent {
+		for _, child := range m.children(todoID) {
+			child.ParentID = todo.ParentID
+			child.UpdatedAt = time.Now()
+			result.Reparented = append(result.Reparented, m.withRelations(child))
+		}
+	}
 
-	// Remove from user todos
-	userTodos := m.todosByUser[userID]
-	for i, t := range userTodos {
-		if t.ID == todoID {
-			m.todosByUser[userID] = append(userTodos[:i], userTodos[i+1:]...)
-			break
+	// Collect the todo and whatever subtasks are still under it
+	pending := []int{todoID}
+	for len(pending) > 0 {
+		id := pending[0]
+		pending = pending[1:]
+		result.DeletedIDs = append(result.DeletedIDs, id)
+		for _, child := range m.children(id) {
+			pending = append(pending, child.ID)
 		}
 	}
+	slices.Sort(result.DeletedIDs)
+
+	for _, id := range result.DeletedIDs {
+		delete(m.todos, id)
+		delete(m.todoTags, id)
+	}
+
+	// Remove from user todos
+	m.todosByUser[userID] = slices.DeleteFunc(m.todosByUser[userID], func(t *Todo) bool {
+		return slices.Contains(result.DeletedIDs, t.ID)
+	})
 
-	return nil
+	return result, nil
 }
 
 // ToggleComplete implements Repository interface
@@ -182,7 +301,7 @@ func (m *MockTodoRepository) ToggleComplete(ctx context.Context, todoID, userID
 	todo.Completed = !todo.Completed
 	todo.UpdatedAt = time.Now()
 
-	return todo, nil
+	return m.withRelations(todo), nil
 }
 
 // CountByUserID implements Repository interface
@@ -194,7 +313,428 @@ func (m *MockTodoRepository) CountByUserID(ctx context.Context, userID int) (int
 	return len(m.todosByUser[userID]), nil
 }
 
+// GetStats implements Repository interface
+func (m *MockTodoRepository) GetStats(ctx context.Context, userID int, projectID *int, day *calendarDay) (*TodoStats, error) {
+	if m.shouldFail {
+		return nil, m.failureError
+	}
+
+	stats := &TodoStats{}
+	for _, todo := range m.todosByUser[userID] {
+		if projectID != nil && todo.ProjectID != *projectID {
+			continue
+		}
+		stats.Total++
+		if todo.Completed {
+			stats.Completed++
+		} else {
+			stats.Pending++
+		}
+		if day.isOverdue(todo) {
+			stats.Overdue++
+		}
+	}
+	return stats, nil
+}
+
+// GetUserLocation implements Repository interface
+func (m *MockTodoRepository) GetUserLocation(ctx context.Context, userID int) (*time.Location, error) {
+	if m.shouldFail {
+		return nil, m.failureError
+	}
+
+	if loc, ok := m.locations[userID]; ok {
+		return loc, nil
+	}
+	return time.UTC, nil
+}
+
+// CreateTag implements Repository interface
+func (m *MockTodoRepository) CreateTag(ctx context.Context, userID int, input CreateTagInput) (*Tag, error) {
+	if m.shouldFail {
+		return nil, m.failureError
+	}
+
+	for _, tag := range m.tags {
+		if tag.UserID == userID && strings.EqualFold(tag.Name, input.Name) {
+			return nil, ErrTagNameTaken
+		}
+	}
+
+	tag := &Tag{
+		ID:        m.nextTagID,
+		UserID:    userID,
+		Name:      input.Name,
+		Color:     input.Color,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	m.tags[tag.ID] = tag
+	m.nextTagID++
+
+	return tag, nil
+}
+
+// GetTagsByUserID implements Repository interface
+func (m *MockTodoRepository) GetTagsByUserID(ctx context.Context, userID int) ([]*Tag, error) {
+	if m.shouldFail {
+		return nil, m.failureError
+	}
+
+	tags := []*Tag{}
+	for _, tag := range m.tags {
+		if tag.UserID == userID {
+			tags = append(tags, tag)
+		}
+	}
+	sortTags(tags)
+
+	return tags, nil
+}
+
+// UpdateTag implements Repository interface
+func (m *MockTodoRepository) UpdateTag(ctx context.Context, tagID, userID int, input UpdateTagInput) (*Tag, error) {
+	if m.shouldFail {
+		return nil, m.failureError
+	}
+
+	tag, exists := m.tags[tagID]
+	if !exists || tag.UserID != userID {
+		return nil, ErrTagNotFound
+	}
+
+	if input.Name != nil {
+		for _, other := range m.tags {
+			if other.ID != tagID && other.UserID == userID && strings.EqualFold(other.Name, *input.Name) {
+				return nil, ErrTagNameTaken
+			}
+		}
+		tag.Name = *input.Name
+	}
+
+	if input.Color != nil {
+		tag.Color = *input.Color
+	}
+
+	tag.UpdatedAt = time.Now()
+
+	return tag, nil
+}
+
+// DeleteTag implements Repository interface
+func (m *MockTodoRepository) DeleteTag(ctx context.Context, tagID, userID int) ([]*Todo, error) {
+	if m.shouldFail {
+		return nil, m.failureError
+	}
+
+	tag, exists := m.tags[tagID]
+	if !exists || tag.UserID != userID {
+		return nil, ErrTagNotFound
+	}
+
+	delete(m.tags, tagID)
+	detached := []*Todo{}
+	for todoID, tagIDs := range m.todoTags {
+		if !slices.Contains(tagIDs, tagID) {
+			continue
+		}
+		m.todoTags[todoID] = slices.DeleteFunc(tagIDs, func(id int) bool { return id == tagID })
+		if todo, exists := m.todos[todoID]; exists {
+			todo.UpdatedAt = time.Now()
+			detached = append(detached, m.withRelations(todo))
+		}
+	}
+
+	return detached, nil
+}
+
+// withRelations fills in the todo's project, tags and progress the way loadRelations does
+func (m *MockTodoRepository) withRelations(todo *Todo) *Todo {
+	todo.Project = m.projects[todo.ProjectID]
+	todo.Tags = []*Tag{}
+	for _, tagID := range m.todoTags[todo.ID] {
+		todo.Tags = append(todo.Tags, m.tags[tagID])
+	}
+	sortTags(todo.Tags)
+
+	todo.Progress = TodoProgress{}
+	for _, child := range m.children(todo.ID) {
+		todo.Progress.Total++
+		if child.Completed {
+			todo.Progress.Completed++
+		}
+	}
+
+	return todo
+}
+
+// children returns the direct subtasks of a todo, oldest first
+func (m *MockTodoRepository) children(todoID int) []*Todo {
+	var children []*Todo
+	for _, todo := range m.todos {
+		if todo.ParentID != nil && *todo.ParentID == todoID {
+			children = append(children, todo)
+		}
+	}
+	slices.SortFunc(children, func(a, b *Todo) int { return a.ID - b.ID })
+
+	return children
+}
+
+// sortTags orders tags by name like the real repository
+func sortTags(tags []*Tag) {
+	slices.SortFunc(tags, func(a, b *Tag) int {
+		if c := strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)); c != 0 {
+			return c
+		}
+		return a.ID - b.ID
+	})
+}
+
+// EnsureInbox implements Repository interface
+func (m *MockTodoRepository) EnsureInbox(ctx context.Context, userID int) (*Project, error) {
+	if m.shouldFail {
+		return nil, m.failureError
+	}
+
+	for _, project := range m.projects {
+		if project.UserID == userID && project.IsInbox {
+			return project, nil
+		}
+	}
+
+	project := &Project{
+		ID:        m.nextProjID,
+		UserID:    userID,
+		Name:      InboxName,
+		Color:     DefaultColor,
+		IsInbox:   true,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	m.projects[project.ID] = project
+	m.nextProjID++
+
+	return project, nil
+}
+
+// CreateProject implements Repository interface
+func (m *MockTodoRepository) CreateProject(ctx context.Context, userID int, input CreateProjectInput) (*Project, error) {
+	if m.shouldFail {
+		return nil, m.failureError
+	}
+
+	position := 0
+	if input.Position != nil {
+		position = *input.Position
+	} else {
+		for _, project := range m.projects {
+			if project.UserID == userID && project.Position >= position {
+				position = project.Position + 1
+			}
+		}
+	}
+
+	project := &Project{
+		ID:        m.nextProjID,
+		UserID:    userID,
+		Name:      input.Name,
+		Color:     input.Color,
+		Position:  position,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	m.projects[project.ID] = project
+	m.nextProjID++
+
+	return project, nil
+}
+
+// GetProjectByID implements Repository interface
+func (m *MockTodoRepository) GetProjectByID(ctx context.Context, projectID, userID int) (*Project, error) {
+	if m.shouldFail {
+		return nil, m.failureError
+	}
+
+	project, exists := m.projects[projectID]
+	if !exists || project.UserID != userID {
+		return nil, ErrProjectNotFound
+	}
+
+	return project, nil
+}
+
+// GetProjectsByUserID implements Repository interface
+func (m *MockTodoRepository) GetProjectsByUserID(ctx context.Context, userID int, includeArchived bool) ([]*Project, error) {
+	if m.shouldFail {
+		return nil, m.failureError
+	}
+
+	projects := []*Project{}
+	for _, project := range m.projects {
+		if project.UserID == userID && (includeArchived || !project.Archived) {
+			projects = append(projects, project)
+		}
+	}
+
+	slices.SortFunc(projects, func(a, b *Project) int {
+		if a.IsInbox != b.IsInbox {
+			if a.IsInbox {
+				return -1
+			}
+			return 1
+		}
+		if a.Position != b.Position {
+			return a.Position - b.Position
+		}
+		return a.ID - b.ID
+	})
+
+	return projects, nil
+}
+
+// UpdateProject implements Repository interface
+func (m *MockTodoRepository) UpdateProject(ctx context.Context, projectID, userID int, input UpdateProjectInput) (*Project, error) {
+	if m.shouldFail {
+		return nil, m.failureError
+	}
+
+	project, exists := m.projects[projectID]
+	if !exists || project.UserID != userID {
+		return nil, ErrProjectNotFound
+	}
+
+	if input.Name != nil {
+		project.Name = *input.Name
+	}
+	if input.Color != nil {
+		project.Color = *input.Color
+	}
+	if input.Archived != nil {
+		project.Archived = *input.Archived
+	}
+	if input.Position != nil {
+		project.Position = *input.Position
+	}
+	project.UpdatedAt = time.Now()
+
+	return project, nil
+}
+
+// DeleteProject implements Repository interface
+func (m *MockTodoRepository) DeleteProject(ctx context.Context, projectID, userID int) ([]*Todo, error) {
+	if m.shouldFail {
+		return nil, m.failureError
+	}
+
+	project, exists := m.projects[projectID]
+	if !exists || project.UserID != userID || project.IsInbox {
+		return nil, ErrProjectNotFound
+	}
+
+	inbox, err := m.EnsureInbox(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	moved := []*Todo{}
+	for _, todo := range m.todosByUser[userID] {
+		if todo.ProjectID == projectID {
+			todo.ProjectID = inbox.ID
+			todo.UpdatedAt = time.Now()
+			moved = append(moved, m.withRelations(todo))
+		}
+	}
+	delete(m.projects, projectID)
+
+	return moved, nil
+}
+
+// GetChildren implements Repository interface
+func (m *MockTodoRepository) GetChildren(ctx context.Context, todoID, userID int) ([]*Todo, error) {
+	if m.shouldFail {
+		return nil, m.failureError
+	}
+
+	todos := []*Todo{}
+	for _, child := range m.children(todoID) {
+		if child.UserID == userID {
+			todos = append(todos, m.withRelations(child))
+		}
+	}
+
+	return todos, nil
+}
+
+// GetAncestorIDs implements Repository interface
+func (m *MockTodoRepository) GetAncestorIDs(ctx context.Context, todoID, userID int) ([]int, error) {
+	if m.shouldFail {
+		return nil, m.failureError
+	}
+
+	todo, exists := m.todos[todoID]
+	if !exists || todo.UserID != userID {
+		return nil, ErrTodoNotFound
+	}
+
+	ids := []int{todo.ID}
+	for todo.ParentID != nil && len(ids) <= MaxTodoDepth {
+		todo = m.todos[*todo.ParentID]
+		ids = append(ids, todo.ID)
+	}
+
+	return ids, nil
+}
+
+// GetSubtreeDepth implements Repository interface
+func (m *MockTodoRepository) GetSubtreeDepth(ctx context.Context, todoID, userID int) (int, error) {
+	if m.shouldFail {
+		return 0, m.failureError
+	}
+
+	todo, exists := m.todos[todoID]
+	if !exists || todo.UserID != userID {
+		return 0, ErrTodoNotFound
+	}
+
+	depth := 1
+	for _, child := range m.children(todoID) {
+		childDepth, _ := m.GetSubtreeDepth(ctx, child.ID, userID)
+		depth = max(depth, childDepth+1)
+	}
+
+	return depth, nil
+}
+
+// SetSubtasksCompleted implements Repository interface
+func (m *MockTodoRepository) SetSubtasksCompleted(ctx context.Context, todoID, userID int, completed bool) ([]*Todo, error) {
+	if m.shouldFail {
+		return nil, m.failureError
+	}
+
+	changed := []*Todo{}
+	for _, child := range m.children(todoID) {
+		if child.UserID != userID {
+			continue
+		}
+		if child.Completed != completed {
+			child.Completed = completed
+			child.UpdatedAt = time.Now()
+			changed = append(changed, m.withRelations(child))
+		}
+		grandchildren, _ := m.SetSubtasksCompleted(ctx, child.ID, userID, completed)
+		changed = append(changed, grandchildren...)
+	}
+
+	return changed, nil
+}
+
 // Mock control methods
+func (m *MockTodoRepository) SetUserLocation(userID int, loc *time.Location) {
+	m.locations[userID] = loc
+}
+
 func (m *MockTodoRepository) SetShouldFail(shouldFail bool, err error) {
 	m.shouldFail = shouldFail
 	m.failureError = err
@@ -203,7 +743,13 @@ func (m *MockTodoRepository) SetShouldFail(shouldFail bool, err error) {
 func (m *MockTodoRepository) Clear() {
 	m.todos = make(map[int]*Todo)
 	m.todosByUser = make(map[int][]*Todo)
+	m.locations = make(map[int]*time.Location)
+	m.tags = make(map[int]*Tag)
+	m.todoTags = make(map[int][]int)
+	m.projects = make(map[int]*Project)
 	m.nextID = 1
+	m.nextTagID = 1
+	m.nextProjID = 1
 	m.shouldFail = false
 	m.failureError = nil
 }
@@ -416,7 +962,7 @@ func TestDeleteTodo(t *testing.T) {
 	}
 
 	// Delete todo
-	err = repo.Delete(ctx, createdTodo.ID, repoTestData.testUserID)
+	_, err = repo.Delete(ctx, createdTodo.ID, repoTestData.testUserID, DeleteCascade)
 	if err != nil {
 		t.Fatalf("Delete should succeed: %v", err)
 	}
@@ -432,7 +978,7 @@ func TestDeleteNotFound(t *testing.T) {
 	repo := NewMockTodoRepository()
 	ctx := context.Background()
 
-	err := repo.Delete(ctx, 999, 1)
+	_, err := repo.Delete(ctx, 999, 1, DeleteCascade)
 	if err != ErrTodoNotFound {
 		t.Errorf("Expected ErrTodoNotFound, got %v", err)
 	}
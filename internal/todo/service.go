@@ -2,17 +2,26 @@ package todo
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/jayk0001/my-go-next-todo/internal/pubsub"
 )
 
 // TodoService handles todo business logic
 type TodoService struct {
 	repo      Repository
 	validator *ValidatorService
+	events    *pubsub.Hub[Event]
+	stats     *statsBroadcaster
+	// relayed is true when the repository broadcasts events through Postgres NOTIFY.
+	// Local publishing is skipped and events reach subscribers through RelayEvent instead.
+	relayed bool
 }
 
 // TodoServiceInterface defines the contract for TodoService
@@ -21,20 +30,40 @@ type TodoServiceInterface interface {
 	GetTodo(ctx context.Context, todoID, userID int) (*Todo, error)
 	GetUserTodos(ctx context.Context, userID int, filter TodoFilter) (*TodoListResponse, error)
 	UpdateTodo(ctx context.Context, todoID, userID int, input UpdateTodoInput) (*Todo, error)
-	DeleteTodo(ctx context.Context, todoID, userID int) error
-	ToggleTodoComplete(ctx context.Context, todoID, userID int) (*Todo, error)
+	DeleteTodo(ctx context.Context, todoID, userID int, mode DeleteMode) error
+	ToggleTodoComplete(ctx context.Context, todoID, userID int, cascade bool) (*Todo, error)
+	GetSubtasks(ctx context.Context, todoID, userID int) ([]*Todo, error)
 	GetUserTodoStats(ctx context.Context, userID int) (*TodoStats, error)
 	BatchUpdateTodos(ctx context.Context, userID int, todoIDs []int, input UpdateTodoInput) ([]*Todo, error)
+	SubscribeEvents(ctx context.Context, userID int) (<-chan Event, error)
+	SubscribeStats(ctx context.Context, userID int) (<-chan *TodoStats, error)
+	CreateTag(ctx context.Context, userID int, input CreateTagInput) (*Tag, error)
+	GetUserTags(ctx context.Context, userID int) ([]*Tag, error)
+	UpdateTag(ctx context.Context, tagID, userID int, input UpdateTagInput) (*Tag, error)
+	DeleteTag(ctx context.Context, tagID, userID int) error
+	AddTags(ctx context.Context, todoID, userID int, tagIDs []int) (*Todo, error)
+	RemoveTags(ctx context.Context, todoID, userID int, tagIDs []int) (*Todo, error)
+	CreateProject(ctx context.Context, userID int, input CreateProjectInput) (*Project, error)
+	GetProject(ctx context.Context, projectID, userID int) (*Project, error)
+	GetUserProjects(ctx context.Context, userID int, includeArchived bool) ([]*Project, error)
+	UpdateProject(ctx context.Context, projectID, userID int, input UpdateProjectInput) (*Project, error)
+	DeleteProject(ctx context.Context, projectID, userID int) error
+	MoveTodos(ctx context.Context, userID int, todoIDs []int, projectID int) ([]*Todo, error)
+	GetProjectTodoStats(ctx context.Context, projectID, userID int) (*TodoStats, error)
 }
 
 var _ TodoServiceInterface = (*TodoService)(nil)
 
 // NewTodoService creates a new todo service with provided dependencies
 func NewTodoService(repo Repository, validator *ValidatorService) *TodoService {
-	return &TodoService{
+	service := &TodoService{
 		repo:      repo,
 		validator: validator,
+		events:    pubsub.NewHub[Event](),
 	}
+	service.stats = newStatsBroadcaster(service.GetUserTodoStats, DefaultStatsDebounce, DefaultStatsMaxWait)
+
+	return service
 }
 
 // NewTodoServiceWithDB creates a new todo service with database connection
@@ -45,6 +74,19 @@ func NewTodoServiceWithDB(db *pgxpool.Pool) *TodoService {
 	)
 }
 
+// NewTodoServiceWithNotifier creates a todo service whose events are fanned out
+// to every API instance through Postgres LISTEN/NOTIFY
+func NewTodoServiceWithNotifier(db *pgxpool.Pool, notifier EventRelay) *TodoService {
+	service := NewTodoService(
+		NewTodoRepositoryWithNotifier(db, notifier),
+		NewValidatorService(),
+	)
+	service.relayed = true
+	notifier.Subscribe(EventChannel, service.RelayEvent)
+
+	return service
+}
+
 // CreateTodo creates a new todo with validation
 func (s *TodoService) CreateTodo(ctx context.Context, userID int, input CreateTodoInput) (*Todo, error) {
 	// Validate Input
@@ -52,12 +94,38 @@ func (s *TodoService) CreateTodo(ctx context.Context, userID int, input CreateTo
 		return nil, fmt.Errorf("failed to validate input: %w", err)
 	}
 
+	input.DueAt = normalizeDueAt(input.DueAt, input.AllDay)
+
+	// Subtasks go to their parent's project unless they name another one
+	if input.ParentID != nil {
+		parent, err := s.checkParentTarget(ctx, userID, 0, *input.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if input.ProjectID == nil {
+			input.ProjectID = &parent.ProjectID
+		}
+	}
+
+	// Other todos go to the inbox unless they name one of the user's projects
+	if input.ProjectID == nil {
+		inbox, err := s.CreateInbox(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		input.ProjectID = &inbox.ID
+	} else if err := s.checkProjectTarget(ctx, userID, *input.ProjectID); err != nil {
+		return nil, err
+	}
+
 	// Create todo via repository
 	todo, err := s.repo.Create(ctx, userID, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create a new todo: %w", err)
 	}
 
+	s.publish(EventCreated, userID, todo.ID, todo)
+
 	return todo, nil
 }
 
@@ -78,7 +146,18 @@ func (s *TodoService) GetTodo(ctx context.Context, todoID, userID int) (*Todo, e
 // GetUserTodos retrieves todos by userID with pagination and filtering
 func (s *TodoService) GetUserTodos(ctx context.Context, userID int, filter TodoFilter) (*TodoListResponse, error) {
 	// Validate and normalize filter
+	if err := s.validator.ValidateFilter(ctx, filter); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+	if filter.ProjectID != nil {
+		if _, err := s.GetProject(ctx, *filter.ProjectID, userID); err != nil {
+			return nil, err
+		}
+	}
 	normalizeFilter := s.normalizeFilter(filter)
+	if err := s.resolveDay(ctx, userID, &normalizeFilter); err != nil {
+		return nil, err
+	}
 
 	todos, err := s.repo.GetByUserID(ctx, userID, normalizeFilter)
 
@@ -100,6 +179,7 @@ func (s *TodoService) UpdateTodo(ctx context.Context, todoID, userID int, input
 	if err := s.validator.ValidateUpdateInput(ctx, input); err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
+	input.DueAt = normalizeDueAt(input.DueAt, input.AllDay)
 
 	// Check if todo exists and user owns it
 	_, err := s.repo.GetByID(ctx, todoID, userID)
@@ -110,22 +190,48 @@ func (s *TodoService) UpdateTodo(ctx context.Context, todoID, userID int, input
 		return nil, fmt.Errorf("failed to validate todo ownership: %w", err)
 	}
 
+	if err := s.checkTagOwnership(ctx, userID, input); err != nil {
+		return nil, err
+	}
+
+	if input.ProjectID != nil {
+		if err := s.checkProjectTarget(ctx, userID, *input.ProjectID); err != nil {
+			return nil, err
+		}
+	}
+
+	if input.ParentID != nil {
+		if _, err := s.checkParentTarget(ctx, userID, todoID, *input.ParentID); err != nil {
+			return nil, err
+		}
+	}
+
 	// Update todo
 	todo, err := s.repo.Update(ctx, todoID, userID, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update todo: %w", err)
 	}
 
+	s.publish(EventUpdated, userID, todo.ID, todo)
+
 	return todo, nil
 }
 
-// DeleteTodo deletes a todo with ownership check
-func (s *TodoService) DeleteTodo(ctx context.Context, todoID, userID int) error {
+// DeleteTodo deletes a todo with ownership check. Its subtasks are deleted too, or moved up
+// to the todo's own parent with DeleteReparent; an empty mode means DeleteCascade.
+func (s *TodoService) DeleteTodo(ctx context.Context, todoID, userID int, mode DeleteMode) error {
 	// Validate todoID
 	if todoID <= 0 {
 		return ErrInvalidTodoInput
 	}
 
+	if mode == "" {
+		mode = DeleteCascade
+	}
+	if !mode.IsValid() {
+		return ErrInvalidDeleteMode
+	}
+
 	// Check if todo exists and user owns it
 	_, err := s.repo.GetByID(ctx, todoID, userID)
 	if err != nil {
@@ -136,23 +242,31 @@ func (s *TodoService) DeleteTodo(ctx context.Context, todoID, userID int) error
 	}
 
 	// Delete todo
-	err = s.repo.Delete(ctx, todoID, userID)
+	result, err := s.repo.Delete(ctx, todoID, userID, mode)
 	if err != nil {
 		return fmt.Errorf("failed to delete todo: %w", err)
 	}
 
+	for _, deletedID := range result.DeletedIDs {
+		s.publish(EventDeleted, userID, deletedID, nil)
+	}
+	for _, todo := range result.Reparented {
+		s.publish(EventUpdated, userID, todo.ID, todo)
+	}
+
 	return nil
 }
 
-// ToggleTodoComplete toggles the completed status of a todo
-func (s *TodoService) ToggleTodoComplete(ctx context.Context, todoID, userID int) (*Todo, error) {
+// ToggleTodoComplete toggles the completed status of a todo. With cascade, every subtask
+// at any depth is given the todo's new status as well.
+func (s *TodoService) ToggleTodoComplete(ctx context.Context, todoID, userID int, cascade bool) (*Todo, error) {
 	// Validate todoID
 	if todoID <= 0 {
 		return nil, ErrInvalidTodoInput
 	}
 
 	// check if todo exists and user owns it
-	_, err := s.repo.GetByID(ctx, todoID, userID)
+	existing, err := s.repo.GetByID(ctx, todoID, userID)
 	if err != nil {
 		if err == ErrTodoNotFound {
 			return nil, ErrTodoAccessDenied
@@ -160,43 +274,47 @@ func (s *TodoService) ToggleTodoComplete(ctx context.Context, todoID, userID int
 		return nil, fmt.Errorf("failed to verity todo ownership: %w", err)
 	}
 
+	// Subtasks are updated first so the toggled todo comes back with their progress
+	if cascade {
+		subtasks, err := s.repo.SetSubtasksCompleted(ctx, todoID, userID, !existing.Completed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to toggle subtasks: %w", err)
+		}
+		for _, subtask := range subtasks {
+			s.publish(EventToggled, userID, subtask.ID, subtask)
+		}
+	}
+
 	// Toggle completion
 	todo, err := s.repo.ToggleComplete(ctx, todoID, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to toggle complete todo: %w", err)
 	}
 
+	s.publish(EventToggled, userID, todo.ID, todo)
+
 	return todo, nil
 }
 
 // GetUserTodoStats returns statistics about user's todos
 func (s *TodoService) GetUserTodoStats(ctx context.Context, userID int) (*TodoStats, error) {
-	// Get total count
-	total, err := s.repo.CountByUserID(ctx, userID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to count todos: %w", err)
-	}
+	return s.todoStats(ctx, userID, nil)
+}
 
-	// Get completed count
-	completedFilter := TodoFilter{Completed: &[]bool{true}[0]}
-	completed, err := s.repo.GetByUserID(ctx, userID, completedFilter)
+// todoStats counts the user's todos, only those in the project when projectID is set
+func (s *TodoService) todoStats(ctx context.Context, userID int, projectID *int) (*TodoStats, error) {
+	// Overdue is decided in the user's time zone
+	day, err := s.currentDay(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to count completed todos: %w", err)
+		return nil, err
 	}
 
-	// get pending count
-	pendingFilter := TodoFilter{Completed: &[]bool{false}[0]}
-	pending, err := s.repo.GetByUserID(ctx, userID, pendingFilter)
+	stats, err := s.repo.GetStats(ctx, userID, projectID, day)
 	if err != nil {
-		return nil, fmt.Errorf("failed to count pending todos: %w", err)
+		return nil, fmt.Errorf("failed to count todos: %w", err)
 	}
 
-	return &TodoStats{
-		Total:     total,
-		Completed: len(completed.Todos),
-		Pending:   len(pending.Todos),
-	}, nil
-
+	return stats, nil
 }
 
 // BatchUpdateTodos updates multiple todos at once (bonus feature)
@@ -210,6 +328,18 @@ func (s *TodoService) BatchUpdateTodos(ctx context.Context, userID int, todoIDs
 	if err := s.validator.ValidateUpdateInput(ctx, input); err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
+	input.DueAt = normalizeDueAt(input.DueAt, input.AllDay)
+
+	// Tags and the project are shared by every todo in the batch, so they are checked once up front
+	if err := s.checkTagOwnership(ctx, userID, input); err != nil {
+		return nil, err
+	}
+
+	if input.ProjectID != nil {
+		if err := s.checkProjectTarget(ctx, userID, *input.ProjectID); err != nil {
+			return nil, err
+		}
+	}
 
 	var updatedTodos []*Todo
 	var errs []error
@@ -226,13 +356,22 @@ func (s *TodoService) BatchUpdateTodos(ctx context.Context, userID int, todoIDs
 			errs = append(errs, fmt.Errorf("todo %d: failted to check ownershio: %w", todoID, err))
 		}
 
+		// Cycles and depth depend on each todo's own subtasks
+		if input.ParentID != nil {
+			if _, err := s.checkParentTarget(ctx, userID, todoID, *input.ParentID); err != nil {
+				errs = append(errs, fmt.Errorf("todo %d: %w", todoID, err))
+				continue
+			}
+		}
+
 		// Update if owned
-		updated, err := s.repo.Update(ctx, todoID, userID, input)
+		updated, err := s.repo.Update(withEventKind(ctx, EventBatchUpdated), todoID, userID, input)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("todo %d: failted to update: %w", todoID, err))
 			continue
 		}
 		updatedTodos = append(updatedTodos, updated)
+		s.publish(EventBatchUpdated, userID, updated.ID, updated)
 	}
 
 	if len(errs) > 0 {
@@ -242,6 +381,99 @@ func (s *TodoService) BatchUpdateTodos(ctx context.Context, userID int, todoIDs
 	return updatedTodos, nil
 }
 
+// SubscribeEvents streams change events for the user's todos until ctx is done
+func (s *TodoService) SubscribeEvents(ctx context.Context, userID int) (<-chan Event, error) {
+	if userID <= 0 {
+		return nil, ErrInvalidTodoInput
+	}
+
+	return s.events.Subscribe(ctx, userID), nil
+}
+
+// RelayEvent publishes an event received through Postgres NOTIFY to local subscribers
+func (s *TodoService) RelayEvent(payload string) {
+	var event Event
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		fmt.Printf("TodoService: failed to decode relayed event: %v\n", err)
+		return
+	}
+
+	s.stats.touch(event.UserID)
+
+	// Nobody on this instance is listening for the user's todos
+	if s.events.SubscriberCount(event.UserID) == 0 {
+		return
+	}
+
+	// Oversized payloads are sent without the todo, so reload it
+	if event.Todo == nil && event.Kind != EventDeleted {
+		todo, err := s.repo.GetByID(context.Background(), event.TodoID, event.UserID)
+		if err != nil {
+			fmt.Printf("TodoService: failed to load relayed todo %d: %v\n", event.TodoID, err)
+			return
+		}
+		event.Todo = todo
+	}
+
+	s.events.Publish(event.UserID, event)
+}
+
+// SubscribeStats streams fresh stats whenever the user's todos change, until ctx is done.
+// Bursts of changes (e.g. a batch update) are debounced into a single push.
+func (s *TodoService) SubscribeStats(ctx context.Context, userID int) (<-chan *TodoStats, error) {
+	if userID <= 0 {
+		return nil, ErrInvalidTodoInput
+	}
+
+	return s.stats.subscribe(ctx, userID), nil
+}
+
+// Close stops background stats recomputation
+func (s *TodoService) Close() {
+	s.stats.close()
+}
+
+// publish notifies subscribers about a change to one of the user's todos
+func (s *TodoService) publish(kind EventKind, userID, todoID int, todo *Todo) {
+	// Relayed events are published by RelayEvent once Postgres delivers them
+	if s.relayed {
+		return
+	}
+
+	s.events.Publish(userID, Event{
+		Kind:   kind,
+		UserID: userID,
+		TodoID: todoID,
+		Todo:   todo,
+	})
+	s.stats.touch(userID)
+}
+
+// resolveDay looks up the user's current day when the filter needs it
+func (s *TodoService) resolveDay(ctx context.Context, userID int, filter *TodoFilter) error {
+	if filter.Overdue == nil && filter.DueToday == nil {
+		return nil
+	}
+
+	day, err := s.currentDay(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	filter.day = day
+	return nil
+}
+
+// currentDay returns the current day in the user's time zone
+func (s *TodoService) currentDay(ctx context.Context, userID int) (*calendarDay, error) {
+	loc, err := s.repo.GetUserLocation(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user time zone: %w", err)
+	}
+
+	return newCalendarDay(time.Now(), loc), nil
+}
+
 // normalizeFilter validates and normalizes filter parameters
 func (s *TodoService) normalizeFilter(filter TodoFilter) TodoFilter {
 	normalized := filter
@@ -260,6 +492,10 @@ func (s *TodoService) normalizeFilter(filter TodoFilter) TodoFilter {
 		normalized.Offset = 0
 	}
 
+	if normalized.Sort == (TodoSort{}) {
+		normalized.Sort = DefaultSort
+	}
+
 	// Normalize search term
 	if normalized.Search != nil {
 		trimmed := strings.TrimSpace(*normalized.Search)
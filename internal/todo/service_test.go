@@ -2,9 +2,12 @@ package todo
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 )
 
 // ============================================================================
@@ -235,7 +238,7 @@ func TestServiceGetUserTodosWithCompletedFilter(t *testing.T) {
 		input := CreateTodoInput{Title: todo.title}
 		created, _ := setup.service.CreateTodo(setup.ctx, setup.userID, input)
 		if todo.completed {
-			setup.service.ToggleTodoComplete(setup.ctx, created.ID, setup.userID)
+			setup.service.ToggleTodoComplete(setup.ctx, created.ID, setup.userID, false)
 		}
 	}
 
@@ -478,7 +481,7 @@ func TestServiceDeleteTodo(t *testing.T) {
 	createdTodo, _ := setup.repo.Create(setup.ctx, setup.userID, input)
 
 	// Delete it
-	err := setup.service.DeleteTodo(setup.ctx, createdTodo.ID, setup.userID)
+	err := setup.service.DeleteTodo(setup.ctx, createdTodo.ID, setup.userID, DeleteCascade)
 	if err != nil {
 		t.Fatalf("DeleteTodo should succeed: %v", err)
 	}
@@ -493,7 +496,7 @@ func TestServiceDeleteTodo(t *testing.T) {
 func TestServiceDeleteTodoInvalidID(t *testing.T) {
 	setup := newServiceTestSetup()
 
-	err := setup.service.DeleteTodo(setup.ctx, 0, setup.userID)
+	err := setup.service.DeleteTodo(setup.ctx, 0, setup.userID, DeleteCascade)
 	if err == nil {
 		t.Fatal("DeleteTodo should fail with invalid ID")
 	}
@@ -506,7 +509,7 @@ func TestServiceDeleteTodoInvalidID(t *testing.T) {
 func TestServiceDeleteTodoNotFound(t *testing.T) {
 	setup := newServiceTestSetup()
 
-	err := setup.service.DeleteTodo(setup.ctx, 999, setup.userID)
+	err := setup.service.DeleteTodo(setup.ctx, 999, setup.userID, DeleteCascade)
 	if err == nil {
 		t.Fatal("DeleteTodo should fail for non-existent todo")
 	}
@@ -524,7 +527,7 @@ func TestServiceDeleteTodoWrongUser(t *testing.T) {
 	createdTodo, _ := setup.repo.Create(setup.ctx, 1, input)
 
 	// User 2 tries to delete it
-	err := setup.service.DeleteTodo(setup.ctx, createdTodo.ID, 2)
+	err := setup.service.DeleteTodo(setup.ctx, createdTodo.ID, 2, DeleteCascade)
 	if err == nil {
 		t.Fatal("DeleteTodo should fail for different user")
 	}
@@ -546,7 +549,7 @@ func TestServiceToggleTodoComplete(t *testing.T) {
 	createdTodo, _ := setup.repo.Create(setup.ctx, setup.userID, input)
 
 	// Toggle to completed
-	toggledTodo, err := setup.service.ToggleTodoComplete(setup.ctx, createdTodo.ID, setup.userID)
+	toggledTodo, err := setup.service.ToggleTodoComplete(setup.ctx, createdTodo.ID, setup.userID, false)
 	if err != nil {
 		t.Fatalf("ToggleTodoComplete should succeed: %v", err)
 	}
@@ -556,7 +559,7 @@ func TestServiceToggleTodoComplete(t *testing.T) {
 	}
 
 	// Toggle back to incomplete
-	toggledTodo, err = setup.service.ToggleTodoComplete(setup.ctx, createdTodo.ID, setup.userID)
+	toggledTodo, err = setup.service.ToggleTodoComplete(setup.ctx, createdTodo.ID, setup.userID, false)
 	if err != nil {
 		t.Fatalf("Second toggle should succeed: %v", err)
 	}
@@ -569,7 +572,7 @@ func TestServiceToggleTodoComplete(t *testing.T) {
 func TestServiceToggleTodoCompleteInvalidID(t *testing.T) {
 	setup := newServiceTestSetup()
 
-	_, err := setup.service.ToggleTodoComplete(setup.ctx, 0, setup.userID)
+	_, err := setup.service.ToggleTodoComplete(setup.ctx, 0, setup.userID, false)
 	if err == nil {
 		t.Fatal("ToggleTodoComplete should fail with invalid ID")
 	}
@@ -582,7 +585,7 @@ func TestServiceToggleTodoCompleteInvalidID(t *testing.T) {
 func TestServiceToggleTodoCompleteNotFound(t *testing.T) {
 	setup := newServiceTestSetup()
 
-	_, err := setup.service.ToggleTodoComplete(setup.ctx, 999, setup.userID)
+	_, err := setup.service.ToggleTodoComplete(setup.ctx, 999, setup.userID, false)
 	if err == nil {
 		t.Fatal("ToggleTodoComplete should fail for non-existent todo")
 	}
@@ -600,7 +603,7 @@ func TestServiceToggleTodoCompleteWrongUser(t *testing.T) {
 	createdTodo, _ := setup.repo.Create(setup.ctx, 1, input)
 
 	// User 2 tries to toggle it
-	_, err := setup.service.ToggleTodoComplete(setup.ctx, createdTodo.ID, 2)
+	_, err := setup.service.ToggleTodoComplete(setup.ctx, createdTodo.ID, 2, false)
 	if err == nil {
 		t.Fatal("ToggleTodoComplete should fail for different user")
 	}
@@ -798,3 +801,1562 @@ func TestServiceBatchUpdateTodosPartialFailure(t *testing.T) {
 		}
 	}
 }
+
+// ============================================================================
+// Tests - Due dates
+// ============================================================================
+
+func TestServiceCreateTodoDueDate(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	// 01:30 on March 11 in Seoul is still March 10 in UTC; the date the client meant is kept
+	seoul := time.FixedZone("KST", 9*60*60)
+	dueAt := time.Date(2026, 3, 11, 1, 30, 0, 0, seoul)
+
+	created, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{
+		Title:  "All day",
+		DueAt:  &dueAt,
+		AllDay: true,
+	})
+	if err != nil {
+		t.Fatalf("CreateTodo should succeed: %v", err)
+	}
+
+	expected := time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)
+	if created.DueAt == nil || !created.DueAt.Equal(expected) || !created.AllDay {
+		t.Errorf("Expected all-day due date %v, got %v (all day %v)", expected, created.DueAt, created.AllDay)
+	}
+
+	_, err = setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: "No date", AllDay: true})
+	if !errors.Is(err, ErrTodoAllDayWithoutDueDate) {
+		t.Errorf("Expected ErrTodoAllDayWithoutDueDate, got: %v", err)
+	}
+}
+
+func TestServiceUpdateTodoDueDate(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	created, _ := setup.repo.Create(setup.ctx, setup.userID, CreateTodoInput{Title: "Todo"})
+	dueAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	updated, err := setup.service.UpdateTodo(setup.ctx, created.ID, setup.userID, UpdateTodoInput{DueAt: &dueAt})
+	if err != nil {
+		t.Fatalf("UpdateTodo should succeed: %v", err)
+	}
+	if updated.DueAt == nil || !updated.DueAt.Equal(dueAt) || updated.AllDay {
+		t.Errorf("Expected timed due date %v, got %v", dueAt, updated.DueAt)
+	}
+
+	_, err = setup.service.UpdateTodo(setup.ctx, created.ID, setup.userID, UpdateTodoInput{DueAt: &dueAt, ClearDueAt: true})
+	if !errors.Is(err, ErrInvalidTodoInput) {
+		t.Errorf("Expected ErrInvalidTodoInput when setting and clearing, got: %v", err)
+	}
+
+	_, err = setup.service.UpdateTodo(setup.ctx, created.ID, setup.userID, UpdateTodoInput{AllDay: true})
+	if !errors.Is(err, ErrTodoAllDayWithoutDueDate) {
+		t.Errorf("Expected ErrTodoAllDayWithoutDueDate, got: %v", err)
+	}
+
+	cleared, err := setup.service.UpdateTodo(setup.ctx, created.ID, setup.userID, UpdateTodoInput{ClearDueAt: true})
+	if err != nil {
+		t.Fatalf("UpdateTodo should succeed: %v", err)
+	}
+	if cleared.DueAt != nil || cleared.AllDay {
+		t.Errorf("Expected due date to be cleared, got %v", cleared.DueAt)
+	}
+}
+
+func TestServiceGetUserTodosDueFilters(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	seoul, err := time.LoadLocation("Asia/Seoul")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	setup.repo.SetUserLocation(setup.userID, seoul)
+
+	now := time.Now()
+	today := newCalendarDay(now, seoul)
+	yesterday := today.date.AddDate(0, 0, -1)
+	pastHour := now.Add(-time.Hour)
+	laterToday := today.end.Add(-time.Second)
+	nextWeek := now.Add(7 * 24 * time.Hour)
+
+	create := func(title string, dueAt *time.Time, allDay bool) *Todo {
+		todo, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: title, DueAt: dueAt, AllDay: allDay})
+		if err != nil {
+			t.Fatalf("CreateTodo should succeed: %v", err)
+		}
+		return todo
+	}
+
+	create("Late", &pastHour, false)
+	done := create("Late but done", &pastHour, false)
+	setup.repo.ToggleComplete(setup.ctx, done.ID, setup.userID)
+	create("Yesterday", &yesterday, true)
+	create("Today", &today.date, true)
+	create("Later today", &laterToday, false)
+	create("Next week", &nextWeek, false)
+	create("Someday", nil, false)
+
+	titles := func(filter TodoFilter) []string {
+		t.Helper()
+		result, err := setup.service.GetUserTodos(setup.ctx, setup.userID, filter)
+		if err != nil {
+			t.Fatalf("GetUserTodos should succeed: %v", err)
+		}
+		var titles []string
+		for _, todo := range result.Todos {
+			titles = append(titles, todo.Title)
+		}
+		return titles
+	}
+
+	if got := titles(TodoFilter{Overdue: boolPtr(true)}); fmt.Sprint(got) != "[Yesterday Late]" {
+		t.Errorf("Unexpected overdue todos: %v", got)
+	}
+	if got := titles(TodoFilter{Overdue: boolPtr(false)}); len(got) != 5 {
+		t.Errorf("Expected 5 todos that aren't overdue, got %v", got)
+	}
+
+	// "Late" may fall on the previous day when the test runs just after midnight in Seoul
+	got := titles(TodoFilter{DueToday: boolPtr(true), Completed: boolPtr(false)})
+	if !containsAll(got, "Today", "Later today") || containsAll(got, "Yesterday") || containsAll(got, "Next week") {
+		t.Errorf("Unexpected todos due today: %v", got)
+	}
+
+	// The all-day "Today" is stored at midnight UTC, which may be on either side of now
+	got = titles(TodoFilter{DueAfter: &now})
+	if !containsAll(got, "Later today", "Next week") || containsAll(got, "Late") || containsAll(got, "Someday") {
+		t.Errorf("Unexpected todos due after now: %v", got)
+	}
+	got = titles(TodoFilter{DueBefore: &now})
+	if !containsAll(got, "Late", "Late but done", "Yesterday") || containsAll(got, "Later today") || containsAll(got, "Someday") {
+		t.Errorf("Unexpected todos due before now: %v", got)
+	}
+
+	stats, err := setup.service.GetUserTodoStats(setup.ctx, setup.userID)
+	if err != nil {
+		t.Fatalf("GetUserTodoStats should succeed: %v", err)
+	}
+	if stats.Overdue != 2 {
+		t.Errorf("Expected overdue 2, got %d", stats.Overdue)
+	}
+}
+
+func TestCalendarDayTimeZone(t *testing.T) {
+	seoul := time.FixedZone("KST", 9*60*60)
+	// 20:00 UTC on March 10 is already March 11 in Seoul
+	now := time.Date(2026, 3, 10, 20, 0, 0, 0, time.UTC)
+	march10 := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	allDay := &Todo{DueAt: &march10, AllDay: true}
+
+	if utc := newCalendarDay(now, time.UTC); utc.isOverdue(allDay) || !utc.isDueToday(allDay) {
+		t.Error("In UTC the all-day todo should be due today and not overdue")
+	}
+	if kst := newCalendarDay(now, seoul); !kst.isOverdue(allDay) || kst.isDueToday(allDay) {
+		t.Error("In Seoul the all-day todo should be overdue and not due today")
+	}
+
+	// 23:30 in Seoul on March 11 is within the Seoul day but after it ends in UTC terms
+	timed := time.Date(2026, 3, 11, 14, 30, 0, 0, time.UTC)
+	if !newCalendarDay(now, seoul).isDueToday(&Todo{DueAt: &timed}) {
+		t.Error("A todo due at 23:30 Seoul time should be due today in Seoul")
+	}
+	if newCalendarDay(now, time.UTC).isDueToday(&Todo{DueAt: &timed}) {
+		t.Error("A todo due tomorrow in UTC should not be due today in UTC")
+	}
+}
+
+// containsAll reports whether titles contains every one of want
+func containsAll(titles []string, want ...string) bool {
+	for _, w := range want {
+		found := false
+		for _, title := range titles {
+			if title == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// ============================================================================
+// Tests - Priorities and sorting
+// ============================================================================
+
+func TestServicePriorityValidation(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	_, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: "Todo", Priority: PriorityUrgent + 1})
+	if !errors.Is(err, ErrInvalidTodoPriority) {
+		t.Errorf("Expected ErrInvalidTodoPriority, got: %v", err)
+	}
+
+	created, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: "Todo", Priority: PriorityHigh})
+	if err != nil {
+		t.Fatalf("CreateTodo should succeed: %v", err)
+	}
+	if created.Priority != PriorityHigh {
+		t.Errorf("Expected high priority, got %v", created.Priority)
+	}
+
+	invalid := Priority(-1)
+	_, err = setup.service.UpdateTodo(setup.ctx, created.ID, setup.userID, UpdateTodoInput{Priority: &invalid})
+	if !errors.Is(err, ErrInvalidTodoPriority) {
+		t.Errorf("Expected ErrInvalidTodoPriority, got: %v", err)
+	}
+
+	low := PriorityLow
+	updated, err := setup.service.UpdateTodo(setup.ctx, created.ID, setup.userID, UpdateTodoInput{Priority: &low})
+	if err != nil {
+		t.Fatalf("UpdateTodo with only a priority should succeed: %v", err)
+	}
+	if updated.Priority != PriorityLow {
+		t.Errorf("Expected low priority, got %v", updated.Priority)
+	}
+
+	_, err = setup.service.GetUserTodos(setup.ctx, setup.userID, TodoFilter{Priorities: []Priority{PriorityLow, invalid}})
+	if !errors.Is(err, ErrInvalidTodoPriority) {
+		t.Errorf("Expected ErrInvalidTodoPriority for the filter, got: %v", err)
+	}
+
+	_, err = setup.service.GetUserTodos(setup.ctx, setup.userID, TodoFilter{Sort: TodoSort{Field: "color"}})
+	if !errors.Is(err, ErrInvalidTodoSort) {
+		t.Errorf("Expected ErrInvalidTodoSort, got: %v", err)
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	for _, name := range []string{"none", "low", "MEDIUM", "High", "urgent"} {
+		priority, err := ParsePriority(name)
+		if err != nil {
+			t.Errorf("ParsePriority(%q) should succeed: %v", name, err)
+		}
+		if !strings.EqualFold(priority.String(), name) {
+			t.Errorf("ParsePriority(%q) returned %v", name, priority)
+		}
+	}
+
+	if _, err := ParsePriority("critical"); !errors.Is(err, ErrInvalidTodoPriority) {
+		t.Errorf("Expected ErrInvalidTodoPriority, got: %v", err)
+	}
+
+	// Priorities travel by name in events relayed between instances
+	encoded, err := json.Marshal(Todo{Priority: PriorityUrgent})
+	if err != nil || !strings.Contains(string(encoded), `"priority":"urgent"`) {
+		t.Fatalf("Expected priority encoded by name, got %s (%v)", encoded, err)
+	}
+	var decoded Todo
+	if err := json.Unmarshal(encoded, &decoded); err != nil || decoded.Priority != PriorityUrgent {
+		t.Errorf("Expected urgent after decoding, got %v (%v)", decoded.Priority, err)
+	}
+}
+
+func TestServiceGetUserTodosPriorityFilter(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	for i, priority := range []Priority{PriorityNone, PriorityLow, PriorityHigh, PriorityUrgent, PriorityHigh} {
+		input := CreateTodoInput{Title: fmt.Sprintf("Todo %d", i+1), Priority: priority}
+		if _, err := setup.service.CreateTodo(setup.ctx, setup.userID, input); err != nil {
+			t.Fatalf("CreateTodo should succeed: %v", err)
+		}
+	}
+
+	result, err := setup.service.GetUserTodos(setup.ctx, setup.userID, TodoFilter{
+		Priorities: []Priority{PriorityHigh, PriorityUrgent},
+	})
+	if err != nil {
+		t.Fatalf("GetUserTodos should succeed: %v", err)
+	}
+	if result.Total != 3 {
+		t.Errorf("Expected 3 high or urgent todos, got %d", result.Total)
+	}
+	for _, todo := range result.Todos {
+		if todo.Priority != PriorityHigh && todo.Priority != PriorityUrgent {
+			t.Errorf("Unexpected priority %v", todo.Priority)
+		}
+	}
+}
+
+func TestServiceGetUserTodosSort(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	dueSoon := base.Add(time.Hour)
+	dueLater := base.Add(48 * time.Hour)
+
+	// Todos 2, 3 and 4 share a priority so the tie-break decides their order
+	inputs := []CreateTodoInput{
+		{Title: "banana", Priority: PriorityLow, DueAt: &dueLater},
+		{Title: "Apple", Priority: PriorityHigh},
+		{Title: "cherry", Priority: PriorityHigh, DueAt: &dueSoon},
+		{Title: "date", Priority: PriorityHigh},
+		{Title: "Elderberry", Priority: PriorityUrgent},
+	}
+	for _, input := range inputs {
+		if _, err := setup.service.CreateTodo(setup.ctx, setup.userID, input); err != nil {
+			t.Fatalf("CreateTodo should succeed: %v", err)
+		}
+	}
+
+	ids := func(sort TodoSort, limit, offset int) []int {
+		t.Helper()
+		result, err := setup.service.GetUserTodos(setup.ctx, setup.userID, TodoFilter{Sort: sort, Limit: limit, Offset: offset})
+		if err != nil {
+			t.Fatalf("GetUserTodos should succeed: %v", err)
+		}
+		var ids []int
+		for _, todo := range result.Todos {
+			ids = append(ids, todo.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name string
+		sort TodoSort
+		want string
+	}{
+		{"Default is newest first", TodoSort{}, "[5 4 3 2 1]"},
+		{"Priority ascending", TodoSort{Field: SortByPriority}, "[1 2 3 4 5]"},
+		{"Priority descending", TodoSort{Field: SortByPriority, Descending: true}, "[5 4 3 2 1]"},
+		{"Due ascending, undated last", TodoSort{Field: SortByDue}, "[3 1 2 4 5]"},
+		{"Due descending, undated last", TodoSort{Field: SortByDue, Descending: true}, "[1 3 5 4 2]"},
+		{"Title ignores case", TodoSort{Field: SortByTitle}, "[2 1 3 4 5]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fmt.Sprint(ids(tt.sort, 0, 0)); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("Pages neither skip nor repeat ties", func(t *testing.T) {
+		sort := TodoSort{Field: SortByPriority, Descending: true}
+		var paged []int
+		for offset := 0; offset < len(inputs); offset += 2 {
+			paged = append(paged, ids(sort, 2, offset)...)
+		}
+		if fmt.Sprint(paged) != fmt.Sprint(ids(sort, 0, 0)) {
+			t.Errorf("Expected pages to match the full list, got %v", paged)
+		}
+	})
+}
+
+func TestTodoSortOrderBy(t *testing.T) {
+	tests := []struct {
+		sort TodoSort
+		want string
+	}{
+		{DefaultSort, "created_at DESC, id DESC"},
+		{TodoSort{Field: SortByDue}, "due_at ASC NULLS LAST, id ASC"},
+		{TodoSort{Field: SortByTitle, Descending: true}, "LOWER(title) DESC, id DESC"},
+		{TodoSort{Field: "id; DROP TABLE todos"}, "created_at DESC, id DESC"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.sort.orderBy(); got != tt.want {
+			t.Errorf("orderBy(%+v) = %q, want %q", tt.sort, got, tt.want)
+		}
+	}
+}
+
+// ============================================================================
+// Tests - Tags
+// ============================================================================
+
+// createTags creates tags with the given names for the user and returns their IDs
+func createTags(t *testing.T, setup *serviceTestSetup, userID int, names ...string) []int {
+	t.Helper()
+
+	var ids []int
+	for _, name := range names {
+		tag, err := setup.service.CreateTag(setup.ctx, userID, CreateTagInput{Name: name})
+		if err != nil {
+			t.Fatalf("CreateTag should succeed: %v", err)
+		}
+		ids = append(ids, tag.ID)
+	}
+	return ids
+}
+
+// tagNames returns the names of the todo's tags in order
+func tagNames(todo *Todo) string {
+	var names []string
+	for _, tag := range todo.Tags {
+		names = append(names, tag.Name)
+	}
+	return fmt.Sprint(names)
+}
+
+func TestServiceCreateTag(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	tag, err := setup.service.CreateTag(setup.ctx, setup.userID, CreateTagInput{Name: "  work  "})
+	if err != nil {
+		t.Fatalf("CreateTag should succeed: %v", err)
+	}
+	if tag.Name != "work" {
+		t.Errorf("Expected trimmed name, got %q", tag.Name)
+	}
+	if tag.Color != DefaultColor {
+		t.Errorf("Expected default color, got %q", tag.Color)
+	}
+
+	tag, err = setup.service.CreateTag(setup.ctx, setup.userID, CreateTagInput{Name: "home", Color: "#3b82f6"})
+	if err != nil {
+		t.Fatalf("CreateTag should succeed: %v", err)
+	}
+	if tag.Color != "#3B82F6" {
+		t.Errorf("Expected upper-case color, got %q", tag.Color)
+	}
+
+	// Another user may reuse the name
+	if _, err := setup.service.CreateTag(setup.ctx, 2, CreateTagInput{Name: "work"}); err != nil {
+		t.Errorf("Tag names should be unique per user only: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		input CreateTagInput
+		want  error
+	}{
+		{"Duplicate name ignores case", CreateTagInput{Name: "Work"}, ErrTagNameTaken},
+		{"Empty name", CreateTagInput{Name: "   "}, ErrTagNameRequired},
+		{"Long name", CreateTagInput{Name: strings.Repeat("a", 51)}, ErrTagNameTooLong},
+		{"Named color", CreateTagInput{Name: "red", Color: "red"}, ErrInvalidTagColor},
+		{"Short hex color", CreateTagInput{Name: "red", Color: "#F00"}, ErrInvalidTagColor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := setup.service.CreateTag(setup.ctx, setup.userID, tt.input); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	tags, err := setup.service.GetUserTags(setup.ctx, setup.userID)
+	if err != nil {
+		t.Fatalf("GetUserTags should succeed: %v", err)
+	}
+	if len(tags) != 2 || tags[0].Name != "home" || tags[1].Name != "work" {
+		t.Errorf("Expected the user's two tags by name, got %d", len(tags))
+	}
+}
+
+func TestServiceUpdateAndDeleteTag(t *testing.T) {
+	setup := newServiceTestSetup()
+	ids := createTags(t, setup, setup.userID, "work", "home")
+	otherIDs := createTags(t, setup, 2, "theirs")
+
+	todo, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: "Tagged"})
+	if err != nil {
+		t.Fatalf("CreateTodo should succeed: %v", err)
+	}
+	if _, err := setup.service.AddTags(setup.ctx, todo.ID, setup.userID, ids); err != nil {
+		t.Fatalf("AddTags should succeed: %v", err)
+	}
+
+	tag, err := setup.service.UpdateTag(setup.ctx, ids[0], setup.userID, UpdateTagInput{Name: stringPtr("office")})
+	if err != nil {
+		t.Fatalf("UpdateTag should succeed: %v", err)
+	}
+	if tag.Name != "office" || tag.Color != DefaultColor {
+		t.Errorf("Expected only the name to change, got %+v", tag)
+	}
+
+	if _, err := setup.service.UpdateTag(setup.ctx, ids[0], setup.userID, UpdateTagInput{Name: stringPtr("HOME")}); !errors.Is(err, ErrTagNameTaken) {
+		t.Errorf("Expected ErrTagNameTaken, got %v", err)
+	}
+	if _, err := setup.service.UpdateTag(setup.ctx, ids[0], setup.userID, UpdateTagInput{}); !errors.Is(err, ErrInvalidTodoInput) {
+		t.Errorf("Expected ErrInvalidTodoInput for an empty update, got %v", err)
+	}
+	if _, err := setup.service.UpdateTag(setup.ctx, otherIDs[0], setup.userID, UpdateTagInput{Color: stringPtr("#000000")}); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("Expected ErrTagNotFound for another user's tag, got %v", err)
+	}
+
+	got, err := setup.service.GetTodo(setup.ctx, todo.ID, setup.userID)
+	if err != nil {
+		t.Fatalf("GetTodo should succeed: %v", err)
+	}
+	if tagNames(got) != "[home office]" {
+		t.Errorf("Expected renamed tag on the todo, got %s", tagNames(got))
+	}
+
+	if err := setup.service.DeleteTag(setup.ctx, otherIDs[0], setup.userID); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("Expected ErrTagNotFound for another user's tag, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(setup.ctx)
+	defer cancel()
+	events, _ := setup.service.SubscribeEvents(ctx, setup.userID)
+
+	if err := setup.service.DeleteTag(setup.ctx, ids[1], setup.userID); err != nil {
+		t.Fatalf("DeleteTag should succeed: %v", err)
+	}
+
+	// Subscribers are told about every todo the tag was detached from
+	if event := nextEvent(t, events); event.Kind != EventUpdated || event.TodoID != todo.ID || tagNames(event.Todo) != "[office]" {
+		t.Errorf("Expected updated event for todo %d without the tag, got %+v", todo.ID, event)
+	}
+
+	got, err = setup.service.GetTodo(setup.ctx, todo.ID, setup.userID)
+	if err != nil {
+		t.Fatalf("GetTodo should succeed: %v", err)
+	}
+	if tagNames(got) != "[office]" {
+		t.Errorf("Expected deleted tag to be detached, got %s", tagNames(got))
+	}
+}
+
+func TestServiceAddRemoveTags(t *testing.T) {
+	setup := newServiceTestSetup()
+	ids := createTags(t, setup, setup.userID, "work", "errand", "urgent")
+	otherIDs := createTags(t, setup, 2, "theirs")
+
+	todo, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: "Tagged"})
+	if err != nil {
+		t.Fatalf("CreateTodo should succeed: %v", err)
+	}
+
+	got, err := setup.service.AddTags(setup.ctx, todo.ID, setup.userID, []int{ids[0], ids[1]})
+	if err != nil {
+		t.Fatalf("AddTags should succeed: %v", err)
+	}
+	if tagNames(got) != "[errand work]" {
+		t.Errorf("Expected tags ordered by name, got %s", tagNames(got))
+	}
+
+	// Adding an attached tag again is a no-op
+	got, err = setup.service.AddTags(setup.ctx, todo.ID, setup.userID, []int{ids[0]})
+	if err != nil {
+		t.Fatalf("AddTags should succeed: %v", err)
+	}
+	if len(got.Tags) != 2 {
+		t.Errorf("Expected 2 tags, got %d", len(got.Tags))
+	}
+
+	got, err = setup.service.RemoveTags(setup.ctx, todo.ID, setup.userID, []int{ids[1]})
+	if err != nil {
+		t.Fatalf("RemoveTags should succeed: %v", err)
+	}
+	if tagNames(got) != "[work]" {
+		t.Errorf("Expected only work left, got %s", tagNames(got))
+	}
+
+	t.Run("Another user's tag", func(t *testing.T) {
+		if _, err := setup.service.AddTags(setup.ctx, todo.ID, setup.userID, otherIDs); !errors.Is(err, ErrTagNotFound) {
+			t.Errorf("Expected ErrTagNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Another user's todo", func(t *testing.T) {
+		if _, err := setup.service.AddTags(setup.ctx, todo.ID, 2, otherIDs); !errors.Is(err, ErrTodoAccessDenied) {
+			t.Errorf("Expected ErrTodoAccessDenied, got %v", err)
+		}
+	})
+
+	t.Run("Add and remove the same tag", func(t *testing.T) {
+		input := UpdateTodoInput{AddTagIDs: []int{ids[2]}, RemoveTagIDs: []int{ids[2]}}
+		if _, err := setup.service.UpdateTodo(setup.ctx, todo.ID, setup.userID, input); !errors.Is(err, ErrInvalidTodoInput) {
+			t.Errorf("Expected ErrInvalidTodoInput, got %v", err)
+		}
+	})
+
+	t.Run("Alongside other fields", func(t *testing.T) {
+		input := UpdateTodoInput{Title: stringPtr("Retitled"), AddTagIDs: []int{ids[2]}, RemoveTagIDs: []int{ids[0]}}
+		got, err := setup.service.UpdateTodo(setup.ctx, todo.ID, setup.userID, input)
+		if err != nil {
+			t.Fatalf("UpdateTodo should succeed: %v", err)
+		}
+		if got.Title != "Retitled" || tagNames(got) != "[urgent]" {
+			t.Errorf("Expected title and tags to change, got %q %s", got.Title, tagNames(got))
+		}
+	})
+}
+
+func TestServiceBatchUpdateTodosTags(t *testing.T) {
+	setup := newServiceTestSetup()
+	ids := createTags(t, setup, setup.userID, "work")
+	otherIDs := createTags(t, setup, 2, "theirs")
+
+	var todoIDs []int
+	for i := 1; i <= 3; i++ {
+		todo, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: fmt.Sprintf("Todo %d", i)})
+		if err != nil {
+			t.Fatalf("CreateTodo should succeed: %v", err)
+		}
+		todoIDs = append(todoIDs, todo.ID)
+	}
+
+	updated, err := setup.service.BatchUpdateTodos(setup.ctx, setup.userID, todoIDs, UpdateTodoInput{AddTagIDs: ids})
+	if err != nil {
+		t.Fatalf("BatchUpdateTodos should succeed: %v", err)
+	}
+	for _, todo := range updated {
+		if tagNames(todo) != "[work]" {
+			t.Errorf("Todo %d: expected [work], got %s", todo.ID, tagNames(todo))
+		}
+	}
+
+	// A foreign tag fails the whole batch before any todo changes
+	input := UpdateTodoInput{RemoveTagIDs: ids, AddTagIDs: otherIDs}
+	if _, err := setup.service.BatchUpdateTodos(setup.ctx, setup.userID, todoIDs, input); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("Expected ErrTagNotFound, got %v", err)
+	}
+	todo, err := setup.service.GetTodo(setup.ctx, todoIDs[0], setup.userID)
+	if err != nil {
+		t.Fatalf("GetTodo should succeed: %v", err)
+	}
+	if tagNames(todo) != "[work]" {
+		t.Errorf("Expected tags unchanged, got %s", tagNames(todo))
+	}
+}
+
+func TestServiceGetUserTodosTagFilters(t *testing.T) {
+	setup := newServiceTestSetup()
+	ids := createTags(t, setup, setup.userID, "work", "home", "urgent")
+	work, home, urgent := ids[0], ids[1], ids[2]
+
+	tagged := map[string][]int{
+		"Report":   {work, urgent},
+		"Laundry":  {home},
+		"Taxes":    {home, urgent},
+		"Untagged": nil,
+	}
+	for _, title := range []string{"Report", "Laundry", "Taxes", "Untagged"} {
+		todo, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: title})
+		if err != nil {
+			t.Fatalf("CreateTodo should succeed: %v", err)
+		}
+		if len(tagged[title]) > 0 {
+			if _, err := setup.service.AddTags(setup.ctx, todo.ID, setup.userID, tagged[title]); err != nil {
+				t.Fatalf("AddTags should succeed: %v", err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter TodoFilter
+		want   string
+	}{
+		{"Any", TodoFilter{TagsAny: []int{work, home}}, "[Taxes Laundry Report]"},
+		{"All", TodoFilter{TagsAll: []int{home, urgent}}, "[Taxes]"},
+		{"All with a repeated tag", TodoFilter{TagsAll: []int{urgent, urgent}}, "[Taxes Report]"},
+		{"Any and all", TodoFilter{TagsAny: []int{work, home}, TagsAll: []int{urgent}}, "[Taxes Report]"},
+		{"Unknown tag", TodoFilter{TagsAny: []int{999}}, "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := setup.service.GetUserTodos(setup.ctx, setup.userID, tt.filter)
+			if err != nil {
+				t.Fatalf("GetUserTodos should succeed: %v", err)
+			}
+			var titles []string
+			for _, todo := range result.Todos {
+				titles = append(titles, todo.Title)
+			}
+			if got := fmt.Sprint(titles); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFilterConditionsTags(t *testing.T) {
+	where, args := filterConditions(1, TodoFilter{TagsAny: []int{3, 1}, TagsAll: []int{2, 2, 1}})
+
+	if !strings.Contains(where, "tag_id = ANY($2)") {
+		t.Errorf("Expected tagsAny condition, got %q", where)
+	}
+	if !strings.Contains(where, "tag_id = ANY($3)) = $4") {
+		t.Errorf("Expected tagsAll condition, got %q", where)
+	}
+	if got := fmt.Sprint(args[2:]); got != "[[1 2] 2]" {
+		t.Errorf("Expected deduplicated tagsAll arguments, got %s", got)
+	}
+}
+
+// ============================================================================
+// Tests - Projects
+// ============================================================================
+
+// createProject creates a project with the given name for the user
+func createProject(t *testing.T, setup *serviceTestSetup, userID int, name string) *Project {
+	t.Helper()
+
+	project, err := setup.service.CreateProject(setup.ctx, userID, CreateProjectInput{Name: name})
+	if err != nil {
+		t.Fatalf("CreateProject should succeed: %v", err)
+	}
+	return project
+}
+
+// createTestTodo creates a todo with the given title in the user's inbox
+func createTestTodo(t *testing.T, setup *serviceTestSetup, title string) *Todo {
+	t.Helper()
+
+	todo, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: title})
+	if err != nil {
+		t.Fatalf("CreateTodo should succeed: %v", err)
+	}
+	return todo
+}
+
+func TestServiceCreateTodoDefaultsToInbox(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	todo, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: "Loose end"})
+	if err != nil {
+		t.Fatalf("CreateTodo should succeed: %v", err)
+	}
+	if todo.Project == nil || !todo.Project.IsInbox {
+		t.Fatalf("Expected todo in the inbox, got %+v", todo.Project)
+	}
+
+	// The inbox is created once per user
+	other, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: "Another"})
+	if err != nil {
+		t.Fatalf("CreateTodo should succeed: %v", err)
+	}
+	if other.ProjectID != todo.ProjectID {
+		t.Errorf("Expected the same inbox, got %d and %d", todo.ProjectID, other.ProjectID)
+	}
+
+	work := createProject(t, setup, setup.userID, "Work")
+	todo, err = setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: "Report", ProjectID: &work.ID})
+	if err != nil {
+		t.Fatalf("CreateTodo should succeed: %v", err)
+	}
+	if todo.ProjectID != work.ID || todo.Project.Name != "Work" {
+		t.Errorf("Expected todo in Work, got %+v", todo.Project)
+	}
+
+	// Another user's project is not found
+	theirs := createProject(t, setup, 2, "Theirs")
+	if _, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: "Sneaky", ProjectID: &theirs.ID}); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("Expected ErrProjectNotFound, got %v", err)
+	}
+}
+
+func TestServiceCreateProject(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	project, err := setup.service.CreateProject(setup.ctx, setup.userID, CreateProjectInput{Name: "  Work  ", Color: "#3b82f6"})
+	if err != nil {
+		t.Fatalf("CreateProject should succeed: %v", err)
+	}
+	if project.Name != "Work" || project.Color != "#3B82F6" {
+		t.Errorf("Expected trimmed name and upper-case color, got %q %q", project.Name, project.Color)
+	}
+
+	tests := []struct {
+		name  string
+		input CreateProjectInput
+		want  error
+	}{
+		{"Empty name", CreateProjectInput{Name: "  "}, ErrProjectNameRequired},
+		{"Long name", CreateProjectInput{Name: strings.Repeat("a", 101)}, ErrProjectNameTooLong},
+		{"Invalid color", CreateProjectInput{Name: "Home", Color: "blue"}, ErrInvalidProjectColor},
+		{"Negative position", CreateProjectInput{Name: "Home", Position: &[]int{-1}[0]}, ErrInvalidProjectPosition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := setup.service.CreateProject(setup.ctx, setup.userID, tt.input); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestServiceGetUserProjects(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	// The inbox is created on first listing
+	projects, err := setup.service.GetUserProjects(setup.ctx, setup.userID, false)
+	if err != nil {
+		t.Fatalf("GetUserProjects should succeed: %v", err)
+	}
+	if len(projects) != 1 || !projects[0].IsInbox || projects[0].Name != InboxName {
+		t.Fatalf("Expected only the inbox, got %+v", projects)
+	}
+
+	home := createProject(t, setup, setup.userID, "Home")
+	work := createProject(t, setup, setup.userID, "Work")
+	if work.Position <= home.Position {
+		t.Errorf("Expected new projects after existing ones, got %d and %d", home.Position, work.Position)
+	}
+
+	first := 0
+	if _, err := setup.service.UpdateProject(setup.ctx, work.ID, setup.userID, UpdateProjectInput{Position: &first}); err != nil {
+		t.Fatalf("UpdateProject should succeed: %v", err)
+	}
+	archived := true
+	if _, err := setup.service.UpdateProject(setup.ctx, home.ID, setup.userID, UpdateProjectInput{Archived: &archived}); err != nil {
+		t.Fatalf("UpdateProject should succeed: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		includeArchived bool
+		want            []string
+	}{
+		{"Active only", false, []string{InboxName, "Work"}},
+		{"Including archived", true, []string{InboxName, "Work", "Home"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projects, err := setup.service.GetUserProjects(setup.ctx, setup.userID, tt.includeArchived)
+			if err != nil {
+				t.Fatalf("GetUserProjects should succeed: %v", err)
+			}
+			var names []string
+			for _, project := range projects {
+				names = append(names, project.Name)
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, names)
+			}
+		})
+	}
+}
+
+func TestServiceUpdateProject(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	work := createProject(t, setup, setup.userID, "Work")
+	inbox, err := setup.service.CreateInbox(setup.ctx, setup.userID)
+	if err != nil {
+		t.Fatalf("CreateInbox should succeed: %v", err)
+	}
+
+	name := "Office"
+	project, err := setup.service.UpdateProject(setup.ctx, work.ID, setup.userID, UpdateProjectInput{Name: &name})
+	if err != nil {
+		t.Fatalf("UpdateProject should succeed: %v", err)
+	}
+	if project.Name != "Office" {
+		t.Errorf("Expected renamed project, got %q", project.Name)
+	}
+
+	// The inbox may be renamed but not archived
+	if _, err := setup.service.UpdateProject(setup.ctx, inbox.ID, setup.userID, UpdateProjectInput{Name: &name}); err != nil {
+		t.Errorf("Renaming the inbox should succeed: %v", err)
+	}
+
+	archived := true
+	empty := ""
+	tests := []struct {
+		name      string
+		projectID int
+		userID    int
+		input     UpdateProjectInput
+		want      error
+	}{
+		{"Archive inbox", inbox.ID, setup.userID, UpdateProjectInput{Archived: &archived}, ErrInboxProject},
+		{"No fields", work.ID, setup.userID, UpdateProjectInput{}, ErrInvalidTodoInput},
+		{"Empty name", work.ID, setup.userID, UpdateProjectInput{Name: &empty}, ErrProjectNameRequired},
+		{"Other user", work.ID, 2, UpdateProjectInput{Name: &name}, ErrProjectNotFound},
+		{"Missing project", 999, setup.userID, UpdateProjectInput{Name: &name}, ErrProjectNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := setup.service.UpdateProject(setup.ctx, tt.projectID, tt.userID, tt.input); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestServiceDeleteProjectMovesTodosToInbox(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	work := createProject(t, setup, setup.userID, "Work")
+	todo, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: "Report", ProjectID: &work.ID})
+	if err != nil {
+		t.Fatalf("CreateTodo should succeed: %v", err)
+	}
+
+	if err := setup.service.DeleteProject(setup.ctx, work.ID, 2); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("Expected ErrProjectNotFound for another user, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(setup.ctx)
+	defer cancel()
+	events, _ := setup.service.SubscribeEvents(ctx, setup.userID)
+
+	if err := setup.service.DeleteProject(setup.ctx, work.ID, setup.userID); err != nil {
+		t.Fatalf("DeleteProject should succeed: %v", err)
+	}
+
+	// Subscribers are told about every todo that moved
+	if event := nextEvent(t, events); event.Kind != EventUpdated || event.TodoID != todo.ID || event.Todo.Project == nil || !event.Todo.Project.IsInbox {
+		t.Errorf("Expected updated event for todo %d in the inbox, got %+v", todo.ID, event)
+	}
+
+	todo, err = setup.service.GetTodo(setup.ctx, todo.ID, setup.userID)
+	if err != nil {
+		t.Fatalf("Todo should survive its project: %v", err)
+	}
+	if todo.Project == nil || !todo.Project.IsInbox {
+		t.Errorf("Expected todo moved to the inbox, got %+v", todo.Project)
+	}
+
+	if err := setup.service.DeleteProject(setup.ctx, todo.ProjectID, setup.userID); !errors.Is(err, ErrInboxProject) {
+		t.Errorf("Expected ErrInboxProject, got %v", err)
+	}
+}
+
+func TestServiceMoveTodos(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	first := createTestTodo(t, setup, "First")
+	second := createTestTodo(t, setup, "Second")
+	work := createProject(t, setup, setup.userID, "Work")
+
+	todos, err := setup.service.MoveTodos(setup.ctx, setup.userID, []int{first.ID, second.ID}, work.ID)
+	if err != nil {
+		t.Fatalf("MoveTodos should succeed: %v", err)
+	}
+	for _, todo := range todos {
+		if todo.ProjectID != work.ID {
+			t.Errorf("Expected todo %d in Work, got project %d", todo.ID, todo.ProjectID)
+		}
+	}
+
+	result, err := setup.service.GetUserTodos(setup.ctx, setup.userID, TodoFilter{ProjectID: &work.ID})
+	if err != nil {
+		t.Fatalf("GetUserTodos should succeed: %v", err)
+	}
+	if result.Total != 2 {
+		t.Errorf("Expected 2 todos in Work, got %d", result.Total)
+	}
+
+	archived := true
+	if _, err := setup.service.UpdateProject(setup.ctx, work.ID, setup.userID, UpdateProjectInput{Archived: &archived}); err != nil {
+		t.Fatalf("UpdateProject should succeed: %v", err)
+	}
+	theirs := createProject(t, setup, 2, "Theirs")
+
+	tests := []struct {
+		name      string
+		projectID int
+		want      error
+	}{
+		{"Archived project", work.ID, ErrProjectArchived},
+		{"Other user's project", theirs.ID, ErrProjectNotFound},
+		{"Missing project", 999, ErrProjectNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := setup.service.MoveTodos(setup.ctx, setup.userID, []int{first.ID}, tt.projectID); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// Filtering by another user's project is not found either
+	if _, err := setup.service.GetUserTodos(setup.ctx, setup.userID, TodoFilter{ProjectID: &theirs.ID}); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("Expected ErrProjectNotFound, got %v", err)
+	}
+}
+
+func TestServiceGetProjectTodoStats(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	work := createProject(t, setup, setup.userID, "Work")
+	for _, title := range []string{"Report", "Review"} {
+		if _, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: title, ProjectID: &work.ID}); err != nil {
+			t.Fatalf("CreateTodo should succeed: %v", err)
+		}
+	}
+	done := createTestTodo(t, setup, "Inbox todo")
+	if _, err := setup.service.ToggleTodoComplete(setup.ctx, done.ID, setup.userID, false); err != nil {
+		t.Fatalf("ToggleTodoComplete should succeed: %v", err)
+	}
+
+	stats, err := setup.service.GetProjectTodoStats(setup.ctx, work.ID, setup.userID)
+	if err != nil {
+		t.Fatalf("GetProjectTodoStats should succeed: %v", err)
+	}
+	if stats.Total != 2 || stats.Pending != 2 || stats.Completed != 0 {
+		t.Errorf("Expected 2 pending todos in Work, got %+v", stats)
+	}
+
+	stats, err = setup.service.GetProjectTodoStats(setup.ctx, done.ProjectID, setup.userID)
+	if err != nil {
+		t.Fatalf("GetProjectTodoStats should succeed: %v", err)
+	}
+	if stats.Total != 1 || stats.Completed != 1 {
+		t.Errorf("Expected 1 completed todo in the inbox, got %+v", stats)
+	}
+
+	if _, err := setup.service.GetProjectTodoStats(setup.ctx, work.ID, 2); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("Expected ErrProjectNotFound, got %v", err)
+	}
+}
+
+// ============================================================================
+// Tests - Subtasks
+// ============================================================================
+
+// createSubtask creates a todo with the given title under parentID
+func createSubtask(t *testing.T, setup *serviceTestSetup, title string, parentID int) *Todo {
+	t.Helper()
+
+	todo, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: title, ParentID: &parentID})
+	if err != nil {
+		t.Fatalf("CreateTodo should succeed: %v", err)
+	}
+	return todo
+}
+
+func TestServiceCreateSubtask(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	work := createProject(t, setup, setup.userID, "Work")
+	launch, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: "Launch", ProjectID: &work.ID})
+	if err != nil {
+		t.Fatalf("CreateTodo should succeed: %v", err)
+	}
+
+	draft := createSubtask(t, setup, "Write draft", launch.ID)
+	if draft.ParentID == nil || *draft.ParentID != launch.ID {
+		t.Fatalf("Expected subtask of %d, got %v", launch.ID, draft.ParentID)
+	}
+	if draft.ProjectID != work.ID {
+		t.Errorf("Expected subtask in its parent's project, got %d", draft.ProjectID)
+	}
+
+	// The third level is the deepest allowed
+	review := createSubtask(t, setup, "Review draft", draft.ID)
+	tooDeep := review.ID
+	if _, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: "Too deep", ParentID: &tooDeep}); !errors.Is(err, ErrTodoTooDeep) {
+		t.Errorf("Expected ErrTodoTooDeep, got %v", err)
+	}
+
+	theirs, err := setup.service.CreateTodo(setup.ctx, 2, CreateTodoInput{Title: "Theirs"})
+	if err != nil {
+		t.Fatalf("CreateTodo should succeed: %v", err)
+	}
+	if _, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: "Sneaky", ParentID: &theirs.ID}); !errors.Is(err, ErrTodoNotFound) {
+		t.Errorf("Expected ErrTodoNotFound, got %v", err)
+	}
+
+	launch, err = setup.service.GetTodo(setup.ctx, launch.ID, setup.userID)
+	if err != nil {
+		t.Fatalf("GetTodo should succeed: %v", err)
+	}
+	if launch.Progress != (TodoProgress{Completed: 0, Total: 1}) {
+		t.Errorf("Expected 0/1 progress, got %+v", launch.Progress)
+	}
+
+	children, err := setup.service.GetSubtasks(setup.ctx, launch.ID, setup.userID)
+	if err != nil {
+		t.Fatalf("GetSubtasks should succeed: %v", err)
+	}
+	if len(children) != 1 || children[0].ID != draft.ID {
+		t.Errorf("Expected only the direct subtask, got %d todos", len(children))
+	}
+}
+
+func TestServiceUpdateTodoParent(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	root := createTestTodo(t, setup, "Root")
+	child := createSubtask(t, setup, "Child", root.ID)
+	grandchild := createSubtask(t, setup, "Grandchild", child.ID)
+	other := createTestTodo(t, setup, "Other")
+	otherChild := createSubtask(t, setup, "Other child", other.ID)
+
+	tests := []struct {
+		name   string
+		todoID int
+		input  UpdateTodoInput
+		want   error
+	}{
+		{"Own parent", root.ID, UpdateTodoInput{ParentID: &root.ID}, ErrTodoCycle},
+		{"Under own child", root.ID, UpdateTodoInput{ParentID: &child.ID}, ErrTodoCycle},
+		{"Under own grandchild", root.ID, UpdateTodoInput{ParentID: &grandchild.ID}, ErrTodoCycle},
+		{"Subtree too deep", child.ID, UpdateTodoInput{ParentID: &otherChild.ID}, ErrTodoTooDeep},
+		{"Set and clear", child.ID, UpdateTodoInput{ParentID: &other.ID, ClearParent: true}, ErrInvalidTodoInput},
+		{"Missing parent", child.ID, UpdateTodoInput{ParentID: &[]int{999}[0]}, ErrTodoNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := setup.service.UpdateTodo(setup.ctx, tt.todoID, setup.userID, tt.input); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// Moving a subtree keeps it within the depth limit
+	moved, err := setup.service.UpdateTodo(setup.ctx, child.ID, setup.userID, UpdateTodoInput{ParentID: &other.ID})
+	if err != nil {
+		t.Fatalf("UpdateTodo should succeed: %v", err)
+	}
+	if *moved.ParentID != other.ID || moved.Progress.Total != 1 {
+		t.Errorf("Expected subtree moved under %d, got parent %v and %+v", other.ID, moved.ParentID, moved.Progress)
+	}
+
+	moved, err = setup.service.UpdateTodo(setup.ctx, child.ID, setup.userID, UpdateTodoInput{ClearParent: true})
+	if err != nil {
+		t.Fatalf("UpdateTodo should succeed: %v", err)
+	}
+	if moved.ParentID != nil {
+		t.Errorf("Expected a top-level todo, got parent %d", *moved.ParentID)
+	}
+
+	// Batch updates check each todo on its own
+	_, err = setup.service.BatchUpdateTodos(setup.ctx, setup.userID, []int{root.ID, other.ID}, UpdateTodoInput{ParentID: &otherChild.ID})
+	if !errors.Is(err, ErrTodoCycle) {
+		t.Errorf("Expected ErrTodoCycle for the todo above the parent, got %v", err)
+	}
+	root, err = setup.service.GetTodo(setup.ctx, root.ID, setup.userID)
+	if err != nil {
+		t.Fatalf("GetTodo should succeed: %v", err)
+	}
+	if root.ParentID == nil || *root.ParentID != otherChild.ID {
+		t.Errorf("Expected the other todo in the batch to be moved, got parent %v", root.ParentID)
+	}
+}
+
+func TestServiceToggleTodoCascade(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	root := createTestTodo(t, setup, "Root")
+	child := createSubtask(t, setup, "Child", root.ID)
+	grandchild := createSubtask(t, setup, "Grandchild", child.ID)
+	sibling := createSubtask(t, setup, "Sibling", root.ID)
+
+	// Without cascade only the todo itself changes
+	if _, err := setup.service.ToggleTodoComplete(setup.ctx, sibling.ID, setup.userID, false); err != nil {
+		t.Fatalf("ToggleTodoComplete should succeed: %v", err)
+	}
+	root, err := setup.service.GetTodo(setup.ctx, root.ID, setup.userID)
+	if err != nil {
+		t.Fatalf("GetTodo should succeed: %v", err)
+	}
+	if root.Progress != (TodoProgress{Completed: 1, Total: 2}) {
+		t.Errorf("Expected 1/2 progress, got %+v", root.Progress)
+	}
+
+	ctx, cancel := context.WithCancel(setup.ctx)
+	defer cancel()
+	events, err := setup.service.SubscribeEvents(ctx, setup.userID)
+	if err != nil {
+		t.Fatalf("SubscribeEvents should succeed: %v", err)
+	}
+
+	root, err = setup.service.ToggleTodoComplete(setup.ctx, root.ID, setup.userID, true)
+	if err != nil {
+		t.Fatalf("ToggleTodoComplete should succeed: %v", err)
+	}
+	if !root.Completed || root.Progress != (TodoProgress{Completed: 2, Total: 2}) {
+		t.Errorf("Expected completed root with 2/2 progress, got %v %+v", root.Completed, root.Progress)
+	}
+
+	// The already completed sibling is left alone
+	var toggled []int
+	for range 3 {
+		event := nextEvent(t, events)
+		if event.Kind != EventToggled {
+			t.Errorf("Expected toggled event, got %+v", event)
+		}
+		toggled = append(toggled, event.TodoID)
+	}
+	if fmt.Sprint(toggled) != fmt.Sprint([]int{child.ID, grandchild.ID, root.ID}) {
+		t.Errorf("Expected toggled events for child, grandchild and root, got %v", toggled)
+	}
+
+	// Uncompleting cascades as well
+	if _, err := setup.service.ToggleTodoComplete(setup.ctx, root.ID, setup.userID, true); err != nil {
+		t.Fatalf("ToggleTodoComplete should succeed: %v", err)
+	}
+	result, err := setup.service.GetUserTodos(setup.ctx, setup.userID, TodoFilter{Completed: boolPtr(true)})
+	if err != nil {
+		t.Fatalf("GetUserTodos should succeed: %v", err)
+	}
+	if result.Total != 0 {
+		t.Errorf("Expected no completed todos, got %d", result.Total)
+	}
+}
+
+func TestServiceDeleteTodoSubtasks(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          DeleteMode
+		wantDeleted   int
+		wantRemaining []string
+	}{
+		{"Cascade", DeleteCascade, 4, []string{"Other"}},
+		{"Default cascades", "", 4, []string{"Other"}},
+		{"Reparent", DeleteReparent, 1, []string{"Grandchild", "Other", "Root", "Sibling"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setup := newServiceTestSetup()
+
+			root := createTestTodo(t, setup, "Root")
+			child := createSubtask(t, setup, "Child", root.ID)
+			createSubtask(t, setup, "Grandchild", child.ID)
+			createSubtask(t, setup, "Sibling", root.ID)
+			createTestTodo(t, setup, "Other")
+
+			// Cascading from the root takes the whole tree, reparenting the child moves its subtask up
+			target := child.ID
+			if tt.mode != DeleteReparent {
+				target = root.ID
+			}
+
+			ctx, cancel := context.WithCancel(setup.ctx)
+			defer cancel()
+			events, err := setup.service.SubscribeEvents(ctx, setup.userID)
+			if err != nil {
+				t.Fatalf("SubscribeEvents should succeed: %v", err)
+			}
+
+			if err := setup.service.DeleteTodo(setup.ctx, target, setup.userID, tt.mode); err != nil {
+				t.Fatalf("DeleteTodo should succeed: %v", err)
+			}
+
+			deleted := 0
+			for len(events) > 0 {
+				if event := <-events; event.Kind == EventDeleted {
+					deleted++
+				}
+			}
+			if deleted != tt.wantDeleted {
+				t.Errorf("Expected %d deleted events, got %d", tt.wantDeleted, deleted)
+			}
+
+			result, err := setup.service.GetUserTodos(setup.ctx, setup.userID, TodoFilter{Sort: TodoSort{Field: SortByTitle}})
+			if err != nil {
+				t.Fatalf("GetUserTodos should succeed: %v", err)
+			}
+			var titles []string
+			for _, todo := range result.Todos {
+				titles = append(titles, todo.Title)
+				if todo.Title == "Grandchild" && (todo.ParentID == nil || *todo.ParentID != root.ID) {
+					t.Errorf("Expected grandchild moved under the root, got parent %v", todo.ParentID)
+				}
+			}
+			if fmt.Sprint(titles) != fmt.Sprint(tt.wantRemaining) {
+				t.Errorf("Expected %v to remain, got %v", tt.wantRemaining, titles)
+			}
+		})
+	}
+
+	setup := newServiceTestSetup()
+	todo := createTestTodo(t, setup, "Root")
+	if err := setup.service.DeleteTodo(setup.ctx, todo.ID, setup.userID, "orphan"); !errors.Is(err, ErrInvalidDeleteMode) {
+		t.Errorf("Expected ErrInvalidDeleteMode, got %v", err)
+	}
+}
+
+func TestServiceGetUserTodosTopLevel(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	root := createTestTodo(t, setup, "Root")
+	createSubtask(t, setup, "Child", root.ID)
+
+	tests := []struct {
+		name     string
+		topLevel bool
+		want     string
+	}{
+		{"Top-level", true, "Root"},
+		{"Subtasks", false, "Child"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := setup.service.GetUserTodos(setup.ctx, setup.userID, TodoFilter{TopLevel: &tt.topLevel})
+			if err != nil {
+				t.Fatalf("GetUserTodos should succeed: %v", err)
+			}
+			if result.Total != 1 || result.Todos[0].Title != tt.want {
+				t.Errorf("Expected only %q, got %d todos", tt.want, result.Total)
+			}
+		})
+	}
+}
+
+func TestFilterConditionsTopLevel(t *testing.T) {
+	where, _ := filterConditions(1, TodoFilter{TopLevel: boolPtr(false)})
+	if !strings.Contains(where, "NOT parent_id IS NULL") {
+		t.Errorf("Expected a subtask condition, got %q", where)
+	}
+}
+
+// ============================================================================
+// Tests - Events
+// ============================================================================
+
+// nextEvent waits for the next event or fails the test
+func nextEvent(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("expected an event")
+		return Event{}
+	}
+}
+
+func TestServiceSubscribeEvents(t *testing.T) {
+	setup := newServiceTestSetup()
+	ctx, cancel := context.WithCancel(setup.ctx)
+	defer cancel()
+
+	events, err := setup.service.SubscribeEvents(ctx, setup.userID)
+	if err != nil {
+		t.Fatalf("SubscribeEvents should succeed: %v", err)
+	}
+
+	created, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: "Live"})
+	if err != nil {
+		t.Fatalf("CreateTodo should succeed: %v", err)
+	}
+	if event := nextEvent(t, events); event.Kind != EventCreated || event.TodoID != created.ID || event.Todo == nil {
+		t.Errorf("Expected created event for todo %d, got %+v", created.ID, event)
+	}
+
+	if _, err := setup.service.UpdateTodo(setup.ctx, created.ID, setup.userID, UpdateTodoInput{Title: stringPtr("Edited")}); err != nil {
+		t.Fatalf("UpdateTodo should succeed: %v", err)
+	}
+	if event := nextEvent(t, events); event.Kind != EventUpdated || event.Todo.Title != "Edited" {
+		t.Errorf("Expected updated event, got %+v", event)
+	}
+
+	if _, err := setup.service.ToggleTodoComplete(setup.ctx, created.ID, setup.userID, false); err != nil {
+		t.Fatalf("ToggleTodoComplete should succeed: %v", err)
+	}
+	if event := nextEvent(t, events); event.Kind != EventToggled {
+		t.Errorf("Expected toggled event, got %+v", event)
+	}
+
+	if _, err := setup.service.BatchUpdateTodos(setup.ctx, setup.userID, []int{created.ID}, UpdateTodoInput{Completed: boolPtr(false)}); err != nil {
+		t.Fatalf("BatchUpdateTodos should succeed: %v", err)
+	}
+	if event := nextEvent(t, events); event.Kind != EventBatchUpdated {
+		t.Errorf("Expected batch updated event, got %+v", event)
+	}
+
+	if err := setup.service.DeleteTodo(setup.ctx, created.ID, setup.userID, DeleteCascade); err != nil {
+		t.Fatalf("DeleteTodo should succeed: %v", err)
+	}
+	if event := nextEvent(t, events); event.Kind != EventDeleted || event.TodoID != created.ID || event.Todo != nil {
+		t.Errorf("Expected deleted event without todo, got %+v", event)
+	}
+}
+
+func TestServiceSubscribeEventsOtherUser(t *testing.T) {
+	setup := newServiceTestSetup()
+	ctx, cancel := context.WithCancel(setup.ctx)
+	defer cancel()
+
+	events, err := setup.service.SubscribeEvents(ctx, 2)
+	if err != nil {
+		t.Fatalf("SubscribeEvents should succeed: %v", err)
+	}
+
+	if _, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: "Private"}); err != nil {
+		t.Fatalf("CreateTodo should succeed: %v", err)
+	}
+
+	select {
+	case event := <-events:
+		t.Errorf("User 2 should not receive user 1's events, got %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestServiceSubscribeEventsInvalidUser(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	_, err := setup.service.SubscribeEvents(setup.ctx, 0)
+	if !errors.Is(err, ErrInvalidTodoInput) {
+		t.Errorf("Expected ErrInvalidTodoInput, got: %v", err)
+	}
+}
+
+func TestServiceRelayEvent(t *testing.T) {
+	setup := newServiceTestSetup()
+	setup.service.relayed = true
+	ctx, cancel := context.WithCancel(setup.ctx)
+	defer cancel()
+
+	events, err := setup.service.SubscribeEvents(ctx, setup.userID)
+	if err != nil {
+		t.Fatalf("SubscribeEvents should succeed: %v", err)
+	}
+
+	// Relayed services leave publishing to the NOTIFY round trip
+	created, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: "Relayed"})
+	if err != nil {
+		t.Fatalf("CreateTodo should succeed: %v", err)
+	}
+	select {
+	case event := <-events:
+		t.Fatalf("Relayed service should not publish locally, got %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Payload without the todo (oversized NOTIFY) is reloaded from the repository
+	setup.service.RelayEvent(fmt.Sprintf(`{"kind":"UPDATED","user_id":%d,"todo_id":%d}`, setup.userID, created.ID))
+	event := nextEvent(t, events)
+	if event.Kind != EventUpdated || event.Todo == nil || event.Todo.Title != "Relayed" {
+		t.Errorf("Expected reloaded updated event, got %+v", event)
+	}
+
+	// Malformed payloads are ignored
+	setup.service.RelayEvent("not json")
+	select {
+	case event := <-events:
+		t.Errorf("Malformed payload should be dropped, got %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// ============================================================================
+// Tests - Stats subscription
+// ============================================================================
+
+func TestServiceSubscribeStatsDebouncesBatch(t *testing.T) {
+	setup := newServiceTestSetup()
+	setup.service.stats.delay = 20 * time.Millisecond
+	ctx, cancel := context.WithCancel(setup.ctx)
+	defer cancel()
+
+	var todoIDs []int
+	for i := 1; i <= 200; i++ {
+		created, _ := setup.repo.Create(setup.ctx, setup.userID, CreateTodoInput{Title: fmt.Sprintf("Todo %d", i)})
+		todoIDs = append(todoIDs, created.ID)
+	}
+
+	updates, err := setup.service.SubscribeStats(ctx, setup.userID)
+	if err != nil {
+		t.Fatalf("SubscribeStats should succeed: %v", err)
+	}
+
+	if _, err := setup.service.BatchUpdateTodos(setup.ctx, setup.userID, todoIDs, UpdateTodoInput{Completed: boolPtr(true)}); err != nil {
+		t.Fatalf("BatchUpdateTodos should succeed: %v", err)
+	}
+
+	select {
+	case stats := <-updates:
+		if stats.Total != 200 || stats.Completed != 200 || stats.Pending != 0 {
+			t.Errorf("Expected 200/200/0 stats, got %+v", stats)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a stats push")
+	}
+
+	// The whole batch must collapse into a single push
+	select {
+	case stats := <-updates:
+		t.Errorf("Expected a single stats push, got another: %+v", stats)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestServiceSubscribeStatsFlushesSteadyStream(t *testing.T) {
+	setup := newServiceTestSetup()
+	setup.service.stats.delay = 50 * time.Millisecond
+	setup.service.stats.maxWait = 100 * time.Millisecond
+	ctx, cancel := context.WithCancel(setup.ctx)
+	defer cancel()
+
+	updates, err := setup.service.SubscribeStats(ctx, setup.userID)
+	if err != nil {
+		t.Fatalf("SubscribeStats should succeed: %v", err)
+	}
+
+	// Changes arrive faster than the debounce delay for longer than the max wait
+	start := time.Now()
+	for i := 0; time.Since(start) < 400*time.Millisecond; i++ {
+		if _, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: fmt.Sprintf("Todo %d", i)}); err != nil {
+			t.Fatalf("CreateTodo should succeed: %v", err)
+		}
+
+		select {
+		case <-updates:
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	t.Fatal("expected a stats push while changes kept coming")
+}
+
+func TestServiceCloseStopsStatsRecomputation(t *testing.T) {
+	setup := newServiceTestSetup()
+	setup.service.stats.delay = 20 * time.Millisecond
+	ctx, cancel := context.WithCancel(setup.ctx)
+	defer cancel()
+
+	updates, err := setup.service.SubscribeStats(ctx, setup.userID)
+	if err != nil {
+		t.Fatalf("SubscribeStats should succeed: %v", err)
+	}
+
+	if _, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: "Pending"}); err != nil {
+		t.Fatalf("CreateTodo should succeed: %v", err)
+	}
+
+	setup.service.Close()
+
+	if _, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: "After close"}); err != nil {
+		t.Fatalf("CreateTodo should succeed: %v", err)
+	}
+
+	select {
+	case stats := <-updates:
+		t.Errorf("Expected no stats push after Close, got %+v", stats)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestServiceSubscribeStatsSkipsUsersWithoutSubscribers(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	if _, err := setup.service.CreateTodo(setup.ctx, setup.userID, CreateTodoInput{Title: "Quiet"}); err != nil {
+		t.Fatalf("CreateTodo should succeed: %v", err)
+	}
+
+	setup.service.stats.mu.Lock()
+	pending := len(setup.service.stats.pending)
+	setup.service.stats.mu.Unlock()
+
+	if pending != 0 {
+		t.Errorf("Expected no recomputation without subscribers, got %d pending", pending)
+	}
+}
+
+func TestServiceSubscribeStatsInvalidUser(t *testing.T) {
+	setup := newServiceTestSetup()
+
+	_, err := setup.service.SubscribeStats(setup.ctx, 0)
+	if !errors.Is(err, ErrInvalidTodoInput) {
+		t.Errorf("Expected ErrInvalidTodoInput, got: %v", err)
+	}
+}
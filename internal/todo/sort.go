@@ -0,0 +1,98 @@
+package todo
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+)
+
+// SortField is what a list of todos is ordered by
+type SortField string
+
+const (
+	SortByCreated  SortField = "created"
+	SortByUpdated  SortField = "updated"
+	SortByDue      SortField = "due"
+	SortByPriority SortField = "priority"
+	SortByTitle    SortField = "title"
+)
+
+// sortColumns maps each sort field to the expression it orders by
+var sortColumns = map[SortField]string{
+	SortByCreated:  "created_at",
+	SortByUpdated:  "updated_at",
+	SortByDue:      "due_at",
+	SortByPriority: "priority",
+	SortByTitle:    "LOWER(title)",
+}
+
+// TodoSort orders a list of todos. Ties are broken by ID in the same direction, so every
+// todo has a fixed position and offset pagination neither skips nor repeats rows.
+type TodoSort struct {
+	Field      SortField `json:"field"`
+	Descending bool      `json:"descending,omitempty"`
+}
+
+// DefaultSort lists the newest todos first
+var DefaultSort = TodoSort{Field: SortByCreated, Descending: true}
+
+// IsValid returns true if the sort field is known
+func (s TodoSort) IsValid() bool {
+	_, ok := sortColumns[s.Field]
+	return ok
+}
+
+// orderBy returns the ORDER BY expressions for the sort. Todos without a due date come last
+// in either direction.
+func (s TodoSort) orderBy() string {
+	if !s.IsValid() {
+		s = DefaultSort
+	}
+
+	direction := "ASC"
+	if s.Descending {
+		direction = "DESC"
+	}
+
+	nulls := ""
+	if s.Field == SortByDue {
+		nulls = " NULLS LAST"
+	}
+
+	return fmt.Sprintf("%s %s%s, id %s", sortColumns[s.Field], direction, nulls, direction)
+}
+
+// less reports whether a comes before b, matching orderBy
+func (s TodoSort) less(a, b *Todo) bool {
+	if !s.IsValid() {
+		s = DefaultSort
+	}
+
+	if s.Field == SortByDue && (a.DueAt == nil) != (b.DueAt == nil) {
+		return b.DueAt == nil
+	}
+
+	var c int
+	switch s.Field {
+	case SortByCreated:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	case SortByUpdated:
+		c = a.UpdatedAt.Compare(b.UpdatedAt)
+	case SortByDue:
+		if a.DueAt != nil && b.DueAt != nil {
+			c = a.DueAt.Compare(*b.DueAt)
+		}
+	case SortByPriority:
+		c = cmp.Compare(a.Priority, b.Priority)
+	case SortByTitle:
+		c = strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	}
+	if c == 0 {
+		c = cmp.Compare(a.ID, b.ID)
+	}
+
+	if s.Descending {
+		return c > 0
+	}
+	return c < 0
+}
@@ -0,0 +1,125 @@
+package todo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jayk0001/my-go-next-todo/internal/pubsub"
+)
+
+// DefaultStatsDebounce is how long stats recomputation waits for further changes
+const DefaultStatsDebounce = 250 * time.Millisecond
+
+// DefaultStatsMaxWait caps how long a steady stream of changes can hold back a stats push
+const DefaultStatsMaxWait = 2 * time.Second
+
+// statsBroadcaster recomputes a user's stats once a burst of changes has settled
+// and pushes the result to stats subscribers
+type statsBroadcaster struct {
+	compute func(ctx context.Context, userID int) (*TodoStats, error)
+	hub     *pubsub.Hub[*TodoStats]
+	delay   time.Duration
+	maxWait time.Duration
+
+	// ctx is cancelled by close, aborting in-flight recomputations
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	pending map[int]*pendingStats
+}
+
+// pendingStats is a scheduled recomputation for one user
+type pendingStats struct {
+	timer *time.Timer
+	// deadline is when the recomputation runs even if changes keep coming
+	deadline time.Time
+}
+
+// newStatsBroadcaster creates a broadcaster that uses compute to build fresh stats
+func newStatsBroadcaster(compute func(ctx context.Context, userID int) (*TodoStats, error), delay, maxWait time.Duration) *statsBroadcaster {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &statsBroadcaster{
+		compute: compute,
+		hub:     pubsub.NewHub[*TodoStats](),
+		delay:   delay,
+		maxWait: maxWait,
+		ctx:     ctx,
+		cancel:  cancel,
+		pending: make(map[int]*pendingStats),
+	}
+}
+
+// subscribe streams stats pushes for the user until ctx is done
+func (b *statsBroadcaster) subscribe(ctx context.Context, userID int) <-chan *TodoStats {
+	return b.hub.Subscribe(ctx, userID)
+}
+
+// hasSubscribers reports whether anyone is listening for the user's stats
+func (b *statsBroadcaster) hasSubscribers(userID int) bool {
+	return b.hub.SubscriberCount(userID) > 0
+}
+
+// touch marks the user's stats as stale, restarting the debounce timer
+// without pushing the recomputation past its deadline
+func (b *statsBroadcaster) touch(userID int) {
+	if !b.hasSubscribers(userID) {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.ctx.Err() != nil {
+		return
+	}
+
+	entry, ok := b.pending[userID]
+	if ok {
+		entry.timer.Stop()
+	} else {
+		entry = &pendingStats{deadline: time.Now().Add(b.maxWait)}
+		b.pending[userID] = entry
+	}
+
+	wait := max(min(b.delay, time.Until(entry.deadline)), 0)
+	entry.timer = time.AfterFunc(wait, func() { b.flush(userID, entry) })
+}
+
+// flush recomputes and publishes the user's stats
+func (b *statsBroadcaster) flush(userID int, entry *pendingStats) {
+	b.mu.Lock()
+	// Another timer of this entry already published, or close dropped it
+	if b.pending[userID] != entry {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, userID)
+	b.mu.Unlock()
+
+	stats, err := b.compute(b.ctx, userID)
+	if err != nil {
+		if b.ctx.Err() == nil {
+			fmt.Printf("TodoService: failed to recompute stats for user %d: %v\n", userID, err)
+		}
+		return
+	}
+
+	b.hub.Publish(userID, stats)
+}
+
+// close stops pending recomputations and cancels the ones in flight
+func (b *statsBroadcaster) close() {
+	b.cancel()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for userID, entry := range b.pending {
+		entry.timer.Stop()
+		delete(b.pending, userID)
+	}
+}
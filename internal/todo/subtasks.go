@@ -0,0 +1,265 @@
+package todo
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MaxTodoDepth is how many levels todos can be nested, counting the top-level todo
+const MaxTodoDepth = 3
+
+// DeleteMode decides what happens to the subtasks of a deleted todo
+type DeleteMode string
+
+const (
+	// DeleteCascade deletes the subtasks along with the todo
+	DeleteCascade DeleteMode = "cascade"
+	// DeleteReparent moves the subtasks up to the todo's own parent
+	DeleteReparent DeleteMode = "reparent"
+)
+
+// IsValid returns true if the delete mode is known
+func (m DeleteMode) IsValid() bool {
+	return m == DeleteCascade || m == DeleteReparent
+}
+
+// TodoProgress counts the direct subtasks of a todo
+type TodoProgress struct {
+	Completed int `json:"completed"`
+	Total     int `json:"total"`
+}
+
+// DeleteResult lists the todos changed by a delete
+type DeleteResult struct {
+	// DeletedIDs holds the todo and, with DeleteCascade, all of its subtasks
+	DeletedIDs []int
+	// Reparented holds the subtasks moved up by DeleteReparent
+	Reparented []*Todo
+}
+
+// GetChildren lists the direct subtasks of one of the user's todos, oldest first
+func (r *TodoRepository) GetChildren(ctx context.Context, todoID, userID int) ([]*Todo, error) {
+	query := `SELECT ` + todoColumns + ` FROM todos WHERE parent_id = $1 AND user_id = $2 ORDER BY created_at, id`
+
+	rows, err := r.db.Query(ctx, query, todoID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subtasks: %w", err)
+	}
+
+	todos, err := scanTodos(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loadRelations(ctx, r.db, todos...); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+// GetAncestorIDs returns the ID of one of the user's todos followed by the IDs of its
+// parent, grandparent and so on up to the top-level todo
+func (r *TodoRepository) GetAncestorIDs(ctx context.Context, todoID, userID int) ([]int, error) {
+	// The depth guard stops the walk should the hierarchy ever contain a cycle
+	query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id, 1 AS depth FROM todos WHERE id = $1 AND user_id = $2
+			UNION ALL
+			SELECT t.id, t.parent_id, a.depth + 1
+			FROM todos t JOIN ancestors a ON t.id = a.parent_id
+			WHERE a.depth <= $3
+		)
+		SELECT id FROM ancestors ORDER BY depth
+	`
+
+	rows, err := r.db.Query(ctx, query, todoID, userID, MaxTodoDepth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query todo ancestors: %w", err)
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan todo ancestors: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return nil, ErrTodoNotFound
+	}
+
+	return ids, nil
+}
+
+// GetSubtreeDepth returns how many levels one of the user's todos spans with its subtasks,
+// 1 for a todo without subtasks
+func (r *TodoRepository) GetSubtreeDepth(ctx context.Context, todoID, userID int) (int, error) {
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id, 1 AS depth FROM todos WHERE id = $1 AND user_id = $2
+			UNION ALL
+			SELECT t.id, s.depth + 1
+			FROM todos t JOIN subtree s ON t.parent_id = s.id
+			WHERE s.depth <= $3
+		)
+		SELECT COALESCE(MAX(depth), 0) FROM subtree
+	`
+
+	depth := 0
+	if err := r.db.QueryRow(ctx, query, todoID, userID, MaxTodoDepth).Scan(&depth); err != nil {
+		return 0, fmt.Errorf("failed to get subtree depth: %w", err)
+	}
+
+	if depth == 0 {
+		return 0, ErrTodoNotFound
+	}
+
+	return depth, nil
+}
+
+// SetSubtasksCompleted marks every subtask of one of the user's todos, at any depth, as
+// completed or pending. Only the subtasks that changed are returned.
+func (r *TodoRepository) SetSubtasksCompleted(ctx context.Context, todoID, userID int, completed bool) ([]*Todo, error) {
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM todos WHERE parent_id = $1 AND user_id = $2
+			UNION
+			SELECT t.id FROM todos t JOIN subtree s ON t.parent_id = s.id
+		)
+		UPDATE todos
+		SET completed = $3, updated_at = NOW()
+		WHERE id IN (SELECT id FROM subtree) AND completed <> $3
+		RETURNING ` + todoColumns
+
+	rows, err := r.db.Query(ctx, query, todoID, userID, completed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update subtasks: %w", err)
+	}
+
+	todos, err := scanTodos(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loadRelations(ctx, r.db, todos...); err != nil {
+		return nil, err
+	}
+
+	for _, todo := range todos {
+		r.notify(ctx, EventToggled, userID, todo.ID, todo)
+	}
+
+	return todos, nil
+}
+
+// loadProgress fills in the subtask progress of todos with a single query
+func loadProgress(ctx context.Context, db querier, todos ...*Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*Todo, len(todos))
+	todoIDs := make([]int, len(todos))
+	for i, todo := range todos {
+		todo.Progress = TodoProgress{}
+		byID[todo.ID] = todo
+		todoIDs[i] = todo.ID
+	}
+
+	query := `
+		SELECT parent_id, COUNT(*) FILTER (WHERE completed), COUNT(*)
+		FROM todos
+		WHERE parent_id = ANY($1)
+		GROUP BY parent_id
+	`
+
+	rows, err := db.Query(ctx, query, todoIDs)
+	if err != nil {
+		return fmt.Errorf("failed to query subtask progress: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var parentID int
+		var progress TodoProgress
+		if err := rows.Scan(&parentID, &progress.Completed, &progress.Total); err != nil {
+			return fmt.Errorf("failed to scan subtask progress: %w", err)
+		}
+		if todo, ok := byID[parentID]; ok {
+			todo.Progress = progress
+		}
+	}
+
+	return rows.Err()
+}
+
+// scanTodos scans and closes rows selected with todoColumns
+func scanTodos(rows pgx.Rows) ([]*Todo, error) {
+	defer rows.Close()
+
+	todos := []*Todo{}
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan todo: %w", err)
+		}
+		todos = append(todos, todo)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate todos: %w", err)
+	}
+
+	return todos, nil
+}
+
+// GetSubtasks lists the direct subtasks of one of the user's todos, oldest first
+func (s *TodoService) GetSubtasks(ctx context.Context, todoID, userID int) ([]*Todo, error) {
+	if todoID <= 0 {
+		return nil, ErrInvalidTodoInput
+	}
+
+	todos, err := s.repo.GetChildren(ctx, todoID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subtasks: %w", err)
+	}
+
+	return todos, nil
+}
+
+// checkParentTarget returns the todo that would become the parent, or an error unless the
+// todo can be nested under it. todoID is 0 for a todo that doesn't exist yet.
+func (s *TodoService) checkParentTarget(ctx context.Context, userID, todoID, parentID int) (*Todo, error) {
+	parent, err := s.repo.GetByID(ctx, parentID, userID)
+	if err != nil {
+		if err == ErrTodoNotFound {
+			return nil, fmt.Errorf("parent todo %d: %w", parentID, ErrTodoNotFound)
+		}
+		return nil, fmt.Errorf("failed to get parent todo: %w", err)
+	}
+
+	ancestors, err := s.repo.GetAncestorIDs(ctx, parentID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get parent todo ancestors: %w", err)
+	}
+
+	if slices.Contains(ancestors, todoID) {
+		return nil, ErrTodoCycle
+	}
+
+	// The todo brings its own subtasks along
+	depth := 1
+	if todoID != 0 {
+		if depth, err = s.repo.GetSubtreeDepth(ctx, todoID, userID); err != nil {
+			return nil, fmt.Errorf("failed to get subtask depth: %w", err)
+		}
+	}
+
+	if len(ancestors)+depth > MaxTodoDepth {
+		return nil, ErrTodoTooDeep
+	}
+
+	return parent, nil
+}
@@ -0,0 +1,356 @@
+package todo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DefaultColor is used for tags and projects created without a color
+const DefaultColor = "#6B7280"
+
+// uniqueViolation is the Postgres error code for a unique constraint violation
+const uniqueViolation = "23505"
+
+// Tag is a user-defined label that can be attached to any number of todos
+type Tag struct {
+	ID        int       `db:"id" json:"id"`
+	UserID    int       `db:"user_id" json:"user_id"`
+	Name      string    `db:"name" json:"name"`
+	Color     string    `db:"color" json:"color"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// CreateTagInput represents input for creating a new tag
+type CreateTagInput struct {
+	Name string `json:"name"`
+	// Color is a #RRGGBB hex color, DefaultColor when empty
+	Color string `json:"color,omitempty"`
+}
+
+// UpdateTagInput represents input for renaming or recoloring a tag
+type UpdateTagInput struct {
+	Name  *string `json:"name,omitempty"`
+	Color *string `json:"color,omitempty"`
+}
+
+// CreateTag creates a new tag for the user
+func (r *TodoRepository) CreateTag(ctx context.Context, userID int, input CreateTagInput) (*Tag, error) {
+	query := `
+		INSERT INTO tags (user_id, name, color, created_at, updated_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		RETURNING ` + tagColumns
+
+	tag, err := scanTag(r.db.QueryRow(ctx, query, userID, input.Name, input.Color))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrTagNameTaken
+		}
+		return nil, fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	return tag, nil
+}
+
+// GetTagsByUserID lists the user's tags ordered by name
+func (r *TodoRepository) GetTagsByUserID(ctx context.Context, userID int) ([]*Tag, error) {
+	query := `SELECT ` + tagColumns + ` FROM tags WHERE user_id = $1 ORDER BY LOWER(name), id`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []*Tag{}
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// UpdateTag renames or recolors one of the user's tags
+func (r *TodoRepository) UpdateTag(ctx context.Context, tagID, userID int, input UpdateTagInput) (*Tag, error) {
+	query := `
+		UPDATE tags
+		SET name = COALESCE($3, name), color = COALESCE($4, color), updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING ` + tagColumns
+
+	tag, err := scanTag(r.db.QueryRow(ctx, query, tagID, userID, input.Name, input.Color))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrTagNotFound
+		}
+		if isUniqueViolation(err) {
+			return nil, ErrTagNameTaken
+		}
+		return nil, fmt.Errorf("failed to update tag: %w", err)
+	}
+
+	return tag, nil
+}
+
+// DeleteTag deletes one of the user's tags, detaching it from every todo.
+// The todos it was detached from are returned.
+func (r *TodoRepository) DeleteTag(ctx context.Context, tagID, userID int) ([]*Todo, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tag delete: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE todos
+		SET updated_at = NOW()
+		WHERE id IN (SELECT todo_id FROM todo_tags WHERE tag_id = $1) AND user_id = $2
+		RETURNING ` + todoColumns
+
+	rows, err := tx.Query(ctx, query, tagID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detach tag: %w", err)
+	}
+	detached, err := scanTodos(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM tags WHERE id = $1 AND user_id = $2`, tagID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete tag: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return nil, ErrTagNotFound
+	}
+
+	// Loaded after the delete, so the tag is gone from the todos
+	if err := loadRelations(ctx, tx, detached...); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit tag delete: %w", err)
+	}
+
+	for _, todo := range detached {
+		r.notify(ctx, EventUpdated, userID, todo.ID, todo)
+	}
+
+	return detached, nil
+}
+
+// querier is satisfied by both the pool and a transaction
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// loadTags fills in the tags of todos with a single query
+func loadTags(ctx context.Context, db querier, todos ...*Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*Todo, len(todos))
+	todoIDs := make([]int, len(todos))
+	for i, todo := range todos {
+		todo.Tags = []*Tag{}
+		byID[todo.ID] = todo
+		todoIDs[i] = todo.ID
+	}
+
+	query := `
+		SELECT tt.todo_id, t.id, t.user_id, t.name, t.color, t.created_at, t.updated_at
+		FROM todo_tags tt
+		JOIN tags t ON t.id = tt.tag_id
+		WHERE tt.todo_id = ANY($1)
+		ORDER BY LOWER(t.name), t.id
+	`
+
+	rows, err := db.Query(ctx, query, todoIDs)
+	if err != nil {
+		return fmt.Errorf("failed to query todo tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var todoID int
+		var tag Tag
+		if err := rows.Scan(&todoID, &tag.ID, &tag.UserID, &tag.Name, &tag.Color, &tag.CreatedAt, &tag.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to scan todo tag: %w", err)
+		}
+		if todo, ok := byID[todoID]; ok {
+			todo.Tags = append(todo.Tags, &tag)
+		}
+	}
+
+	return rows.Err()
+}
+
+// updateTodoTags attaches and detaches tags on one of the user's todos.
+// Tags of other users are never attached.
+func updateTodoTags(ctx context.Context, tx pgx.Tx, todoID, userID int, add, remove []int) error {
+	if len(add) > 0 {
+		query := `
+			INSERT INTO todo_tags (todo_id, tag_id)
+			SELECT $1, id FROM tags WHERE user_id = $2 AND id = ANY($3)
+			ON CONFLICT DO NOTHING
+		`
+		if _, err := tx.Exec(ctx, query, todoID, userID, add); err != nil {
+			return fmt.Errorf("failed to add tags: %w", err)
+		}
+	}
+
+	if len(remove) > 0 {
+		if _, err := tx.Exec(ctx, `DELETE FROM todo_tags WHERE todo_id = $1 AND tag_id = ANY($2)`, todoID, remove); err != nil {
+			return fmt.Errorf("failed to remove tags: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// tagColumns is the column list scanned by scanTag
+const tagColumns = `id, user_id, name, color, created_at, updated_at`
+
+// scanTag scans a row selected with tagColumns
+func scanTag(row pgx.Row) (*Tag, error) {
+	var tag Tag
+	err := row.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.Color, &tag.CreatedAt, &tag.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+// isUniqueViolation reports whether err is a Postgres unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// CreateTag creates a new tag with validation
+func (s *TodoService) CreateTag(ctx context.Context, userID int, input CreateTagInput) (*Tag, error) {
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Color == "" {
+		input.Color = DefaultColor
+	}
+	input.Color = strings.ToUpper(input.Color)
+
+	if err := s.validator.ValidateTagInput(ctx, input); err != nil {
+		return nil, fmt.Errorf("failed to validate input: %w", err)
+	}
+
+	tag, err := s.repo.CreateTag(ctx, userID, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	return tag, nil
+}
+
+// GetUserTags lists the user's tags ordered by name
+func (s *TodoService) GetUserTags(ctx context.Context, userID int) ([]*Tag, error) {
+	tags, err := s.repo.GetTagsByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+// UpdateTag renames or recolors a tag with validation and ownership check
+func (s *TodoService) UpdateTag(ctx context.Context, tagID, userID int, input UpdateTagInput) (*Tag, error) {
+	if tagID <= 0 {
+		return nil, ErrTagNotFound
+	}
+
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		input.Name = &name
+	}
+	if input.Color != nil {
+		color := strings.ToUpper(*input.Color)
+		input.Color = &color
+	}
+
+	if err := s.validator.ValidateTagUpdateInput(ctx, input); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	tag, err := s.repo.UpdateTag(ctx, tagID, userID, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update tag: %w", err)
+	}
+
+	return tag, nil
+}
+
+// DeleteTag deletes a tag with ownership check, detaching it from every todo
+func (s *TodoService) DeleteTag(ctx context.Context, tagID, userID int) error {
+	if tagID <= 0 {
+		return ErrTagNotFound
+	}
+
+	detached, err := s.repo.DeleteTag(ctx, tagID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+
+	for _, todo := range detached {
+		s.publish(EventUpdated, userID, todo.ID, todo)
+	}
+
+	return nil
+}
+
+// AddTags attaches the user's tags to one of their todos
+func (s *TodoService) AddTags(ctx context.Context, todoID, userID int, tagIDs []int) (*Todo, error) {
+	return s.UpdateTodo(ctx, todoID, userID, UpdateTodoInput{AddTagIDs: tagIDs})
+}
+
+// RemoveTags detaches tags from one of the user's todos
+func (s *TodoService) RemoveTags(ctx context.Context, todoID, userID int, tagIDs []int) (*Todo, error) {
+	return s.UpdateTodo(ctx, todoID, userID, UpdateTodoInput{RemoveTagIDs: tagIDs})
+}
+
+// checkTagOwnership returns ErrTagNotFound unless every tag the update mentions belongs to the user
+func (s *TodoService) checkTagOwnership(ctx context.Context, userID int, input UpdateTodoInput) error {
+	if len(input.AddTagIDs) == 0 && len(input.RemoveTagIDs) == 0 {
+		return nil
+	}
+
+	tags, err := s.repo.GetTagsByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get tags: %w", err)
+	}
+
+	owned := make(map[int]bool, len(tags))
+	for _, tag := range tags {
+		owned[tag.ID] = true
+	}
+
+	for _, tagID := range append(append([]int{}, input.AddTagIDs...), input.RemoveTagIDs...) {
+		if !owned[tagID] {
+			return fmt.Errorf("tag %d: %w", tagID, ErrTagNotFound)
+		}
+	}
+
+	return nil
+}
@@ -1,6 +1,13 @@
 package todo
 
-import "context"
+import (
+	"context"
+	"regexp"
+	"slices"
+)
+
+// colorPattern matches the #RRGGBB hex colors of tags and projects
+var colorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
 
 // ValidatorService handles todo input validation
 type ValidatorService struct{}
@@ -22,16 +29,52 @@ func (v *ValidatorService) ValidateTodoInput(ctx context.Context, input CreateTo
 		}
 	}
 
+	if input.AllDay && input.DueAt == nil {
+		return ErrTodoAllDayWithoutDueDate
+	}
+
+	if !input.Priority.IsValid() {
+		return ErrInvalidTodoPriority
+	}
+
 	return nil
 }
 
 // ValidateUpdateInput validates update todo input
 func (v *ValidatorService) ValidateUpdateInput(ctx context.Context, input UpdateTodoInput) error {
 	// At least one field should be provided for update
-	if input.Completed == nil && input.Description == nil && input.Title == nil {
+	if input.Completed == nil && input.Description == nil && input.Title == nil &&
+		input.DueAt == nil && !input.AllDay && !input.ClearDueAt && input.Priority == nil &&
+		input.ProjectID == nil && input.ParentID == nil && !input.ClearParent &&
+		len(input.AddTagIDs) == 0 && len(input.RemoveTagIDs) == 0 {
+		return ErrInvalidTodoInput
+	}
+
+	// A tag can't be added and removed at once
+	for _, tagID := range input.AddTagIDs {
+		if slices.Contains(input.RemoveTagIDs, tagID) {
+			return ErrInvalidTodoInput
+		}
+	}
+
+	// A due date can't be set and cleared at once
+	if input.DueAt != nil && input.ClearDueAt {
 		return ErrInvalidTodoInput
 	}
 
+	// Neither can a parent
+	if input.ParentID != nil && input.ClearParent {
+		return ErrInvalidTodoInput
+	}
+
+	if input.AllDay && input.DueAt == nil {
+		return ErrTodoAllDayWithoutDueDate
+	}
+
+	if input.Priority != nil && !input.Priority.IsValid() {
+		return ErrInvalidTodoPriority
+	}
+
 	if input.Title != nil {
 		if err := v.validateTitle(*input.Title); err != nil {
 			return err
@@ -47,6 +90,126 @@ func (v *ValidatorService) ValidateUpdateInput(ctx context.Context, input Update
 	return nil
 }
 
+// ValidateFilter validates the priorities and sort order of a todo query
+func (v *ValidatorService) ValidateFilter(ctx context.Context, filter TodoFilter) error {
+	for _, priority := range filter.Priorities {
+		if !priority.IsValid() {
+			return ErrInvalidTodoPriority
+		}
+	}
+
+	if filter.Sort != (TodoSort{}) && !filter.Sort.IsValid() {
+		return ErrInvalidTodoSort
+	}
+
+	return nil
+}
+
+// ValidateTagInput validates create tag input
+func (v *ValidatorService) ValidateTagInput(ctx context.Context, input CreateTagInput) error {
+	if err := v.validateTagName(input.Name); err != nil {
+		return err
+	}
+
+	return v.validateTagColor(input.Color)
+}
+
+// ValidateTagUpdateInput validates update tag input
+func (v *ValidatorService) ValidateTagUpdateInput(ctx context.Context, input UpdateTagInput) error {
+	if input.Name == nil && input.Color == nil {
+		return ErrInvalidTodoInput
+	}
+
+	if input.Name != nil {
+		if err := v.validateTagName(*input.Name); err != nil {
+			return err
+		}
+	}
+
+	if input.Color != nil {
+		if err := v.validateTagColor(*input.Color); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateTagName validates tag name
+func (v *ValidatorService) validateTagName(name string) error {
+	if name == "" {
+		return ErrTagNameRequired
+	}
+
+	if len([]rune(name)) > 50 {
+		return ErrTagNameTooLong
+	}
+
+	return nil
+}
+
+// validateTagColor validates tag color
+func (v *ValidatorService) validateTagColor(color string) error {
+	if !colorPattern.MatchString(color) {
+		return ErrInvalidTagColor
+	}
+
+	return nil
+}
+
+// ValidateProjectInput validates create project input
+func (v *ValidatorService) ValidateProjectInput(ctx context.Context, input CreateProjectInput) error {
+	if err := v.validateProjectName(input.Name); err != nil {
+		return err
+	}
+
+	if !colorPattern.MatchString(input.Color) {
+		return ErrInvalidProjectColor
+	}
+
+	if input.Position != nil && *input.Position < 0 {
+		return ErrInvalidProjectPosition
+	}
+
+	return nil
+}
+
+// ValidateProjectUpdateInput validates update project input
+func (v *ValidatorService) ValidateProjectUpdateInput(ctx context.Context, input UpdateProjectInput) error {
+	if input.Name == nil && input.Color == nil && input.Archived == nil && input.Position == nil {
+		return ErrInvalidTodoInput
+	}
+
+	if input.Name != nil {
+		if err := v.validateProjectName(*input.Name); err != nil {
+			return err
+		}
+	}
+
+	if input.Color != nil && !colorPattern.MatchString(*input.Color) {
+		return ErrInvalidProjectColor
+	}
+
+	if input.Position != nil && *input.Position < 0 {
+		return ErrInvalidProjectPosition
+	}
+
+	return nil
+}
+
+// validateProjectName validates project name
+func (v *ValidatorService) validateProjectName(name string) error {
+	if name == "" {
+		return ErrProjectNameRequired
+	}
+
+	if len([]rune(name)) > 100 {
+		return ErrProjectNameTooLong
+	}
+
+	return nil
+}
+
 // ValidateTitle validates todo title
 func (v *ValidatorService) validateTitle(title string) error {
 	if title == "" {
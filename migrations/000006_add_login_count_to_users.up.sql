@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN login_count INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,16 @@
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP WITH TIME ZONE;
+
+-- Accounts created before verification existed keep working under every unverified email policy
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    used_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_user_id ON email_verification_tokens(user_id);
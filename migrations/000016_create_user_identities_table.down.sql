@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS oidc_login_states;
+DROP TABLE IF EXISTS user_identities;
+
+-- Fails while users without a password exist; they have to set one or be removed first
+ALTER TABLE users ALTER COLUMN password_hash SET NOT NULL;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_todos_user_id_due_at;
+
+ALTER TABLE todos DROP COLUMN IF EXISTS all_day;
+ALTER TABLE todos DROP COLUMN IF EXISTS due_at;
+
+ALTER TABLE users DROP COLUMN IF EXISTS time_zone;
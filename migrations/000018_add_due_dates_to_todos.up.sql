@@ -0,0 +1,8 @@
+-- Overdue and due-today filters are decided in the user's time zone
+ALTER TABLE users ADD COLUMN time_zone TEXT NOT NULL DEFAULT 'UTC';
+
+-- All-day todos store their due date at midnight UTC
+ALTER TABLE todos ADD COLUMN due_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE todos ADD COLUMN all_day BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_todos_user_id_due_at ON todos(user_id, due_at);
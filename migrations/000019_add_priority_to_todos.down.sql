@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_todos_user_id_priority;
+
+ALTER TABLE todos DROP COLUMN IF EXISTS priority;
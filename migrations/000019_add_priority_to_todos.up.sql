@@ -0,0 +1,4 @@
+-- 0 none, 1 low, 2 medium, 3 high, 4 urgent, so sorting by priority sorts by urgency
+ALTER TABLE todos ADD COLUMN priority SMALLINT NOT NULL DEFAULT 0 CHECK (priority BETWEEN 0 AND 4);
+
+CREATE INDEX IF NOT EXISTS idx_todos_user_id_priority ON todos(user_id, priority);
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS projects (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    color VARCHAR(7) NOT NULL DEFAULT '#6B7280',
+    archived BOOLEAN NOT NULL DEFAULT FALSE,
+    position INTEGER NOT NULL DEFAULT 0,
+    is_inbox BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_projects_user_id_position ON projects(user_id, position);
+
+-- Each user has one inbox, which holds todos created without a project
+CREATE UNIQUE INDEX IF NOT EXISTS idx_projects_user_id_inbox ON projects(user_id) WHERE is_inbox;
+
+INSERT INTO projects (user_id, name, is_inbox)
+SELECT id, 'Inbox', TRUE FROM users
+ON CONFLICT (user_id) WHERE is_inbox DO NOTHING;
+
+-- Existing todos move into their owner's inbox
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE;
+
+UPDATE todos SET project_id = projects.id
+FROM projects
+WHERE projects.user_id = todos.user_id AND projects.is_inbox AND todos.project_id IS NULL;
+
+ALTER TABLE todos ALTER COLUMN project_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_todos_project_id ON todos(project_id);
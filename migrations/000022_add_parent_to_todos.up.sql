@@ -0,0 +1,4 @@
+-- Subtasks point at their parent todo and are deleted along with it
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES todos(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_todos_parent_id ON todos(parent_id);
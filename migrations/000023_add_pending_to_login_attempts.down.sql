@@ -0,0 +1,2 @@
+ALTER TABLE login_attempts DROP COLUMN IF EXISTS reserved_at;
+ALTER TABLE login_attempts DROP COLUMN IF EXISTS pending;
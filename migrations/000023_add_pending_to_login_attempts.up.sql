@@ -0,0 +1,4 @@
+-- Logins in progress are counted apart from confirmed failures, so attempts that succeed
+-- don't move last_failure_at. Reservations older than the lockout window are ignored.
+ALTER TABLE login_attempts ADD COLUMN IF NOT EXISTS pending INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE login_attempts ADD COLUMN IF NOT EXISTS reserved_at TIMESTAMP WITH TIME ZONE;